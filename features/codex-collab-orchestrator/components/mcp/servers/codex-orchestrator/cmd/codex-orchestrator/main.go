@@ -33,22 +33,22 @@ var toolGroups = []toolGroup{
 	{
 		Name:        "orch_task",
 		Description: "Task lifecycle, case execution, and resume management",
-		Methods:     []string{"task.create", "task.list", "task.get", "case.begin", "step.check", "case.complete", "resume.next", "resume.candidates.list", "resume.candidates.attach"},
+		Methods:     []string{"task.create", "task.list", "task.get", "task.tree", "task.update", "task.cancel", "task.block", "task.unblock", "task.move", "task.delete", "case.begin", "step.check", "case.complete", "resume.next", "resume.candidates.list", "resume.candidates.attach"},
 	},
 	{
 		Name:        "orch_graph",
 		Description: "Dependency graph, checklists, and snapshots",
-		Methods:     []string{"graph.node.create", "graph.node.list", "graph.edge.create", "graph.checklist.upsert", "graph.snapshot.create"},
+		Methods:     []string{"graph.node.create", "graph.node.list", "graph.node.update", "graph.node.delete", "graph.node.history", "graph.node.link_task", "graph.node.unlink_task", "graph.edge.create", "graph.edge.delete", "graph.export", "graph.checklist.upsert", "graph.checklist.reorder", "graph.checklist.delete", "graph.snapshot.create"},
 	},
 	{
 		Name:        "orch_workspace",
 		Description: "Worktree scheduling, creation, merging, and lock management",
-		Methods:     []string{"scheduler.decide_worktree", "worktree.create", "worktree.list", "worktree.spawn", "worktree.merge_to_parent", "lock.acquire", "lock.heartbeat", "lock.release"},
+		Methods:     []string{"scheduler.decide_worktree", "worktree.create", "worktree.list", "worktree.spawn", "worktree.merge_to_parent", "lock.acquire", "lock.heartbeat", "lock.release", "lock.list", "lock.audit"},
 	},
 	{
 		Name:        "orch_thread",
-		Description: "Child thread spawning, directives, and lifecycle control",
-		Methods:     []string{"thread.child.spawn", "thread.child.directive", "thread.child.list", "thread.child.interrupt", "thread.child.stop", "thread.child.status", "thread.child.wait_status", "thread.attach_info"},
+		Description: "Child thread spawning, directives, lifecycle control, transcripts, and supervision",
+		Methods:     []string{"thread.child.spawn", "thread.child.directive", "thread.child.answer", "thread.child.list", "thread.child.interrupt", "thread.child.stop", "thread.child.status", "thread.child.wait_status", "thread.directive.list", "thread.transcript.get", "thread.attach_info", "thread.supervisor.get", "thread.supervisor.update", "thread.supervisor.sweep"},
 	},
 	{
 		Name:        "orch_lifecycle",
@@ -58,7 +58,7 @@ var toolGroups = []toolGroup{
 	{
 		Name:        "orch_merge",
 		Description: "Branch merge requests, reviews, and main-line merge operations",
-		Methods:     []string{"merge.request", "merge.review_context", "merge.review.request_auto", "merge.review.thread_status", "merge.main.request", "merge.main.next", "merge.main.status", "merge.main.acquire_lock", "merge.main.release_lock"},
+		Methods:     []string{"merge.request", "merge.review_context", "merge.review.request_auto", "merge.review.submit", "merge.review.thread_status", "merge.main.request", "merge.main.next", "merge.main.status", "merge.main.acquire_lock", "merge.main.release_lock"},
 	},
 	{
 		Name:        "orch_inbox",
@@ -67,8 +67,8 @@ var toolGroups = []toolGroup{
 	},
 	{
 		Name:        "orch_system",
-		Description: "Runtime, mirror, and plan management utilities",
-		Methods:     []string{"runtime.tmux.ensure", "runtime.bundle.info", "mirror.status", "mirror.refresh", "plan.bootstrap", "plan.slice.generate", "plan.slice.replan", "plan.rollup.preview", "plan.rollup.submit", "plan.rollup.approve", "plan.rollup.reject"},
+		Description: "Runtime, mirror, plan management, search, usage metrics, and log retention utilities",
+		Methods:     []string{"runtime.tmux.ensure", "runtime.bundle.info", "mirror.status", "mirror.refresh", "plan.bootstrap", "plan.slice.generate", "plan.slice.replan", "plan.import", "plan.template.apply", "plan.ready", "plan.analyze", "plan.dispatch", "plan.rules.get", "plan.rules.update", "plan.rollup.preview", "plan.rollup.submit", "plan.rollup.approve", "plan.rollup.reject", "plan.rollup.approvals", "search.query", "metrics.usage", "metrics.budget.set", "logs.gc"},
 	},
 }
 
@@ -132,11 +132,22 @@ const (
 
 func main() {
 	repoPath := flag.String("repo", ".", "repository root path")
-	mode := flag.String("mode", "serve", "execution mode: serve|once")
+	mode := flag.String("mode", "serve", "execution mode: serve|once|provider-check|provider-promote")
 	transport := flag.String("transport", "stdio", "transport mode: stdio|http")
 	port := flag.Int("port", 8090, "HTTP port (only used with --transport http)")
 	method := flag.String("method", "", "method for once mode")
 	params := flag.String("params", "{}", "JSON params for once mode")
+	supervise := flag.Bool("supervise", true, "run the background child thread supervisor (serve mode only)")
+	childBackend := flag.String("child-backend", "tmux", "default child runner backend: tmux|headless|auto")
+	fixtureDir := flag.String("fixtures", "", "provider fixture corpus directory (default <repo>/.codex-orch/provider-fixtures)")
+	capturePath := flag.String("capture", "", "new pane capture to probe with every provider (provider-check mode)")
+	providerType := flag.String("provider", "", "provider filter for provider-check, provider type for provider-promote")
+	threadID := flag.Int64("thread", 0, "thread whose log is promoted (provider-promote mode)")
+	logPath := flag.String("log", "", "log file to promote instead of the thread log (provider-promote mode)")
+	fixtureName := flag.String("name", "", "fixture name (provider-promote mode)")
+	expectedStatus := flag.String("status", "", "expected status override (provider-promote mode)")
+	expectedResponse := flag.String("response", "", "expected response override (provider-promote mode)")
+	captureLines := flag.Int("lines", 200, "log tail lines kept in the fixture (provider-promote mode)")
 	flag.Parse()
 
 	service, err := orchestrator.NewService(*repoPath)
@@ -145,11 +156,40 @@ func main() {
 		os.Exit(1)
 	}
 	defer service.Close()
+	if err := service.SetDefaultRunnerBackend(*childBackend); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --child-backend: %v\n", err)
+		os.Exit(2)
+	}
 
 	switch strings.ToLower(*mode) {
 	case "once":
 		runOnce(service, *method, *params)
+	case "provider-check":
+		runProviderCheck(service, orchestrator.ProviderCheckOptions{
+			FixtureDir:  *fixtureDir,
+			CapturePath: *capturePath,
+			Provider:    *providerType,
+		})
+	case "provider-promote":
+		options := orchestrator.ProviderPromoteOptions{
+			ThreadID:   *threadID,
+			LogPath:    *logPath,
+			Provider:   *providerType,
+			Name:       *fixtureName,
+			Status:     *expectedStatus,
+			Lines:      *captureLines,
+			FixtureDir: *fixtureDir,
+		}
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "response" {
+				options.Response = expectedResponse
+			}
+		})
+		runProviderPromote(service, options)
 	case "serve":
+		if *supervise {
+			service.StartSupervisor(context.Background())
+		}
 		switch strings.ToLower(*transport) {
 		case "http":
 			runHTTPServe(service, *port)
@@ -199,6 +239,38 @@ func runOnce(service *orchestrator.Service, method, params string) {
 	}
 }
 
+// runProviderCheck prints the corpus report and exits 1 when any fixture
+// fails, so it can gate CI.
+func runProviderCheck(service *orchestrator.Service, options orchestrator.ProviderCheckOptions) {
+	report, err := service.ProviderCheck(context.Background(), options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "provider-check failed: %v\n", err)
+		os.Exit(1)
+	}
+	printJSON(report)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runProviderPromote(service *orchestrator.Service, options orchestrator.ProviderPromoteOptions) {
+	result, err := service.PromoteProviderFixture(context.Background(), options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "provider-promote failed: %v\n", err)
+		os.Exit(1)
+	}
+	printJSON(result)
+}
+
+func printJSON(value any) {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode response: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(encoded))
+}
+
 func runServe(service *orchestrator.Service) {
 	reader := framedReader{reader: bufio.NewReader(os.Stdin)}
 	writer := framedWriter{writer: bufio.NewWriter(os.Stdout)}
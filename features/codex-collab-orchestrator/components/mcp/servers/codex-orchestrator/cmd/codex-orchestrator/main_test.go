@@ -189,14 +189,14 @@ func TestNoMethodDuplicates(t *testing.T) {
 func TestToolGroupMethodCounts(t *testing.T) {
 	expectedCounts := map[string]int{
 		"orch_session":   7, // workspace.init, session.open, session.heartbeat, session.close, session.cleanup, session.list, session.context
-		"orch_task":      9, // task.create, task.list, task.get, case.begin, step.check, case.complete, resume.next, resume.candidates.list, resume.candidates.attach
-		"orch_graph":     5, // graph.node.create, graph.node.list, graph.edge.create, graph.checklist.upsert, graph.snapshot.create
-		"orch_workspace": 8, // scheduler.decide_worktree, worktree.create, worktree.list, worktree.spawn, worktree.merge_to_parent, lock.acquire, lock.heartbeat, lock.release
-		"orch_thread":    8, // thread.child.spawn, thread.child.directive, thread.child.list, thread.child.interrupt, thread.child.stop, thread.child.status, thread.child.wait_status, thread.attach_info
+		"orch_task":      16, // task.create, task.list, task.get, task.tree, task.update, task.cancel, task.block, task.unblock, task.move, task.delete, case.begin, step.check, case.complete, resume.next, resume.candidates.list, resume.candidates.attach
+		"orch_graph":     14, // graph.node.create, graph.node.list, graph.node.update, graph.node.delete, graph.node.history, graph.node.link_task, graph.node.unlink_task, graph.edge.create, graph.edge.delete, graph.export, graph.checklist.upsert, graph.checklist.reorder, graph.checklist.delete, graph.snapshot.create
+		"orch_workspace": 10, // scheduler.decide_worktree, worktree.create, worktree.list, worktree.spawn, worktree.merge_to_parent, lock.acquire, lock.heartbeat, lock.release, lock.list, lock.audit
+		"orch_thread":    14, // thread.child.spawn, thread.child.directive, thread.child.answer, thread.child.list, thread.child.interrupt, thread.child.stop, thread.child.status, thread.child.wait_status, thread.directive.list, thread.transcript.get, thread.attach_info, thread.supervisor.get, thread.supervisor.update, thread.supervisor.sweep
 		"orch_lifecycle": 2, // work.current_ref, work.current_ref.ack
-		"orch_merge":     9, // merge.request, merge.review_context, merge.review.request_auto, merge.review.thread_status, merge.main.request, merge.main.next, merge.main.status, merge.main.acquire_lock, merge.main.release_lock
+		"orch_merge":     10, // merge.request, merge.review_context, merge.review.request_auto, merge.review.submit, merge.review.thread_status, merge.main.request, merge.main.next, merge.main.status, merge.main.acquire_lock, merge.main.release_lock
 		"orch_inbox":     4, // inbox.send, inbox.pending, inbox.list, inbox.deliver
-		"orch_system":    11, // runtime.tmux.ensure, runtime.bundle.info, mirror.status, mirror.refresh, plan.bootstrap, plan.slice.generate, plan.slice.replan, plan.rollup.preview, plan.rollup.submit, plan.rollup.approve, plan.rollup.reject
+		"orch_system":    23, // runtime.tmux.ensure, runtime.bundle.info, mirror.status, mirror.refresh, plan.bootstrap, plan.slice.generate, plan.slice.replan, plan.import, plan.template.apply, plan.ready, plan.analyze, plan.dispatch, plan.rules.get, plan.rules.update, plan.rollup.preview, plan.rollup.submit, plan.rollup.approve, plan.rollup.reject, plan.rollup.approvals, search.query, metrics.usage, metrics.budget.set, logs.gc
 	}
 
 	for _, g := range toolGroups {
@@ -0,0 +1,47 @@
+package headless
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty allocates a pseudo-terminal pair from /dev/ptmx.
+func openPty() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, request := range []uint{unix.TIOCPTYGRANT, unix.TIOCPTYUNLK} {
+		if err := unix.IoctlSetInt(int(master.Fd()), request, 0); err != nil {
+			_ = master.Close()
+			return nil, nil, fmt.Errorf("unlock pty: %w", err)
+		}
+	}
+	name := make([]byte, 128)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, master.Fd(), uintptr(unix.TIOCPTYGNAME), uintptr(unsafe.Pointer(&name[0]))); errno != 0 {
+		_ = master.Close()
+		return nil, nil, fmt.Errorf("get pty name: %w", errno)
+	}
+	if end := bytes.IndexByte(name, 0); end >= 0 {
+		name = name[:end]
+	}
+	slave, err := os.OpenFile(string(name), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		_ = master.Close()
+		return nil, nil, err
+	}
+	// Full-screen agents lay out against the terminal size; without one they
+	// see 0x0 and render nothing useful.
+	_ = unix.IoctlSetWinsize(int(slave.Fd()), unix.TIOCSWINSZ, &unix.Winsize{Row: ptyRows, Col: ptyColumns})
+	// Keep line-mode output as plain newlines in the log, as with pipes.
+	if termios, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TIOCGETA); err == nil {
+		termios.Oflag &^= unix.ONLCR
+		_ = unix.IoctlSetTermios(int(slave.Fd()), unix.TIOCSETA, termios)
+	}
+	return master, slave, nil
+}
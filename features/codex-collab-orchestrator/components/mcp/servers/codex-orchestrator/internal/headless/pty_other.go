@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package headless
+
+import (
+	"errors"
+	"os"
+)
+
+// openPty is unsupported here; Start falls back to plain pipes, which only
+// suits agents that do not need a terminal.
+func openPty() (*os.File, *os.File, error) {
+	return nil, nil, errors.New("pseudo-terminals are not supported on this platform")
+}
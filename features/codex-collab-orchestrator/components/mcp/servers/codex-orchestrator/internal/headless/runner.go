@@ -0,0 +1,334 @@
+package headless
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StartOptions describes one headless agent process. Command runs through
+// `sh -c` on a pseudo-terminal so the same launch string used for tmux panes,
+// including full-screen TUIs, works unchanged.
+type StartOptions struct {
+	ThreadID    int64
+	Workdir     string
+	Command     string
+	LogFilePath string
+	Env         []string
+}
+
+// ProcessInfo is a snapshot of a managed process.
+type ProcessInfo struct {
+	ThreadID    int64  `json:"thread_id"`
+	PID         int    `json:"pid"`
+	Running     bool   `json:"running"`
+	ExitError   string `json:"exit_error,omitempty"`
+	LogFilePath string `json:"log_file_path"`
+	StartedAt   string `json:"started_at"`
+}
+
+const (
+	ptyRows    = 50
+	ptyColumns = 200
+	ptyTerm    = "xterm-256color"
+	// ptyDrainWindow bounds how long an exited process waits for the last
+	// terminal output to reach the log.
+	ptyDrainWindow = time.Second
+)
+
+type process struct {
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	terminal  bool
+	logPath   string
+	startedAt time.Time
+	done      chan struct{}
+	exitErr   error
+}
+
+// Runner starts agent processes without a terminal multiplexer. Output goes to
+// the thread log file; directives are written to the terminal input. Platforms
+// without pseudo-terminals fall back to plain pipes. Processes are tracked in
+// memory; one that outlives a server restart is an orphan, reachable only by
+// pid.
+type Runner struct {
+	mu        sync.Mutex
+	processes map[int64]*process
+}
+
+func NewRunner() *Runner {
+	return &Runner{processes: make(map[int64]*process)}
+}
+
+func (runner *Runner) Start(options StartOptions) (ProcessInfo, error) {
+	if options.ThreadID <= 0 {
+		return ProcessInfo{}, errors.New("thread id is required")
+	}
+	if strings.TrimSpace(options.Command) == "" {
+		return ProcessInfo{}, errors.New("command is required")
+	}
+	if strings.TrimSpace(options.LogFilePath) == "" {
+		return ProcessInfo{}, errors.New("log file path is required")
+	}
+
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	if existing, ok := runner.processes[options.ThreadID]; ok && existing.running() {
+		return ProcessInfo{}, fmt.Errorf("thread %d already has a running headless process (pid %d)", options.ThreadID, existing.cmd.Process.Pid)
+	}
+
+	logFile, err := os.OpenFile(options.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+
+	cmd := exec.Command("sh", "-c", options.Command)
+	cmd.Dir = options.Workdir
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("TERM"); !ok {
+		cmd.Env = append(cmd.Env, "TERM="+ptyTerm)
+	}
+	cmd.Env = append(cmd.Env, options.Env...)
+
+	// Interactive agents such as the codex TUI refuse to start without a
+	// terminal, so the process gets a pty whenever the platform has one; the
+	// master side is copied into the log and takes directives.
+	var stdin io.WriteCloser
+	master, slave, ptyErr := openPty()
+	if ptyErr == nil {
+		cmd.Stdin = slave
+		cmd.Stdout = slave
+		cmd.Stderr = slave
+		setTerminalSession(cmd)
+		stdin = master
+	} else {
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		setProcessGroup(cmd)
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			_ = logFile.Close()
+			return ProcessInfo{}, err
+		}
+	}
+	if err := cmd.Start(); err != nil {
+		if master != nil {
+			_ = master.Close()
+			_ = slave.Close()
+		}
+		_ = logFile.Close()
+		return ProcessInfo{}, fmt.Errorf("failed to start headless process: %w", err)
+	}
+
+	copied := make(chan struct{})
+	if master != nil {
+		_ = slave.Close()
+		go func() {
+			defer close(copied)
+			_, _ = io.Copy(logFile, master)
+		}()
+	} else {
+		close(copied)
+	}
+
+	started := &process{
+		cmd:       cmd,
+		stdin:     stdin,
+		terminal:  master != nil,
+		logPath:   options.LogFilePath,
+		startedAt: time.Now().UTC(),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		started.exitErr = cmd.Wait()
+		// A background child can keep the pty open after the shell exits;
+		// do not wait on it forever.
+		select {
+		case <-copied:
+		case <-time.After(ptyDrainWindow):
+		}
+		if master != nil {
+			_ = master.Close()
+		}
+		_ = logFile.Close()
+		close(started.done)
+	}()
+	runner.processes[options.ThreadID] = started
+	return started.info(options.ThreadID), nil
+}
+
+// Info reports the process bound to a thread; ok is false when this server
+// never started one (or it was already stopped).
+func (runner *Runner) Info(threadID int64) (ProcessInfo, bool) {
+	current := runner.lookup(threadID)
+	if current == nil {
+		return ProcessInfo{}, false
+	}
+	return current.info(threadID), true
+}
+
+func (runner *Runner) Alive(threadID int64) bool {
+	current := runner.lookup(threadID)
+	return current != nil && current.running()
+}
+
+// Orphans are agents started by an earlier server process. The runner lost
+// their terminal when that server exited, but the process group may still be
+// running, so it can be watched, interrupted and stopped by pid.
+
+// OrphanAlive reports whether the process group led by pid is still running.
+func OrphanAlive(pid int) bool {
+	return pid > 0 && groupAlive(pid)
+}
+
+// InterruptOrphan sends SIGINT to an orphan's process group.
+func InterruptOrphan(pid int) error {
+	return interruptGroup(pid)
+}
+
+// StopOrphan asks an orphan's process group to terminate and kills it if it
+// has not exited within grace.
+func StopOrphan(pid int, grace time.Duration) error {
+	return stopGroup(pid, grace)
+}
+
+// Send writes text followed by Enter to the process input.
+func (runner *Runner) Send(threadID int64, text string) error {
+	current, err := runner.live(threadID)
+	if err != nil {
+		return err
+	}
+	// A terminal in raw mode (as TUIs run it) submits on carriage return; in
+	// line mode the pty maps it to a newline anyway.
+	lineEnd := "\n"
+	if current.terminal {
+		lineEnd = "\r"
+	}
+	_, err = io.WriteString(current.stdin, strings.TrimSuffix(text, "\n")+lineEnd)
+	return err
+}
+
+// Interrupt sends SIGINT to the process group, the headless equivalent of C-c.
+func (runner *Runner) Interrupt(threadID int64) error {
+	current, err := runner.live(threadID)
+	if err != nil {
+		return err
+	}
+	return signalInterrupt(current.cmd)
+}
+
+// stdinExitWindow is how long Stop waits after closing stdin, so an agent that
+// was just sent its exit command (or exits on EOF) can shut down cleanly
+// before it is signalled.
+const stdinExitWindow = 500 * time.Millisecond
+
+// Stop closes stdin, asks the process group to terminate and kills it if it
+// has not exited within grace. The thread is forgotten either way.
+func (runner *Runner) Stop(threadID int64, grace time.Duration) error {
+	runner.mu.Lock()
+	current, ok := runner.processes[threadID]
+	delete(runner.processes, threadID)
+	runner.mu.Unlock()
+	if !ok || !current.running() {
+		return nil
+	}
+
+	_ = current.stdin.Close()
+	select {
+	case <-current.done:
+		return nil
+	case <-time.After(min(grace, stdinExitWindow)):
+	}
+	_ = signalTerminate(current.cmd)
+	select {
+	case <-current.done:
+		return nil
+	case <-time.After(grace):
+	}
+	if err := signalKill(current.cmd); err != nil {
+		return err
+	}
+	<-current.done
+	return nil
+}
+
+func (runner *Runner) StopAll(grace time.Duration) {
+	runner.mu.Lock()
+	threadIDs := make([]int64, 0, len(runner.processes))
+	for threadID := range runner.processes {
+		threadIDs = append(threadIDs, threadID)
+	}
+	runner.mu.Unlock()
+
+	var wait sync.WaitGroup
+	for _, threadID := range threadIDs {
+		wait.Add(1)
+		go func(threadID int64) {
+			defer wait.Done()
+			_ = runner.Stop(threadID, grace)
+		}(threadID)
+	}
+	wait.Wait()
+}
+
+// Capture returns the last lines of the thread log, standing in for tmux
+// capture-pane.
+func (runner *Runner) Capture(threadID int64, lines int) (string, error) {
+	current := runner.lookup(threadID)
+	if current == nil {
+		return "", fmt.Errorf("thread %d has no headless process", threadID)
+	}
+	content, err := os.ReadFile(current.logPath)
+	if err != nil {
+		return "", err
+	}
+	allLines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if lines > 0 && len(allLines) > lines {
+		allLines = allLines[len(allLines)-lines:]
+	}
+	return strings.Join(allLines, "\n"), nil
+}
+
+func (runner *Runner) lookup(threadID int64) *process {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	return runner.processes[threadID]
+}
+
+func (runner *Runner) live(threadID int64) (*process, error) {
+	current := runner.lookup(threadID)
+	if current == nil {
+		return nil, fmt.Errorf("thread %d has no headless process", threadID)
+	}
+	if !current.running() {
+		return nil, fmt.Errorf("headless process for thread %d has exited", threadID)
+	}
+	return current, nil
+}
+
+func (current *process) running() bool {
+	select {
+	case <-current.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (current *process) info(threadID int64) ProcessInfo {
+	info := ProcessInfo{
+		ThreadID:    threadID,
+		PID:         current.cmd.Process.Pid,
+		Running:     current.running(),
+		LogFilePath: current.logPath,
+		StartedAt:   current.startedAt.Format(time.RFC3339Nano),
+	}
+	if !info.Running && current.exitErr != nil {
+		info.ExitError = current.exitErr.Error()
+	}
+	return info
+}
@@ -0,0 +1,123 @@
+//go:build linux || darwin
+
+package headless
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunnerSendShowsInCapture(t *testing.T) {
+	runner := NewRunner()
+	defer runner.StopAll(time.Second)
+
+	logPath := filepath.Join(t.TempDir(), "thread_1.log")
+	if _, err := runner.Start(StartOptions{ThreadID: 1, Command: "[ -t 0 ] && echo on-a-tty; exec cat", LogFilePath: logPath}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	waitForCapture(t, runner, 1, "on-a-tty")
+
+	if err := runner.Send(1, "hello headless"); err != nil {
+		t.Fatalf("failed to send text: %v", err)
+	}
+	waitForCapture(t, runner, 1, "hello headless")
+
+	if _, err := runner.Start(StartOptions{ThreadID: 1, Command: "cat", LogFilePath: logPath}); err == nil || !strings.Contains(err.Error(), "already has a running headless process") {
+		t.Fatalf("expected a second start to be rejected, got %v", err)
+	}
+}
+
+func TestRunnerInterruptReapsProcessGroup(t *testing.T) {
+	runner := NewRunner()
+	defer runner.StopAll(time.Second)
+
+	logPath := filepath.Join(t.TempDir(), "thread_2.log")
+	if _, err := runner.Start(StartOptions{ThreadID: 2, Command: "sh -c 'echo child=$$; exec sleep 30' | cat", LogFilePath: logPath}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	childPID := waitForChildPID(t, runner, 2)
+
+	if err := runner.Interrupt(2); err != nil {
+		t.Fatalf("failed to interrupt: %v", err)
+	}
+	waitUntil(t, "interrupted process to exit", func() bool { return !runner.Alive(2) })
+	waitUntil(t, "interrupted child to exit", func() bool { return processGone(childPID) })
+	if _, err := runner.Start(StartOptions{ThreadID: 2, Command: "cat", LogFilePath: logPath}); err != nil {
+		t.Fatalf("expected a restart after exit to be accepted, got %v", err)
+	}
+}
+
+func TestRunnerStopReapsProcessGroup(t *testing.T) {
+	runner := NewRunner()
+	defer runner.StopAll(time.Second)
+
+	logPath := filepath.Join(t.TempDir(), "thread_3.log")
+	info, err := runner.Start(StartOptions{ThreadID: 3, Command: "sleep 30 & echo child=$!; wait", LogFilePath: logPath})
+	if err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	childPID := waitForChildPID(t, runner, 3)
+
+	if err := runner.Stop(3, time.Second); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+	if _, ok := runner.Info(3); ok || runner.Alive(3) {
+		t.Fatalf("expected stopped thread to be forgotten")
+	}
+	waitUntil(t, "stopped shell to exit", func() bool { return processGone(info.PID) })
+	waitUntil(t, "stopped child to exit", func() bool { return processGone(childPID) })
+}
+
+func waitForCapture(t *testing.T, runner *Runner, threadID int64, expected string) string {
+	t.Helper()
+	var captured string
+	waitUntil(t, fmt.Sprintf("%q in capture", expected), func() bool {
+		captured, _ = runner.Capture(threadID, 50)
+		return strings.Contains(captured, expected)
+	})
+	return captured
+}
+
+var childPIDPattern = regexp.MustCompile(`child=(\d+)`)
+
+func waitForChildPID(t *testing.T, runner *Runner, threadID int64) int {
+	t.Helper()
+	match := childPIDPattern.FindStringSubmatch(waitForCapture(t, runner, threadID, "child="))
+	if match == nil {
+		t.Fatalf("child pid not found in capture")
+	}
+	pid, err := strconv.Atoi(match[1])
+	if err != nil {
+		t.Fatalf("invalid child pid %q: %v", match[1], err)
+	}
+	return pid
+}
+
+func waitUntil(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// processGone reports whether pid has exited. An orphan that init has not
+// reaped yet still answers signals, so zombies count as gone.
+func processGone(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return true
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	return err == nil && strings.Contains(string(stat), ") Z ")
+}
@@ -0,0 +1,34 @@
+//go:build !unix
+
+package headless
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func setTerminalSession(cmd *exec.Cmd) {}
+
+func signalInterrupt(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(os.Interrupt)
+}
+
+func signalTerminate(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func signalKill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+var errNoProcessGroups = errors.New("process groups are not supported on this platform")
+
+func groupAlive(pid int) bool { return false }
+
+func interruptGroup(pid int) error { return errNoProcessGroups }
+
+func stopGroup(pid int, grace time.Duration) error { return errNoProcessGroups }
@@ -0,0 +1,70 @@
+//go:build unix
+
+package headless
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup puts the shell and the agent it launches in their own group
+// so signals reach the whole tree and not the orchestrator itself.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// setTerminalSession starts the shell in a new session with the pty on its
+// stdin as controlling terminal. The session leader also leads its process
+// group, so the group signals below still reach the whole tree.
+func setTerminalSession(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
+}
+
+func signalInterrupt(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+}
+
+func signalTerminate(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+func signalKill(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// groupAlive reports whether the process group led by pid still has members.
+// Every agent runs as the leader of its own group, so a recycled pid that now
+// belongs to an unrelated process does not count.
+func groupAlive(pid int) bool {
+	err := syscall.Kill(-pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+func signalGroup(pid int, signal syscall.Signal) error {
+	if err := syscall.Kill(-pid, signal); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
+
+func interruptGroup(pid int) error {
+	return signalGroup(pid, syscall.SIGINT)
+}
+
+// stopGroup terminates the process group led by pid and kills it if it has
+// not exited within grace.
+func stopGroup(pid int, grace time.Duration) error {
+	if err := signalGroup(pid, syscall.SIGTERM); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(grace)
+	for groupAlive(pid) {
+		if time.Now().After(deadline) {
+			return signalGroup(pid, syscall.SIGKILL)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil
+}
@@ -38,6 +38,11 @@ func (service *Service) runtimeBundleInfo(_ context.Context, _ runtimeBundleInfo
 			"mcp_root":    pathExists(mcpRoot),
 		},
 		"role_templates": roleTemplates,
+		"providers":      service.provider.Types(),
+		"providers_config": map[string]any{
+			"path":   service.providerConfigPath(),
+			"exists": pathExists(service.providerConfigPath()),
+		},
 		"sync_verify": map[string]any{
 			"command": fmt.Sprintf("./scripts/verify-feature-sync.sh %s %s", featureBundleName, service.repoPath),
 		},
@@ -0,0 +1,222 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const (
+	directiveMaxAttempts           = 3
+	defaultDirectiveConfirmTimeout = 5 * time.Second
+	directivePollInterval          = 200 * time.Millisecond
+	directiveCaptureLines          = 200
+
+	directiveEchoMissing   = "missing"
+	directiveEchoIdle      = "echoed"
+	directiveEchoConfirmed = "confirmed"
+)
+
+// directiveMarker prefixes delivered directives so their echo can be found in
+// the pane and the worker knows which id to acknowledge.
+func directiveMarker(directiveID int64) string {
+	return fmt.Sprintf("[directive %d]", directiveID)
+}
+
+// directiveEcho classifies a capture taken after sending: the marker must be
+// visible and the provider must have left the idle prompt (processing, or
+// already completed or asking) for the directive to count as taken up.
+func directiveEcho(captured string, marker string, status provider.Status) string {
+	if !strings.Contains(provider.StripEscapes(captured), marker) {
+		return directiveEchoMissing
+	}
+	switch status {
+	case provider.StatusProcessing, provider.StatusCompleted, provider.StatusWaitingUserAnswer:
+		return directiveEchoConfirmed
+	}
+	return directiveEchoIdle
+}
+
+func (service *Service) captureProviderStatus(ctx context.Context, thread store.Thread, runner threadRunner) (string, provider.Status, error) {
+	captured, err := runner.Capture(ctx, thread, directiveCaptureLines)
+	if err != nil {
+		return "", "", err
+	}
+	p, err := service.providerForThread(thread)
+	if err != nil {
+		return captured, "", err
+	}
+	return captured, p.GetStatus(provider.StripEscapes(captured)), nil
+}
+
+// waitDirectiveReady gives an interrupted agent up to timeout to leave the
+// processing state so the pasted text is not swallowed by a running tool.
+func (service *Service) waitDirectiveReady(ctx context.Context, thread store.Thread, runner threadRunner, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, status, err := service.captureProviderStatus(ctx, thread, runner); err != nil || status != provider.StatusProcessing {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(directivePollInterval):
+		}
+	}
+}
+
+// waitDirectiveEcho polls the pane until the directive is confirmed or the
+// timeout passes, returning the last classification.
+func (service *Service) waitDirectiveEcho(ctx context.Context, thread store.Thread, runner threadRunner, marker string, timeout time.Duration) string {
+	deadline := time.Now().Add(timeout)
+	echo := directiveEchoMissing
+	for {
+		if captured, status, err := service.captureProviderStatus(ctx, thread, runner); err == nil {
+			if echo = directiveEcho(captured, marker, status); echo == directiveEchoConfirmed {
+				return echo
+			}
+		}
+		if !time.Now().Before(deadline) {
+			return echo
+		}
+		select {
+		case <-ctx.Done():
+			return echo
+		case <-time.After(directivePollInterval):
+		}
+	}
+}
+
+// deliverDirective sends a recorded directive and confirms it by watching the
+// pane. Text that never shows up is sent again, up to directiveMaxAttempts,
+// after clearing whatever part of it is left at the prompt; after that the
+// directive is failed and the parent inbox is told. A directive the agent
+// visibly takes up is delivered; one echoed but left idle stays sent rather
+// than being typed twice, as does one sent to a headless process, whose stdin
+// cannot drop it. Only the worker marks it acknowledged or applied.
+//
+// interrupt_patch interrupts once, before the first send, and only an agent
+// that is still processing: a second interrupt at an idle prompt would exit
+// it.
+func (service *Service) deliverDirective(ctx context.Context, thread store.Thread, runner threadRunner, directive store.ThreadDirective, timeout time.Duration) (store.ThreadDirective, error) {
+	marker := directiveMarker(directive.ID)
+	text := marker + " " + directive.Directive
+	for attempt := 1; attempt <= directiveMaxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := runner.ClearInput(ctx, thread); err != nil {
+				return service.failDirective(ctx, directive, err)
+			}
+		}
+		if directive.Mode == "interrupt_patch" && attempt == 1 {
+			if _, status, err := service.captureProviderStatus(ctx, thread, runner); err == nil && status == provider.StatusProcessing {
+				if err := runner.Interrupt(ctx, thread); err != nil {
+					return service.failDirective(ctx, directive, err)
+				}
+				if timeout > 0 {
+					service.waitDirectiveReady(ctx, thread, runner, timeout)
+				}
+			}
+		}
+		if err := runner.SendText(ctx, thread, text); err != nil {
+			return service.failDirective(ctx, directive, err)
+		}
+		noError := ""
+		updated, err := service.store.AdvanceThreadDirective(ctx, directive.ID, store.ThreadDirectiveAdvanceArgs{Status: store.DirectiveStatusSent, CountAttempt: true, LastError: &noError})
+		if err != nil {
+			return directive, err
+		}
+		directive = updated
+		if timeout <= 0 {
+			return directive, nil
+		}
+
+		switch service.waitDirectiveEcho(ctx, thread, runner, marker, timeout) {
+		case directiveEchoConfirmed:
+			return service.store.AdvanceThreadDirective(ctx, directive.ID, store.ThreadDirectiveAdvanceArgs{Status: store.DirectiveStatusDelivered})
+		case directiveEchoIdle:
+			return directive, nil
+		}
+		if runner.Backend() == runnerBackendHeadless {
+			return directive, nil
+		}
+	}
+
+	reason := fmt.Sprintf("directive was not echoed in the pane after %d attempts", directiveMaxAttempts)
+	failed, err := service.store.AdvanceThreadDirective(ctx, directive.ID, store.ThreadDirectiveAdvanceArgs{Status: store.DirectiveStatusFailed, LastError: &reason})
+	if err != nil {
+		return directive, err
+	}
+	service.notifyParentThread(ctx, thread, fmt.Sprintf("[directive] %s to thread %d failed: %s", marker, thread.ID, reason))
+	return failed, nil
+}
+
+// failDirective records a send error and returns it.
+func (service *Service) failDirective(ctx context.Context, directive store.ThreadDirective, cause error) (store.ThreadDirective, error) {
+	reason := cause.Error()
+	if failed, err := service.store.AdvanceThreadDirective(ctx, directive.ID, store.ThreadDirectiveAdvanceArgs{Status: store.DirectiveStatusFailed, LastError: &reason}); err == nil {
+		directive = failed
+	}
+	return directive, cause
+}
+
+// reportDirective applies a worker's acknowledgement. Only acknowledged and
+// applied can be reported; the thread must own the directive when given.
+func (service *Service) reportDirective(ctx context.Context, directiveID int64, threadID int64, sessionID int64, status string) (store.ThreadDirective, error) {
+	if status == "" {
+		status = store.DirectiveStatusAcknowledged
+	}
+	if status != store.DirectiveStatusAcknowledged && status != store.DirectiveStatusApplied {
+		return store.ThreadDirective{}, fmt.Errorf("directive_status must be %s or %s", store.DirectiveStatusAcknowledged, store.DirectiveStatusApplied)
+	}
+	directive, err := service.store.GetThreadDirective(ctx, directiveID)
+	if err != nil {
+		return store.ThreadDirective{}, err
+	}
+	if threadID > 0 && directive.ThreadID != threadID {
+		return store.ThreadDirective{}, fmt.Errorf("directive %d was sent to thread %d, not %d", directive.ID, directive.ThreadID, threadID)
+	}
+	if sessionID > 0 && directive.SessionID != sessionID {
+		return store.ThreadDirective{}, fmt.Errorf("directive %d belongs to session %d, not %d", directive.ID, directive.SessionID, sessionID)
+	}
+	return service.store.AdvanceThreadDirective(ctx, directive.ID, store.ThreadDirectiveAdvanceArgs{Status: status})
+}
+
+func (service *Service) ackCurrentRef(ctx context.Context, input workCurrentRefAckInput) (any, error) {
+	if input.DirectiveID <= 0 {
+		return service.store.AckCurrentRef(ctx, input.SessionID, input.RefID)
+	}
+	result := map[string]any{}
+	if input.RefID > 0 {
+		currentRef, err := service.store.AckCurrentRef(ctx, input.SessionID, input.RefID)
+		if err != nil {
+			return nil, err
+		}
+		result["current_ref"] = currentRef
+	}
+	directive, err := service.reportDirective(ctx, input.DirectiveID, 0, input.SessionID, store.DirectiveStatusApplied)
+	if err != nil {
+		return nil, err
+	}
+	result["directive"] = directive
+	return result, nil
+}
+
+func (service *Service) listThreadDirectives(ctx context.Context, input threadDirectiveListInput) (map[string]any, error) {
+	if input.ThreadID <= 0 && input.SessionID <= 0 {
+		return nil, errors.New("thread_id or session_id is required")
+	}
+	directives, err := service.store.ListThreadDirectives(ctx, store.ThreadDirectiveFilter{
+		ThreadID:  input.ThreadID,
+		SessionID: input.SessionID,
+		Status:    input.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"directives": directives, "count": len(directives)}, nil
+}
@@ -0,0 +1,157 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+// swallowingRunner drops the first swallow sends and echoes the rest into
+// its capture, like a pane that ate the paste while a tool was running;
+// onSwallow is what the pane shows instead. An interrupt stops the work and
+// leaves the agent at its idle prompt.
+type swallowingRunner struct {
+	swallow    int
+	onSwallow  string
+	sends      int
+	clears     int
+	interrupts int
+	captured   string
+}
+
+func (runner *swallowingRunner) Backend() string                          { return runnerBackendTmux }
+func (runner *swallowingRunner) Alive(context.Context, store.Thread) bool { return true }
+func (runner *swallowingRunner) Interrupt(context.Context, store.Thread) error {
+	runner.interrupts++
+	runner.captured += "■ Conversation interrupted\n› "
+	return nil
+}
+func (runner *swallowingRunner) ClearInput(context.Context, store.Thread) error {
+	runner.clears++
+	return nil
+}
+func (runner *swallowingRunner) Stop(context.Context, store.Thread, bool) error { return nil }
+func (runner *swallowingRunner) SendAnswer(context.Context, store.Thread, string, bool) error {
+	return nil
+}
+func (runner *swallowingRunner) SendText(_ context.Context, _ store.Thread, text string) error {
+	runner.sends++
+	if runner.sends > runner.swallow {
+		runner.captured += "You\n" + text + "\n• Working (1s • esc to interrupt)\n"
+	} else {
+		runner.captured += runner.onSwallow
+	}
+	return nil
+}
+func (runner *swallowingRunner) Capture(context.Context, store.Thread, int) (string, error) {
+	return runner.captured, nil
+}
+
+func TestDirectiveEcho(t *testing.T) {
+	testCases := []struct {
+		captured string
+		status   provider.Status
+		want     string
+	}{
+		{"› ", provider.StatusIdle, directiveEchoMissing},
+		{"\x1b[1m[directive 7]\x1b[0m fix it\n› ", provider.StatusIdle, directiveEchoIdle},
+		{"[directive 7] fix it\nWorking", provider.StatusProcessing, directiveEchoConfirmed},
+		{"[directive 7] fix it\ncodex: done\n› ", provider.StatusCompleted, directiveEchoConfirmed},
+	}
+	for _, testCase := range testCases {
+		if got := directiveEcho(testCase.captured, directiveMarker(7), testCase.status); got != testCase.want {
+			t.Fatalf("directiveEcho(%q, %s) = %s, want %s", testCase.captured, testCase.status, got, testCase.want)
+		}
+	}
+}
+
+func TestDeliverDirectiveRetriesThenEscalates(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	thread, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "running", ProviderType: "codex"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+
+	newDirective := func(text string) store.ThreadDirective {
+		directive, err := service.store.CreateThreadDirective(ctx, store.ThreadDirectiveCreateArgs{ThreadID: thread.ID, SessionID: session.ID, Mode: "queue", Directive: text})
+		if err != nil {
+			t.Fatalf("failed to create directive: %v", err)
+		}
+		return directive
+	}
+
+	runner := &swallowingRunner{swallow: 1}
+	delivered, err := service.deliverDirective(ctx, thread, runner, newDirective("use the v2 client"), 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to deliver directive: %v", err)
+	}
+	if delivered.Status != store.DirectiveStatusDelivered || delivered.DeliveredAt == nil || delivered.AcknowledgedAt != nil || delivered.Attempts != 2 || runner.sends != 2 || runner.clears != 1 {
+		t.Fatalf("expected delivery on the second attempt after clearing the prompt, got %+v after %d sends and %d clears", delivered, runner.sends, runner.clears)
+	}
+	if !strings.Contains(runner.captured, directiveMarker(delivered.ID)+" use the v2 client") {
+		t.Fatalf("expected marked directive text, got %q", runner.captured)
+	}
+
+	// interrupt_patch stops a busy agent once. The retry does not interrupt
+	// again even though the agent looks busy.
+	runner = &swallowingRunner{swallow: 1, onSwallow: "• Working (1s • esc to interrupt)\n", captured: "• Working (3s • esc to interrupt)\n"}
+	patch, err := service.store.CreateThreadDirective(ctx, store.ThreadDirectiveCreateArgs{ThreadID: thread.ID, SessionID: session.ID, Mode: "interrupt_patch", Directive: "revert api.go"})
+	if err != nil {
+		t.Fatalf("failed to create directive: %v", err)
+	}
+	if patch, err = service.deliverDirective(ctx, thread, runner, patch, 300*time.Millisecond); err != nil {
+		t.Fatalf("failed to deliver interrupt directive: %v", err)
+	}
+	if patch.Status != store.DirectiveStatusDelivered || runner.sends != 2 || runner.clears != 1 || runner.interrupts != 1 {
+		t.Fatalf("expected one interrupt before delivery, got %+v after %d sends, %d clears and %d interrupts", patch, runner.sends, runner.clears, runner.interrupts)
+	}
+
+	runner = &swallowingRunner{swallow: directiveMaxAttempts}
+	failed, err := service.deliverDirective(ctx, thread, runner, newDirective("stop editing api.go"), 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed delivery must not error: %v", err)
+	}
+	if failed.Status != store.DirectiveStatusFailed || failed.Attempts != directiveMaxAttempts || failed.LastError == nil {
+		t.Fatalf("expected failed directive after %d attempts, got %+v", directiveMaxAttempts, failed)
+	}
+	messages, err := service.store.ListPendingInboxMessages(ctx, root.ID)
+	if err != nil || len(messages) != 1 || !strings.Contains(messages[0].Message, "[directive]") {
+		t.Fatalf("expected escalation in the parent inbox, got %+v (%v)", messages, err)
+	}
+
+	if _, err := service.inboxSend(ctx, inboxSendInput{SenderThreadID: root.ID, ReceiverThreadID: root.ID, Message: "ack", DirectiveID: failed.ID}); err == nil {
+		t.Fatal("expected acknowledgement from another thread to be rejected")
+	}
+	acked, err := service.inboxSend(ctx, inboxSendInput{SenderThreadID: thread.ID, ReceiverThreadID: root.ID, Message: "got it", DirectiveID: failed.ID})
+	if err != nil || acked["directive"].(*store.ThreadDirective).Status != store.DirectiveStatusAcknowledged {
+		t.Fatalf("expected inbox acknowledgement, got %+v (%v)", acked, err)
+	}
+	applied, err := service.ackCurrentRef(ctx, workCurrentRefAckInput{SessionID: session.ID, DirectiveID: failed.ID})
+	if err != nil || applied.(map[string]any)["directive"].(store.ThreadDirective).Status != store.DirectiveStatusApplied {
+		t.Fatalf("expected work.current_ref.ack to apply the directive, got %+v (%v)", applied, err)
+	}
+
+	listed, err := service.listThreadDirectives(ctx, threadDirectiveListInput{ThreadID: thread.ID, Status: store.DirectiveStatusApplied})
+	if err != nil || listed["count"] != 1 {
+		t.Fatalf("unexpected directive list: %+v (%v)", listed, err)
+	}
+}
@@ -0,0 +1,286 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func (service *Service) planDispatch(ctx context.Context, input planDispatchInput) (map[string]any, error) {
+	if input.SessionID <= 0 {
+		return nil, errors.New("session_id is required")
+	}
+	if input.PlanNodeID <= 0 {
+		return nil, errors.New("plan_node_id is required")
+	}
+
+	session, err := service.store.GetSessionByID(ctx, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	session, rootThread, err := service.ensureRootThreadRecord(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	planNode, err := service.store.GetGraphNodeByID(ctx, input.PlanNodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentWorktreeID := int64(0)
+	if input.ParentWorktreeID != nil {
+		parentWorktreeID = *input.ParentWorktreeID
+	} else if session.SessionRootWorktreeID != nil {
+		parentWorktreeID = *session.SessionRootWorktreeID
+	}
+	if parentWorktreeID <= 0 {
+		return nil, errors.New("parent_worktree_id is required when the session has no root worktree")
+	}
+
+	maxConcurrentChildren := intValueOrDefault(input.MaxConcurrentChildren, defaultMaxChildThreads)
+	if maxConcurrentChildren <= 0 {
+		maxConcurrentChildren = defaultMaxChildThreads
+	}
+	activeChildren, err := service.countActiveChildThreads(ctx, session.ID, rootThread.ID)
+	if err != nil {
+		return nil, err
+	}
+	availableSlots := maxConcurrentChildren - activeChildren
+	if availableSlots < 0 {
+		availableSlots = 0
+	}
+
+	readySet, err := service.store.PlanReadySet(ctx, planNode.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"plan_node_id":            planNode.ID,
+		"session_id":              session.ID,
+		"max_concurrent_children": maxConcurrentChildren,
+		"active_children":         activeChildren,
+		"ready_count":             len(readySet.Ready),
+		"dispatched":              []map[string]any{},
+		"dry_run":                 input.DryRun,
+	}
+	if len(readySet.Ready) == 0 || availableSlots == 0 {
+		return result, nil
+	}
+
+	selected := readySet.Ready
+	if len(selected) > availableSlots {
+		selected = selected[:availableSlots]
+	}
+	if input.DryRun {
+		result["selected"] = selected
+		return result, nil
+	}
+
+	parentTask, err := service.resolveDispatchParentTask(ctx, input.ParentTaskID, planNode)
+	if err != nil {
+		return nil, err
+	}
+	result["parent_task"] = parentTask
+
+	dispatched := make([]map[string]any, 0, len(selected))
+	skipped := make([]int64, 0)
+	for _, entry := range selected {
+		dispatchedSlice, dispatchErr := service.dispatchSlice(ctx, input, session.ID, parentWorktreeID, parentTask.ID, planNode.ID, entry.Node, maxConcurrentChildren)
+		if errors.Is(dispatchErr, store.ErrGraphNodeClaimed) {
+			skipped = append(skipped, entry.Node.ID)
+			continue
+		}
+		if dispatchErr != nil {
+			result["skipped_node_ids"] = skipped
+			result["dispatched"] = dispatched
+			result["failed"] = map[string]any{
+				"node_id": entry.Node.ID,
+				"title":   entry.Node.Title,
+				"error":   dispatchErr.Error(),
+			}
+			return result, nil
+		}
+		dispatched = append(dispatched, dispatchedSlice)
+	}
+	result["dispatched"] = dispatched
+	result["skipped_node_ids"] = skipped
+	return result, nil
+}
+
+// dispatchSlice claims the slice before creating anything, so concurrent
+// dispatches cannot start it twice. On failure everything created so far is
+// undone and the slice goes back to todo.
+func (service *Service) dispatchSlice(ctx context.Context, input planDispatchInput, sessionID int64, parentWorktreeID int64, parentTaskID int64, planNodeID int64, slice store.GraphNode, maxConcurrentChildren int) (map[string]any, error) {
+	if _, err := service.store.ClaimGraphNodeForDispatch(ctx, slice.ID); err != nil {
+		return nil, err
+	}
+	var (
+		caseTask store.Task
+		worktree *store.Worktree
+		thread   *store.Thread
+	)
+	abandon := func() {
+		if thread != nil {
+			terminatePane := true
+			_, _ = service.stopChildThread(ctx, threadChildStopInput{ThreadID: thread.ID, TerminatePane: &terminatePane})
+		}
+		if worktree != nil {
+			if worktree.Status == "active" {
+				_ = service.runGitWorktreeRemove(worktree.Path, worktree.Branch)
+			}
+			_, _ = service.store.AbandonWorktree(ctx, worktree.ID)
+		}
+		if caseTask.ID > 0 {
+			_, _, _ = service.store.CancelTask(ctx, caseTask.ID, "plan.dispatch failed")
+		}
+		_, _ = service.store.ReleaseGraphNodeClaim(ctx, slice.ID)
+	}
+
+	caseTask, err := service.store.CreateTask(ctx, store.TaskCreateArgs{
+		Level:    "case",
+		Title:    slice.Title,
+		ParentID: &parentTaskID,
+		Priority: slice.Priority,
+	})
+	if err != nil {
+		abandon()
+		return nil, err
+	}
+	if _, err := service.store.LinkGraphNodeTask(ctx, slice.ID, caseTask.ID); err != nil {
+		abandon()
+		return nil, err
+	}
+
+	spawnedWorktree, err := service.spawnWorktree(ctx, worktreeSpawnInput{
+		SessionID:        sessionID,
+		ParentWorktreeID: parentWorktreeID,
+		TaskID:           &caseTask.ID,
+		Reason:           slice.Title,
+		CreateOnDisk:     input.CreateOnDisk,
+	})
+	if err != nil {
+		abandon()
+		return nil, err
+	}
+	worktree = &spawnedWorktree
+
+	affectedFiles := decodeStringSliceJSON(valueOrEmpty(slice.AffectedFilesJSON))
+	taskSpec := map[string]any{
+		"thread_role":    "worker",
+		"title":          slice.Title,
+		"objective":      valueOrEmpty(slice.Summary),
+		"plan_node_id":   planNodeID,
+		"slice_node_id":  slice.ID,
+		"case_id":        caseTask.ID,
+		"affected_files": affectedFiles,
+		"worktree_path":  worktree.Path,
+		"branch":         worktree.Branch,
+	}
+	if slice.TokenEstimate != nil {
+		taskSpec["token_estimate"] = *slice.TokenEstimate
+	}
+	taskSpecJSON, err := json.Marshal(taskSpec)
+	if err != nil {
+		abandon()
+		return nil, err
+	}
+
+	objective := valueOrEmpty(slice.Summary)
+	if objective == "" {
+		objective = slice.Title
+	}
+	spawnedThread, _, tmuxResult, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{
+		SessionID:             sessionID,
+		WorktreeID:            &worktree.ID,
+		Role:                  "worker",
+		Title:                 slice.Title,
+		Objective:             objective,
+		EnsureTmux:            input.EnsureTmux,
+		LaunchCodex:           input.LaunchCodex,
+		SkipReadyCheck:        input.SkipReadyCheck,
+		RunnerKind:            input.RunnerKind,
+		Backend:               input.Backend,
+		ProviderType:          input.ProviderType,
+		CodexCommand:          input.CodexCommand,
+		MaxConcurrentChildren: &maxConcurrentChildren,
+		TaskSpec:              taskSpecJSON,
+		ScopeTaskIDs:          []int64{parentTaskID},
+		ScopeCaseIDs:          []int64{caseTask.ID},
+		ScopeNodeIDs:          []int64{slice.ID},
+	})
+	if err != nil {
+		abandon()
+		return nil, err
+	}
+	thread = &spawnedThread
+
+	dispatchedNode, err := service.store.MarkGraphNodeDispatched(ctx, slice.ID, &worktree.ID)
+	if err != nil {
+		abandon()
+		return nil, err
+	}
+
+	return map[string]any{
+		"node":     dispatchedNode,
+		"case":     caseTask,
+		"worktree": *worktree,
+		"thread":   *thread,
+		"tmux":     tmuxResult,
+	}, nil
+}
+
+// resolveDispatchParentTask picks the task that dispatched cases hang under:
+// the caller's parent_task_id, or the feature linked to the plan node, created
+// and linked on first dispatch.
+func (service *Service) resolveDispatchParentTask(ctx context.Context, parentTaskID *int64, planNode store.GraphNode) (store.Task, error) {
+	if parentTaskID != nil {
+		return service.store.GetTaskByID(ctx, *parentTaskID)
+	}
+
+	links, err := service.store.ListGraphNodeTaskLinks(ctx, store.GraphNodeTaskLinkFilter{NodeID: &planNode.ID})
+	if err != nil {
+		return store.Task{}, err
+	}
+	for _, link := range links {
+		linkedTask, err := service.store.GetTaskByID(ctx, link.TaskID)
+		if err != nil {
+			return store.Task{}, err
+		}
+		if linkedTask.Level == "feature" && linkedTask.DeletedAt == nil {
+			return linkedTask, nil
+		}
+	}
+
+	feature, err := service.store.CreateTask(ctx, store.TaskCreateArgs{
+		Level:    "feature",
+		Title:    planNode.Title,
+		Priority: planNode.Priority,
+	})
+	if err != nil {
+		return store.Task{}, err
+	}
+	if _, err := service.store.LinkGraphNodeTask(ctx, planNode.ID, feature.ID); err != nil {
+		return store.Task{}, err
+	}
+	return feature, nil
+}
+
+func (service *Service) countActiveChildThreads(ctx context.Context, sessionID int64, parentThreadID int64) (int, error) {
+	childThreads, err := service.store.ListThreads(ctx, store.ThreadFilter{
+		SessionID:      sessionID,
+		ParentThreadID: &parentThreadID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	active := 0
+	for _, childThread := range childThreads {
+		if !isChildThreadReusable(childThread.Status) {
+			active++
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,203 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestPlanDispatchStartsReadySlicesUpToLimit(t *testing.T) {
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	ctx := context.Background()
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	rootWorktree, err := service.store.CreateWorktreeRecord(ctx, store.WorktreeCreateArgs{
+		Path:           repoPath,
+		Branch:         "task/root",
+		Status:         "active",
+		Kind:           "session_root",
+		OwnerSessionID: &session.ID,
+		MergeState:     "active",
+	})
+	if err != nil {
+		t.Fatalf("failed to create session root worktree: %v", err)
+	}
+	if _, err := service.store.UpdateSession(ctx, session.ID, store.SessionUpdateArgs{SessionRootWorktreeID: &rootWorktree.ID}); err != nil {
+		t.Fatalf("failed to bind session root worktree: %v", err)
+	}
+
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "initiative", PlanTitle: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	planNode := bootstrap["plan"].(store.GraphNode)
+	generated, err := service.planSliceGenerate(ctx, planSliceGenerateInput{
+		PlanNodeID: planNode.ID,
+		SliceSpecs: []planSliceSpecInput{
+			{Title: "schema", Priority: 5, AffectedFiles: []string{"db/schema.sql"}},
+			{Title: "api", Priority: 3},
+			{Title: "docs", Priority: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate slices: %v", err)
+	}
+	slices := generated["slices"].([]store.GraphNode)
+	if _, err := service.store.CreateGraphEdge(ctx, store.GraphEdgeCreateArgs{FromNodeID: slices[1].ID, ToNodeID: slices[0].ID, EdgeType: "depends_on"}); err != nil {
+		t.Fatalf("failed to create dependency edge: %v", err)
+	}
+
+	// A feature that merely shares the plan's title is not the plan's feature.
+	decoy, err := service.store.CreateTask(ctx, store.TaskCreateArgs{Level: "feature", Title: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to create decoy feature: %v", err)
+	}
+
+	maxChildren := 2
+	input := planDispatchInput{
+		SessionID:             session.ID,
+		PlanNodeID:            planNode.ID,
+		MaxConcurrentChildren: &maxChildren,
+		CreateOnDisk:          pointerToBool(false),
+		EnsureTmux:            pointerToBool(false),
+	}
+	result, err := service.planDispatch(ctx, input)
+	if err != nil {
+		t.Fatalf("failed to dispatch plan: %v", err)
+	}
+	if _, failed := result["failed"]; failed {
+		t.Fatalf("unexpected dispatch failure: %+v", result["failed"])
+	}
+	dispatched := result["dispatched"].([]map[string]any)
+	if len(dispatched) != 2 {
+		t.Fatalf("expected 2 dispatched slices, got %+v", dispatched)
+	}
+
+	first := dispatched[0]
+	node := first["node"].(store.GraphNode)
+	if node.ID != slices[0].ID || node.Status != "in_progress" || node.WorktreeID == nil {
+		t.Fatalf("expected schema slice in_progress with worktree, got %+v", node)
+	}
+	caseTask := first["case"].(store.Task)
+	parentTask := result["parent_task"].(store.Task)
+	if caseTask.Level != "case" || caseTask.ParentID == nil || *caseTask.ParentID != parentTask.ID || parentTask.Title != "checkout" {
+		t.Fatalf("expected case under plan feature, got case=%+v parent=%+v", caseTask, parentTask)
+	}
+	if parentTask.ID == decoy.ID {
+		t.Fatalf("expected a new feature for the plan, got the decoy %+v", decoy)
+	}
+	if links, err := service.store.ListGraphNodeTaskLinks(ctx, store.GraphNodeTaskLinkFilter{NodeID: &planNode.ID}); err != nil || len(links) != 1 || links[0].TaskID != parentTask.ID {
+		t.Fatalf("expected the plan linked to its feature, got %+v (%v)", links, err)
+	}
+	thread := first["thread"].(store.Thread)
+	if scope := decodeInt64JSON(valueOrEmpty(thread.ScopeNodeIDsJSON)); len(scope) != 1 || scope[0] != slices[0].ID {
+		t.Fatalf("expected thread scoped to slice node, got %v", scope)
+	}
+	if dispatched[1]["node"].(store.GraphNode).ID != slices[2].ID {
+		t.Fatalf("expected docs slice dispatched second, got %+v", dispatched[1]["node"])
+	}
+
+	again, err := service.planDispatch(ctx, input)
+	if err != nil {
+		t.Fatalf("failed to re-dispatch plan: %v", err)
+	}
+	if len(again["dispatched"].([]map[string]any)) != 0 || again["active_children"] != 2 {
+		t.Fatalf("expected no capacity for further dispatch, got %+v", again)
+	}
+	if reused, err := service.resolveDispatchParentTask(ctx, nil, planNode); err != nil || reused.ID != parentTask.ID {
+		t.Fatalf("expected later dispatches to reuse the linked feature, got %+v (%v)", reused, err)
+	}
+}
+
+func TestPlanDispatchSkipsClaimedSlicesAndRollsBackFailures(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	rootWorktree, err := service.store.CreateWorktreeRecord(ctx, store.WorktreeCreateArgs{Path: repoPath, Branch: "task/root", Status: "active", Kind: "session_root", OwnerSessionID: &session.ID, MergeState: "active"})
+	if err != nil {
+		t.Fatalf("failed to create session root worktree: %v", err)
+	}
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "initiative", PlanTitle: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	planNode := bootstrap["plan"].(store.GraphNode)
+	generated, err := service.planSliceGenerate(ctx, planSliceGenerateInput{
+		PlanNodeID: planNode.ID,
+		SliceSpecs: []planSliceSpecInput{{Title: "schema", Priority: 5}, {Title: "api", Priority: 3}},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate slices: %v", err)
+	}
+	slices := generated["slices"].([]store.GraphNode)
+
+	// A concurrent dispatch already owns the schema slice.
+	if _, err := service.store.ClaimGraphNodeForDispatch(ctx, slices[0].ID); err != nil {
+		t.Fatalf("failed to claim slice: %v", err)
+	}
+	if _, err := service.store.ClaimGraphNodeForDispatch(ctx, slices[0].ID); !errors.Is(err, store.ErrGraphNodeClaimed) {
+		t.Fatalf("expected a second claim to fail, got %v", err)
+	}
+	if _, err := service.store.ReleaseGraphNodeClaim(ctx, slices[0].ID); err != nil {
+		t.Fatalf("failed to release claim: %v", err)
+	}
+	if _, err := service.store.ClaimGraphNodeForDispatch(ctx, slices[0].ID); err != nil {
+		t.Fatalf("failed to re-claim slice: %v", err)
+	}
+
+	result, err := service.dispatchSlice(ctx, planDispatchInput{CreateOnDisk: pointerToBool(false), EnsureTmux: pointerToBool(false)}, session.ID, rootWorktree.ID, 0, planNode.ID, slices[0], 1)
+	if !errors.Is(err, store.ErrGraphNodeClaimed) || result != nil {
+		t.Fatalf("expected claimed slice to be skipped, got %+v (%v)", result, err)
+	}
+
+	failed, err := service.planDispatch(ctx, planDispatchInput{
+		SessionID:        session.ID,
+		PlanNodeID:       planNode.ID,
+		ParentWorktreeID: &rootWorktree.ID,
+		CreateOnDisk:     pointerToBool(false),
+		EnsureTmux:       pointerToBool(false),
+		ProviderType:     "no-such-provider",
+	})
+	if err != nil {
+		t.Fatalf("failed to dispatch plan: %v", err)
+	}
+	if _, ok := failed["failed"]; !ok {
+		t.Fatalf("expected the api slice to fail on the unknown provider, got %+v", failed)
+	}
+	node, err := service.store.GetGraphNodeByID(ctx, slices[1].ID)
+	if err != nil || node.Status != "todo" {
+		t.Fatalf("expected failed slice back to todo, got %+v (%v)", node, err)
+	}
+	cases, err := service.store.ListTasks(ctx, store.TaskFilter{Level: "case"})
+	if err != nil || len(cases) != 1 || cases[0].Status != "cancelled" {
+		t.Fatalf("expected the case to be cancelled, got %+v (%v)", cases, err)
+	}
+	worktrees, err := service.store.ListWorktrees(ctx)
+	if err != nil {
+		t.Fatalf("failed to list worktrees: %v", err)
+	}
+	for _, worktree := range worktrees {
+		if worktree.ID != rootWorktree.ID && (worktree.Status != "closed" || valueOrEmpty(worktree.MergeState) != "abandoned") {
+			t.Fatalf("expected the dispatch worktree to be abandoned, got %+v", worktree)
+		}
+	}
+}
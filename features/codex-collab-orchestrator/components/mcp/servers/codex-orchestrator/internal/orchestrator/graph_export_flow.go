@@ -0,0 +1,255 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const (
+	graphExportFormatMermaid = "mermaid"
+	graphExportFormatDOT     = "dot"
+	graphExportFormatJSON    = "json"
+
+	// graphContainsEdgeType labels parent -> child links, whether stored as
+	// edges or implied by parent_id.
+	graphContainsEdgeType = "contains"
+)
+
+var graphStatusFillColors = map[string]string{
+	"todo":        "#e0e0e0",
+	"in_progress": "#cce5ff",
+	"in_review":   "#fff3cd",
+	"blocked":     "#f8d7da",
+	"done":        "#d4edda",
+	"cancelled":   "#f5f5f5",
+}
+
+var graphApprovalBorderColors = map[string]string{
+	"pending":  "#ff9800",
+	"approved": "#28a745",
+	"rejected": "#dc3545",
+}
+
+const (
+	graphDefaultFillColor   = "#ffffff"
+	graphDefaultBorderColor = "#555555"
+)
+
+func (service *Service) exportGraph(ctx context.Context, input graphExportInput) (map[string]any, error) {
+	if input.NodeID <= 0 {
+		return nil, errors.New("node_id is required")
+	}
+	format := strings.ToLower(strings.TrimSpace(input.Format))
+	if format == "" {
+		format = graphExportFormatMermaid
+	}
+
+	subtree, err := service.store.GetGraphSubtree(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"format":       format,
+		"root_node_id": subtree.Root.ID,
+		"node_count":   len(subtree.Nodes),
+		"edge_count":   len(subtree.Edges),
+	}
+	switch format {
+	case graphExportFormatMermaid:
+		result["content"] = renderGraphMermaid(subtree)
+	case graphExportFormatDOT:
+		result["content"] = renderGraphDOT(subtree)
+	case graphExportFormatJSON:
+		result["graph"] = buildGraphNodeLink(subtree)
+	default:
+		return nil, fmt.Errorf("unsupported graph export format: %s (expected mermaid, dot or json)", input.Format)
+	}
+	return result, nil
+}
+
+func graphFillColor(status string) string {
+	if color, ok := graphStatusFillColors[status]; ok {
+		return color
+	}
+	return graphDefaultFillColor
+}
+
+func graphBorderColor(approvalState string) string {
+	if color, ok := graphApprovalBorderColors[approvalState]; ok {
+		return color
+	}
+	return graphDefaultBorderColor
+}
+
+// subtreeContainsLinks returns the parent -> child pairs inside the subtree
+// that are not already stored as explicit contains edges.
+func subtreeContainsLinks(subtree store.GraphSubtree) [][2]int64 {
+	inSubtree := make(map[int64]bool, len(subtree.Nodes))
+	for _, node := range subtree.Nodes {
+		inSubtree[node.ID] = true
+	}
+	explicit := make(map[[2]int64]bool)
+	for _, edge := range subtree.Edges {
+		if edge.EdgeType == graphContainsEdgeType {
+			explicit[[2]int64{edge.FromNodeID, edge.ToNodeID}] = true
+		}
+	}
+	links := make([][2]int64, 0, len(subtree.Nodes))
+	for _, node := range subtree.Nodes {
+		if node.ID == subtree.Root.ID || node.ParentID == nil || !inSubtree[*node.ParentID] {
+			continue
+		}
+		if explicit[[2]int64{*node.ParentID, node.ID}] {
+			continue
+		}
+		links = append(links, [2]int64{*node.ParentID, node.ID})
+	}
+	return links
+}
+
+func renderGraphMermaid(subtree store.GraphSubtree) string {
+	builder := strings.Builder{}
+	builder.WriteString("flowchart TD\n")
+	for _, node := range subtree.Nodes {
+		label := fmt.Sprintf("#%d %s: %s<br/>%s", node.ID, node.NodeType, node.Title, node.Status)
+		if node.ApprovalState != "" && node.ApprovalState != "none" {
+			label += " / " + node.ApprovalState
+		}
+		builder.WriteString(fmt.Sprintf("  n%d[\"%s\"]\n", node.ID, escapeMermaidLabel(label)))
+	}
+	for _, link := range subtreeContainsLinks(subtree) {
+		builder.WriteString(fmt.Sprintf("  n%d -.->|%s| n%d\n", link[0], graphContainsEdgeType, link[1]))
+	}
+	for _, edge := range subtree.Edges {
+		arrow := "-->"
+		if edge.EdgeType == graphContainsEdgeType {
+			arrow = "-.->"
+		}
+		builder.WriteString(fmt.Sprintf("  n%d %s|%s| n%d\n", edge.FromNodeID, arrow, escapeMermaidLabel(edge.EdgeType), edge.ToNodeID))
+	}
+	for _, node := range subtree.Nodes {
+		builder.WriteString(fmt.Sprintf("  style n%d fill:%s,stroke:%s", node.ID, graphFillColor(node.Status), graphBorderColor(node.ApprovalState)))
+		if _, ok := graphApprovalBorderColors[node.ApprovalState]; ok {
+			builder.WriteString(",stroke-width:3px")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func escapeMermaidLabel(value string) string {
+	replacer := strings.NewReplacer(`"`, "#quot;", "\n", " ", "\r", " ", "|", "#124;")
+	return replacer.Replace(value)
+}
+
+func renderGraphDOT(subtree store.GraphSubtree) string {
+	builder := strings.Builder{}
+	builder.WriteString("digraph planning_graph {\n")
+	builder.WriteString("  rankdir=TB;\n")
+	builder.WriteString("  node [shape=box, style=\"rounded,filled\"];\n")
+	for _, node := range subtree.Nodes {
+		label := fmt.Sprintf("#%d %s\n%s\n%s", node.ID, node.NodeType, node.Title, node.Status)
+		penWidth := 1
+		if _, ok := graphApprovalBorderColors[node.ApprovalState]; ok {
+			label += " / " + node.ApprovalState
+			penWidth = 3
+		}
+		builder.WriteString(fmt.Sprintf(
+			"  n%d [label=\"%s\", fillcolor=\"%s\", color=\"%s\", penwidth=%d];\n",
+			node.ID,
+			escapeDOTString(label),
+			graphFillColor(node.Status),
+			graphBorderColor(node.ApprovalState),
+			penWidth,
+		))
+	}
+	for _, link := range subtreeContainsLinks(subtree) {
+		builder.WriteString(fmt.Sprintf("  n%d -> n%d [label=\"%s\", style=dashed];\n", link[0], link[1], graphContainsEdgeType))
+	}
+	for _, edge := range subtree.Edges {
+		style := ""
+		if edge.EdgeType == graphContainsEdgeType {
+			style = ", style=dashed"
+		}
+		builder.WriteString(fmt.Sprintf("  n%d -> n%d [label=\"%s\"%s];\n", edge.FromNodeID, edge.ToNodeID, escapeDOTString(edge.EdgeType), style))
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func escapeDOTString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// buildGraphNodeLink renders the subtree in the node-link layout used by
+// d3/networkx: {"directed", "nodes": [...], "links": [{"source", "target", "type"}]}.
+func buildGraphNodeLink(subtree store.GraphSubtree) map[string]any {
+	nodes := make([]map[string]any, 0, len(subtree.Nodes))
+	for _, node := range subtree.Nodes {
+		entry := map[string]any{
+			"id":             node.ID,
+			"node_type":      node.NodeType,
+			"facet":          node.Facet,
+			"title":          node.Title,
+			"status":         node.Status,
+			"approval_state": node.ApprovalState,
+			"priority":       node.Priority,
+			"fill_color":     graphFillColor(node.Status),
+			"border_color":   graphBorderColor(node.ApprovalState),
+		}
+		if node.ParentID != nil {
+			entry["parent_id"] = *node.ParentID
+		}
+		nodes = append(nodes, entry)
+	}
+
+	links := make([]map[string]any, 0, len(subtree.Nodes)+len(subtree.Edges))
+	for _, link := range subtreeContainsLinks(subtree) {
+		links = append(links, map[string]any{
+			"source": link[0],
+			"target": link[1],
+			"type":   graphContainsEdgeType,
+		})
+	}
+	for _, edge := range subtree.Edges {
+		links = append(links, map[string]any{
+			"id":     edge.ID,
+			"source": edge.FromNodeID,
+			"target": edge.ToNodeID,
+			"type":   edge.EdgeType,
+		})
+	}
+
+	return map[string]any{
+		"directed":     true,
+		"root_node_id": subtree.Root.ID,
+		"nodes":        nodes,
+		"links":        links,
+	}
+}
+
+// listPlanningGraphRoots returns the subtree of every live top-level graph node.
+func (service *Service) listPlanningGraphRoots(ctx context.Context) ([]store.GraphSubtree, error) {
+	nodes, err := service.store.ListGraphNodes(ctx, store.GraphNodeFilter{})
+	if err != nil {
+		return nil, err
+	}
+	subtrees := make([]store.GraphSubtree, 0)
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			continue
+		}
+		subtree, err := service.store.GetGraphSubtree(ctx, node.ID)
+		if err != nil {
+			return nil, err
+		}
+		subtrees = append(subtrees, subtree)
+	}
+	return subtrees, nil
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestExportGraphRendersSubtree(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	planNode, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: `plan "v1"`, Status: "in_progress"})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	first, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "first", Status: "done", ParentID: &planNode.ID, ApprovalState: "approved"})
+	if err != nil {
+		t.Fatalf("failed to create first slice: %v", err)
+	}
+	second, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "second", Status: "todo", ParentID: &planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to create second slice: %v", err)
+	}
+	// plan.bootstrap and plan.slice.generate store the parent links as edges too.
+	for _, child := range []store.GraphNode{first, second} {
+		if _, err := service.store.CreateGraphEdge(ctx, store.GraphEdgeCreateArgs{FromNodeID: planNode.ID, ToNodeID: child.ID, EdgeType: graphContainsEdgeType}); err != nil {
+			t.Fatalf("failed to create contains edge: %v", err)
+		}
+	}
+	if _, err := service.store.CreateGraphEdge(ctx, store.GraphEdgeCreateArgs{FromNodeID: second.ID, ToNodeID: first.ID, EdgeType: store.EdgeTypeDependsOn}); err != nil {
+		t.Fatalf("failed to create edge: %v", err)
+	}
+
+	mermaid, err := service.exportGraph(ctx, graphExportInput{NodeID: planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to export mermaid: %v", err)
+	}
+	mermaidContent := mermaid["content"].(string)
+	if !strings.HasPrefix(mermaidContent, "flowchart TD\n") || !strings.Contains(mermaidContent, "#quot;v1#quot;") {
+		t.Fatalf("unexpected mermaid header or label escaping:\n%s", mermaidContent)
+	}
+	if !strings.Contains(mermaidContent, "-->|depends_on|") || !strings.Contains(mermaidContent, "-.->|contains|") {
+		t.Fatalf("expected labeled dependency and containment edges:\n%s", mermaidContent)
+	}
+	if count := strings.Count(mermaidContent, "|contains|"); count != 2 {
+		t.Fatalf("expected each parent link drawn once, got %d:\n%s", count, mermaidContent)
+	}
+	if !strings.Contains(mermaidContent, "fill:#d4edda,stroke:#28a745,stroke-width:3px") {
+		t.Fatalf("expected done/approved coloring:\n%s", mermaidContent)
+	}
+
+	dot, err := service.exportGraph(ctx, graphExportInput{NodeID: planNode.ID, Format: "dot"})
+	if err != nil {
+		t.Fatalf("failed to export dot: %v", err)
+	}
+	dotContent := dot["content"].(string)
+	if !strings.HasPrefix(dotContent, "digraph planning_graph {") || !strings.Contains(dotContent, `\"v1\"`) || !strings.Contains(dotContent, `[label="depends_on"]`) {
+		t.Fatalf("unexpected dot output:\n%s", dotContent)
+	}
+	if count := strings.Count(dotContent, `[label="contains"`); count != 2 {
+		t.Fatalf("expected each parent link drawn once in dot, got %d:\n%s", count, dotContent)
+	}
+
+	nodeLink, err := service.exportGraph(ctx, graphExportInput{NodeID: planNode.ID, Format: "json"})
+	if err != nil {
+		t.Fatalf("failed to export json: %v", err)
+	}
+	graph := nodeLink["graph"].(map[string]any)
+	if len(graph["nodes"].([]map[string]any)) != 3 || len(graph["links"].([]map[string]any)) != 3 {
+		t.Fatalf("expected 3 nodes and 3 links, got %+v", graph)
+	}
+
+	if _, err := service.exportGraph(ctx, graphExportInput{NodeID: planNode.ID, Format: "svg"}); err == nil {
+		t.Fatalf("expected unsupported format to fail")
+	}
+
+	mirrorPath := filepath.Join(t.TempDir(), "mirror.md")
+	if _, err := service.refreshMirror(ctx, mirrorRefreshInput{RequesterRole: docMirrorManagerRole, TargetPath: mirrorPath}); err != nil {
+		t.Fatalf("failed to refresh mirror: %v", err)
+	}
+	mirrorContent, err := os.ReadFile(mirrorPath)
+	if err != nil {
+		t.Fatalf("failed to read mirror: %v", err)
+	}
+	if !strings.Contains(string(mirrorContent), "## Planning graph") || !strings.Contains(string(mirrorContent), "```mermaid\nflowchart TD\n") {
+		t.Fatalf("expected mirror to embed the mermaid graph:\n%s", mirrorContent)
+	}
+}
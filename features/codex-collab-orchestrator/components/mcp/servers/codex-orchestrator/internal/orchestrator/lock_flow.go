@@ -0,0 +1,317 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func (service *Service) acquireLock(ctx context.Context, input lockAcquireInput) (store.Lock, error) {
+	ownerSessionID := input.OwnerSessionID
+	if input.OwnerThreadID != nil {
+		thread, err := service.store.GetThreadByID(ctx, *input.OwnerThreadID)
+		if err != nil {
+			return store.Lock{}, err
+		}
+		if ownerSessionID == nil {
+			threadSessionID := thread.SessionID
+			ownerSessionID = &threadSessionID
+		} else if *ownerSessionID != thread.SessionID {
+			return store.Lock{}, fmt.Errorf("owner_thread_id %d does not belong to owner_session_id %d", thread.ID, *ownerSessionID)
+		}
+	}
+	if ownerSessionID != nil {
+		if _, err := service.store.GetSessionByID(ctx, *ownerSessionID); err != nil {
+			return store.Lock{}, err
+		}
+	}
+
+	return service.store.AcquireLock(ctx, store.LockAcquireArgs{
+		ScopeType:      input.ScopeType,
+		ScopePath:      input.ScopePath,
+		OwnerSession:   input.OwnerSession,
+		OwnerSessionID: ownerSessionID,
+		OwnerThreadID:  input.OwnerThreadID,
+		TTLSeconds:     input.TTLSeconds,
+	})
+}
+
+func (service *Service) listLocks(ctx context.Context, input lockListInput) (map[string]any, error) {
+	state := strings.TrimSpace(strings.ToLower(input.State))
+	switch state {
+	case "":
+		state = "active"
+	case "all":
+		state = ""
+	}
+
+	locks, err := service.store.ListLocks(ctx, store.LockFilter{
+		State:          state,
+		OwnerSession:   input.OwnerSession,
+		OwnerSessionID: input.OwnerSessionID,
+		OwnerThreadID:  input.OwnerThreadID,
+		OwnerCaseID:    input.OwnerCaseID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"locks": locks,
+		"count": len(locks),
+	}, nil
+}
+
+// releaseSessionLocks and releaseThreadLocks release every active lock of the
+// owner and return how many were released. A failure is returned, not
+// swallowed: callers report it so a leaked lock does not go unnoticed.
+func (service *Service) releaseSessionLocks(ctx context.Context, sessionID int64, reason string) (int, error) {
+	released, err := service.store.ReleaseLocksByOwner(ctx, store.LockReleaseByOwnerArgs{
+		OwnerSessionID: &sessionID,
+		Reason:         reason,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to release locks of session %d: %w", sessionID, err)
+	}
+	return len(released), nil
+}
+
+func (service *Service) releaseThreadLocks(ctx context.Context, threadID int64, reason string) (int, error) {
+	released, err := service.store.ReleaseLocksByOwner(ctx, store.LockReleaseByOwnerArgs{
+		OwnerThreadID: &threadID,
+		Reason:        reason,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to release locks of thread %d: %w", threadID, err)
+	}
+	return len(released), nil
+}
+
+// claimCaseLocks claims the case's file locks and returns the ones this call
+// acquired. Locks the case already held are left out, so a case.begin that
+// fails afterwards does not release them.
+func (service *Service) claimCaseLocks(ctx context.Context, input caseBeginInput) ([]store.Lock, error) {
+	if !boolValueOrDefault(input.AutoLock, true) || input.CaseID <= 0 {
+		return nil, nil
+	}
+
+	paths := append([]string{}, input.RequiredFiles...)
+	if input.NodeID != nil {
+		node, err := service.store.GetGraphNodeByID(ctx, *input.NodeID)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, decodeStringSliceJSON(valueOrEmpty(node.AffectedFilesJSON))...)
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	var ownerSessionID *int64
+	if input.SessionID > 0 {
+		sessionID := input.SessionID
+		ownerSessionID = &sessionID
+	}
+	if input.ThreadID != nil && ownerSessionID == nil {
+		thread, err := service.store.GetThreadByID(ctx, *input.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		threadSessionID := thread.SessionID
+		ownerSessionID = &threadSessionID
+	}
+	ownerSession := ""
+	if ownerSessionID == nil {
+		ownerSession = fmt.Sprintf("case:%d", input.CaseID)
+	}
+
+	caseID := input.CaseID
+	heldLocks, err := service.store.ListLocks(ctx, store.LockFilter{State: "active", OwnerCaseID: &caseID})
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[int64]bool, len(heldLocks))
+	for _, lock := range heldLocks {
+		held[lock.ID] = true
+	}
+
+	claimedLocks, err := service.store.ClaimCaseLocks(ctx, store.CaseLockClaimArgs{
+		CaseID:         input.CaseID,
+		Paths:          paths,
+		OwnerSession:   ownerSession,
+		OwnerSessionID: ownerSessionID,
+		OwnerThreadID:  input.ThreadID,
+		TTLSeconds:     input.LockTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	acquiredLocks := make([]store.Lock, 0, len(claimedLocks))
+	for _, lock := range claimedLocks {
+		if !held[lock.ID] {
+			acquiredLocks = append(acquiredLocks, lock)
+		}
+	}
+	return acquiredLocks, nil
+}
+
+func decodeStringSliceJSON(raw string) []string {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return []string{}
+	}
+	values := make([]string, 0)
+	if err := json.Unmarshal([]byte(trimmed), &values); err != nil {
+		return []string{}
+	}
+	return values
+}
+
+func (service *Service) auditLocks(ctx context.Context, input lockAuditInput) (map[string]any, error) {
+	if input.ThreadID <= 0 {
+		return nil, errors.New("thread_id is required")
+	}
+	thread, err := service.store.GetThreadByID(ctx, input.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread.WorktreeID == nil {
+		return nil, fmt.Errorf("thread has no worktree bound: %d", thread.ID)
+	}
+	worktree, err := service.store.GetWorktreeByID(ctx, *thread.WorktreeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Committed edits count too, so the diff starts where the worktree
+	// branched off: the commit recorded at creation, else the parent branch,
+	// else the repository's default branch. HEAD would hide every commit.
+	baseRef := strings.TrimSpace(input.BaseRef)
+	if baseRef == "" && worktree.BaseRef != nil {
+		baseRef = *worktree.BaseRef
+	}
+	if baseRef == "" && worktree.ParentWorktree != nil {
+		parentWorktree, parentErr := service.store.GetWorktreeByID(ctx, *worktree.ParentWorktree)
+		if parentErr == nil && strings.TrimSpace(parentWorktree.Branch) != "" {
+			baseRef = parentWorktree.Branch
+		}
+	}
+	if baseRef == "" {
+		if baseRef, err = gitDefaultBranch(worktree.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	changedPaths, err := gitChangedPaths(worktree.Path, baseRef)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := thread.SessionID
+	heldLocks, err := service.store.ListLocks(ctx, store.LockFilter{
+		State:          "active",
+		OwnerSessionID: &sessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	violations := make([]string, 0)
+	for _, changedPath := range changedPaths {
+		covered := false
+		for _, lock := range heldLocks {
+			if store.LockCoversPath(lock, changedPath) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			violations = append(violations, changedPath)
+		}
+	}
+
+	result := map[string]any{
+		"thread_id":     thread.ID,
+		"session_id":    thread.SessionID,
+		"worktree_path": worktree.Path,
+		"base_ref":      baseRef,
+		"changed_paths": changedPaths,
+		"held_locks":    heldLocks,
+		"violations":    violations,
+		"clean":         len(violations) == 0,
+		"notified":      false,
+	}
+	if len(violations) == 0 || !boolValueOrDefault(input.Notify, true) {
+		return result, nil
+	}
+
+	rootThread, err := service.store.GetSessionRootThread(ctx, thread.SessionID)
+	if err != nil || rootThread == nil || rootThread.ID == thread.ID {
+		return result, nil
+	}
+	message, err := service.store.CreateInboxMessage(ctx, store.InboxMessageCreateArgs{
+		SenderThreadID:   thread.ID,
+		ReceiverThreadID: rootThread.ID,
+		Message: fmt.Sprintf(
+			"[lock.audit] thread %d modified %d path(s) without a held lock: %s",
+			thread.ID,
+			len(violations),
+			strings.Join(violations, ", "),
+		),
+	})
+	if err == nil {
+		result["notified"] = true
+		result["inbox_message_id"] = message.ID
+	}
+	return result, nil
+}
+
+// gitDefaultBranch names the branch the repository's origin points at, or a
+// local main/master when there is no remote.
+func gitDefaultBranch(worktreePath string) (string, error) {
+	output, err := exec.Command("git", "-C", worktreePath, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD").Output()
+	if err == nil && strings.TrimSpace(string(output)) != "" {
+		return strings.TrimSpace(string(output)), nil
+	}
+	for _, candidate := range []string{"main", "master"} {
+		if exec.Command("git", "-C", worktreePath, "rev-parse", "--verify", "--quiet", "refs/heads/"+candidate).Run() == nil {
+			return candidate, nil
+		}
+	}
+	return "", errors.New("cannot determine the worktree base; pass base_ref")
+}
+
+func gitChangedPaths(worktreePath string, baseRef string) ([]string, error) {
+	diffBase := baseRef
+	mergeBaseOutput, err := exec.Command("git", "-C", worktreePath, "merge-base", baseRef, "HEAD").Output()
+	if err == nil && strings.TrimSpace(string(mergeBaseOutput)) != "" {
+		diffBase = strings.TrimSpace(string(mergeBaseOutput))
+	}
+
+	diffOutput, err := exec.Command("git", "-C", worktreePath, "diff", "--name-only", diffBase).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w (%s)", err, strings.TrimSpace(string(diffOutput)))
+	}
+	untrackedOutput, err := exec.Command("git", "-C", worktreePath, "ls-files", "--others", "--exclude-standard").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w (%s)", err, strings.TrimSpace(string(untrackedOutput)))
+	}
+
+	seen := make(map[string]bool)
+	paths := make([]string, 0)
+	for _, line := range strings.Split(string(diffOutput)+"\n"+string(untrackedOutput), "\n") {
+		path := strings.TrimSpace(line)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,149 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestGitChangedPathsIncludesUntrackedAndModified(t *testing.T) {
+	repoPath := t.TempDir()
+	runGit := func(args ...string) {
+		command := exec.Command("git", append([]string{"-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v (%s)", args, err, output)
+		}
+	}
+	writeFile := func(relativePath string, content string) {
+		absolutePath := filepath.Join(repoPath, relativePath)
+		if err := os.MkdirAll(filepath.Dir(absolutePath), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(absolutePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	runGit("init", "-q")
+	writeFile("src/api/users.go", "package api\n")
+	runGit("add", "-A")
+	runGit("commit", "-q", "-m", "init")
+
+	writeFile("src/api/users.go", "package api\n\n// changed\n")
+	writeFile("src/ui/app.go", "package ui\n")
+
+	paths, err := gitChangedPaths(repoPath, "HEAD")
+	if err != nil {
+		t.Fatalf("failed to collect changed paths: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "src/api/users.go" || paths[1] != "src/ui/app.go" {
+		t.Fatalf("expected modified and untracked paths, got %+v", paths)
+	}
+}
+
+func TestAuditLocksReportsCommittedUnlockedEdits(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	runGit := func(dir string, args ...string) {
+		command := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v (%s)", args, err, output)
+		}
+	}
+	writeFile := func(dir string, relativePath string, content string) {
+		absolutePath := filepath.Join(dir, relativePath)
+		if err := os.MkdirAll(filepath.Dir(absolutePath), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(absolutePath, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	runGit(repoPath, "init", "-q")
+	writeFile(repoPath, "src/api/users.go", "package api\n")
+	runGit(repoPath, "add", "-A")
+	runGit(repoPath, "commit", "-q", "-m", "init")
+
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	worktree, err := service.createWorktree(ctx, worktreeCreateInput{Branch: "task/audit", CreateOnDisk: true})
+	if err != nil {
+		t.Fatalf("failed to create worktree: %v", err)
+	}
+	if worktree.BaseRef == nil || *worktree.BaseRef == "" {
+		t.Fatalf("expected the worktree to record its base commit, got %+v", worktree)
+	}
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running", WorktreeID: &worktree.ID})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	if _, err := service.acquireLock(ctx, lockAcquireInput{ScopeType: "file", ScopePath: "src/api/users.go", OwnerSessionID: &session.ID, OwnerThreadID: &thread.ID}); err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	writeFile(worktree.Path, "src/api/users.go", "package api\n\n// locked\n")
+	writeFile(worktree.Path, "src/ui/app.go", "package ui\n")
+	runGit(worktree.Path, "add", "-A")
+	runGit(worktree.Path, "commit", "-q", "-m", "work")
+
+	audit, err := service.auditLocks(ctx, lockAuditInput{ThreadID: thread.ID, Notify: pointerToBool(false)})
+	if err != nil {
+		t.Fatalf("failed to audit locks: %v", err)
+	}
+	violations := audit["violations"].([]string)
+	if audit["base_ref"] != *worktree.BaseRef || len(violations) != 1 || violations[0] != "src/ui/app.go" {
+		t.Fatalf("expected the committed unlocked file to be reported, got %+v", audit)
+	}
+}
+
+func TestFailedCaseBeginReleasesOnlyItsOwnLocks(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	// BeginCase refuses a task that is not a case, after the locks are claimed.
+	feature, err := service.store.CreateTask(ctx, store.TaskCreateArgs{Level: "feature", Title: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	held, err := service.store.ClaimCaseLocks(ctx, store.CaseLockClaimArgs{CaseID: feature.ID, Paths: []string{"api.go"}, OwnerSessionID: &session.ID})
+	if err != nil || len(held) != 1 {
+		t.Fatalf("failed to claim the earlier lock: %+v (%v)", held, err)
+	}
+
+	params := fmt.Sprintf(`{"case_id":%d,"session_id":%d,"required_files":["api.go","ui.go"]}`, feature.ID, session.ID)
+	if _, err := service.Handle(ctx, "case.begin", json.RawMessage(params)); err == nil {
+		t.Fatal("expected case.begin to fail for a non-case task")
+	}
+	active, err := service.store.ListLocks(ctx, store.LockFilter{State: "active", OwnerCaseID: &feature.ID})
+	if err != nil || len(active) != 1 || active[0].ID != held[0].ID {
+		t.Fatalf("expected the earlier lock to survive, got %+v (%v)", active, err)
+	}
+	released, err := service.store.ListLocks(ctx, store.LockFilter{State: "released", OwnerCaseID: &feature.ID})
+	if err != nil || len(released) != 1 || released[0].ScopePath != "ui.go" || valueOrEmpty(released[0].ReleaseReason) != "case_begin_failed" {
+		t.Fatalf("expected only the lock claimed by the failed call released, got %+v (%v)", released, err)
+	}
+}
@@ -0,0 +1,355 @@
+package orchestrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const (
+	defaultLogRotateBytes  = 32 * 1024 * 1024
+	defaultLogKeepSegments = 5
+	defaultLogMaxAgeDays   = 14
+	logSegmentSuffix       = ".gz"
+)
+
+// logRetentionPolicy is .codex-orch/logs.yaml. Zero or missing fields use the
+// defaults (32 MiB, 5 segments, 14 days).
+type logRetentionPolicy struct {
+	RotateBytes  int64 `yaml:"rotate_bytes" json:"rotate_bytes"`
+	KeepSegments int   `yaml:"keep_segments" json:"keep_segments"`
+	MaxAgeDays   int   `yaml:"max_age_days" json:"max_age_days"`
+}
+
+// logSegment is one rotated, gzip-compressed piece of a thread log:
+// thread_N.log.0001.gz is the oldest.
+type logSegment struct {
+	Path    string
+	Number  int
+	Size    int64
+	ModTime time.Time
+}
+
+type logRotation struct {
+	ThreadID int64  `json:"thread_id"`
+	Segment  string `json:"segment"`
+	Bytes    int64  `json:"bytes"`
+}
+
+func (service *Service) logRetentionPolicyPath() string {
+	return service.orchConfigPath("logs.yaml")
+}
+
+// loadLogRetentionPolicy returns the rotation size and segment retention
+// limits, with unset fields at their defaults. logs.gc and the supervisor
+// sweep read it per run; a broken file still yields the defaults alongside
+// the error so the sweep keeps rotating.
+func (service *Service) loadLogRetentionPolicy() (logRetentionPolicy, error) {
+	var policy logRetentionPolicy
+	if err := service.loadOrchConfigYAML("logs.yaml", &policy); err != nil {
+		return logRetentionPolicy{}.withDefaults(), err
+	}
+	return policy.withDefaults(), nil
+}
+
+func (policy logRetentionPolicy) withDefaults() logRetentionPolicy {
+	if policy.RotateBytes <= 0 {
+		policy.RotateBytes = defaultLogRotateBytes
+	}
+	if policy.KeepSegments <= 0 {
+		policy.KeepSegments = defaultLogKeepSegments
+	}
+	if policy.MaxAgeDays <= 0 {
+		policy.MaxAgeDays = defaultLogMaxAgeDays
+	}
+	return policy
+}
+
+// listLogSegments returns the rotated segments of a log, oldest first.
+func listLogSegments(logPath string) ([]logSegment, error) {
+	entries, err := os.ReadDir(filepath.Dir(logPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := filepath.Base(logPath) + "."
+	segments := make([]logSegment, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, logSegmentSuffix) {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), logSegmentSuffix))
+		if err != nil || number <= 0 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, logSegment{
+			Path:    filepath.Join(filepath.Dir(logPath), name),
+			Number:  number,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	sort.Slice(segments, func(left, right int) bool { return segments[left].Number < segments[right].Number })
+	return segments, nil
+}
+
+// readLogSegmentsTail returns up to maxBytes of the newest rotated output,
+// decompressing segments newest first.
+func readLogSegmentsTail(logPath string, maxBytes int64) (string, error) {
+	if maxBytes <= 0 {
+		return "", nil
+	}
+	segments, err := listLogSegments(logPath)
+	if err != nil {
+		return "", err
+	}
+	chunks := make([][]byte, 0)
+	remaining := maxBytes
+	for index := len(segments) - 1; index >= 0 && remaining > 0; index-- {
+		content, err := readLogSegment(segments[index].Path)
+		if err != nil {
+			return joinReversed(chunks), err
+		}
+		if int64(len(content)) > remaining {
+			content = content[int64(len(content))-remaining:]
+		}
+		chunks = append(chunks, content)
+		remaining -= int64(len(content))
+	}
+	return joinReversed(chunks), nil
+}
+
+func joinReversed(chunks [][]byte) string {
+	var buffer bytes.Buffer
+	for index := len(chunks) - 1; index >= 0; index-- {
+		buffer.Write(chunks[index])
+	}
+	return buffer.String()
+}
+
+func readLogSegment(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// rotateLog moves the current content of a live log into the next compressed
+// segment and truncates the log in place. Writers append (tmux `cat >>`, the
+// headless runner opens with O_APPEND), so they carry on at the new end;
+// output written between the copy and the truncate is lost.
+func rotateLog(logPath string) (string, int64, error) {
+	content, err := os.ReadFile(logPath)
+	if err != nil || len(content) == 0 {
+		return "", 0, err
+	}
+	segmentPath, err := writeLogSegment(logPath, content)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Truncate(logPath, 0); err != nil {
+		return "", 0, err
+	}
+	return segmentPath, int64(len(content)), nil
+}
+
+// compressClosedLog turns the log of a thread that no longer runs into its
+// last segment.
+func compressClosedLog(logPath string) (string, int64, error) {
+	content, err := os.ReadFile(logPath)
+	if err != nil || len(content) == 0 {
+		return "", 0, err
+	}
+	segmentPath, err := writeLogSegment(logPath, content)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.Remove(logPath); err != nil {
+		return "", 0, err
+	}
+	return segmentPath, int64(len(content)), nil
+}
+
+func writeLogSegment(logPath string, content []byte) (string, error) {
+	segments, err := listLogSegments(logPath)
+	if err != nil {
+		return "", err
+	}
+	number := 1
+	if len(segments) > 0 {
+		number = segments[len(segments)-1].Number + 1
+	}
+	segmentPath := fmt.Sprintf("%s.%04d%s", logPath, number, logSegmentSuffix)
+	file, err := os.OpenFile(segmentPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return "", err
+	}
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write(content); err != nil {
+		_ = file.Close()
+		_ = os.Remove(segmentPath)
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(segmentPath)
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(segmentPath)
+		return "", err
+	}
+	return segmentPath, nil
+}
+
+// pruneLogSegments removes segments beyond the newest keep and any older than
+// maxAge. With dryRun it only reports them.
+func pruneLogSegments(logPath string, keep int, maxAge time.Duration, now time.Time, dryRun bool) ([]string, int64, error) {
+	segments, err := listLogSegments(logPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	removed := make([]string, 0)
+	freed := int64(0)
+	for index, segment := range segments {
+		if index >= len(segments)-keep && now.Sub(segment.ModTime) <= maxAge {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(segment.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return removed, freed, err
+			}
+		}
+		removed = append(removed, segment.Path)
+		freed += segment.Size
+	}
+	return removed, freed, nil
+}
+
+// rotateThreadLog rotates a live thread log once it reaches the policy size
+// and prunes its old segments. It returns nil when nothing was rotated.
+func (service *Service) rotateThreadLog(thread store.Thread, policy logRetentionPolicy, now time.Time) (*logRotation, error) {
+	logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+	if logPath == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(logPath)
+	if err != nil || info.Size() < policy.RotateBytes {
+		return nil, nil
+	}
+	segmentPath, size, err := rotateLog(logPath)
+	if err != nil || segmentPath == "" {
+		return nil, err
+	}
+	if _, _, err := pruneLogSegments(logPath, policy.KeepSegments, time.Duration(policy.MaxAgeDays)*24*time.Hour, now, false); err != nil {
+		return nil, err
+	}
+	return &logRotation{ThreadID: thread.ID, Segment: segmentPath, Bytes: size}, nil
+}
+
+// threadAgentAlive reports whether the thread's pane or headless process still
+// exists. The DB status cannot tell: interrupted threads keep running and a
+// thread marked completed may still be writing to its log.
+func (service *Service) threadAgentAlive(ctx context.Context, thread store.Thread) bool {
+	runner, err := service.runnerFor(thread)
+	return err == nil && runner.Alive(ctx, thread)
+}
+
+// logsGC rotates oversized live logs, compresses the logs of threads whose
+// pane or process is gone and applies count and age retention to every
+// thread's segments.
+func (service *Service) logsGC(ctx context.Context, input logsGCInput) (map[string]any, error) {
+	policy, err := service.loadLogRetentionPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if input.KeepSegments != nil {
+		if *input.KeepSegments < 0 {
+			return nil, errors.New("keep_segments must not be negative")
+		}
+		policy.KeepSegments = *input.KeepSegments
+	}
+	if input.MaxAgeDays != nil {
+		if *input.MaxAgeDays <= 0 {
+			return nil, errors.New("max_age_days must be positive")
+		}
+		policy.MaxAgeDays = *input.MaxAgeDays
+	}
+	threads, err := service.store.ListThreads(ctx, store.ThreadFilter{SessionID: input.SessionID})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	maxAge := time.Duration(policy.MaxAgeDays) * 24 * time.Hour
+	rotated := make([]logRotation, 0)
+	compressed := make([]logRotation, 0)
+	removed := make([]string, 0)
+	freed := int64(0)
+	for _, thread := range threads {
+		logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+		if logPath == "" {
+			continue
+		}
+		info, statErr := os.Stat(logPath)
+		hasContent := statErr == nil && info.Size() > 0
+		alive := service.threadAgentAlive(ctx, thread)
+		switch {
+		case alive && hasContent && info.Size() >= policy.RotateBytes:
+			rotation := logRotation{ThreadID: thread.ID, Bytes: info.Size()}
+			if !input.DryRun {
+				if rotation.Segment, rotation.Bytes, err = rotateLog(logPath); err != nil {
+					return nil, fmt.Errorf("thread %d: %w", thread.ID, err)
+				}
+			}
+			rotated = append(rotated, rotation)
+		case !alive && hasContent:
+			rotation := logRotation{ThreadID: thread.ID, Bytes: info.Size()}
+			if !input.DryRun {
+				if rotation.Segment, rotation.Bytes, err = compressClosedLog(logPath); err != nil {
+					return nil, fmt.Errorf("thread %d: %w", thread.ID, err)
+				}
+			}
+			compressed = append(compressed, rotation)
+		}
+		pruned, prunedBytes, err := pruneLogSegments(logPath, policy.KeepSegments, maxAge, now, input.DryRun)
+		if err != nil {
+			return nil, fmt.Errorf("thread %d: %w", thread.ID, err)
+		}
+		removed = append(removed, pruned...)
+		freed += prunedBytes
+	}
+	return map[string]any{
+		"policy":      policy,
+		"dry_run":     input.DryRun,
+		"rotated":     rotated,
+		"compressed":  compressed,
+		"removed":     removed,
+		"bytes_freed": freed,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestLogsGCRotatesCompressesAndPrunes(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	if err := os.WriteFile(service.logRetentionPolicyPath(), []byte("rotate_bytes: 16\nkeep_segments: 2\n"), 0o644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	// The live worker is already marked completed; its process decides.
+	running, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "completed"})
+	if err != nil {
+		t.Fatalf("failed to create running thread: %v", err)
+	}
+	stopped, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "stopped"})
+	if err != nil {
+		t.Fatalf("failed to create stopped thread: %v", err)
+	}
+	runningLog := service.threadLogFilePath(running)
+	stoppedLog := service.threadLogFilePath(stopped)
+	for thread, logPath := range map[int64]string{running.ID: runningLog, stopped.ID: stoppedLog} {
+		if _, err := service.store.UpdateThread(ctx, thread, store.ThreadUpdateArgs{LogFilePath: &logPath}); err != nil {
+			t.Fatalf("failed to bind log: %v", err)
+		}
+	}
+	backend := runnerBackendHeadless
+	if _, err := service.store.UpdateThread(ctx, running.ID, store.ThreadUpdateArgs{RunnerBackend: &backend}); err != nil {
+		t.Fatalf("failed to set backend: %v", err)
+	}
+	if _, err := service.headless.Start(headless.StartOptions{ThreadID: running.ID, Command: "exec cat", LogFilePath: runningLog}); err != nil {
+		t.Fatalf("failed to start live process: %v", err)
+	}
+	running, _ = service.store.GetThreadByID(ctx, running.ID)
+
+	policy, err := service.loadLogRetentionPolicy()
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	for _, chunk := range []string{"first chunk of output\n", "second chunk of output\n", "third chunk of output\n"} {
+		if err := os.WriteFile(runningLog, []byte(chunk), 0o644); err != nil {
+			t.Fatalf("failed to write log: %v", err)
+		}
+		rotation, err := service.rotateThreadLog(running, policy, time.Now())
+		if err != nil || rotation == nil {
+			t.Fatalf("expected rotation, got %+v (%v)", rotation, err)
+		}
+	}
+	segments, err := listLogSegments(runningLog)
+	if err != nil || len(segments) != 2 || segments[0].Number != 2 {
+		t.Fatalf("expected the newest two segments, got %+v (%v)", segments, err)
+	}
+	if err := os.WriteFile(runningLog, []byte("live\n"), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	if tail, err := readFileTail(runningLog, 1024); err != nil || tail != "second chunk of output\nthird chunk of output\nlive\n" {
+		t.Fatalf("expected tail across segments, got %q (%v)", tail, err)
+	}
+
+	if err := os.WriteFile(stoppedLog, []byte("finished output\n"), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	preview, err := service.logsGC(ctx, logsGCInput{DryRun: true})
+	if err != nil {
+		t.Fatalf("logs.gc dry run failed: %v", err)
+	}
+	if compressed := preview["compressed"].([]logRotation); len(compressed) != 1 || compressed[0].Segment != "" {
+		t.Fatalf("unexpected dry run: %+v", preview)
+	}
+	if _, err := os.Stat(stoppedLog); err != nil {
+		t.Fatalf("dry run must not touch logs: %v", err)
+	}
+
+	keep := 1
+	result, err := service.logsGC(ctx, logsGCInput{KeepSegments: &keep})
+	if err != nil {
+		t.Fatalf("logs.gc failed: %v", err)
+	}
+	compressed := result["compressed"].([]logRotation)
+	if len(compressed) != 1 || compressed[0].ThreadID != stopped.ID || !strings.HasSuffix(compressed[0].Segment, ".0001.gz") {
+		t.Fatalf("expected the stopped log to be compressed, got %+v", result)
+	}
+	if removed := result["removed"].([]string); len(removed) != 1 || filepath.Base(removed[0]) != filepath.Base(segments[0].Path) {
+		t.Fatalf("expected the older running segment to be pruned, got %+v", removed)
+	}
+	if _, err := os.Stat(stoppedLog); !os.IsNotExist(err) {
+		t.Fatalf("expected closed log to be replaced by its segment, got %v", err)
+	}
+	if tail, err := readFileTail(stoppedLog, 4096); err != nil || tail != "finished output\n" {
+		t.Fatalf("expected compressed log to stay readable, got %q (%v)", tail, err)
+	}
+	if _, err := os.Stat(runningLog); err != nil {
+		t.Fatalf("expected the live log to stay uncompressed, got %v", err)
+	}
+
+	// Once the process is gone the log is closed, whatever the status says.
+	if err := service.headless.Stop(running.ID, time.Second); err != nil {
+		t.Fatalf("failed to stop live process: %v", err)
+	}
+	result, err = service.logsGC(ctx, logsGCInput{})
+	if err != nil {
+		t.Fatalf("logs.gc failed: %v", err)
+	}
+	if compressed := result["compressed"].([]logRotation); len(compressed) != 1 || compressed[0].ThreadID != running.ID {
+		t.Fatalf("expected the exited worker's log to be compressed, got %+v", result)
+	}
+}
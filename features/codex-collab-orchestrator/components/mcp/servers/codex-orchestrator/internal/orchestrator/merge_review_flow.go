@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+// submitMergeReview records the reviewer's verdict and drives the merge
+// pipeline: approval leaves the main merge lock with the session so the
+// approved queue item can merge next; request_changes and reject release the
+// lock and post the findings to the worker's inbox.
+func (service *Service) submitMergeReview(ctx context.Context, input mergeReviewSubmitInput) (map[string]any, error) {
+	reviewJobID, err := service.resolveReviewJobID(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]store.ReviewFindingArgs, 0, len(input.Findings))
+	for _, finding := range input.Findings {
+		findings = append(findings, store.ReviewFindingArgs{
+			FilePath: finding.File,
+			Line:     finding.Line,
+			Severity: finding.Severity,
+			Message:  finding.Message,
+		})
+	}
+	submission, err := service.store.SubmitReview(ctx, store.ReviewSubmitArgs{
+		ReviewJobID: reviewJobID,
+		Verdict:     input.Verdict,
+		Summary:     input.Summary,
+		Findings:    findings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string]any{
+		"review_job":         submission.ReviewJob,
+		"findings":           submission.Findings,
+		"merge_request":      submission.MergeRequest,
+		"main_merge_request": submission.MainMergeRequest,
+		"lock_released":      false,
+	}
+	if valueOrEmpty(submission.ReviewJob.Verdict) == store.ReviewVerdictApprove {
+		return response, nil
+	}
+
+	if _, err := service.store.ReleaseMainMergeLock(ctx, submission.ReviewJob.SessionID); err != nil {
+		response["lock_release_error"] = err.Error()
+	} else {
+		response["lock_released"] = true
+	}
+
+	workerThreadIDs, err := service.resolveReviewWorkerThreads(ctx, submission, input.WorkerThreadID)
+	if err != nil {
+		response["inbox_error"] = err.Error()
+		return response, nil
+	}
+	senderThreadID := int64(0)
+	if submission.ReviewJob.ReviewerThreadID != nil {
+		senderThreadID = *submission.ReviewJob.ReviewerThreadID
+	} else if len(workerThreadIDs) > 0 {
+		senderThreadID = workerThreadIDs[0]
+	}
+	message := formatReviewFindingsMessage(submission, input.Summary)
+	notified := make([]int64, 0, len(workerThreadIDs))
+	for _, workerThreadID := range workerThreadIDs {
+		if _, err := service.store.CreateInboxMessage(ctx, store.InboxMessageCreateArgs{
+			SenderThreadID:   senderThreadID,
+			ReceiverThreadID: workerThreadID,
+			Message:          message,
+		}); err != nil {
+			response["inbox_error"] = err.Error()
+			continue
+		}
+		notified = append(notified, workerThreadID)
+	}
+	response["notified_thread_ids"] = notified
+	return response, nil
+}
+
+func (service *Service) resolveReviewJobID(ctx context.Context, input mergeReviewSubmitInput) (int64, error) {
+	switch {
+	case input.ReviewJobID != nil && *input.ReviewJobID > 0:
+		return *input.ReviewJobID, nil
+	case input.MergeRequestID != nil && *input.MergeRequestID > 0:
+		job, err := service.store.GetLatestReviewJobByMergeRequest(ctx, *input.MergeRequestID)
+		if err != nil {
+			return 0, err
+		}
+		if job == nil {
+			return 0, fmt.Errorf("merge request %d has no review job", *input.MergeRequestID)
+		}
+		return job.ID, nil
+	default:
+		return 0, errors.New("review_job_id or merge_request_id is required")
+	}
+}
+
+// resolveReviewWorkerThreads finds the worker threads whose scope covers the
+// reviewed feature or its cases. When none match, the findings go to the
+// session root thread so they are never dropped.
+func (service *Service) resolveReviewWorkerThreads(ctx context.Context, submission store.ReviewSubmission, override *int64) ([]int64, error) {
+	if override != nil && *override > 0 {
+		if _, err := service.store.GetThreadByID(ctx, *override); err != nil {
+			return nil, err
+		}
+		return []int64{*override}, nil
+	}
+
+	scopeIDs := map[int64]bool{submission.MergeRequest.FeatureTaskID: true}
+	children, err := service.store.ListTasks(ctx, store.TaskFilter{ParentID: &submission.MergeRequest.FeatureTaskID})
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		scopeIDs[child.ID] = true
+	}
+
+	threads, err := service.store.ListThreads(ctx, store.ThreadFilter{SessionID: submission.ReviewJob.SessionID, Role: "worker"})
+	if err != nil {
+		return nil, err
+	}
+	workerThreadIDs := make([]int64, 0)
+	for _, thread := range threads {
+		scoped := append(decodeInt64JSON(valueOrEmpty(thread.ScopeTaskIDsJSON)), decodeInt64JSON(valueOrEmpty(thread.ScopeCaseIDsJSON))...)
+		for _, taskID := range scoped {
+			if scopeIDs[taskID] {
+				workerThreadIDs = append(workerThreadIDs, thread.ID)
+				break
+			}
+		}
+	}
+	if len(workerThreadIDs) > 0 {
+		return workerThreadIDs, nil
+	}
+
+	rootThread, err := service.store.GetSessionRootThread(ctx, submission.ReviewJob.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if rootThread == nil {
+		return nil, fmt.Errorf("no worker or root thread found for session %d", submission.ReviewJob.SessionID)
+	}
+	return []int64{rootThread.ID}, nil
+}
+
+func formatReviewFindingsMessage(submission store.ReviewSubmission, summary string) string {
+	lines := []string{
+		fmt.Sprintf("[merge-review] merge request %d: %s (review job %d)", submission.MergeRequest.ID, valueOrEmpty(submission.ReviewJob.Verdict), submission.ReviewJob.ID),
+	}
+	if summary = strings.TrimSpace(summary); summary != "" {
+		lines = append(lines, summary)
+	}
+	for _, finding := range submission.Findings {
+		location := finding.FilePath
+		if finding.Line != nil {
+			location = fmt.Sprintf("%s:%d", finding.FilePath, *finding.Line)
+		}
+		lines = append(lines, fmt.Sprintf("- [%s] %s %s", finding.Severity, location, finding.Message))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,83 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestSubmitMergeReviewRequestChangesReleasesLockAndNotifiesWorker(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	feature, err := service.store.CreateTask(ctx, store.TaskCreateArgs{Level: "epic", Title: "payments"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{
+		SessionID:        session.ID,
+		ParentThreadID:   &root.ID,
+		Role:             "worker",
+		Status:           "running",
+		ScopeTaskIDsJSON: marshalInt64Slice([]int64{feature.ID}),
+	})
+	if err != nil {
+		t.Fatalf("failed to create worker thread: %v", err)
+	}
+	reviewer, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "merge-reviewer", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create reviewer thread: %v", err)
+	}
+	mergeRequest, err := service.store.CreateMergeRequest(ctx, store.MergeRequestArgs{FeatureTaskID: feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create merge request: %v", err)
+	}
+	if _, err := service.store.AcquireMainMergeLock(ctx, session.ID, 0); err != nil {
+		t.Fatalf("failed to acquire main lock: %v", err)
+	}
+	if _, err := service.store.CreateReviewJob(ctx, store.ReviewJobCreateArgs{MergeRequestID: mergeRequest.ID, SessionID: session.ID, ReviewerThreadID: &reviewer.ID, State: "running"}); err != nil {
+		t.Fatalf("failed to create review job: %v", err)
+	}
+
+	line := 7
+	result, err := service.submitMergeReview(ctx, mergeReviewSubmitInput{
+		MergeRequestID: &mergeRequest.ID,
+		Verdict:        "request_changes",
+		Summary:        "missing idempotency key",
+		Findings:       []mergeReviewFindingInput{{File: "api/pay.go", Line: &line, Severity: "major", Message: "send Idempotency-Key"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	if result["lock_released"] != true {
+		t.Fatalf("expected main merge lock released, got %+v", result)
+	}
+	if notified := result["notified_thread_ids"].([]int64); len(notified) != 1 || notified[0] != worker.ID {
+		t.Fatalf("expected worker notified, got %+v", result)
+	}
+	messages, err := service.store.ListInboxMessages(ctx, worker.ID)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected one worker inbox message, got %+v (%v)", messages, err)
+	}
+	if messages[0].SenderThreadID != reviewer.ID || !strings.Contains(messages[0].Message, "- [major] api/pay.go:7 send Idempotency-Key") {
+		t.Fatalf("unexpected findings message: %+v", messages[0])
+	}
+	if _, err := service.store.AcquireMainMergeLock(ctx, session.ID+1, 0); err != nil {
+		t.Fatalf("expected another session to take the released lock: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+// usageLogTailBytes is how much of a thread log is scanned for the latest
+// usage report; exit summaries and /cost blocks sit at the end.
+const usageLogTailBytes = 64 * 1024
+
+// restartAttemptMarker starts the line written to a thread log before each
+// supervisor restart. Restarts append to the same log, so usage parsing skips
+// everything before the last marker to avoid charging the previous attempt's
+// report to the new one.
+const restartAttemptMarker = "[codex-orchestrator] restart attempt "
+
+func appendRestartAttemptMarker(logPath string, attempt int) error {
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(logFile, "\n%s%d\n", restartAttemptMarker, attempt); err != nil {
+		_ = logFile.Close()
+		return err
+	}
+	return logFile.Close()
+}
+
+// usageTotals is the sum shared by every metrics.usage rollup.
+type usageTotals struct {
+	Threads           int      `json:"threads"`
+	InputTokens       int64    `json:"input_tokens"`
+	CachedInputTokens int64    `json:"cached_input_tokens"`
+	OutputTokens      int64    `json:"output_tokens"`
+	TotalTokens       int64    `json:"total_tokens"`
+	CostUSD           *float64 `json:"cost_usd,omitempty"`
+}
+
+func (totals *usageTotals) add(thread store.ThreadUsageTotal) {
+	totals.Threads++
+	totals.InputTokens += thread.InputTokens
+	totals.CachedInputTokens += thread.CachedInputTokens
+	totals.OutputTokens += thread.OutputTokens
+	totals.TotalTokens += thread.TotalTokens
+	if thread.CostUSD != nil {
+		cost := *thread.CostUSD
+		if totals.CostUSD != nil {
+			cost += *totals.CostUSD
+		}
+		totals.CostUSD = &cost
+	}
+}
+
+type sessionUsage struct {
+	SessionID int64 `json:"session_id"`
+	usageTotals
+}
+
+type roleUsage struct {
+	Role string `json:"role"`
+	usageTotals
+}
+
+// planNodeUsage compares actual tokens with the planned token_estimate of a
+// slice, or of all used slices under an initiative.
+type planNodeUsage struct {
+	NodeID        int64  `json:"node_id"`
+	Title         string `json:"title"`
+	TokenEstimate *int64 `json:"token_estimate,omitempty"`
+	EstimateDelta *int64 `json:"estimate_delta,omitempty"`
+	usageTotals
+}
+
+func (usage *planNodeUsage) addEstimate(estimate *int) {
+	if estimate == nil {
+		return
+	}
+	total := int64(*estimate)
+	if usage.TokenEstimate != nil {
+		total += *usage.TokenEstimate
+	}
+	usage.TokenEstimate = &total
+}
+
+func (usage *planNodeUsage) finish() {
+	if usage.TokenEstimate != nil {
+		delta := usage.TotalTokens - *usage.TokenEstimate
+		usage.EstimateDelta = &delta
+	}
+}
+
+// recordThreadUsage stores the latest usage report the current attempt wrote
+// to the thread log. It reports whether one was found.
+func (service *Service) recordThreadUsage(ctx context.Context, thread store.Thread) (bool, error) {
+	logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+	if logPath == "" {
+		return false, nil
+	}
+	logTail, err := readFileTail(logPath, usageLogTailBytes)
+	if err != nil || logTail == "" {
+		return false, nil
+	}
+	if index := strings.LastIndex(logTail, restartAttemptMarker); index >= 0 {
+		logTail = logTail[index:]
+	}
+	usage, ok := provider.ParseUsage(provider.StripEscapes(logTail))
+	if !ok {
+		return false, nil
+	}
+	_, err = service.store.RecordThreadUsage(ctx, store.ThreadUsageArgs{
+		ThreadID:          thread.ID,
+		Attempt:           thread.RestartCount,
+		SessionID:         thread.SessionID,
+		ProviderType:      valueOrEmpty(thread.ProviderType),
+		InputTokens:       usage.InputTokens,
+		CachedInputTokens: usage.CachedInputTokens,
+		OutputTokens:      usage.OutputTokens,
+		TotalTokens:       usage.TotalTokens,
+		CostUSD:           usage.CostUSD,
+		Source:            "log",
+	})
+	return err == nil, err
+}
+
+// refreshUsage re-reads the logs of every thread (of one session when
+// sessionID is set) so rollups and budget checks see current numbers.
+func (service *Service) refreshUsage(ctx context.Context, sessionID int64) error {
+	threads, err := service.store.ListThreads(ctx, store.ThreadFilter{SessionID: sessionID})
+	if err != nil {
+		return err
+	}
+	for _, thread := range threads {
+		if _, err := service.recordThreadUsage(ctx, thread); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSessionBudget blocks new spawns once the session has used its token or
+// cost budget.
+func (service *Service) checkSessionBudget(ctx context.Context, sessionID int64) error {
+	budget, err := service.store.GetSessionBudget(ctx, sessionID)
+	if err != nil || budget == nil {
+		return err
+	}
+	if err := service.refreshUsage(ctx, sessionID); err != nil {
+		return err
+	}
+	used, err := service.sessionUsageTotals(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if budget.TokenBudget != nil && used.TotalTokens >= *budget.TokenBudget {
+		return fmt.Errorf("session %d exceeded its token budget (%d of %d tokens used); raise it with metrics.budget.set", sessionID, used.TotalTokens, *budget.TokenBudget)
+	}
+	if budget.CostBudgetUSD != nil && used.CostUSD != nil && *used.CostUSD >= *budget.CostBudgetUSD {
+		return fmt.Errorf("session %d exceeded its cost budget ($%.4f of $%.4f used); raise it with metrics.budget.set", sessionID, *used.CostUSD, *budget.CostBudgetUSD)
+	}
+	return nil
+}
+
+func (service *Service) sessionUsageTotals(ctx context.Context, sessionID int64) (usageTotals, error) {
+	threads, err := service.store.ListThreadUsageTotals(ctx, sessionID)
+	if err != nil {
+		return usageTotals{}, err
+	}
+	totals := usageTotals{}
+	for _, thread := range threads {
+		totals.add(thread)
+	}
+	return totals, nil
+}
+
+func (service *Service) metricsUsage(ctx context.Context, input metricsUsageInput) (map[string]any, error) {
+	if input.SessionID > 0 {
+		if _, err := service.store.GetSessionByID(ctx, input.SessionID); err != nil {
+			return nil, err
+		}
+	}
+	if err := service.refreshUsage(ctx, input.SessionID); err != nil {
+		return nil, err
+	}
+	threads, err := service.store.ListThreadUsageTotals(ctx, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := usageTotals{}
+	sessions := map[int64]*sessionUsage{}
+	roles := map[string]*roleUsage{}
+	slices := map[int64]*planNodeUsage{}
+	initiatives := map[int64]*planNodeUsage{}
+	nodes := map[int64]*store.GraphNode{}
+	for _, thread := range threads {
+		totals.add(thread)
+		if sessions[thread.SessionID] == nil {
+			sessions[thread.SessionID] = &sessionUsage{SessionID: thread.SessionID}
+		}
+		sessions[thread.SessionID].add(thread)
+		if roles[thread.Role] == nil {
+			roles[thread.Role] = &roleUsage{Role: thread.Role}
+		}
+		roles[thread.Role].add(thread)
+
+		// A thread scoped to several slices of one initiative counts once
+		// towards it.
+		countedInitiatives := map[int64]bool{}
+		for _, nodeID := range decodeInt64JSON(valueOrEmpty(thread.ScopeNodeIDsJSON)) {
+			slice, err := service.cachedGraphNode(ctx, nodes, nodeID)
+			if err != nil || slice == nil || slice.NodeType != "slice" {
+				continue
+			}
+			initiative := service.initiativeOf(ctx, nodes, slice)
+			if initiative != nil && initiatives[initiative.ID] == nil {
+				initiatives[initiative.ID] = &planNodeUsage{NodeID: initiative.ID, Title: initiative.Title}
+			}
+			if slices[slice.ID] == nil {
+				slices[slice.ID] = &planNodeUsage{NodeID: slice.ID, Title: slice.Title}
+				slices[slice.ID].addEstimate(slice.TokenEstimate)
+				// An initiative is estimated as the sum of the slices that ran.
+				if initiative != nil {
+					initiatives[initiative.ID].addEstimate(slice.TokenEstimate)
+				}
+			}
+			slices[slice.ID].add(thread)
+			if initiative != nil && !countedInitiatives[initiative.ID] {
+				countedInitiatives[initiative.ID] = true
+				initiatives[initiative.ID].add(thread)
+			}
+		}
+	}
+
+	result := map[string]any{
+		"totals":        totals,
+		"threads":       threads,
+		"by_session":    sortedSessionUsage(sessions),
+		"by_role":       sortedRoleUsage(roles),
+		"by_slice":      sortedPlanNodeUsage(slices),
+		"by_initiative": sortedPlanNodeUsage(initiatives),
+	}
+	if input.SessionID > 0 {
+		budget, err := service.store.GetSessionBudget(ctx, input.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		result["budget"] = budgetStatus(budget, totals)
+	}
+	return result, nil
+}
+
+func (service *Service) setMetricsBudget(ctx context.Context, input metricsBudgetSetInput) (map[string]any, error) {
+	if input.SessionID <= 0 {
+		return nil, errors.New("session_id is required")
+	}
+	budget, err := service.store.SetSessionBudget(ctx, store.SessionBudgetArgs{
+		SessionID:     input.SessionID,
+		TokenBudget:   input.TokenBudget,
+		CostBudgetUSD: input.CostBudgetUSD,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := service.refreshUsage(ctx, input.SessionID); err != nil {
+		return nil, err
+	}
+	used, err := service.sessionUsageTotals(ctx, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"budget": budgetStatus(&budget, used)}, nil
+}
+
+// budgetStatus reports the limits next to what has been used; exceeded means
+// new spawns in the session are refused.
+func budgetStatus(budget *store.SessionBudget, used usageTotals) map[string]any {
+	status := map[string]any{
+		"tokens_used": used.TotalTokens,
+		"exceeded":    false,
+	}
+	if used.CostUSD != nil {
+		status["cost_used_usd"] = *used.CostUSD
+	}
+	if budget == nil {
+		return status
+	}
+	status["updated_at"] = budget.UpdatedAt
+	if budget.TokenBudget != nil {
+		status["token_budget"] = *budget.TokenBudget
+		status["tokens_remaining"] = *budget.TokenBudget - used.TotalTokens
+		if used.TotalTokens >= *budget.TokenBudget {
+			status["exceeded"] = true
+		}
+	}
+	if budget.CostBudgetUSD != nil {
+		status["cost_budget_usd"] = *budget.CostBudgetUSD
+		if used.CostUSD != nil && *used.CostUSD >= *budget.CostBudgetUSD {
+			status["exceeded"] = true
+		}
+	}
+	return status
+}
+
+func (service *Service) cachedGraphNode(ctx context.Context, nodes map[int64]*store.GraphNode, nodeID int64) (*store.GraphNode, error) {
+	if node, ok := nodes[nodeID]; ok {
+		return node, nil
+	}
+	node, err := service.store.GetGraphNodeByID(ctx, nodeID)
+	if err != nil {
+		nodes[nodeID] = nil
+		return nil, err
+	}
+	nodes[nodeID] = &node
+	return &node, nil
+}
+
+// initiativeOf walks slice -> plan -> initiative.
+func (service *Service) initiativeOf(ctx context.Context, nodes map[int64]*store.GraphNode, node *store.GraphNode) *store.GraphNode {
+	for depth := 0; node != nil && depth < 8; depth++ {
+		if node.NodeType == "initiative" {
+			return node
+		}
+		if node.ParentID == nil {
+			return nil
+		}
+		node, _ = service.cachedGraphNode(ctx, nodes, *node.ParentID)
+	}
+	return nil
+}
+
+func sortedSessionUsage(usage map[int64]*sessionUsage) []sessionUsage {
+	sorted := make([]sessionUsage, 0, len(usage))
+	for _, entry := range usage {
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(left, right int) bool { return sorted[left].SessionID < sorted[right].SessionID })
+	return sorted
+}
+
+func sortedRoleUsage(usage map[string]*roleUsage) []roleUsage {
+	sorted := make([]roleUsage, 0, len(usage))
+	for _, entry := range usage {
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(left, right int) bool { return sorted[left].Role < sorted[right].Role })
+	return sorted
+}
+
+func sortedPlanNodeUsage(usage map[int64]*planNodeUsage) []planNodeUsage {
+	sorted := make([]planNodeUsage, 0, len(usage))
+	for _, entry := range usage {
+		entry.finish()
+		sorted = append(sorted, *entry)
+	}
+	sort.Slice(sorted, func(left, right int) bool { return sorted[left].NodeID < sorted[right].NodeID })
+	return sorted
+}
@@ -0,0 +1,218 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestMetricsUsageRollupsAndSessionBudget(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "payments", PlanTitle: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	initiative := bootstrap["initiative"].(store.GraphNode)
+	generated, err := service.planSliceGenerate(ctx, planSliceGenerateInput{
+		PlanNodeID: bootstrap["plan"].(store.GraphNode).ID,
+		SliceSpecs: []planSliceSpecInput{{Title: "schema", TokenEstimate: 1000}, {Title: "api", TokenEstimate: 5000}},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate slices: %v", err)
+	}
+	slices := generated["slices"].([]store.GraphNode)
+
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	logs := []struct {
+		role    string
+		sliceID int64
+		log     string
+	}{
+		{"worker", slices[0].ID, "codex: done\nToken usage: total=1,250 input=1,000 (+ 4,096 cached) output=250\n"},
+		{"worker", slices[1].ID, "> /cost\n  Total cost: $0.5000\n  Usage by model:\n    claude-sonnet: 3k input, 1k output\n\n> \n"},
+		{"merge-reviewer", 0, "  300 tokens used\n"},
+	}
+	threads := make([]store.Thread, 0, len(logs))
+	for index, entry := range logs {
+		scope := ""
+		if entry.sliceID > 0 {
+			scope = fmt.Sprintf("[%d]", entry.sliceID)
+		}
+		thread, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: entry.role, Status: "running", ScopeNodeIDsJSON: scope})
+		if err != nil {
+			t.Fatalf("failed to create thread: %v", err)
+		}
+		logPath := filepath.Join(repoPath, fmt.Sprintf("thread_%d.log", index))
+		if err := os.WriteFile(logPath, []byte(entry.log), 0o644); err != nil {
+			t.Fatalf("failed to write log: %v", err)
+		}
+		if thread, err = service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{LogFilePath: &logPath}); err != nil {
+			t.Fatalf("failed to bind log: %v", err)
+		}
+		threads = append(threads, thread)
+	}
+
+	usage, err := service.metricsUsage(ctx, metricsUsageInput{SessionID: session.ID})
+	if err != nil {
+		t.Fatalf("metrics.usage failed: %v", err)
+	}
+	totals := usage["totals"].(usageTotals)
+	if totals.Threads != 3 || totals.TotalTokens != 1250+4000+300 || totals.CostUSD == nil || *totals.CostUSD != 0.5 {
+		t.Fatalf("unexpected totals: %+v", totals)
+	}
+	roles := usage["by_role"].([]roleUsage)
+	if len(roles) != 2 || roles[0].Role != "merge-reviewer" || roles[1].TotalTokens != 5250 {
+		t.Fatalf("unexpected role rollup: %+v", roles)
+	}
+	bySlice := usage["by_slice"].([]planNodeUsage)
+	if len(bySlice) != 2 || *bySlice[0].TokenEstimate != 1000 || *bySlice[0].EstimateDelta != 250 || *bySlice[1].EstimateDelta != -1000 {
+		t.Fatalf("unexpected slice rollup: %+v", bySlice)
+	}
+	byInitiative := usage["by_initiative"].([]planNodeUsage)
+	if len(byInitiative) != 1 || byInitiative[0].NodeID != initiative.ID || *byInitiative[0].TokenEstimate != 6000 || byInitiative[0].TotalTokens != 5250 {
+		t.Fatalf("unexpected initiative rollup: %+v", byInitiative)
+	}
+	if budget := usage["budget"].(map[string]any); budget["exceeded"] != false {
+		t.Fatalf("expected no budget yet, got %+v", budget)
+	}
+
+	tokenBudget := int64(5000)
+	set, err := service.setMetricsBudget(ctx, metricsBudgetSetInput{SessionID: session.ID, TokenBudget: &tokenBudget})
+	if err != nil {
+		t.Fatalf("metrics.budget.set failed: %v", err)
+	}
+	if budget := set["budget"].(map[string]any); budget["exceeded"] != true || budget["tokens_remaining"] != int64(-550) {
+		t.Fatalf("expected exceeded budget, got %+v", budget)
+	}
+	if _, _, _, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{SessionID: session.ID, Backend: "headless"}); err == nil || !strings.Contains(err.Error(), "token budget") {
+		t.Fatalf("expected spawn to be blocked by the budget, got %v", err)
+	}
+
+	// The supervisor does not relaunch a crashed worker past the budget.
+	crashedStatus := "crashed"
+	crashed, err := service.store.UpdateThread(ctx, threads[0].ID, store.ThreadUpdateArgs{Status: &crashedStatus})
+	if err != nil {
+		t.Fatalf("failed to mark worker crashed: %v", err)
+	}
+	policy, err := service.store.GetSupervisorPolicy(ctx)
+	if err != nil {
+		t.Fatalf("failed to get supervisor policy: %v", err)
+	}
+	restart, claimed, err := service.restartCrashedThread(ctx, crashed, policy)
+	if err != nil || !claimed || !strings.Contains(restart.Error, "token budget") {
+		t.Fatalf("expected the restart to be refused by the budget, got %+v claimed=%v (%v)", restart, claimed, err)
+	}
+	if refused, err := service.store.GetThreadByID(ctx, crashed.ID); err != nil || refused.Status != "failed" {
+		t.Fatalf("expected refused restart to fail the worker, got %+v (%v)", refused, err)
+	}
+
+	// After a restart only output past the attempt marker counts, so the
+	// previous attempt's report is not charged twice.
+	restartCount := 1
+	restarted, err := service.store.UpdateThread(ctx, threads[0].ID, store.ThreadUpdateArgs{RestartCount: &restartCount})
+	if err != nil {
+		t.Fatalf("failed to bump restart count: %v", err)
+	}
+	logPath := valueOrEmpty(restarted.LogFilePath)
+	if err := appendRestartAttemptMarker(logPath, restartCount); err != nil {
+		t.Fatalf("failed to append attempt marker: %v", err)
+	}
+	if found, err := service.recordThreadUsage(ctx, restarted); err != nil || found {
+		t.Fatalf("expected no usage for the new attempt yet, got %v (%v)", found, err)
+	}
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	_, _ = logFile.WriteString("Token usage: total=100 input=80 output=20\n")
+	_ = logFile.Close()
+	if used, err := service.sessionUsageTotals(ctx, session.ID); err != nil || used.TotalTokens != 5550 {
+		t.Fatalf("expected totals unchanged before refresh, got %+v (%v)", used, err)
+	}
+	if err := service.refreshUsage(ctx, session.ID); err != nil {
+		t.Fatalf("failed to refresh usage: %v", err)
+	}
+	if used, err := service.sessionUsageTotals(ctx, session.ID); err != nil || used.TotalTokens != 5650 {
+		t.Fatalf("expected only the new attempt's 100 tokens to be added, got %+v (%v)", used, err)
+	}
+
+	tokenBudget = 0
+	if _, err := service.setMetricsBudget(ctx, metricsBudgetSetInput{SessionID: session.ID, TokenBudget: &tokenBudget}); err != nil {
+		t.Fatalf("failed to clear budget: %v", err)
+	}
+	if err := service.checkSessionBudget(ctx, session.ID); err != nil {
+		t.Fatalf("expected cleared budget to allow spawns, got %v", err)
+	}
+}
+
+func TestMetricsUsageCountsMultiSliceThreadOncePerInitiative(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "payments", PlanTitle: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	generated, err := service.planSliceGenerate(ctx, planSliceGenerateInput{
+		PlanNodeID: bootstrap["plan"].(store.GraphNode).ID,
+		SliceSpecs: []planSliceSpecInput{{Title: "schema", TokenEstimate: 1000}, {Title: "api", TokenEstimate: 5000}},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate slices: %v", err)
+	}
+	slices := generated["slices"].([]store.GraphNode)
+
+	scope := fmt.Sprintf("[%d,%d]", slices[0].ID, slices[1].ID)
+	thread, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running", ScopeNodeIDsJSON: scope})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	logPath := filepath.Join(repoPath, "thread.log")
+	if err := os.WriteFile(logPath, []byte("Token usage: total=1,250 input=1,000 output=250\n"), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{LogFilePath: &logPath}); err != nil {
+		t.Fatalf("failed to bind log: %v", err)
+	}
+
+	usage, err := service.metricsUsage(ctx, metricsUsageInput{SessionID: session.ID})
+	if err != nil {
+		t.Fatalf("metrics.usage failed: %v", err)
+	}
+	bySlice := usage["by_slice"].([]planNodeUsage)
+	if len(bySlice) != 2 || bySlice[0].TotalTokens != 1250 || bySlice[1].TotalTokens != 1250 {
+		t.Fatalf("expected the thread charged to each slice, got %+v", bySlice)
+	}
+	byInitiative := usage["by_initiative"].([]planNodeUsage)
+	if len(byInitiative) != 1 || byInitiative[0].Threads != 1 || byInitiative[0].TotalTokens != 1250 || *byInitiative[0].TokenEstimate != 6000 {
+		t.Fatalf("expected the thread counted once for the initiative, got %+v", byInitiative)
+	}
+}
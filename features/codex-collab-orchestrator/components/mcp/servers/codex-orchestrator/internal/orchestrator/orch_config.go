@@ -0,0 +1,35 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// orchConfigDir holds the repo-level orchestrator config files
+// (providers.yaml, auto-answer.yaml, logs.yaml) next to state.db.
+const orchConfigDir = ".codex-orch"
+
+func (service *Service) orchConfigPath(name string) string {
+	return filepath.Join(service.repoPath, orchConfigDir, name)
+}
+
+// loadOrchConfigYAML decodes .codex-orch/<name> into target. A missing file
+// is not an error and leaves target untouched, so its zero value stands for
+// "not configured".
+func (service *Service) loadOrchConfigYAML(name string, target any) error {
+	content, err := os.ReadFile(service.orchConfigPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(content, target); err != nil {
+		return fmt.Errorf("failed to parse %s/%s: %w", orchConfigDir, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,551 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const defaultPlanTemplateDir = ".codex/plan-templates"
+
+var (
+	outlineHeadingPattern   = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	outlineAttributePattern = regexp.MustCompile(`^(?:[-*+]\s+)?(?i:(files|after|tokens|priority|risk))\s*:\s*(.*)$`)
+	outlineBulletPattern    = regexp.MustCompile(`^[-*+]\s+(?:\[([ xX])\]\s+)?(.+)$`)
+	templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+)
+
+// builtinPlanTemplates are available in every repo; a file with the same name
+// under .codex/plan-templates overrides them.
+var builtinPlanTemplates = map[string]string{
+	"feature": `# {{feature}}
+
+Deliver {{feature}} end to end.
+
+## Design {{feature}}
+- [ ] Agree on interfaces and data model
+tokens: 6000
+
+## Implement {{feature}}
+after: Design {{feature}}
+- [ ] Core implementation
+- [ ] Error handling
+tokens: 12000
+
+## Test {{feature}}
+after: Implement {{feature}}
+- [ ] Unit tests
+- [ ] Integration tests
+tokens: 8000
+
+## Document {{feature}}
+after: Implement {{feature}}
+- [ ] Update user-facing docs
+tokens: 3000
+`,
+	"bugfix": `# Fix {{bug}}
+
+## Reproduce {{bug}}
+- [ ] Write a failing test that shows the bug
+tokens: 4000
+
+## Fix {{bug}}
+after: Reproduce {{bug}}
+- [ ] Apply the fix
+- [ ] Confirm the failing test passes
+tokens: 8000
+risk: 2
+
+## Guard against {{bug}} regressions
+after: Fix {{bug}}
+- [ ] Add regression coverage around the fix
+tokens: 3000
+`,
+}
+
+type planOutline struct {
+	PlanTitle     string                     `json:"plan_title,omitempty"`
+	PlanSummary   string                     `json:"plan_summary,omitempty"`
+	PlanChecklist []planOutlineChecklistItem `json:"plan_checklist,omitempty"`
+	Slices        []planOutlineSlice         `json:"slices"`
+}
+
+type planOutlineSlice struct {
+	Title         string                     `json:"title"`
+	Summary       string                     `json:"summary,omitempty"`
+	Priority      int                        `json:"priority"`
+	TokenEstimate int                        `json:"token_estimate,omitempty"`
+	RiskLevel     *int                       `json:"risk_level,omitempty"`
+	AffectedFiles []string                   `json:"affected_files"`
+	After         []string                   `json:"after"`
+	Checklist     []planOutlineChecklistItem `json:"checklist"`
+	Line          int                        `json:"line"`
+}
+
+type planOutlineChecklistItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// parsePlanOutline turns a Markdown outline into a plan and its slices.
+// The shallowest heading is the plan and the next heading level holds the
+// slices; with slicesOnly every shallowest heading is a slice. Under a
+// heading, bullets become checklist items ("- [x]" marks them done),
+// "files:", "after:", "tokens:", "priority:" and "risk:" lines set slice
+// fields, and remaining text becomes the summary.
+func parsePlanOutline(markdown string, slicesOnly bool) (planOutline, error) {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	headingLevels := make([]int, 0)
+	seenLevels := make(map[int]bool)
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if match := outlineHeadingPattern.FindStringSubmatch(trimmed); match != nil && !seenLevels[len(match[1])] {
+			seenLevels[len(match[1])] = true
+			headingLevels = append(headingLevels, len(match[1]))
+		}
+	}
+	if len(headingLevels) == 0 {
+		return planOutline{}, errors.New("outline has no headings")
+	}
+	sort.Ints(headingLevels)
+
+	planLevel := 0
+	sliceLevel := headingLevels[0]
+	if !slicesOnly {
+		planLevel = headingLevels[0]
+		if len(headingLevels) < 2 {
+			return planOutline{}, errors.New("outline needs a plan heading followed by deeper slice headings")
+		}
+		sliceLevel = headingLevels[1]
+	}
+
+	outline := planOutline{
+		PlanChecklist: make([]planOutlineChecklistItem, 0),
+		Slices:        make([]planOutlineSlice, 0),
+	}
+	planSeen := false
+	var current *planOutlineSlice
+	summaryLines := make([]string, 0)
+	flushSummary := func() {
+		summary := strings.TrimSpace(strings.Join(summaryLines, "\n"))
+		summaryLines = summaryLines[:0]
+		if summary == "" {
+			return
+		}
+		if current != nil {
+			current.Summary = joinOutlineText(current.Summary, summary)
+		} else {
+			outline.PlanSummary = joinOutlineText(outline.PlanSummary, summary)
+		}
+	}
+
+	inFence = false
+	for index, line := range lines {
+		lineNumber := index + 1
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			summaryLines = append(summaryLines, line)
+			continue
+		}
+		if inFence {
+			summaryLines = append(summaryLines, line)
+			continue
+		}
+
+		if match := outlineHeadingPattern.FindStringSubmatch(trimmed); match != nil {
+			flushSummary()
+			level := len(match[1])
+			title := strings.TrimSpace(match[2])
+			switch level {
+			case planLevel:
+				if planSeen {
+					return planOutline{}, fmt.Errorf("line %d: outline may contain only one plan heading", lineNumber)
+				}
+				planSeen = true
+				outline.PlanTitle = title
+				current = nil
+			case sliceLevel:
+				if !slicesOnly && !planSeen {
+					return planOutline{}, fmt.Errorf("line %d: slice heading %q appears before the plan heading", lineNumber, title)
+				}
+				outline.Slices = append(outline.Slices, planOutlineSlice{
+					Title:         title,
+					AffectedFiles: make([]string, 0),
+					After:         make([]string, 0),
+					Checklist:     make([]planOutlineChecklistItem, 0),
+					Line:          lineNumber,
+				})
+				current = &outline.Slices[len(outline.Slices)-1]
+			default:
+				return planOutline{}, fmt.Errorf("line %d: heading level %d is deeper than the slice level %d", lineNumber, level, sliceLevel)
+			}
+			continue
+		}
+
+		if trimmed == "" {
+			summaryLines = append(summaryLines, "")
+			continue
+		}
+		if !slicesOnly && !planSeen {
+			continue
+		}
+
+		if match := outlineAttributePattern.FindStringSubmatch(trimmed); match != nil {
+			key := strings.ToLower(match[1])
+			value := strings.TrimSpace(match[2])
+			if current == nil {
+				return planOutline{}, fmt.Errorf("line %d: %s: must appear under a slice heading", lineNumber, key)
+			}
+			if err := applyOutlineAttribute(current, key, value); err != nil {
+				return planOutline{}, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+		if match := outlineBulletPattern.FindStringSubmatch(trimmed); match != nil {
+			item := planOutlineChecklistItem{Text: strings.TrimSpace(match[2]), Done: strings.EqualFold(match[1], "x")}
+			if current != nil {
+				current.Checklist = append(current.Checklist, item)
+			} else {
+				outline.PlanChecklist = append(outline.PlanChecklist, item)
+			}
+			continue
+		}
+		summaryLines = append(summaryLines, trimmed)
+	}
+	flushSummary()
+
+	if len(outline.Slices) == 0 {
+		return planOutline{}, errors.New("outline has no slice headings")
+	}
+	if err := validateOutlineDependencies(outline.Slices); err != nil {
+		return planOutline{}, err
+	}
+	return outline, nil
+}
+
+func applyOutlineAttribute(slice *planOutlineSlice, key string, value string) error {
+	switch key {
+	case "files":
+		slice.AffectedFiles = append(slice.AffectedFiles, splitOutlineList(value)...)
+	case "after":
+		slice.After = append(slice.After, splitOutlineList(value)...)
+	case "tokens", "priority", "risk":
+		number, err := strconv.Atoi(strings.ReplaceAll(value, ",", ""))
+		if err != nil || number < 0 {
+			return fmt.Errorf("%s: expects a non-negative integer, got %q", key, value)
+		}
+		switch key {
+		case "tokens":
+			slice.TokenEstimate = number
+		case "priority":
+			slice.Priority = number
+		default:
+			slice.RiskLevel = &number
+		}
+	}
+	return nil
+}
+
+func splitOutlineList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.Trim(strings.TrimSpace(item), "`")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func joinOutlineText(existing string, addition string) string {
+	if existing == "" {
+		return addition
+	}
+	return existing + "\n\n" + addition
+}
+
+// validateOutlineDependencies checks that slice titles are unique, that every
+// "after:" names a slice in the outline or an existing node ("#12"), and that
+// the references do not form a cycle.
+func validateOutlineDependencies(slices []planOutlineSlice) error {
+	indexByTitle := make(map[string]int, len(slices))
+	for index, slice := range slices {
+		key := strings.ToLower(slice.Title)
+		if _, exists := indexByTitle[key]; exists {
+			return fmt.Errorf("line %d: duplicate slice title %q", slice.Line, slice.Title)
+		}
+		indexByTitle[key] = index
+	}
+
+	prerequisites := make([][]int, len(slices))
+	for index, slice := range slices {
+		for _, reference := range slice.After {
+			if _, isNodeID := parseOutlineNodeReference(reference); isNodeID {
+				continue
+			}
+			prerequisiteIndex, ok := indexByTitle[strings.ToLower(reference)]
+			if !ok {
+				return fmt.Errorf("line %d: after: unknown slice %q", slice.Line, reference)
+			}
+			if prerequisiteIndex == index {
+				return fmt.Errorf("line %d: slice %q cannot come after itself", slice.Line, slice.Title)
+			}
+			prerequisites[index] = append(prerequisites[index], prerequisiteIndex)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(slices))
+	var visit func(index int) error
+	visit = func(index int) error {
+		switch state[index] {
+		case visiting:
+			return fmt.Errorf("after: references form a cycle through %q", slices[index].Title)
+		case visited:
+			return nil
+		}
+		state[index] = visiting
+		for _, prerequisiteIndex := range prerequisites[index] {
+			if err := visit(prerequisiteIndex); err != nil {
+				return err
+			}
+		}
+		state[index] = visited
+		return nil
+	}
+	for index := range slices {
+		if err := visit(index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseOutlineNodeReference(reference string) (int64, bool) {
+	if !strings.HasPrefix(reference, "#") {
+		return 0, false
+	}
+	nodeID, err := strconv.ParseInt(strings.TrimPrefix(reference, "#"), 10, 64)
+	if err != nil || nodeID <= 0 {
+		return 0, false
+	}
+	return nodeID, true
+}
+
+func (service *Service) planImport(ctx context.Context, input planImportInput) (map[string]any, error) {
+	if strings.TrimSpace(input.Markdown) == "" {
+		return nil, errors.New("markdown is required")
+	}
+	importIntoPlan := input.PlanNodeID != nil && *input.PlanNodeID > 0
+	outline, err := parsePlanOutline(input.Markdown, importIntoPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := service.store.GetPlanningRule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sliceSpecs := make([]planSliceSpecInput, 0, len(outline.Slices))
+	for _, slice := range outline.Slices {
+		sliceSpecs = append(sliceSpecs, planSliceSpecInput{
+			Title:         slice.Title,
+			TokenEstimate: slice.TokenEstimate,
+			AffectedFiles: slice.AffectedFiles,
+		})
+	}
+	violations := validateSliceSpecs(sliceSpecs, rule)
+	result := map[string]any{
+		"outline":    outline,
+		"dry_run":    input.DryRun,
+		"accepted":   len(violations) == 0,
+		"violations": violations,
+	}
+	if input.DryRun || len(violations) > 0 {
+		if len(violations) > 0 {
+			result["rules"] = planningRuleView(rule)
+		}
+		return result, nil
+	}
+
+	importArgs := store.PlanImportArgs{
+		PlanTitle:      outline.PlanTitle,
+		PlanSummary:    outline.PlanSummary,
+		PlanChecklist:  importChecklistItems(outline.PlanChecklist),
+		Slices:         make([]store.PlanImportSlice, 0, len(outline.Slices)),
+		Priority:       input.Priority,
+		OwnerSessionID: input.OwnerSessionID,
+	}
+	if importIntoPlan {
+		importArgs.PlanNodeID = *input.PlanNodeID
+	} else if input.InitiativeID != nil && *input.InitiativeID > 0 {
+		importArgs.InitiativeID = *input.InitiativeID
+	} else {
+		importArgs.InitiativeTitle = strings.TrimSpace(input.InitiativeTitle)
+		if importArgs.InitiativeTitle == "" {
+			importArgs.InitiativeTitle = outline.PlanTitle
+		}
+	}
+
+	sliceIndexByTitle := make(map[string]int, len(outline.Slices))
+	for index, slice := range outline.Slices {
+		sliceIndexByTitle[strings.ToLower(slice.Title)] = index
+	}
+	for _, slice := range outline.Slices {
+		importSlice := store.PlanImportSlice{
+			Title:             slice.Title,
+			Summary:           slice.Summary,
+			Priority:          slice.Priority,
+			RiskLevel:         slice.RiskLevel,
+			AffectedFilesJSON: marshalStringSlice(slice.AffectedFiles),
+			Checklist:         importChecklistItems(slice.Checklist),
+		}
+		if slice.TokenEstimate > 0 {
+			tokenEstimate := slice.TokenEstimate
+			importSlice.TokenEstimate = &tokenEstimate
+		}
+		for _, reference := range slice.After {
+			if nodeID, isNodeID := parseOutlineNodeReference(reference); isNodeID {
+				importSlice.AfterNodeIDs = append(importSlice.AfterNodeIDs, nodeID)
+			} else {
+				importSlice.AfterSlices = append(importSlice.AfterSlices, sliceIndexByTitle[strings.ToLower(reference)])
+			}
+		}
+		importArgs.Slices = append(importArgs.Slices, importSlice)
+	}
+
+	imported, err := service.store.ImportPlanOutline(ctx, importArgs)
+	if err != nil {
+		return nil, err
+	}
+	if imported.Initiative != nil {
+		result["initiative"] = *imported.Initiative
+	}
+	result["plan"] = imported.Plan
+	result["slices"] = imported.Slices
+	result["dependency_edges"] = imported.DependencyEdges
+	return result, nil
+}
+
+func importChecklistItems(items []planOutlineChecklistItem) []store.PlanImportChecklistItem {
+	converted := make([]store.PlanImportChecklistItem, 0, len(items))
+	for _, item := range items {
+		status := "todo"
+		if item.Done {
+			status = "done"
+		}
+		converted = append(converted, store.PlanImportChecklistItem{ItemText: item.Text, Status: status})
+	}
+	return converted
+}
+
+func (service *Service) planTemplateApply(ctx context.Context, input planTemplateApplyInput) (map[string]any, error) {
+	name := strings.TrimSpace(input.Template)
+	if name == "" {
+		return nil, errors.New("template is required")
+	}
+	templateText, source, err := service.loadPlanTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	markdown, err := renderPlanTemplate(templateText, input.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	result, err := service.planImport(ctx, planImportInput{
+		Markdown:        markdown,
+		PlanNodeID:      input.PlanNodeID,
+		InitiativeID:    input.InitiativeID,
+		InitiativeTitle: input.InitiativeTitle,
+		OwnerSessionID:  input.OwnerSessionID,
+		Priority:        input.Priority,
+		DryRun:          input.DryRun,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	result["template"] = name
+	result["template_source"] = source
+	return result, nil
+}
+
+// loadPlanTemplate resolves a template from the repo's template directory
+// first, then the built-in set.
+func (service *Service) loadPlanTemplate(name string) (string, string, error) {
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", "", fmt.Errorf("invalid template name: %s", name)
+	}
+	templateDir := filepath.Join(service.repoPath, defaultPlanTemplateDir)
+	templatePath := filepath.Join(templateDir, name+".md")
+	content, err := os.ReadFile(templatePath)
+	if err == nil {
+		return string(content), templatePath, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+	if builtin, ok := builtinPlanTemplates[name]; ok {
+		return builtin, "builtin", nil
+	}
+
+	available := make([]string, 0, len(builtinPlanTemplates))
+	for builtinName := range builtinPlanTemplates {
+		available = append(available, builtinName)
+	}
+	if entries, readErr := os.ReadDir(templateDir); readErr == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".md") {
+				available = append(available, strings.TrimSuffix(entry.Name(), ".md"))
+			}
+		}
+	}
+	sort.Strings(available)
+	return "", "", fmt.Errorf("plan template not found: %s (available: %s)", name, strings.Join(available, ", "))
+}
+
+// renderPlanTemplate substitutes {{name}} placeholders and fails on any
+// placeholder without a value.
+func renderPlanTemplate(templateText string, variables map[string]string) (string, error) {
+	missing := make(map[string]bool)
+	rendered := templateVariablePattern.ReplaceAllStringFunc(templateText, func(placeholder string) string {
+		name := templateVariablePattern.FindStringSubmatch(placeholder)[1]
+		value, ok := variables[name]
+		if !ok {
+			missing[name] = true
+			return placeholder
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("missing template variables: %s", strings.Join(names, ", "))
+	}
+	return rendered, nil
+}
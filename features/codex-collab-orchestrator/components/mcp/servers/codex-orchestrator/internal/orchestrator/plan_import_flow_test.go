@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const samplePlanOutline = `# Checkout revamp
+
+Move checkout onto the new payments API.
+
+- [x] Kickoff done
+
+## Schema
+files: db/schema.sql
+tokens: 4000
+- [ ] Add payments table
+
+## API
+after: Schema
+files: api/checkout.go, api/payments.go
+tokens: 6,000
+risk: 3
+Wire the handler to the new table.
+- [ ] Handler
+- [x] Routes
+
+## UI
+- after: API
+- files: web/checkout.ts
+`
+
+func TestParsePlanOutline(t *testing.T) {
+	outline, err := parsePlanOutline(samplePlanOutline, false)
+	if err != nil {
+		t.Fatalf("failed to parse outline: %v", err)
+	}
+	if outline.PlanTitle != "Checkout revamp" || outline.PlanSummary != "Move checkout onto the new payments API." {
+		t.Fatalf("unexpected plan header: %+v", outline)
+	}
+	if len(outline.PlanChecklist) != 1 || !outline.PlanChecklist[0].Done {
+		t.Fatalf("unexpected plan checklist: %+v", outline.PlanChecklist)
+	}
+	if len(outline.Slices) != 3 {
+		t.Fatalf("expected 3 slices, got %+v", outline.Slices)
+	}
+	api := outline.Slices[1]
+	if api.TokenEstimate != 6000 || api.RiskLevel == nil || *api.RiskLevel != 3 || len(api.AffectedFiles) != 2 || api.After[0] != "Schema" {
+		t.Fatalf("unexpected api slice attributes: %+v", api)
+	}
+	if api.Summary != "Wire the handler to the new table." || len(api.Checklist) != 2 || api.Checklist[0].Done || !api.Checklist[1].Done {
+		t.Fatalf("unexpected api slice body: %+v", api)
+	}
+	if ui := outline.Slices[2]; len(ui.After) != 1 || ui.AffectedFiles[0] != "web/checkout.ts" {
+		t.Fatalf("expected bullet-prefixed attributes to parse, got %+v", ui)
+	}
+
+	invalidOutlines := map[string]string{
+		"unknown after":  "# Plan\n## A\nafter: Missing\n",
+		"cycle":          "# Plan\n## A\nafter: B\n## B\nafter: A\n",
+		"duplicate":      "# Plan\n## A\n## a\n",
+		"too deep":       "# Plan\n## A\n### Detail\n",
+		"plan attribute": "# Plan\nfiles: a.go\n## A\n",
+		"no slices":      "# Plan\nJust text\n",
+	}
+	for name, markdown := range invalidOutlines {
+		if _, err := parsePlanOutline(markdown, false); err == nil {
+			t.Fatalf("expected %s outline to be rejected", name)
+		}
+	}
+}
+
+func TestPlanImportCreatesGraph(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	dryRun, err := service.planImport(ctx, planImportInput{Markdown: samplePlanOutline, DryRun: true})
+	if err != nil {
+		t.Fatalf("failed to dry-run import: %v", err)
+	}
+	if _, created := dryRun["plan"]; created || dryRun["accepted"] != true {
+		t.Fatalf("expected dry run to only parse, got %+v", dryRun)
+	}
+
+	result, err := service.planImport(ctx, planImportInput{Markdown: samplePlanOutline})
+	if err != nil {
+		t.Fatalf("failed to import outline: %v", err)
+	}
+	planNode := result["plan"].(store.GraphNode)
+	slices := result["slices"].([]store.GraphNode)
+	if planNode.Title != "Checkout revamp" || planNode.ParentID == nil || len(slices) != 3 {
+		t.Fatalf("unexpected import result: %+v", result)
+	}
+	if edges := result["dependency_edges"].([]store.GraphEdge); len(edges) != 2 || edges[0].FromNodeID != slices[1].ID || edges[0].ToNodeID != slices[0].ID {
+		t.Fatalf("unexpected dependency edges: %+v", edges)
+	}
+
+	readySet, err := service.store.PlanReadySet(ctx, planNode.ID)
+	if err != nil {
+		t.Fatalf("failed to load ready set: %v", err)
+	}
+	if len(readySet.Ready) != 1 || readySet.Ready[0].Node.ID != slices[0].ID {
+		t.Fatalf("expected only the schema slice to be ready, got %+v", readySet.Ready)
+	}
+
+	more, err := service.planImport(ctx, planImportInput{
+		Markdown:   "## Docs\nafter: #" + strconv.FormatInt(slices[2].ID, 10) + "\n- [ ] Changelog\n",
+		PlanNodeID: &planNode.ID,
+	})
+	if err != nil {
+		t.Fatalf("failed to import slices into existing plan: %v", err)
+	}
+	if added := more["slices"].([]store.GraphNode); len(added) != 1 || *added[0].ParentID != planNode.ID {
+		t.Fatalf("expected one slice under the existing plan, got %+v", more)
+	}
+
+	before, err := service.store.ListGraphNodes(ctx, store.GraphNodeFilter{})
+	if err != nil {
+		t.Fatalf("failed to list nodes: %v", err)
+	}
+	if _, err := service.planImport(ctx, planImportInput{Markdown: "# Broken\n## First\n- [ ] item\n## Second\nafter: #999999\n"}); err == nil || !strings.Contains(err.Error(), "graph node not found: 999999") {
+		t.Fatalf("expected missing prerequisite to fail the import, got %v", err)
+	}
+	after, err := service.store.ListGraphNodes(ctx, store.GraphNodeFilter{})
+	if err != nil {
+		t.Fatalf("failed to list nodes: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected a failed import to leave no nodes behind, had %d now %d", len(before), len(after))
+	}
+}
+
+func TestPlanTemplateApply(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	if _, err := service.planTemplateApply(ctx, planTemplateApplyInput{Template: "feature"}); err == nil || !strings.Contains(err.Error(), "missing template variables: feature") {
+		t.Fatalf("expected missing variable error, got %v", err)
+	}
+
+	result, err := service.planTemplateApply(ctx, planTemplateApplyInput{Template: "feature", Variables: map[string]string{"feature": "search"}})
+	if err != nil {
+		t.Fatalf("failed to apply builtin template: %v", err)
+	}
+	if result["template_source"] != "builtin" || len(result["slices"].([]store.GraphNode)) != 4 || result["plan"].(store.GraphNode).Title != "search" {
+		t.Fatalf("unexpected builtin template result: %+v", result)
+	}
+
+	templateDir := filepath.Join(repoPath, defaultPlanTemplateDir)
+	if err := os.MkdirAll(templateDir, 0o755); err != nil {
+		t.Fatalf("failed to create template dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(templateDir, "spike.md"), []byte("# Spike {{topic}}\n## Research {{topic}}\ntokens: 2000\n"), 0o644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	result, err = service.planTemplateApply(ctx, planTemplateApplyInput{Template: "spike", Variables: map[string]string{"topic": "caching"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("failed to apply repo template: %v", err)
+	}
+	if outline := result["outline"].(planOutline); outline.Slices[0].Title != "Research caching" {
+		t.Fatalf("unexpected repo template outline: %+v", outline)
+	}
+
+	if _, err := service.planTemplateApply(ctx, planTemplateApplyInput{Template: "missing"}); err == nil || !strings.Contains(err.Error(), "bugfix, feature, spike") {
+		t.Fatalf("expected available templates in error, got %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
 
 	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
 )
@@ -65,13 +69,38 @@ func (service *Service) planSliceGenerate(ctx context.Context, input planSliceGe
 	if len(input.SliceSpecs) == 0 {
 		return nil, errors.New("slice_specs is required")
 	}
-
-	createdSlices := make([]store.GraphNode, 0, len(input.SliceSpecs))
-	createdEdges := make([]store.GraphEdge, 0, len(input.SliceSpecs))
 	for _, spec := range input.SliceSpecs {
 		if spec.Title == "" {
 			return nil, errors.New("slice_specs[].title is required")
 		}
+	}
+
+	rule, err := service.store.GetPlanningRule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sliceSpecs := input.SliceSpecs
+	if input.AutoSplit {
+		sliceSpecs = splitSliceSpecs(input.SliceSpecs, rule)
+	}
+	violations := validateSliceSpecs(sliceSpecs, rule)
+	if len(violations) > 0 {
+		result := map[string]any{
+			"plan_node_id": input.PlanNodeID,
+			"accepted":     false,
+			"rules":        planningRuleView(rule),
+			"violations":   violations,
+		}
+		if !input.AutoSplit {
+			result["suggested_slice_specs"] = splitSliceSpecs(input.SliceSpecs, rule)
+		}
+		return result, nil
+	}
+
+	createdSlices := make([]store.GraphNode, 0, len(sliceSpecs))
+	createdEdges := make([]store.GraphEdge, 0, len(sliceSpecs))
+	for _, spec := range sliceSpecs {
 		var tokenEstimate *int
 		if spec.TokenEstimate > 0 {
 			tokenEstimate = &spec.TokenEstimate
@@ -106,9 +135,266 @@ func (service *Service) planSliceGenerate(ctx context.Context, input planSliceGe
 	}
 
 	return map[string]any{
-		"plan_node_id": input.PlanNodeID,
-		"slices":       createdSlices,
-		"edges":        createdEdges,
+		"plan_node_id":  input.PlanNodeID,
+		"accepted":      true,
+		"split_applied": len(sliceSpecs) != len(input.SliceSpecs),
+		"slices":        createdSlices,
+		"edges":         createdEdges,
+	}, nil
+}
+
+type sliceRuleViolation struct {
+	SliceIndex int    `json:"slice_index"`
+	Title      string `json:"title"`
+	Rule       string `json:"rule"`
+	Limit      int    `json:"limit"`
+	Actual     int    `json:"actual"`
+	Message    string `json:"message"`
+}
+
+func validateSliceSpecs(specs []planSliceSpecInput, rule store.PlanningRule) []sliceRuleViolation {
+	violations := make([]sliceRuleViolation, 0)
+	for index, spec := range specs {
+		if rule.MaxTokenPerSlice > 0 && spec.TokenEstimate > rule.MaxTokenPerSlice {
+			violations = append(violations, sliceRuleViolation{
+				SliceIndex: index,
+				Title:      spec.Title,
+				Rule:       "max_token_per_slice",
+				Limit:      rule.MaxTokenPerSlice,
+				Actual:     spec.TokenEstimate,
+				Message:    fmt.Sprintf("slice %q estimates %d tokens; limit is %d", spec.Title, spec.TokenEstimate, rule.MaxTokenPerSlice),
+			})
+		}
+		if rule.MaxFilesPerSlice > 0 && len(spec.AffectedFiles) > rule.MaxFilesPerSlice {
+			violations = append(violations, sliceRuleViolation{
+				SliceIndex: index,
+				Title:      spec.Title,
+				Rule:       "max_files_per_slice",
+				Limit:      rule.MaxFilesPerSlice,
+				Actual:     len(spec.AffectedFiles),
+				Message:    fmt.Sprintf("slice %q touches %d files; limit is %d", spec.Title, len(spec.AffectedFiles), rule.MaxFilesPerSlice),
+			})
+		}
+	}
+	return violations
+}
+
+func splitSliceSpecs(specs []planSliceSpecInput, rule store.PlanningRule) []planSliceSpecInput {
+	splitSpecs := make([]planSliceSpecInput, 0, len(specs))
+	for _, spec := range specs {
+		splitSpecs = append(splitSpecs, splitSliceSpec(spec, rule)...)
+	}
+	return splitSpecs
+}
+
+// splitSliceSpec breaks an oversized slice into parts grouped by the
+// directory of each affected file. Token estimates are spread across parts in
+// proportion to their file count. Slices without affected files cannot be
+// split and are returned unchanged.
+func splitSliceSpec(spec planSliceSpecInput, rule store.PlanningRule) []planSliceSpecInput {
+	fileCount := len(spec.AffectedFiles)
+	if fileCount == 0 || len(validateSliceSpecs([]planSliceSpecInput{spec}, rule)) == 0 {
+		return []planSliceSpecInput{spec}
+	}
+
+	filesPerPart := fileCount
+	if rule.MaxFilesPerSlice > 0 && filesPerPart > rule.MaxFilesPerSlice {
+		filesPerPart = rule.MaxFilesPerSlice
+	}
+	if rule.MaxTokenPerSlice > 0 && spec.TokenEstimate > rule.MaxTokenPerSlice {
+		tokenBoundFiles := rule.MaxTokenPerSlice * fileCount / spec.TokenEstimate
+		if tokenBoundFiles < filesPerPart {
+			filesPerPart = tokenBoundFiles
+		}
+	}
+	if filesPerPart < 1 {
+		filesPerPart = 1
+	}
+
+	filesByDirectory := make(map[string][]string)
+	directories := make([]string, 0)
+	for _, affectedFile := range spec.AffectedFiles {
+		directory := path.Dir(path.Clean(strings.ReplaceAll(affectedFile, "\\", "/")))
+		if _, ok := filesByDirectory[directory]; !ok {
+			directories = append(directories, directory)
+		}
+		filesByDirectory[directory] = append(filesByDirectory[directory], affectedFile)
+	}
+	sort.Strings(directories)
+
+	type slicePart struct {
+		directories []string
+		files       []string
+	}
+	parts := make([]slicePart, 0)
+	current := slicePart{}
+	flush := func() {
+		if len(current.files) > 0 {
+			parts = append(parts, current)
+		}
+		current = slicePart{}
+	}
+	for _, directory := range directories {
+		directoryFiles := filesByDirectory[directory]
+		if len(current.files)+len(directoryFiles) > filesPerPart {
+			flush()
+		}
+		for len(directoryFiles) > filesPerPart {
+			parts = append(parts, slicePart{directories: []string{directory}, files: directoryFiles[:filesPerPart]})
+			directoryFiles = directoryFiles[filesPerPart:]
+		}
+		current.directories = append(current.directories, directory)
+		current.files = append(current.files, directoryFiles...)
+	}
+	flush()
+
+	if len(parts) <= 1 {
+		return []planSliceSpecInput{spec}
+	}
+
+	splitSpecs := make([]planSliceSpecInput, 0, len(parts))
+	assignedTokens := 0
+	for index, part := range parts {
+		tokenEstimate := spec.TokenEstimate * len(part.files) / fileCount
+		if index == len(parts)-1 {
+			tokenEstimate = spec.TokenEstimate - assignedTokens
+		}
+		assignedTokens += tokenEstimate
+		splitSpecs = append(splitSpecs, planSliceSpecInput{
+			Title:         fmt.Sprintf("%s [%d/%d: %s]", spec.Title, index+1, len(parts), strings.Join(part.directories, ", ")),
+			Priority:      spec.Priority,
+			TokenEstimate: tokenEstimate,
+			AffectedFiles: part.files,
+			Summary:       spec.Summary,
+		})
+	}
+	return splitSpecs
+}
+
+func (service *Service) getPlanningRules(ctx context.Context) (map[string]any, error) {
+	rule, err := service.store.GetPlanningRule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return planningRuleView(rule), nil
+}
+
+func (service *Service) updatePlanningRules(ctx context.Context, input planRulesUpdateInput) (map[string]any, error) {
+	rule, err := service.store.UpdatePlanningRule(ctx, store.PlanningRuleUpdateArgs{
+		MaxTokenPerSlice: input.MaxTokenPerSlice,
+		MaxFilesPerSlice: input.MaxFilesPerSlice,
+		ReplanTriggers:   input.ReplanTriggers,
+		ApprovalPolicy:   input.ApprovalPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return planningRuleView(rule), nil
+}
+
+func planningRuleView(rule store.PlanningRule) map[string]any {
+	replanTriggers := make([]string, 0)
+	_ = json.Unmarshal([]byte(rule.ReplanTriggersJSON), &replanTriggers)
+	return map[string]any{
+		"max_token_per_slice": rule.MaxTokenPerSlice,
+		"max_files_per_slice": rule.MaxFilesPerSlice,
+		"replan_triggers":     replanTriggers,
+		"approval_policy":     rule.ApprovalPolicy,
+		"updated_at":          rule.UpdatedAt,
+	}
+}
+
+func (service *Service) updateGraphNode(ctx context.Context, input graphNodeUpdateInput) (store.GraphNode, error) {
+	if input.NodeID <= 0 {
+		return store.GraphNode{}, errors.New("node_id is required")
+	}
+	var affectedFilesJSON *string
+	if input.AffectedFiles != nil {
+		encoded := marshalStringSlice(input.AffectedFiles)
+		affectedFilesJSON = &encoded
+	}
+	if input.TokenEstimate != nil || input.AffectedFiles != nil {
+		if err := service.checkSliceUpdateRules(ctx, input); err != nil {
+			return store.GraphNode{}, err
+		}
+	}
+	return service.store.UpdateGraphNode(ctx, input.NodeID, store.GraphNodeUpdateArgs{
+		Title:             input.Title,
+		Status:            input.Status,
+		Priority:          input.Priority,
+		Summary:           input.Summary,
+		RiskLevel:         input.RiskLevel,
+		TokenEstimate:     input.TokenEstimate,
+		AffectedFilesJSON: affectedFilesJSON,
+		OwnerSessionID:    input.OwnerSessionID,
+		Reason:            input.Reason,
+	})
+}
+
+// checkSliceUpdateRules applies the planning rules of plan.slice.generate to
+// a slice whose token estimate or affected files change.
+func (service *Service) checkSliceUpdateRules(ctx context.Context, input graphNodeUpdateInput) error {
+	node, err := service.store.GetGraphNodeByID(ctx, input.NodeID)
+	if err != nil {
+		return err
+	}
+	if node.NodeType != "slice" {
+		return nil
+	}
+	spec := planSliceSpecInput{
+		Title:         node.Title,
+		AffectedFiles: decodeStringSliceJSON(valueOrEmpty(node.AffectedFilesJSON)),
+	}
+	if node.TokenEstimate != nil {
+		spec.TokenEstimate = *node.TokenEstimate
+	}
+	if input.TokenEstimate != nil {
+		spec.TokenEstimate = *input.TokenEstimate
+	}
+	if input.AffectedFiles != nil {
+		spec.AffectedFiles = input.AffectedFiles
+	}
+	rule, err := service.store.GetPlanningRule(ctx)
+	if err != nil {
+		return err
+	}
+	violations := validateSliceSpecs([]planSliceSpecInput{spec}, rule)
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.Message)
+	}
+	return fmt.Errorf("planning rules rejected the update: %s", strings.Join(messages, "; "))
+}
+
+func (service *Service) graphNodeHistory(ctx context.Context, input graphNodeHistoryInput) (map[string]any, error) {
+	if input.NodeID <= 0 {
+		return nil, errors.New("node_id is required")
+	}
+	node, err := service.store.GetGraphNodeByID(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := service.store.ListNodeSnapshots(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"node":      node,
+		"snapshots": snapshots,
+	}, nil
+}
+
+func (service *Service) linkGraphNodeTask(ctx context.Context, input graphNodeTaskLinkInput) (map[string]any, error) {
+	link, err := service.store.LinkGraphNodeTask(ctx, input.NodeID, input.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"link":          link,
+		"nodes_updated": service.syncLinkedGraphNodes(ctx, []int64{input.TaskID}),
 	}, nil
 }
 
@@ -143,6 +429,10 @@ func (service *Service) planRollupSubmit(ctx context.Context, input planRollupSu
 		return nil, err
 	}
 
+	approvalsReset, err := service.store.ResetNodeApprovals(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
 	node, err := service.store.UpdateGraphNodeApprovalState(ctx, input.NodeID, "pending", "in_review")
 	if err != nil {
 		return nil, err
@@ -154,9 +444,84 @@ func (service *Service) planRollupSubmit(ctx context.Context, input planRollupSu
 	}
 
 	return map[string]any{
-		"snapshot": snapshot,
-		"node":     node,
-		"preview":  preview,
+		"snapshot":        snapshot,
+		"node":            node,
+		"preview":         preview,
+		"approvals_reset": approvalsReset,
+	}, nil
+}
+
+func (service *Service) planRollupApprove(ctx context.Context, input planRollupApproveInput) (store.NodeApprovalResult, error) {
+	approver, role, err := service.resolveRollupApprover(ctx, input.SessionID, input.ThreadID)
+	if err != nil {
+		return store.NodeApprovalResult{}, err
+	}
+	return service.store.ApproveNode(ctx, store.NodeApprovalArgs{
+		NodeID:   input.NodeID,
+		Approver: approver,
+		Role:     role,
+		Comment:  input.Comment,
+	})
+}
+
+func (service *Service) planRollupReject(ctx context.Context, input planRollupRejectInput) (store.NodeApprovalResult, error) {
+	approver, role, err := service.resolveRollupApprover(ctx, input.SessionID, input.ThreadID)
+	if err != nil {
+		return store.NodeApprovalResult{}, err
+	}
+	return service.store.RejectNode(ctx, store.NodeApprovalArgs{
+		NodeID:          input.NodeID,
+		Approver:        approver,
+		Role:            role,
+		Comment:         input.Comment,
+		RequiredChanges: input.RequiredChanges,
+	})
+}
+
+// resolveRollupApprover names the deciding agent and takes its role from the
+// store, so a caller cannot claim a role the approval policy requires. A
+// thread decides with its spawn role, a session with its agent_role.
+func (service *Service) resolveRollupApprover(ctx context.Context, sessionID int64, threadID int64) (string, string, error) {
+	if threadID > 0 {
+		thread, err := service.store.GetThreadByID(ctx, threadID)
+		if err != nil {
+			return "", "", err
+		}
+		if sessionID > 0 && thread.SessionID != sessionID {
+			return "", "", fmt.Errorf("thread %d does not belong to session %d", thread.ID, sessionID)
+		}
+		return fmt.Sprintf("thread:%d", thread.ID), thread.Role, nil
+	}
+	if sessionID > 0 {
+		session, err := service.store.GetSessionByID(ctx, sessionID)
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("session:%d", session.ID), session.AgentRole, nil
+	}
+	return "", "", errors.New("session_id or thread_id is required")
+}
+
+func (service *Service) planRollupApprovals(ctx context.Context, input planRollupApprovalsInput) (map[string]any, error) {
+	if input.NodeID <= 0 {
+		return nil, errors.New("node_id is required")
+	}
+	node, err := service.store.GetGraphNodeByID(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := service.store.ListNodeApprovals(ctx, input.NodeID, input.IncludeSuperseded)
+	if err != nil {
+		return nil, err
+	}
+	status, err := service.store.GetNodeApprovalStatus(ctx, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"node":      node,
+		"approvals": approvals,
+		"status":    status,
 	}, nil
 }
 
@@ -177,10 +542,44 @@ type graphNodeCreateInput struct {
 }
 
 type graphNodeListInput struct {
-	NodeType string `json:"node_type"`
-	Facet    string `json:"facet"`
-	Status   string `json:"status"`
-	ParentID *int64 `json:"parent_id"`
+	NodeType       string `json:"node_type"`
+	Facet          string `json:"facet"`
+	Status         string `json:"status"`
+	ParentID       *int64 `json:"parent_id"`
+	IncludeDeleted bool   `json:"include_deleted"`
+}
+
+type graphExportInput struct {
+	NodeID int64  `json:"node_id"`
+	Format string `json:"format"`
+}
+
+type graphNodeUpdateInput struct {
+	NodeID         int64    `json:"node_id"`
+	Title          *string  `json:"title"`
+	Status         *string  `json:"status"`
+	Priority       *int     `json:"priority"`
+	Summary        *string  `json:"summary"`
+	RiskLevel      *int     `json:"risk_level"`
+	TokenEstimate  *int     `json:"token_estimate"`
+	AffectedFiles  []string `json:"affected_files"`
+	OwnerSessionID *int64   `json:"owner_session_id"`
+	Reason         string   `json:"reason"`
+}
+
+type graphNodeDeleteInput struct {
+	NodeID  int64  `json:"node_id"`
+	Reason  string `json:"reason"`
+	Cascade bool   `json:"cascade"`
+}
+
+type graphNodeHistoryInput struct {
+	NodeID int64 `json:"node_id"`
+}
+
+type graphNodeTaskLinkInput struct {
+	NodeID int64 `json:"node_id"`
+	TaskID int64 `json:"task_id"`
 }
 
 type graphEdgeCreateInput struct {
@@ -189,6 +588,19 @@ type graphEdgeCreateInput struct {
 	EdgeType   string `json:"edge_type"`
 }
 
+type graphEdgeDeleteInput struct {
+	EdgeID int64 `json:"edge_id"`
+}
+
+type graphChecklistReorderInput struct {
+	NodeID  int64   `json:"node_id"`
+	ItemIDs []int64 `json:"item_ids"`
+}
+
+type graphChecklistDeleteInput struct {
+	ItemID int64 `json:"item_id"`
+}
+
 type graphChecklistUpsertInput struct {
 	NodeID   int64  `json:"node_id"`
 	ItemText string `json:"item_text"`
@@ -225,6 +637,65 @@ type planSliceGenerateInput struct {
 	PlanNodeID     int64                `json:"plan_node_id"`
 	OwnerSessionID *int64               `json:"owner_session_id"`
 	SliceSpecs     []planSliceSpecInput `json:"slice_specs"`
+	AutoSplit      bool                 `json:"auto_split"`
+}
+
+type planImportInput struct {
+	Markdown        string `json:"markdown"`
+	PlanNodeID      *int64 `json:"plan_node_id"`
+	InitiativeID    *int64 `json:"initiative_id"`
+	InitiativeTitle string `json:"initiative_title"`
+	OwnerSessionID  *int64 `json:"owner_session_id"`
+	Priority        int    `json:"priority"`
+	DryRun          bool   `json:"dry_run"`
+}
+
+type planTemplateApplyInput struct {
+	Template        string            `json:"template"`
+	Variables       map[string]string `json:"variables"`
+	PlanNodeID      *int64            `json:"plan_node_id"`
+	InitiativeID    *int64            `json:"initiative_id"`
+	InitiativeTitle string            `json:"initiative_title"`
+	OwnerSessionID  *int64            `json:"owner_session_id"`
+	Priority        int               `json:"priority"`
+	DryRun          bool              `json:"dry_run"`
+}
+
+type planReadyInput struct {
+	PlanNodeID int64 `json:"plan_node_id"`
+}
+
+// defaultPlanRiskThreshold is the risk_level at which plan.analyze flags a
+// critical-path slice as high risk.
+const defaultPlanRiskThreshold = 3
+
+type planAnalyzeInput struct {
+	PlanNodeID    int64 `json:"plan_node_id"`
+	RiskThreshold *int  `json:"risk_threshold"`
+}
+
+type planDispatchInput struct {
+	SessionID             int64  `json:"session_id"`
+	PlanNodeID            int64  `json:"plan_node_id"`
+	ParentTaskID          *int64 `json:"parent_task_id"`
+	ParentWorktreeID      *int64 `json:"parent_worktree_id"`
+	MaxConcurrentChildren *int   `json:"max_concurrent_children"`
+	CreateOnDisk          *bool  `json:"create_on_disk"`
+	DryRun                bool   `json:"dry_run"`
+	EnsureTmux            *bool  `json:"ensure_tmux"`
+	LaunchCodex           *bool  `json:"launch_codex"`
+	SkipReadyCheck        *bool  `json:"skip_ready_check"`
+	RunnerKind            string `json:"runner_kind"`
+	Backend               string `json:"backend"`
+	ProviderType          string `json:"provider"`
+	CodexCommand          string `json:"codex_command"`
+}
+
+type planRulesUpdateInput struct {
+	MaxTokenPerSlice *int     `json:"max_token_per_slice"`
+	MaxFilesPerSlice *int     `json:"max_files_per_slice"`
+	ReplanTriggers   []string `json:"replan_triggers"`
+	ApprovalPolicy   *string  `json:"approval_policy"`
 }
 
 type planSliceReplanInput struct {
@@ -247,9 +718,21 @@ type planRollupSubmitInput struct {
 }
 
 type planRollupApproveInput struct {
-	NodeID int64 `json:"node_id"`
+	NodeID    int64  `json:"node_id"`
+	SessionID int64  `json:"session_id"`
+	ThreadID  int64  `json:"thread_id"`
+	Comment   string `json:"comment"`
 }
 
 type planRollupRejectInput struct {
-	NodeID int64 `json:"node_id"`
+	NodeID          int64    `json:"node_id"`
+	SessionID       int64    `json:"session_id"`
+	ThreadID        int64    `json:"thread_id"`
+	Comment         string   `json:"comment"`
+	RequiredChanges []string `json:"required_changes"`
+}
+
+type planRollupApprovalsInput struct {
+	NodeID            int64 `json:"node_id"`
+	IncludeSuperseded bool  `json:"include_superseded"`
 }
@@ -0,0 +1,187 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestValidateSliceSpecsReportsRuleViolations(t *testing.T) {
+	rule := store.PlanningRule{MaxTokenPerSlice: 18000, MaxFilesPerSlice: 12}
+	files := make([]string, 0, 80)
+	for index := 0; index < 80; index++ {
+		files = append(files, fmt.Sprintf("pkg/module%d/file.go", index))
+	}
+
+	violations := validateSliceSpecs([]planSliceSpecInput{
+		{Title: "small", TokenEstimate: 4000, AffectedFiles: []string{"a.go"}},
+		{Title: "huge", TokenEstimate: 200000, AffectedFiles: files},
+	}, rule)
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %+v", violations)
+	}
+	for _, violation := range violations {
+		if violation.SliceIndex != 1 {
+			t.Fatalf("expected violations for slice 1 only, got %+v", violation)
+		}
+	}
+	if violations[0].Rule != "max_token_per_slice" || violations[0].Actual != 200000 {
+		t.Fatalf("unexpected token violation: %+v", violations[0])
+	}
+	if violations[1].Rule != "max_files_per_slice" || violations[1].Actual != 80 {
+		t.Fatalf("unexpected files violation: %+v", violations[1])
+	}
+}
+
+func TestSplitSliceSpecGroupsByDirectory(t *testing.T) {
+	rule := store.PlanningRule{MaxTokenPerSlice: 18000, MaxFilesPerSlice: 4}
+	spec := planSliceSpecInput{
+		Title:         "refactor",
+		TokenEstimate: 12000,
+		AffectedFiles: []string{
+			"api/handler.go", "api/routes.go",
+			"store/db.go", "store/query.go", "store/tx.go",
+			"web/app.ts",
+		},
+	}
+
+	parts := splitSliceSpec(spec, rule)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 parts, got %+v", parts)
+	}
+	if len(validateSliceSpecs(parts, rule)) != 0 {
+		t.Fatalf("expected split parts to satisfy rules, got %+v", parts)
+	}
+	totalTokens := 0
+	totalFiles := 0
+	for _, part := range parts {
+		totalTokens += part.TokenEstimate
+		totalFiles += len(part.AffectedFiles)
+	}
+	if totalTokens != spec.TokenEstimate || totalFiles != len(spec.AffectedFiles) {
+		t.Fatalf("expected tokens/files preserved, got tokens=%d files=%d", totalTokens, totalFiles)
+	}
+	if !strings.Contains(parts[1].Title, "store, web") || len(parts[1].AffectedFiles) != 4 {
+		t.Fatalf("expected store and web directories packed together, got %+v", parts[1])
+	}
+}
+
+func TestPlanSliceGenerateRejectsOversizedSlices(t *testing.T) {
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	ctx := context.Background()
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "initiative", PlanTitle: "plan"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	planNode := bootstrap["plan"].(store.GraphNode)
+
+	files := make([]string, 0, 20)
+	for index := 0; index < 20; index++ {
+		files = append(files, fmt.Sprintf("dir%d/file.go", index%4))
+	}
+	input := planSliceGenerateInput{
+		PlanNodeID: planNode.ID,
+		SliceSpecs: []planSliceSpecInput{{Title: "wide", TokenEstimate: 30000, AffectedFiles: files}},
+	}
+
+	rejected, err := service.planSliceGenerate(ctx, input)
+	if err != nil {
+		t.Fatalf("expected structured rejection, got error: %v", err)
+	}
+	if rejected["accepted"] != false {
+		t.Fatalf("expected slice to be rejected, got %+v", rejected)
+	}
+	if _, ok := rejected["suggested_slice_specs"]; !ok {
+		t.Fatalf("expected suggested split in rejection, got %+v", rejected)
+	}
+
+	input.AutoSplit = true
+	accepted, err := service.planSliceGenerate(ctx, input)
+	if err != nil {
+		t.Fatalf("failed to generate split slices: %v", err)
+	}
+	slices := accepted["slices"].([]store.GraphNode)
+	if accepted["accepted"] != true || len(slices) < 2 {
+		t.Fatalf("expected split slices to be created, got %+v", accepted)
+	}
+
+	oversized := 30000
+	if _, err := service.updateGraphNode(ctx, graphNodeUpdateInput{NodeID: slices[0].ID, TokenEstimate: &oversized}); err == nil || !strings.Contains(err.Error(), "planning rules rejected") {
+		t.Fatalf("expected graph.node.update to enforce max_token_per_slice, got %v", err)
+	}
+	if _, err := service.updateGraphNode(ctx, graphNodeUpdateInput{NodeID: slices[0].ID, AffectedFiles: files}); err == nil {
+		t.Fatalf("expected graph.node.update to enforce max_files_per_slice")
+	}
+	smaller := 1000
+	if _, err := service.updateGraphNode(ctx, graphNodeUpdateInput{NodeID: slices[0].ID, TokenEstimate: &smaller}); err != nil {
+		t.Fatalf("failed to shrink slice estimate: %v", err)
+	}
+}
+
+func TestPlanRollupApprovalRoleComesFromTheCaller(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create worker thread: %v", err)
+	}
+	reviewer, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "merge-reviewer", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create reviewer thread: %v", err)
+	}
+	bootstrap, err := service.planBootstrap(ctx, planBootstrapInput{InitiativeTitle: "payments", PlanTitle: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to bootstrap plan: %v", err)
+	}
+	plan := bootstrap["plan"].(store.GraphNode)
+	if _, err := service.planRollupSubmit(ctx, planRollupSubmitInput{NodeID: plan.ID, Summary: "checkout done"}); err != nil {
+		t.Fatalf("failed to submit rollup: %v", err)
+	}
+
+	if _, err := service.planRollupApprove(ctx, planRollupApproveInput{NodeID: plan.ID}); err == nil {
+		t.Fatal("expected an approval without a caller to be rejected")
+	}
+	otherSession, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "other", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if _, err := service.planRollupApprove(ctx, planRollupApproveInput{NodeID: plan.ID, SessionID: otherSession.ID, ThreadID: reviewer.ID}); err == nil {
+		t.Fatal("expected a thread from another session to be rejected")
+	}
+
+	// The default policy wants a merge-reviewer; a worker's approval counts
+	// as a worker's, whatever it would like to claim.
+	byWorker, err := service.planRollupApprove(ctx, planRollupApproveInput{NodeID: plan.ID, ThreadID: worker.ID})
+	if err != nil {
+		t.Fatalf("failed to approve as worker: %v", err)
+	}
+	if byWorker.Approval.Role != "worker" || byWorker.Approval.Approver != fmt.Sprintf("thread:%d", worker.ID) || byWorker.Status.Satisfied {
+		t.Fatalf("expected an unsatisfied worker approval, got %+v", byWorker)
+	}
+	byReviewer, err := service.planRollupApprove(ctx, planRollupApproveInput{NodeID: plan.ID, SessionID: session.ID, ThreadID: reviewer.ID})
+	if err != nil {
+		t.Fatalf("failed to approve as reviewer: %v", err)
+	}
+	if !byReviewer.Status.Satisfied || byReviewer.Node.ApprovalState != "approved" {
+		t.Fatalf("expected the merge-reviewer to satisfy the policy, got %+v", byReviewer)
+	}
+}
@@ -0,0 +1,189 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+)
+
+const defaultFixtureCaptureLines = 200
+
+// ProviderCheckOptions selects the corpus and the optional new capture for
+// --mode provider-check.
+type ProviderCheckOptions struct {
+	FixtureDir  string
+	CapturePath string
+	Provider    string
+}
+
+// ProviderCheckReport is the provider-check output.
+type ProviderCheckReport struct {
+	FixtureDirs []string                 `json:"fixture_dirs"`
+	Results     []provider.FixtureResult `json:"results"`
+	Passed      int                      `json:"passed"`
+	Failed      int                      `json:"failed"`
+	CapturePath string                   `json:"capture_path,omitempty"`
+	Probe       []provider.ProbeResult   `json:"probe,omitempty"`
+}
+
+// ProviderPromoteOptions describes a thread log tail to turn into a fixture.
+// Status and Response default to what the provider currently derives.
+type ProviderPromoteOptions struct {
+	ThreadID   int64
+	LogPath    string
+	Provider   string
+	Name       string
+	Status     string
+	Response   *string
+	Lines      int
+	FixtureDir string
+}
+
+// ProviderPromoteResult is the provider-promote output.
+type ProviderPromoteResult struct {
+	Path    string           `json:"path"`
+	Fixture provider.Fixture `json:"fixture"`
+}
+
+func (service *Service) providerFixtureDir() string {
+	return filepath.Join(service.repoPath, ".codex-orch", "provider-fixtures")
+}
+
+// ProviderCheck runs the built-in corpus plus the repository corpus through
+// their providers and, when a capture file is given, shows how every provider
+// reads it.
+func (service *Service) ProviderCheck(ctx context.Context, options ProviderCheckOptions) (ProviderCheckReport, error) {
+	if err := service.provider.LoadConfig(service.providerConfigPath()); err != nil {
+		return ProviderCheckReport{}, fmt.Errorf("failed to load providers config: %w", err)
+	}
+	fixtures, err := provider.BuiltinFixtures()
+	if err != nil {
+		return ProviderCheckReport{}, err
+	}
+	fixtureDir := strings.TrimSpace(options.FixtureDir)
+	if fixtureDir == "" {
+		fixtureDir = service.providerFixtureDir()
+	}
+	repoFixtures, err := provider.LoadFixtureDir(fixtureDir)
+	if err != nil {
+		return ProviderCheckReport{}, err
+	}
+	fixtures = append(fixtures, repoFixtures...)
+
+	report := ProviderCheckReport{
+		FixtureDirs: []string{"builtin", fixtureDir},
+		Results:     make([]provider.FixtureResult, 0, len(fixtures)),
+	}
+	providerFilter := strings.TrimSpace(options.Provider)
+	for _, fixture := range fixtures {
+		if providerFilter != "" && fixture.Provider != providerFilter {
+			continue
+		}
+		result := service.provider.CheckFixture(fixture)
+		if result.Pass {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if capturePath := strings.TrimSpace(options.CapturePath); capturePath != "" {
+		content, err := os.ReadFile(capturePath)
+		if err != nil {
+			return ProviderCheckReport{}, err
+		}
+		report.CapturePath = capturePath
+		report.Probe = service.provider.Probe(provider.StripEscapes(string(content)))
+	}
+	return report, nil
+}
+
+// PromoteProviderFixture copies the tail of a thread log into the repository
+// corpus so a regression seen in production becomes a fixture.
+func (service *Service) PromoteProviderFixture(ctx context.Context, options ProviderPromoteOptions) (ProviderPromoteResult, error) {
+	logPath := strings.TrimSpace(options.LogPath)
+	providerType := strings.TrimSpace(options.Provider)
+	if options.ThreadID > 0 {
+		thread, err := service.store.GetThreadByID(ctx, options.ThreadID)
+		if err != nil {
+			return ProviderPromoteResult{}, err
+		}
+		if logPath == "" {
+			logPath = service.threadLogFilePath(thread)
+		}
+		if providerType == "" {
+			providerType = strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+		}
+	}
+	if logPath == "" {
+		return ProviderPromoteResult{}, errors.New("thread id or log path is required")
+	}
+	if providerType == "" {
+		providerType = defaultProviderType
+	}
+	if _, err := service.resolveProviderType(providerType); err != nil {
+		return ProviderPromoteResult{}, err
+	}
+
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		return ProviderPromoteResult{}, err
+	}
+	lines := options.Lines
+	if lines <= 0 {
+		lines = defaultFixtureCaptureLines
+	}
+	capture := provider.StripEscapes(string(content))
+	capture = strings.ReplaceAll(capture, "\r\n", "\n")
+	captureLines := strings.Split(strings.TrimRight(capture, "\n"), "\n")
+	if len(captureLines) > lines {
+		captureLines = captureLines[len(captureLines)-lines:]
+	}
+	capture = strings.Join(captureLines, "\n") + "\n"
+
+	p, err := service.provider.New(providerType)
+	if err != nil {
+		return ProviderPromoteResult{}, err
+	}
+	status := provider.Status(strings.TrimSpace(options.Status))
+	if status == "" {
+		status = p.GetStatus(capture)
+	}
+	response := options.Response
+	if response == nil && status == provider.StatusCompleted {
+		extracted := strings.TrimSpace(p.ExtractLastResponse(capture))
+		response = &extracted
+	}
+
+	now := time.Now().UTC()
+	name := strings.TrimSpace(options.Name)
+	if name == "" {
+		name = fmt.Sprintf("%s_%s", strings.TrimSuffix(filepath.Base(logPath), filepath.Ext(logPath)), now.Format("20060102T150405"))
+	}
+	fixture := provider.Fixture{
+		Name:       name,
+		Provider:   providerType,
+		Status:     status,
+		Response:   response,
+		Source:     filepath.Base(logPath),
+		RecordedAt: now.Format(time.RFC3339),
+		Capture:    capture,
+	}
+	fixtureDir := strings.TrimSpace(options.FixtureDir)
+	if fixtureDir == "" {
+		fixtureDir = service.providerFixtureDir()
+	}
+	path, err := provider.WriteFixture(fixtureDir, fixture)
+	if err != nil {
+		return ProviderPromoteResult{}, err
+	}
+	fixture.Path = path
+	return ProviderPromoteResult{Path: path, Fixture: fixture}, nil
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+)
+
+func TestPromoteProviderFixtureFromLog(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	logPath := filepath.Join(repoPath, "thread_12.log")
+	logContent := "boot noise\nYou\n  fix it\n\n\x1b[1mcodex:\x1b[0m Fixed the nil check.\r\n  Tests pass.\n\n› \n"
+	if err := os.WriteFile(logPath, []byte(logContent), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+
+	promoted, err := service.PromoteProviderFixture(ctx, ProviderPromoteOptions{LogPath: logPath, Provider: "codex", Name: "nil_check", Lines: 7})
+	if err != nil {
+		t.Fatalf("failed to promote fixture: %v", err)
+	}
+	if promoted.Fixture.Status != provider.StatusCompleted || promoted.Fixture.Response == nil || *promoted.Fixture.Response != "Fixed the nil check.\nTests pass." {
+		t.Fatalf("unexpected promoted fixture: %+v", promoted.Fixture)
+	}
+	if strings.Contains(promoted.Fixture.Capture, "\x1b") || strings.Contains(promoted.Fixture.Capture, "boot noise") || promoted.Fixture.Source != "thread_12.log" {
+		t.Fatalf("expected a clean log tail capture, got %q from %s", promoted.Fixture.Capture, promoted.Fixture.Source)
+	}
+	if promoted.Path != filepath.Join(repoPath, ".codex-orch", "provider-fixtures", "codex", "nil_check.yaml") {
+		t.Fatalf("unexpected fixture path %s", promoted.Path)
+	}
+
+	report, err := service.ProviderCheck(ctx, ProviderCheckOptions{CapturePath: logPath})
+	if err != nil {
+		t.Fatalf("provider check failed: %v", err)
+	}
+	if report.Failed != 0 || report.Passed < 2 || report.Results[len(report.Results)-1].Name != "codex/nil_check" {
+		t.Fatalf("unexpected provider check report: %+v", report)
+	}
+	if len(report.Probe) != 2 || report.Probe[1].Provider != "codex" || report.Probe[1].Status != provider.StatusCompleted {
+		t.Fatalf("unexpected capture probe: %+v", report.Probe)
+	}
+
+	if _, err := service.PromoteProviderFixture(ctx, ProviderPromoteOptions{LogPath: logPath, Provider: "codex", Name: "mislabelled", Status: "idle"}); err != nil {
+		t.Fatalf("failed to promote overridden fixture: %v", err)
+	}
+	if report, err = service.ProviderCheck(ctx, ProviderCheckOptions{Provider: "codex"}); err != nil || report.Failed != 1 {
+		t.Fatalf("expected the mislabelled fixture to fail, got %+v (%v)", report, err)
+	}
+}
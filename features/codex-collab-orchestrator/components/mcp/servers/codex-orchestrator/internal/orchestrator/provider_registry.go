@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const defaultProviderType = "codex"
+
+func (service *Service) providerConfigPath() string {
+	return service.orchConfigPath("providers.yaml")
+}
+
+// resolveProviderType reloads providers.yaml when it changed and checks that
+// the requested provider exists before anything is spawned. A broken file
+// only fails spawns of providers it would have defined.
+func (service *Service) resolveProviderType(requested string) (string, error) {
+	providerType := strings.TrimSpace(requested)
+	if providerType == "" {
+		providerType = defaultProviderType
+	}
+	configErr := service.provider.LoadConfig(service.providerConfigPath())
+	if _, configDefined := service.provider.Definition(providerType); configErr != nil && configDefined {
+		return "", fmt.Errorf("failed to load providers config: %w", configErr)
+	}
+	if _, err := service.provider.New(providerType); err != nil {
+		if configErr != nil {
+			return "", fmt.Errorf("%w (failed to load providers config: %v)", err, configErr)
+		}
+		return "", fmt.Errorf("%w (available: %s)", err, strings.Join(service.provider.Types(), ", "))
+	}
+	return providerType, nil
+}
+
+// agentLaunchCommand starts the thread's provider: a config-defined
+// launch_command template when the provider has one, else the Agents SDK
+// runner wrapper.
+func (service *Service) agentLaunchCommand(workdir string, sessionID int64, thread store.Thread, prompt string) string {
+	definition, ok := service.provider.Definition(strings.TrimSpace(valueOrEmpty(thread.ProviderType)))
+	if !ok || strings.TrimSpace(definition.LaunchCommand) == "" {
+		return service.defaultAgentsRunnerLaunchCommand(workdir, sessionID, thread, thread.Role, prompt)
+	}
+	replacer := strings.NewReplacer(
+		"{prompt}", shellQuote(strings.TrimSpace(prompt)),
+		"{guide_path}", shellQuote(strings.TrimSpace(normalizePathForThread(valueOrEmpty(thread.AgentGuidePath)))),
+		"{workdir}", shellQuote(workdir),
+		"{role}", shellQuote(thread.Role),
+		"{thread_id}", strconv.FormatInt(thread.ID, 10),
+		"{session_id}", strconv.FormatInt(sessionID, 10),
+	)
+	return fmt.Sprintf("cd %s && %s", shellQuote(workdir), replacer.Replace(strings.TrimSpace(definition.LaunchCommand)))
+}
@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestSpawnWithConfigDefinedProvider(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	config := `providers:
+  echo-agent:
+    launch_command: 'printf "prompt=%s\n> \n" {prompt}; cat'
+    idle_pattern: '^>\s*$'
+    exit_command: "bye"
+`
+	if err := os.WriteFile(filepath.Join(repoPath, ".codex-orch", "providers.yaml"), []byte(config), 0o644); err != nil {
+		t.Fatalf("failed to write providers config: %v", err)
+	}
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+
+	if _, _, _, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{SessionID: session.ID, Backend: "headless", ProviderType: "gemini"}); err == nil || !strings.Contains(err.Error(), "echo-agent") {
+		t.Fatalf("expected unknown provider error listing available providers, got %v", err)
+	}
+	children, err := service.store.ListThreads(ctx, store.ThreadFilter{SessionID: session.ID, Role: "worker"})
+	if err != nil || len(children) != 0 {
+		t.Fatalf("expected no thread record for an unknown provider, got %d (%v)", len(children), err)
+	}
+
+	thread, _, runtimeResult, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{
+		SessionID:     session.ID,
+		Backend:       "headless",
+		ProviderType:  "echo-agent",
+		InitialPrompt: "it's ready",
+	})
+	if err != nil {
+		t.Fatalf("failed to spawn: %v", err)
+	}
+	if runtimeResult["ready_check"] != "idle" || thread.Status != "running" {
+		t.Fatalf("expected provider idle pattern to pass the ready check, got %+v / %s", runtimeResult, thread.Status)
+	}
+	if launchCommand := valueOrEmpty(thread.LaunchCommand); !strings.Contains(launchCommand, `printf "prompt=%s\n> \n" 'it'"'"'s ready'; cat`) {
+		t.Fatalf("expected templated launch command, got %s", launchCommand)
+	}
+
+	if _, err := service.stopChildThread(ctx, threadChildStopInput{ThreadID: thread.ID}); err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+	content, err := os.ReadFile(valueOrEmpty(thread.LogFilePath))
+	if err != nil || !strings.Contains(string(content), "prompt=it's ready") || !strings.Contains(string(content), "bye") {
+		t.Fatalf("expected prompt and exit command in log, got %q (%v)", content, err)
+	}
+}
+
+func TestBrokenProvidersConfigFallsBackToBuiltins(t *testing.T) {
+	repoPath := t.TempDir()
+	configPath := filepath.Join(repoPath, ".codex-orch", "providers.yaml")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		t.Fatalf("failed to create config directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte("providers:\n  echo-agent:\n    launch_command: cat\n"), 0o644); err != nil {
+		t.Fatalf("failed to write providers config: %v", err)
+	}
+
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("expected a broken providers config not to stop the service, got %v", err)
+	}
+	defer service.Close()
+
+	if providerType, err := service.resolveProviderType(""); err != nil || providerType != defaultProviderType {
+		t.Fatalf("expected built-in providers to keep working, got %q (%v)", providerType, err)
+	}
+	if _, err := service.resolveProviderType("echo-agent"); err == nil || !strings.Contains(err.Error(), "idle_pattern is required") {
+		t.Fatalf("expected the config error for a config-defined provider, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+type searchResultLink struct {
+	Method string         `json:"method"`
+	Params map[string]any `json:"params"`
+}
+
+type searchResultView struct {
+	store.SearchResult
+	Link *searchResultLink `json:"link,omitempty"`
+}
+
+func (service *Service) searchQuery(ctx context.Context, input searchQueryInput) (map[string]any, error) {
+	results, err := service.store.Search(ctx, store.SearchArgs{
+		Query:       input.Query,
+		EntityTypes: input.EntityTypes,
+		Limit:       input.Limit,
+		MatchAny:    input.MatchAny,
+		Raw:         input.Raw,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]searchResultView, 0, len(results))
+	for _, result := range results {
+		views = append(views, searchResultView{SearchResult: result, Link: searchLinkFor(result)})
+	}
+	return map[string]any{"query": input.Query, "results": views, "count": len(views)}, nil
+}
+
+// searchLinkFor points each hit at the method that shows it in context:
+// steps and checkpoints open their case, snapshots open their node history.
+func searchLinkFor(result store.SearchResult) *searchResultLink {
+	switch result.EntityType {
+	case store.SearchEntityTask:
+		return &searchResultLink{Method: "task.get", Params: map[string]any{"task_id": result.EntityID}}
+	case store.SearchEntityGraphNode:
+		return &searchResultLink{Method: "graph.node.history", Params: map[string]any{"node_id": result.EntityID}}
+	}
+	if result.ParentID == nil {
+		return nil
+	}
+	switch result.EntityType {
+	case store.SearchEntityStep, store.SearchEntityCheckpoint:
+		return &searchResultLink{Method: "task.get", Params: map[string]any{"task_id": *result.ParentID}}
+	case store.SearchEntityNodeSnapshot:
+		return &searchResultLink{Method: "graph.node.history", Params: map[string]any{"node_id": *result.ParentID}}
+	case store.SearchEntityInboxMessage:
+		return &searchResultLink{Method: "inbox.list", Params: map[string]any{"thread_id": *result.ParentID}}
+	}
+	return nil
+}
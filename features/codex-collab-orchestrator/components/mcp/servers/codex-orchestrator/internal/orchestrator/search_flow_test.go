@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestSearchQueryLinksResultsToTheirContext(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	node, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "HTTP client"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	if _, err := service.store.CreateNodeSnapshot(ctx, store.NodeSnapshotCreateArgs{NodeID: node.ID, SnapshotType: "decision", Summary: "retry policy: exponential backoff, 3 attempts"}); err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if _, err := service.store.CreateInboxMessage(ctx, store.InboxMessageCreateArgs{SenderThreadID: 4, ReceiverThreadID: 9, Message: "which retry policy applies to uploads?"}); err != nil {
+		t.Fatalf("failed to create inbox message: %v", err)
+	}
+
+	result, err := service.searchQuery(ctx, searchQueryInput{Query: "where did we decide the retry policy?", MatchAny: true})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	views := result["results"].([]searchResultView)
+	if len(views) != 2 {
+		t.Fatalf("expected snapshot and inbox hits, got %+v", views)
+	}
+	links := map[string]*searchResultLink{}
+	for _, view := range views {
+		links[view.EntityType] = view.Link
+	}
+	if link := links[store.SearchEntityNodeSnapshot]; link == nil || link.Method != "graph.node.history" || link.Params["node_id"] != node.ID {
+		t.Fatalf("unexpected snapshot link: %+v", link)
+	}
+	if link := links[store.SearchEntityInboxMessage]; link == nil || link.Method != "inbox.list" || link.Params["thread_id"] != int64(9) {
+		t.Fatalf("unexpected inbox link: %+v", link)
+	}
+
+	if _, err := service.searchQuery(ctx, searchQueryInput{Query: "  "}); err == nil {
+		t.Fatalf("expected empty query to be rejected")
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
 	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
 	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
 	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/tmux"
@@ -26,7 +28,11 @@ type Service struct {
 	repoPath string
 	store    *store.Store
 	tmux     *tmux.Client
+	headless *headless.Runner
 	provider *provider.Manager
+
+	defaultRunnerBackend string
+	supervisor           threadSupervisor
 }
 
 func NewService(repoPath string) (*Service, error) {
@@ -41,15 +47,24 @@ func NewService(repoPath string) (*Service, error) {
 		return nil, err
 	}
 
-	return &Service{
+	service := &Service{
 		repoPath: absoluteRepoPath,
 		store:    stateStore,
 		tmux:     tmux.NewClient(),
+		headless: headless.NewRunner(),
 		provider: provider.NewManager(),
-	}, nil
+	}
+	// A broken providers.yaml must not take the server down: start with the
+	// built-in providers and let a spawn that needs the file report it.
+	if err := service.provider.LoadConfig(service.providerConfigPath()); err != nil {
+		log.Printf("ignoring providers config, using built-in providers: %v", err)
+	}
+	return service, nil
 }
 
 func (service *Service) Close() error {
+	service.stopSupervisor()
+	service.headless.StopAll(headlessStopGrace)
 	return service.store.Close()
 }
 
@@ -77,7 +92,7 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
-		return service.store.CloseSession(ctx, input.SessionID)
+		return service.closeSession(ctx, input)
 	case "session.context":
 		var input sessionContextInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -122,9 +137,10 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.store.ListTasks(ctx, store.TaskFilter{
-			Level:    input.Level,
-			Status:   input.Status,
-			ParentID: input.ParentID,
+			Level:          input.Level,
+			Status:         input.Status,
+			ParentID:       input.ParentID,
+			IncludeDeleted: input.IncludeDeleted,
 		})
 	case "task.get":
 		var input taskGetInput
@@ -132,6 +148,56 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.store.GetTaskByID(ctx, input.TaskID)
+	case "task.tree":
+		var input taskTreeInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		if input.TaskID <= 0 {
+			return nil, errors.New("task_id is required")
+		}
+		return service.store.BuildTaskTree(ctx, input.TaskID)
+	case "task.update":
+		var input taskUpdateInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.UpdateTask(ctx, input.TaskID, store.TaskUpdateArgs{
+			Title:           input.Title,
+			Priority:        input.Priority,
+			AssigneeSession: input.AssigneeSession,
+			NextAction:      input.NextAction,
+		})
+	case "task.cancel":
+		var input taskCancelInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.cancelTask(ctx, input)
+	case "task.block":
+		var input taskBlockInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.blockTask(ctx, input)
+	case "task.unblock":
+		var input taskUnblockInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.unblockTask(ctx, input)
+	case "task.move":
+		var input taskMoveInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.MoveTask(ctx, input.TaskID, input.ParentID)
+	case "task.delete":
+		var input taskDeleteInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.deleteTask(ctx, input)
 	case "graph.node.create":
 		var input graphNodeCreateInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -158,11 +224,55 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.store.ListGraphNodes(ctx, store.GraphNodeFilter{
-			NodeType: input.NodeType,
-			Facet:    input.Facet,
-			Status:   input.Status,
-			ParentID: input.ParentID,
+			NodeType:       input.NodeType,
+			Facet:          input.Facet,
+			Status:         input.Status,
+			ParentID:       input.ParentID,
+			IncludeDeleted: input.IncludeDeleted,
 		})
+	case "graph.node.update":
+		var input graphNodeUpdateInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.updateGraphNode(ctx, input)
+	case "graph.node.delete":
+		var input graphNodeDeleteInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.DeleteGraphNode(ctx, input.NodeID, input.Reason, input.Cascade)
+	case "graph.node.history":
+		var input graphNodeHistoryInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.graphNodeHistory(ctx, input)
+	case "graph.node.link_task":
+		var input graphNodeTaskLinkInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.linkGraphNodeTask(ctx, input)
+	case "graph.node.unlink_task":
+		var input graphNodeTaskLinkInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		if err := service.store.UnlinkGraphNodeTask(ctx, input.NodeID, input.TaskID); err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"node_id":  input.NodeID,
+			"task_id":  input.TaskID,
+			"unlinked": true,
+		}, nil
+	case "graph.export":
+		var input graphExportInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.exportGraph(ctx, input)
 	case "graph.edge.create":
 		var input graphEdgeCreateInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -173,6 +283,12 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			ToNodeID:   input.ToNodeID,
 			EdgeType:   input.EdgeType,
 		})
+	case "graph.edge.delete":
+		var input graphEdgeDeleteInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.DeleteGraphEdge(ctx, input.EdgeID)
 	case "graph.checklist.upsert":
 		var input graphChecklistUpsertInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -185,6 +301,18 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			OrderNo:  input.OrderNo,
 			Facet:    input.Facet,
 		})
+	case "graph.checklist.reorder":
+		var input graphChecklistReorderInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.ReorderNodeChecklist(ctx, input.NodeID, input.ItemIDs)
+	case "graph.checklist.delete":
+		var input graphChecklistDeleteInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.store.DeleteNodeChecklistItem(ctx, input.ItemID)
 	case "graph.snapshot.create":
 		var input graphSnapshotCreateInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -215,6 +343,50 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.planSliceReplan(ctx, input)
+	case "plan.import":
+		var input planImportInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.planImport(ctx, input)
+	case "plan.template.apply":
+		var input planTemplateApplyInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.planTemplateApply(ctx, input)
+	case "plan.ready":
+		var input planReadyInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		if input.PlanNodeID <= 0 {
+			return nil, errors.New("plan_node_id is required")
+		}
+		return service.store.PlanReadySet(ctx, input.PlanNodeID)
+	case "plan.analyze":
+		var input planAnalyzeInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		if input.PlanNodeID <= 0 {
+			return nil, errors.New("plan_node_id is required")
+		}
+		return service.store.AnalyzePlan(ctx, input.PlanNodeID, intValueOrDefault(input.RiskThreshold, defaultPlanRiskThreshold))
+	case "plan.dispatch":
+		var input planDispatchInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.planDispatch(ctx, input)
+	case "plan.rules.get":
+		return service.getPlanningRules(ctx)
+	case "plan.rules.update":
+		var input planRulesUpdateInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.updatePlanningRules(ctx, input)
 	case "plan.rollup.preview":
 		var input planRollupPreviewInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -232,13 +404,19 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
-		return service.store.UpdateGraphNodeApprovalState(ctx, input.NodeID, "approved", "done")
+		return service.planRollupApprove(ctx, input)
 	case "plan.rollup.reject":
 		var input planRollupRejectInput
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
-		return service.store.UpdateGraphNodeApprovalState(ctx, input.NodeID, "rejected", "blocked")
+		return service.planRollupReject(ctx, input)
+	case "plan.rollup.approvals":
+		var input planRollupApprovalsInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.planRollupApprovals(ctx, input)
 	case "scheduler.decide_worktree":
 		var input worktreeDecisionInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -277,6 +455,12 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.directiveChildThread(ctx, input)
+	case "thread.child.answer":
+		var input threadChildAnswerInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.answerChildThread(ctx, input)
 	case "thread.child.list":
 		var input threadChildListInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -301,12 +485,34 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.childThreadStatus(ctx, input)
+	case "thread.directive.list":
+		var input threadDirectiveListInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.listThreadDirectives(ctx, input)
+	case "thread.transcript.get":
+		var input threadTranscriptGetInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.threadTranscript(ctx, input)
 	case "thread.child.wait_status":
 		var input threadChildWaitStatusInput
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
 		return service.waitChildThreadStatus(ctx, input)
+	case "thread.supervisor.get":
+		return service.supervisorStatus(ctx)
+	case "thread.supervisor.update":
+		var input threadSupervisorUpdateInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.updateSupervisorPolicy(ctx, input)
+	case "thread.supervisor.sweep":
+		return service.superviseThreads(ctx)
 	case "thread.attach_info":
 		var input threadAttachInfoInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -318,12 +524,7 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
-		return service.store.AcquireLock(ctx, store.LockAcquireArgs{
-			ScopeType:    input.ScopeType,
-			ScopePath:    input.ScopePath,
-			OwnerSession: input.OwnerSession,
-			TTLSeconds:   input.TTLSeconds,
-		})
+		return service.acquireLock(ctx, input)
 	case "lock.heartbeat":
 		var input lockHeartbeatInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -336,19 +537,48 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.store.ReleaseLock(ctx, input.LockID)
+	case "lock.list":
+		var input lockListInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.listLocks(ctx, input)
+	case "lock.audit":
+		var input lockAuditInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.auditLocks(ctx, input)
 	case "case.begin":
 		var input caseBeginInput
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
+		acquiredLocks, err := service.claimCaseLocks(ctx, input)
+		if err != nil {
+			return nil, err
+		}
 		caseTask, err := service.store.BeginCase(ctx, store.CaseBeginArgs{
 			TaskID:        input.CaseID,
 			InputContract: input.InputContract,
 			Fixtures:      input.Fixtures,
 		})
 		if err != nil {
+			if len(acquiredLocks) > 0 {
+				caseID := input.CaseID
+				lockIDs := make([]int64, 0, len(acquiredLocks))
+				for _, lock := range acquiredLocks {
+					lockIDs = append(lockIDs, lock.ID)
+				}
+				_, _ = service.store.ReleaseLocksByOwner(ctx, store.LockReleaseByOwnerArgs{
+					OwnerCaseID: &caseID,
+					LockIDs:     lockIDs,
+					Reason:      "case_begin_failed",
+				})
+			}
 			return nil, err
 		}
+		service.syncLinkedGraphNodes(ctx, []int64{caseTask.ID})
 		if input.SessionID > 0 {
 			requiredFilesJSON := marshalStringSlice(input.RequiredFiles)
 			_, _ = service.store.UpsertCurrentRef(ctx, store.WorkCurrentRefUpsertArgs{
@@ -390,6 +620,13 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 				RequiredFilesJSON: requiredFilesJSON,
 			})
 		}
+		if boolValueOrDefault(input.AuditLocks, false) && input.ThreadID != nil {
+			audit, auditErr := service.auditLocks(ctx, lockAuditInput{ThreadID: *input.ThreadID})
+			if auditErr != nil {
+				return map[string]any{"step": stepResult, "lock_audit_error": auditErr.Error()}, nil
+			}
+			return map[string]any{"step": stepResult, "lock_audit": audit}, nil
+		}
 		return stepResult, nil
 	case "case.complete":
 		var input caseCompleteInput
@@ -404,6 +641,12 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 		if err != nil {
 			return nil, err
 		}
+		completedCaseID := completedCase.ID
+		_, _ = service.store.ReleaseLocksByOwner(ctx, store.LockReleaseByOwnerArgs{
+			OwnerCaseID: &completedCaseID,
+			Reason:      "case_complete",
+		})
+		service.syncLinkedGraphNodes(ctx, []int64{completedCaseID})
 		if input.SessionID > 0 {
 			requiredFilesJSON := marshalStringSlice(input.RequiredFiles)
 			_, _ = service.store.UpsertCurrentRef(ctx, store.WorkCurrentRefUpsertArgs{
@@ -443,7 +686,7 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 		if err := decodeParams(rawParams, &input); err != nil {
 			return nil, err
 		}
-		return service.store.AckCurrentRef(ctx, input.SessionID, input.RefID)
+		return service.ackCurrentRef(ctx, input)
 	case "merge.request":
 		var input mergeRequestInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -466,6 +709,12 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.requestAutoMergeReview(ctx, input)
+	case "merge.review.submit":
+		var input mergeReviewSubmitInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.submitMergeReview(ctx, input)
 	case "merge.review.thread_status":
 		var input mergeReviewThreadStatusInput
 		if err := decodeParams(rawParams, &input); err != nil {
@@ -502,13 +751,14 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 				return nil, errors.New("merge_request_id is required when auto_review=true")
 			}
 			autoReviewResponse, autoReviewErr := service.requestAutoMergeReview(ctx, mergeReviewRequestAutoInput{
-				SessionID:      input.SessionID,
-				MergeRequestID: *input.MergeRequestID,
-				ReviewerRole:   input.ReviewerRole,
-				AgentGuidePath: input.AgentGuidePath,
-				EnsureTmux:     pointerToBool(true),
-				AutoInstall:    pointerToBool(true),
-				RunnerKind:     defaultRunnerKind,
+				SessionID:          input.SessionID,
+				MergeRequestID:     *input.MergeRequestID,
+				ReviewerRole:       input.ReviewerRole,
+				AgentGuidePath:     input.AgentGuidePath,
+				EnsureTmux:         pointerToBool(true),
+				AutoInstall:        pointerToBool(true),
+				RunnerKind:         defaultRunnerKind,
+				MainMergeRequestID: &mainMergeRequest.ID,
 			})
 			if autoReviewErr != nil {
 				response["review_dispatch_error"] = autoReviewErr.Error()
@@ -569,6 +819,30 @@ func (service *Service) Handle(ctx context.Context, method string, rawParams jso
 			return nil, err
 		}
 		return service.inboxDeliver(ctx, input)
+	case "search.query":
+		var input searchQueryInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.searchQuery(ctx, input)
+	case "logs.gc":
+		var input logsGCInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.logsGC(ctx, input)
+	case "metrics.usage":
+		var input metricsUsageInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.metricsUsage(ctx, input)
+	case "metrics.budget.set":
+		var input metricsBudgetSetInput
+		if err := decodeParams(rawParams, &input); err != nil {
+			return nil, err
+		}
+		return service.setMetricsBudget(ctx, input)
 	default:
 		return nil, fmt.Errorf("unsupported method: %s", method)
 	}
@@ -581,8 +855,10 @@ func (service *Service) createWorktree(ctx context.Context, input worktreeCreate
 		worktreePath = filepath.Join(service.repoPath, ".codex-orch", "worktrees", branchSlug)
 	}
 
+	baseCommit := ""
 	if input.CreateOnDisk {
-		if err := service.runGitWorktreeAdd(worktreePath, input.Branch, input.BaseRef); err != nil {
+		var err error
+		if baseCommit, err = service.runGitWorktreeAdd(worktreePath, input.Branch, input.BaseRef); err != nil {
 			return store.Worktree{}, err
 		}
 	}
@@ -593,33 +869,56 @@ func (service *Service) createWorktree(ctx context.Context, input worktreeCreate
 	}
 
 	return service.store.CreateWorktreeRecord(ctx, store.WorktreeCreateArgs{
-		TaskID: input.TaskID,
-		Path:   worktreePath,
-		Branch: input.Branch,
-		Status: status,
+		TaskID:  input.TaskID,
+		Path:    worktreePath,
+		Branch:  input.Branch,
+		Status:  status,
+		BaseRef: baseCommit,
 	})
 }
 
-func (service *Service) runGitWorktreeAdd(worktreePath string, branch string, baseRef string) error {
+// runGitWorktreeAdd creates a worktree on a new branch from baseRef (HEAD
+// when empty) and returns the commit it was created from, so later audits
+// can diff against it.
+func (service *Service) runGitWorktreeAdd(worktreePath string, branch string, baseRef string) (string, error) {
 	if strings.TrimSpace(branch) == "" {
-		return errors.New("branch is required when create_on_disk=true")
+		return "", errors.New("branch is required when create_on_disk=true")
 	}
 
 	if err := os.MkdirAll(filepath.Dir(worktreePath), 0o755); err != nil {
-		return fmt.Errorf("failed to create worktree parent directory: %w", err)
+		return "", fmt.Errorf("failed to create worktree parent directory: %w", err)
 	}
 
-	args := []string{"-C", service.repoPath, "worktree", "add", "-b", branch, worktreePath}
-	if strings.TrimSpace(baseRef) != "" {
-		args = append(args, baseRef)
-	} else {
-		args = append(args, "HEAD")
+	if strings.TrimSpace(baseRef) == "" {
+		baseRef = "HEAD"
+	}
+	baseOutput, err := exec.Command("git", "-C", service.repoPath, "rev-parse", "--verify", baseRef+"^{commit}").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve worktree base %s: %w (%s)", baseRef, err, strings.TrimSpace(string(baseOutput)))
 	}
+	baseCommit := strings.TrimSpace(string(baseOutput))
 
-	command := exec.Command("git", args...)
+	command := exec.Command("git", "-C", service.repoPath, "worktree", "add", "-b", branch, worktreePath, baseCommit)
 	output, err := command.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("git worktree add failed: %w (%s)", err, strings.TrimSpace(string(output)))
+		return "", fmt.Errorf("git worktree add failed: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	return baseCommit, nil
+}
+
+// runGitWorktreeRemove deletes a worktree created by runGitWorktreeAdd along
+// with its branch.
+func (service *Service) runGitWorktreeRemove(worktreePath string, branch string) error {
+	output, err := exec.Command("git", "-C", service.repoPath, "worktree", "remove", "--force", worktreePath).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git worktree remove failed: %w (%s)", err, strings.TrimSpace(string(output)))
+	}
+	if strings.TrimSpace(branch) == "" {
+		return nil
+	}
+	output, err = exec.Command("git", "-C", service.repoPath, "branch", "-D", branch).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git branch delete failed: %w (%s)", err, strings.TrimSpace(string(output)))
 	}
 	return nil
 }
@@ -690,7 +989,12 @@ func (service *Service) refreshMirror(ctx context.Context, input mirrorRefreshIn
 		return nil, err
 	}
 
-	if err := writeMirrorMarkdown(targetPath, status.DBVersion, taskStatusCounts, activeLocks); err != nil {
+	planningGraphs, err := service.listPlanningGraphRoots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeMirrorMarkdown(targetPath, status.DBVersion, taskStatusCounts, activeLocks, planningGraphs); err != nil {
 		return nil, err
 	}
 
@@ -706,7 +1010,7 @@ func (service *Service) refreshMirror(ctx context.Context, input mirrorRefreshIn
 	}, nil
 }
 
-func writeMirrorMarkdown(targetPath string, dbVersion int64, taskStatusCounts map[string]int64, activeLocks []store.Lock) error {
+func writeMirrorMarkdown(targetPath string, dbVersion int64, taskStatusCounts map[string]int64, activeLocks []store.Lock, planningGraphs []store.GraphSubtree) error {
 	if err := os.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
 		return fmt.Errorf("failed to create mirror directory: %w", err)
 	}
@@ -734,6 +1038,18 @@ func writeMirrorMarkdown(targetPath string, dbVersion int64, taskStatusCounts ma
 		}
 	}
 
+	builder.WriteString("\n## Planning graph\n\n")
+	if len(planningGraphs) == 0 {
+		builder.WriteString("- (none)\n")
+	} else {
+		for _, subtree := range planningGraphs {
+			builder.WriteString(fmt.Sprintf("### #%d %s: %s\n\n", subtree.Root.ID, subtree.Root.NodeType, subtree.Root.Title))
+			builder.WriteString("```mermaid\n")
+			builder.WriteString(renderGraphMermaid(subtree))
+			builder.WriteString("```\n\n")
+		}
+	}
+
 	if err := os.WriteFile(targetPath, []byte(builder.String()), 0o644); err != nil {
 		return fmt.Errorf("failed to write mirror file: %w", err)
 	}
@@ -793,15 +1109,53 @@ type sessionContextInput struct {
 }
 
 type taskListInput struct {
-	Level    string `json:"level"`
-	Status   string `json:"status"`
-	ParentID *int64 `json:"parent_id"`
+	Level          string `json:"level"`
+	Status         string `json:"status"`
+	ParentID       *int64 `json:"parent_id"`
+	IncludeDeleted bool   `json:"include_deleted"`
 }
 
 type taskGetInput struct {
 	TaskID int64 `json:"task_id"`
 }
 
+type taskTreeInput struct {
+	TaskID int64 `json:"task_id"`
+}
+
+type taskUpdateInput struct {
+	TaskID          int64   `json:"task_id"`
+	Title           *string `json:"title"`
+	Priority        *int    `json:"priority"`
+	AssigneeSession *string `json:"assignee_session"`
+	NextAction      *string `json:"next_action"`
+}
+
+type taskCancelInput struct {
+	TaskID int64  `json:"task_id"`
+	Reason string `json:"reason"`
+}
+
+type taskBlockInput struct {
+	TaskID int64  `json:"task_id"`
+	Reason string `json:"reason"`
+}
+
+type taskUnblockInput struct {
+	TaskID int64 `json:"task_id"`
+}
+
+type taskMoveInput struct {
+	TaskID   int64  `json:"task_id"`
+	ParentID *int64 `json:"parent_id"`
+}
+
+type taskDeleteInput struct {
+	TaskID int64  `json:"task_id"`
+	Reason string `json:"reason"`
+	Force  bool   `json:"force"`
+}
+
 type worktreeDecisionInput struct {
 	ChangedFiles     int `json:"changed_files"`
 	EstimateMinutes  int `json:"estimate_minutes"`
@@ -883,10 +1237,12 @@ type worktreeMergeToParentInput struct {
 }
 
 type lockAcquireInput struct {
-	ScopeType    string `json:"scope_type"`
-	ScopePath    string `json:"scope_path"`
-	OwnerSession string `json:"owner_session"`
-	TTLSeconds   int    `json:"ttl_seconds"`
+	ScopeType      string `json:"scope_type"`
+	ScopePath      string `json:"scope_path"`
+	OwnerSession   string `json:"owner_session"`
+	OwnerSessionID *int64 `json:"owner_session_id"`
+	OwnerThreadID  *int64 `json:"owner_thread_id"`
+	TTLSeconds     int    `json:"ttl_seconds"`
 }
 
 type lockHeartbeatInput struct {
@@ -898,21 +1254,41 @@ type lockReleaseInput struct {
 	LockID int64 `json:"lock_id"`
 }
 
+type lockListInput struct {
+	State          string `json:"state"`
+	OwnerSession   string `json:"owner_session"`
+	OwnerSessionID *int64 `json:"owner_session_id"`
+	OwnerThreadID  *int64 `json:"owner_thread_id"`
+	OwnerCaseID    *int64 `json:"owner_case_id"`
+}
+
+type lockAuditInput struct {
+	ThreadID int64  `json:"thread_id"`
+	BaseRef  string `json:"base_ref"`
+	Notify   *bool  `json:"notify"`
+}
+
 type caseBeginInput struct {
 	CaseID        int64           `json:"case_id"`
 	SessionID     int64           `json:"session_id"`
+	ThreadID      *int64          `json:"thread_id"`
+	NodeID        *int64          `json:"node_id"`
 	InputContract json.RawMessage `json:"input_contract"`
 	Fixtures      []string        `json:"fixtures"`
 	RequiredFiles []string        `json:"required_files"`
+	AutoLock      *bool           `json:"auto_lock"`
+	LockTTL       int             `json:"lock_ttl_seconds"`
 }
 
 type stepCheckInput struct {
 	CaseID        int64    `json:"case_id"`
 	SessionID     int64    `json:"session_id"`
+	ThreadID      *int64   `json:"thread_id"`
 	StepTitle     string   `json:"step_title"`
 	Result        string   `json:"result"`
 	Artifacts     []string `json:"artifacts"`
 	RequiredFiles []string `json:"required_files"`
+	AuditLocks    *bool    `json:"audit_locks"`
 }
 
 type caseCompleteInput struct {
@@ -940,8 +1316,9 @@ type workCurrentRefInput struct {
 }
 
 type workCurrentRefAckInput struct {
-	SessionID int64 `json:"session_id"`
-	RefID     int64 `json:"ref_id"`
+	SessionID   int64 `json:"session_id"`
+	RefID       int64 `json:"ref_id"`
+	DirectiveID int64 `json:"directive_id"`
 }
 
 type mergeRequestInput struct {
@@ -1007,6 +1384,7 @@ type threadChildSpawnInput struct {
 	InitialPrompt         string          `json:"initial_prompt"`
 	CodexCommand          string          `json:"codex_command"`
 	RunnerKind            string          `json:"runner_kind"`
+	Backend               string          `json:"backend"`
 	InteractionMode       string          `json:"interaction_mode"`
 	ProviderType          string          `json:"provider"`
 	LaunchCodex           *bool           `json:"launch_codex"`
@@ -1019,9 +1397,29 @@ type threadChildSpawnInput struct {
 }
 
 type threadChildDirectiveInput struct {
+	ThreadID              int64  `json:"thread_id"`
+	Directive             string `json:"directive"`
+	Mode                  string `json:"mode"`
+	ConfirmTimeoutSeconds *int   `json:"confirm_timeout_seconds"`
+}
+
+type threadDirectiveListInput struct {
 	ThreadID  int64  `json:"thread_id"`
-	Directive string `json:"directive"`
-	Mode      string `json:"mode"`
+	SessionID int64  `json:"session_id"`
+	Status    string `json:"status"`
+}
+
+type threadChildAnswerInput struct {
+	ThreadID int64  `json:"thread_id"`
+	Option   string `json:"option"`
+}
+
+type threadTranscriptGetInput struct {
+	ThreadID int64  `json:"thread_id"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+	Format   string `json:"format"`
+	Refresh  *bool  `json:"refresh"`
 }
 
 type threadChildListInput struct {
@@ -1051,6 +1449,15 @@ type threadChildWaitStatusInput struct {
 	TimeoutSeconds *int     `json:"timeout_seconds"`
 }
 
+type threadSupervisorUpdateInput struct {
+	Enabled               *bool `json:"enabled"`
+	IntervalSeconds       *int  `json:"interval_seconds"`
+	StallTimeoutSeconds   *int  `json:"stall_timeout_seconds"`
+	MaxRestarts           *int  `json:"max_restarts"`
+	RestartBackoffSeconds *int  `json:"restart_backoff_seconds"`
+	MaxBackoffSeconds     *int  `json:"max_backoff_seconds"`
+}
+
 type threadAttachInfoInput struct {
 	SessionID int64  `json:"session_id"`
 	ThreadID  *int64 `json:"thread_id"`
@@ -1065,6 +1472,25 @@ type mergeReviewRequestAutoInput struct {
 	EnsureTmux     *bool           `json:"ensure_tmux"`
 	AutoInstall    *bool           `json:"auto_install"`
 	RunnerKind     string          `json:"runner_kind"`
+	// MainMergeRequestID links the review to a merge_main_queue item so the
+	// verdict can advance or park it.
+	MainMergeRequestID *int64 `json:"main_merge_request_id"`
+}
+
+type mergeReviewFindingInput struct {
+	File     string `json:"file"`
+	Line     *int   `json:"line"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type mergeReviewSubmitInput struct {
+	ReviewJobID    *int64                    `json:"review_job_id"`
+	MergeRequestID *int64                    `json:"merge_request_id"`
+	Verdict        string                    `json:"verdict"`
+	Summary        string                    `json:"summary"`
+	Findings       []mergeReviewFindingInput `json:"findings"`
+	WorkerThreadID *int64                    `json:"worker_thread_id"`
 }
 
 type mergeReviewThreadStatusInput struct {
@@ -1076,6 +1502,8 @@ type inboxSendInput struct {
 	SenderThreadID   int64  `json:"sender_thread_id"`
 	ReceiverThreadID int64  `json:"receiver_thread_id"`
 	Message          string `json:"message"`
+	DirectiveID      int64  `json:"directive_id"`
+	DirectiveStatus  string `json:"directive_status"`
 }
 
 type inboxPendingInput struct {
@@ -1090,6 +1518,31 @@ type inboxDeliverInput struct {
 	MessageID int64 `json:"message_id"`
 }
 
+type searchQueryInput struct {
+	Query       string   `json:"query"`
+	EntityTypes []string `json:"entity_types"`
+	Limit       int      `json:"limit"`
+	MatchAny    bool     `json:"match_any"`
+	Raw         bool     `json:"raw"`
+}
+
+type logsGCInput struct {
+	SessionID    int64 `json:"session_id"`
+	KeepSegments *int  `json:"keep_segments"`
+	MaxAgeDays   *int  `json:"max_age_days"`
+	DryRun       bool  `json:"dry_run"`
+}
+
+type metricsUsageInput struct {
+	SessionID int64 `json:"session_id"`
+}
+
+type metricsBudgetSetInput struct {
+	SessionID     int64    `json:"session_id"`
+	TokenBudget   *int64   `json:"token_budget"`
+	CostBudgetUSD *float64 `json:"cost_budget_usd"`
+}
+
 func (service *Service) waitChildThreadStatus(ctx context.Context, input threadChildWaitStatusInput) (map[string]any, error) {
 	if input.ThreadID <= 0 {
 		return nil, errors.New("thread_id is required")
@@ -1130,6 +1583,17 @@ func (service *Service) waitChildThreadStatus(ctx context.Context, input threadC
 }
 
 func (service *Service) inboxSend(ctx context.Context, input inboxSendInput) (map[string]any, error) {
+	var directive *store.ThreadDirective
+	if input.DirectiveID > 0 {
+		if input.SenderThreadID <= 0 {
+			return nil, errors.New("sender_thread_id is required to report a directive")
+		}
+		reported, err := service.reportDirective(ctx, input.DirectiveID, input.SenderThreadID, 0, strings.TrimSpace(input.DirectiveStatus))
+		if err != nil {
+			return nil, err
+		}
+		directive = &reported
+	}
 	msg, err := service.store.CreateInboxMessage(ctx, store.InboxMessageCreateArgs{
 		SenderThreadID:   input.SenderThreadID,
 		ReceiverThreadID: input.ReceiverThreadID,
@@ -1138,7 +1602,11 @@ func (service *Service) inboxSend(ctx context.Context, input inboxSendInput) (ma
 	if err != nil {
 		return nil, err
 	}
-	return map[string]any{"message": msg}, nil
+	result := map[string]any{"message": msg}
+	if directive != nil {
+		result["directive"] = directive
+	}
+	return result, nil
 }
 
 func (service *Service) inboxPending(ctx context.Context, input inboxPendingInput) (map[string]any, error) {
@@ -1194,18 +1662,18 @@ func (service *Service) cleanupSession(ctx context.Context, input sessionCleanup
 		if thread.ParentThreadID == nil {
 			continue
 		}
-		paneID := strings.TrimSpace(valueOrEmpty(thread.TmuxPaneID))
-		if paneID == "" {
+		runner, err := service.runnerFor(thread)
+		if err != nil {
 			continue
 		}
-		_ = service.tmux.StopPipePane(ctx, paneID)
-		_ = service.tmux.SendKeysRaw(ctx, paneID, "C-c")
-		_ = service.tmux.KillPane(ctx, paneID)
+		_ = runner.Interrupt(ctx, thread)
+		_ = runner.Stop(ctx, thread, true)
 		service.provider.Remove(thread.ID)
 		stoppedStatus := "stopped"
 		_, _ = service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{
 			Status:     &stoppedStatus,
 			TmuxPaneID: pointerToString(""),
+			ProcessID:  pointerToInt64(0),
 		})
 		stopped++
 	}
@@ -1224,13 +1692,30 @@ func (service *Service) cleanupSession(ctx context.Context, input sessionCleanup
 		}
 	}
 
+	locksReleased, releaseErr := service.releaseSessionLocks(ctx, input.SessionID, "session_cleanup")
 	_, _ = service.store.CloseSession(ctx, input.SessionID)
 
-	return map[string]any{
-		"result":         "cleaned_up",
+	result := map[string]any{
+		"result":          "cleaned_up",
 		"threads_stopped": stopped,
 		"session_killed":  sessionKilled,
-	}, nil
+		"locks_released":  locksReleased,
+	}
+	if releaseErr != nil {
+		result["locks_release_error"] = releaseErr.Error()
+	}
+	return result, nil
+}
+
+func (service *Service) closeSession(ctx context.Context, input sessionCloseInput) (store.Session, error) {
+	session, err := service.store.CloseSession(ctx, input.SessionID)
+	if err != nil {
+		return store.Session{}, err
+	}
+	if _, err := service.releaseSessionLocks(ctx, session.ID, "session_close"); err != nil {
+		return session, err
+	}
+	return session, nil
 }
 
 func (service *Service) listSessions(ctx context.Context) (map[string]any, error) {
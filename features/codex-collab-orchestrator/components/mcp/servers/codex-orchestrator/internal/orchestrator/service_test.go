@@ -3,8 +3,11 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
 )
 
 func TestDecideWorktreeSharedMode(t *testing.T) {
@@ -98,3 +101,57 @@ func TestThreadChildDirectiveDecode(t *testing.T) {
 		t.Fatalf("expected mode interrupt_patch, got %s", input.Mode)
 	}
 }
+
+func TestCaseStatusChangesSyncLinkedGraphNodes(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	planNode, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "todo"})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	slice, err := service.store.CreateGraphNode(ctx, store.GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "slice", Status: "todo", ParentID: &planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to create slice: %v", err)
+	}
+	feature, err := service.store.CreateTask(ctx, store.TaskCreateArgs{Level: "feature", Title: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	caseTask, err := service.store.CreateTask(ctx, store.TaskCreateArgs{Level: "case", Title: "case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create case: %v", err)
+	}
+	if _, err := service.store.LinkGraphNodeTask(ctx, slice.ID, caseTask.ID); err != nil {
+		t.Fatalf("failed to link slice: %v", err)
+	}
+
+	expectStatuses := func(step string, sliceStatus string, planStatus string) {
+		t.Helper()
+		for nodeID, expected := range map[int64]string{slice.ID: sliceStatus, planNode.ID: planStatus} {
+			node, err := service.store.GetGraphNodeByID(ctx, nodeID)
+			if err != nil || node.Status != expected {
+				t.Fatalf("after %s expected node %d %s, got %+v (%v)", step, nodeID, expected, node, err)
+			}
+		}
+	}
+	for _, call := range []struct {
+		method      string
+		params      string
+		sliceStatus string
+		planStatus  string
+	}{
+		{"case.begin", fmt.Sprintf(`{"case_id":%d}`, caseTask.ID), "in_progress", "in_progress"},
+		{"task.block", fmt.Sprintf(`{"task_id":%d,"reason":"waiting on schema"}`, caseTask.ID), "blocked", "blocked"},
+		{"task.unblock", fmt.Sprintf(`{"task_id":%d}`, caseTask.ID), "in_progress", "in_progress"},
+	} {
+		if _, err := service.Handle(ctx, call.method, json.RawMessage(call.params)); err != nil {
+			t.Fatalf("%s failed: %v", call.method, err)
+		}
+		expectStatuses(call.method, call.sliceStatus, call.planStatus)
+	}
+}
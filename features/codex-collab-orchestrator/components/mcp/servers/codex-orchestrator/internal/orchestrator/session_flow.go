@@ -137,6 +137,7 @@ func (service *Service) spawnWorktree(ctx context.Context, input worktreeSpawnIn
 		worktreePath = filepath.Join(service.repoPath, ".codex-orch", "worktrees", slug)
 	}
 
+	baseCommit := ""
 	if createOnDisk {
 		candidateResolved := false
 		for attempt := 0; attempt < 64; attempt++ {
@@ -152,12 +153,14 @@ func (service *Service) spawnWorktree(ctx context.Context, input worktreeSpawnIn
 			if service.worktreeCandidateTaken(candidatePath, candidateBranch) {
 				continue
 			}
-			if err := service.runGitWorktreeAdd(candidatePath, candidateBranch, baseRef); err != nil {
+			candidateBase, err := service.runGitWorktreeAdd(candidatePath, candidateBranch, baseRef)
+			if err != nil {
 				if isLikelyWorktreeConflictError(err) && strings.TrimSpace(input.Branch) == "" && strings.TrimSpace(input.Path) == "" {
 					continue
 				}
 				return store.Worktree{}, err
 			}
+			baseCommit = candidateBase
 			branch = candidateBranch
 			worktreePath = candidatePath
 			candidateResolved = true
@@ -182,6 +185,7 @@ func (service *Service) spawnWorktree(ctx context.Context, input worktreeSpawnIn
 		ParentWorktree: &input.ParentWorktreeID,
 		OwnerSessionID: &input.SessionID,
 		MergeState:     "active",
+		BaseRef:        baseCommit,
 	})
 }
 
@@ -194,7 +198,8 @@ func (service *Service) createSessionRootWorktree(ctx context.Context, sessionID
 		if service.worktreeCandidateTaken(candidatePath, candidateBranch) {
 			continue
 		}
-		if err := service.runGitWorktreeAdd(candidatePath, candidateBranch, mainWorktree.Branch); err != nil {
+		baseCommit, err := service.runGitWorktreeAdd(candidatePath, candidateBranch, mainWorktree.Branch)
+		if err != nil {
 			if isLikelyWorktreeConflictError(err) {
 				continue
 			}
@@ -210,6 +215,7 @@ func (service *Service) createSessionRootWorktree(ctx context.Context, sessionID
 			ParentWorktree: &mainWorktree.ID,
 			OwnerSessionID: &sessionID,
 			MergeState:     "active",
+			BaseRef:        baseCommit,
 		})
 		if err != nil {
 			return store.Worktree{}, "", err
@@ -357,6 +363,10 @@ func pointerToBool(value bool) *bool {
 	return &value
 }
 
+func pointerToInt64(value int64) *int64 {
+	return &value
+}
+
 func marshalStringSlice(values []string) string {
 	if len(values) == 0 {
 		return "[]"
@@ -0,0 +1,707 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const (
+	defaultSupervisorIntervalSeconds = 15
+	supervisorRestartRole            = "worker"
+	providerErrorReason              = "provider reported an error"
+)
+
+// threadSupervisor owns the background sweep loop. Sweeps are serialized so a
+// manual thread.supervisor.sweep never races the ticker.
+type threadSupervisor struct {
+	mu        sync.Mutex
+	sweepMu   sync.Mutex
+	cancel    context.CancelFunc
+	done      chan struct{}
+	lastSweep *supervisorSweep
+	// answered holds, per thread, the capture the last auto-answer was given
+	// for, so a prompt still on screen is not answered twice.
+	answered map[int64]string
+}
+
+type supervisorTransition struct {
+	ThreadID   int64  `json:"thread_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Reason     string `json:"reason"`
+}
+
+type supervisorRestart struct {
+	ThreadID  int64  `json:"thread_id"`
+	Attempt   int    `json:"attempt"`
+	PaneID    string `json:"pane_id,omitempty"`
+	ProcessID int64  `json:"process_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type supervisorSweep struct {
+	SweptAt     string                 `json:"swept_at"`
+	Checked     int                    `json:"checked"`
+	Transitions []supervisorTransition `json:"transitions"`
+	Restarts    []supervisorRestart    `json:"restarts"`
+	AutoAnswers []threadAutoAnswer     `json:"auto_answers,omitempty"`
+	RotatedLogs []logRotation          `json:"rotated_logs,omitempty"`
+	Errors      []string               `json:"errors,omitempty"`
+}
+
+// StartSupervisor launches the background loop that reconciles child thread
+// statuses with their panes. It re-reads the policy every tick, so
+// thread.supervisor.update takes effect without a restart. Calling it twice is
+// a no-op; Close stops the loop.
+func (service *Service) StartSupervisor(ctx context.Context) {
+	service.supervisor.mu.Lock()
+	defer service.supervisor.mu.Unlock()
+	if service.supervisor.cancel != nil {
+		return
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	service.supervisor.cancel = cancel
+	service.supervisor.done = done
+
+	go func() {
+		defer close(done)
+		for {
+			interval := time.Duration(defaultSupervisorIntervalSeconds) * time.Second
+			policy, err := service.store.GetSupervisorPolicy(loopCtx)
+			if err == nil {
+				interval = time.Duration(policy.IntervalSeconds) * time.Second
+				if policy.Enabled {
+					_, _ = service.superviseThreads(loopCtx)
+				}
+			}
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+func (service *Service) stopSupervisor() {
+	service.supervisor.mu.Lock()
+	cancel := service.supervisor.cancel
+	done := service.supervisor.done
+	service.supervisor.cancel = nil
+	service.supervisor.done = nil
+	service.supervisor.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+func (service *Service) supervisorStatus(ctx context.Context) (map[string]any, error) {
+	policy, err := service.store.GetSupervisorPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	service.supervisor.mu.Lock()
+	running := service.supervisor.cancel != nil
+	lastSweep := service.supervisor.lastSweep
+	service.supervisor.mu.Unlock()
+	return map[string]any{
+		"policy":     policy,
+		"running":    running,
+		"last_sweep": lastSweep,
+	}, nil
+}
+
+func (service *Service) updateSupervisorPolicy(ctx context.Context, input threadSupervisorUpdateInput) (store.SupervisorPolicy, error) {
+	return service.store.UpdateSupervisorPolicy(ctx, store.SupervisorPolicyUpdateArgs{
+		Enabled:               input.Enabled,
+		IntervalSeconds:       input.IntervalSeconds,
+		StallTimeoutSeconds:   input.StallTimeoutSeconds,
+		MaxRestarts:           input.MaxRestarts,
+		RestartBackoffSeconds: input.RestartBackoffSeconds,
+		MaxBackoffSeconds:     input.MaxBackoffSeconds,
+	})
+}
+
+// superviseThreads runs one reconciliation pass over every live child thread:
+// agents that exited after finishing become completed, other lost panes become
+// crashed, provider-reported errors are posted to the parent,
+// silent processing past the stall timeout becomes stalled, and crashed
+// workers are relaunched from their last checkpoint once their backoff elapses.
+func (service *Service) superviseThreads(ctx context.Context) (supervisorSweep, error) {
+	service.supervisor.sweepMu.Lock()
+	defer service.supervisor.sweepMu.Unlock()
+
+	policy, err := service.store.GetSupervisorPolicy(ctx)
+	if err != nil {
+		return supervisorSweep{}, err
+	}
+	threads, err := service.store.ListThreads(ctx, store.ThreadFilter{})
+	if err != nil {
+		return supervisorSweep{}, err
+	}
+
+	now := time.Now().UTC()
+	sweep := supervisorSweep{
+		SweptAt:     now.Format(time.RFC3339Nano),
+		Transitions: make([]supervisorTransition, 0),
+		Restarts:    make([]supervisorRestart, 0),
+	}
+	logPolicy, err := service.loadLogRetentionPolicy()
+	if err != nil {
+		sweep.Errors = append(sweep.Errors, err.Error())
+	}
+	for _, thread := range threads {
+		if thread.ParentThreadID == nil {
+			continue
+		}
+		switch thread.Status {
+		case "running", "initializing", "stalled":
+			sweep.Checked++
+			transition, autoAnswer, err := service.superviseLiveThread(ctx, thread, policy, now)
+			if err != nil {
+				sweep.Errors = append(sweep.Errors, fmt.Sprintf("thread %d: %v", thread.ID, err))
+			}
+			if transition != nil {
+				sweep.Transitions = append(sweep.Transitions, *transition)
+			}
+			if autoAnswer != nil {
+				sweep.AutoAnswers = append(sweep.AutoAnswers, *autoAnswer)
+			}
+			rotation, err := service.rotateThreadLog(thread, logPolicy, now)
+			if err != nil {
+				sweep.Errors = append(sweep.Errors, fmt.Sprintf("thread %d: log rotation: %v", thread.ID, err))
+			}
+			if rotation != nil {
+				sweep.RotatedLogs = append(sweep.RotatedLogs, *rotation)
+			}
+		case "crashed":
+			sweep.Checked++
+			if thread.Role != supervisorRestartRole {
+				continue
+			}
+			if thread.RestartCount >= policy.MaxRestarts {
+				failedStatus := "failed"
+				reason := fmt.Sprintf("restart budget exhausted after %d restarts", thread.RestartCount)
+				if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{Status: &failedStatus, StatusReason: &reason}); err != nil {
+					sweep.Errors = append(sweep.Errors, fmt.Sprintf("thread %d: %v", thread.ID, err))
+					continue
+				}
+				service.notifyParentThread(ctx, thread, fmt.Sprintf("[supervisor] thread %d failed: %s", thread.ID, reason))
+				sweep.Transitions = append(sweep.Transitions, supervisorTransition{ThreadID: thread.ID, FromStatus: thread.Status, ToStatus: failedStatus, Reason: reason})
+				continue
+			}
+			if now.Before(restartDueAt(thread, policy)) {
+				continue
+			}
+			restart, claimed, err := service.restartCrashedThread(ctx, thread, policy)
+			if err != nil {
+				sweep.Errors = append(sweep.Errors, fmt.Sprintf("thread %d: %v", thread.ID, err))
+				continue
+			}
+			if claimed {
+				sweep.Restarts = append(sweep.Restarts, restart)
+			}
+		case "restarting":
+			sweep.Checked++
+			transition, err := service.reconcileRestartingThread(ctx, thread, policy, now)
+			if err != nil {
+				sweep.Errors = append(sweep.Errors, fmt.Sprintf("thread %d: %v", thread.ID, err))
+			}
+			if transition != nil {
+				sweep.Transitions = append(sweep.Transitions, *transition)
+			}
+		}
+	}
+
+	service.supervisor.mu.Lock()
+	service.supervisor.lastSweep = &sweep
+	service.supervisor.mu.Unlock()
+	return sweep, nil
+}
+
+func (service *Service) superviseLiveThread(ctx context.Context, thread store.Thread, policy store.SupervisorPolicy, now time.Time) (*supervisorTransition, *threadAutoAnswer, error) {
+	runner, runnerErr := service.runnerFor(thread)
+	paneExists := runnerErr == nil && runner.Alive(ctx, thread)
+
+	lastActivity := threadLastActivity(thread)
+	providerStatus := provider.Status("")
+	var autoAnswer *threadAutoAnswer
+	if paneExists {
+		if logActivity, ok := logFileModTime(thread); ok && logActivity.After(lastActivity) {
+			lastActivity = logActivity
+			heartbeatAt := logActivity.UTC().Format(time.RFC3339Nano)
+			if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{HeartbeatAt: &heartbeatAt}); err != nil {
+				return nil, nil, err
+			}
+		}
+		providerStatus = service.observeProviderStatus(ctx, thread, runner)
+		_, _ = service.recordThreadUsage(ctx, thread)
+		if providerStatus == provider.StatusWaitingUserAnswer {
+			answered, err := service.autoAnswerThread(ctx, thread, runner)
+			if err != nil {
+				return nil, nil, err
+			}
+			if answered != nil {
+				autoAnswer = answered
+				providerStatus = provider.StatusProcessing
+				lastActivity = now
+			}
+		}
+	}
+
+	if providerStatus == provider.StatusError {
+		if err := service.reportProviderError(ctx, thread); err != nil {
+			return nil, autoAnswer, err
+		}
+	}
+
+	exitedCleanly := !paneExists && service.threadExitedCleanly(thread)
+	nextStatus, reason := classifyThreadHealth(thread.Status, paneExists, exitedCleanly, providerStatus, now.Sub(lastActivity), policy)
+	if nextStatus == thread.Status {
+		return nil, autoAnswer, nil
+	}
+
+	gone := nextStatus == "crashed" || nextStatus == "completed"
+	updateArgs := store.ThreadUpdateArgs{Status: &nextStatus, StatusReason: &reason}
+	if gone {
+		if runnerErr == nil {
+			_ = runner.Stop(ctx, thread, true)
+		}
+		service.provider.Remove(thread.ID)
+		updateArgs.TmuxPaneID = pointerToString("")
+		updateArgs.ProcessID = pointerToInt64(0)
+	}
+	if _, err := service.store.UpdateThread(ctx, thread.ID, updateArgs); err != nil {
+		return nil, autoAnswer, err
+	}
+	var releaseErr error
+	if gone {
+		_, releaseErr = service.releaseThreadLocks(ctx, thread.ID, "thread_"+nextStatus)
+	}
+	if nextStatus != "running" {
+		service.notifyParentThread(ctx, thread, fmt.Sprintf("[supervisor] thread %d is %s: %s", thread.ID, nextStatus, reason))
+	}
+	return &supervisorTransition{ThreadID: thread.ID, FromStatus: thread.Status, ToStatus: nextStatus, Reason: reason}, autoAnswer, releaseErr
+}
+
+// reportProviderError tells the parent that the provider flagged an error on
+// the child's screen. The pane is left running for the parent to inspect; the
+// status reason remembers the report so the same error is posted once.
+func (service *Service) reportProviderError(ctx context.Context, thread store.Thread) error {
+	if valueOrEmpty(thread.StatusReason) == providerErrorReason {
+		return nil
+	}
+	reason := providerErrorReason
+	if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{StatusReason: &reason}); err != nil {
+		return err
+	}
+	service.notifyParentThread(ctx, thread, fmt.Sprintf("[supervisor] thread %d %s; the pane was left running", thread.ID, reason))
+	return nil
+}
+
+// classifyThreadHealth decides the next DB status for a live child thread.
+// It returns the current status unchanged when nothing needs to move. Only a
+// lost pane or process is a crash, and not when the agent exited cleanly:
+// providers fall back to StatusError on screens they do not recognize and
+// report StatusCompleted at a ready prompt, so neither says the agent is gone.
+func classifyThreadHealth(current string, paneExists bool, exitedCleanly bool, providerStatus provider.Status, idle time.Duration, policy store.SupervisorPolicy) (string, string) {
+	if !paneExists {
+		if exitedCleanly {
+			return "completed", "agent exited after finishing its work"
+		}
+		return "crashed", "agent pane or process no longer exists"
+	}
+	switch providerStatus {
+	case provider.StatusProcessing, provider.StatusWaitingUserAnswer:
+		stallTimeout := time.Duration(policy.StallTimeoutSeconds) * time.Second
+		if idle >= stallTimeout {
+			return "stalled", fmt.Sprintf("no output for %s while %s", idle.Truncate(time.Second), providerStatus)
+		}
+		if current == "stalled" {
+			return "running", "output resumed"
+		}
+	case provider.StatusIdle, provider.StatusCompleted:
+		if current != "running" {
+			return "running", "provider is idle and ready"
+		}
+	}
+	return current, ""
+}
+
+// reconcileRestartingThread settles a thread left in restarting by a restart
+// that never recorded its outcome, e.g. because the server died mid-relaunch.
+// A live agent is running; otherwise, once the restart has had a stall
+// timeout to finish, the thread goes back to crashed for the next attempt.
+func (service *Service) reconcileRestartingThread(ctx context.Context, thread store.Thread, policy store.SupervisorPolicy, now time.Time) (*supervisorTransition, error) {
+	nextStatus, reason := "running", "restarted agent is alive"
+	if runner, err := service.runnerFor(thread); err != nil || !runner.Alive(ctx, thread) {
+		claimedAt, err := time.Parse(time.RFC3339Nano, thread.UpdatedAt)
+		if err == nil && now.Sub(claimedAt) < time.Duration(policy.StallTimeoutSeconds)*time.Second {
+			return nil, nil
+		}
+		nextStatus, reason = "crashed", "restart did not finish"
+	}
+	if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{Status: &nextStatus, StatusReason: &reason}); err != nil {
+		return nil, err
+	}
+	return &supervisorTransition{ThreadID: thread.ID, FromStatus: thread.Status, ToStatus: nextStatus, Reason: reason}, nil
+}
+
+// restartBackoff doubles the base delay per previous restart, capped at the
+// policy maximum.
+func restartBackoff(policy store.SupervisorPolicy, restartCount int) time.Duration {
+	backoff := time.Duration(policy.RestartBackoffSeconds) * time.Second
+	maxBackoff := time.Duration(policy.MaxBackoffSeconds) * time.Second
+	for attempt := 0; attempt < restartCount && backoff < maxBackoff; attempt++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func restartDueAt(thread store.Thread, policy store.SupervisorPolicy) time.Time {
+	crashedAt, err := time.Parse(time.RFC3339Nano, thread.UpdatedAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return crashedAt.Add(restartBackoff(policy, thread.RestartCount))
+}
+
+// restartCrashedThread relaunches a crashed worker. It first claims the
+// thread (crashed -> restarting) and returns claimed=false when another
+// server process got there first. A session over its budget fails the thread
+// instead; a failed relaunch puts it back to crashed so the next sweep retries
+// after the backoff. When the outcome cannot be recorded the relaunched agent
+// is stopped and the thread stays restarting for the sweep to reconcile.
+func (service *Service) restartCrashedThread(ctx context.Context, thread store.Thread, policy store.SupervisorPolicy) (supervisorRestart, bool, error) {
+	claimed, err := service.store.ClaimCrashedThreadForRestart(ctx, thread.ID)
+	if err != nil || !claimed {
+		return supervisorRestart{}, false, err
+	}
+
+	attempt := thread.RestartCount + 1
+	restart := supervisorRestart{ThreadID: thread.ID, Attempt: attempt}
+	restartedAt := time.Now().UTC().Format(time.RFC3339Nano)
+
+	if err := service.checkSessionBudget(ctx, thread.SessionID); err != nil {
+		restart.Error = err.Error()
+		failedStatus := "failed"
+		reason := "restart refused: " + err.Error()
+		if _, updateErr := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{Status: &failedStatus, StatusReason: &reason}); updateErr != nil {
+			return supervisorRestart{}, true, updateErr
+		}
+		service.notifyParentThread(ctx, thread, fmt.Sprintf("[supervisor] thread %d failed: %s", thread.ID, reason))
+		return restart, true, nil
+	}
+
+	relaunchThread := service.relaunchThreadPane
+	if threadRunnerBackend(thread) == runnerBackendHeadless {
+		relaunchThread = service.relaunchHeadlessThread
+	}
+	relaunched, err := relaunchThread(ctx, thread, attempt, policy.MaxRestarts)
+	if err != nil {
+		restart.Error = err.Error()
+		crashedStatus := "crashed"
+		reason := "restart failed: " + err.Error()
+		if _, updateErr := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{
+			Status:        &crashedStatus,
+			RestartCount:  &attempt,
+			LastRestartAt: &restartedAt,
+			StatusReason:  &reason,
+		}); updateErr != nil {
+			return restart, true, updateErr
+		}
+		return restart, true, nil
+	}
+
+	runningStatus := "running"
+	reason := fmt.Sprintf("restarted after crash (%d/%d)", attempt, policy.MaxRestarts)
+	updateArgs := store.ThreadUpdateArgs{
+		Status:        &runningStatus,
+		StatusReason:  &reason,
+		LaunchCommand: &relaunched.launchCommand,
+		LogFilePath:   &relaunched.logFilePath,
+		RestartCount:  &attempt,
+		LastRestartAt: &restartedAt,
+		HeartbeatAt:   &restartedAt,
+	}
+	location := "pane " + relaunched.paneID
+	if relaunched.processID > 0 {
+		updateArgs.ProcessID = &relaunched.processID
+		location = fmt.Sprintf("process %d", relaunched.processID)
+	} else {
+		updateArgs.TmuxPaneID = &relaunched.paneID
+	}
+	if _, err := service.store.UpdateThread(ctx, thread.ID, updateArgs); err != nil {
+		restart.Error = err.Error()
+		service.stopRelaunchedThread(ctx, thread.ID, relaunched)
+		return restart, true, err
+	}
+	restart.PaneID = relaunched.paneID
+	restart.ProcessID = relaunched.processID
+	service.notifyParentThread(ctx, thread, fmt.Sprintf("[supervisor] thread %d %s in %s", thread.ID, reason, location))
+	return restart, true, nil
+}
+
+// relaunchedThread is where a restarted agent now runs: a tmux pane or a
+// headless process.
+type relaunchedThread struct {
+	paneID        string
+	processID     int64
+	launchCommand string
+	logFilePath   string
+}
+
+// stopRelaunchedThread undoes a relaunch whose outcome could not be stored.
+func (service *Service) stopRelaunchedThread(ctx context.Context, threadID int64, relaunched relaunchedThread) {
+	service.provider.Remove(threadID)
+	if relaunched.processID > 0 {
+		_ = service.headless.Stop(threadID, headlessStopGrace)
+		return
+	}
+	_ = service.tmux.StopPipePane(ctx, relaunched.paneID)
+	_ = service.tmux.KillPane(ctx, relaunched.paneID)
+}
+
+func (service *Service) relaunchThreadPane(ctx context.Context, thread store.Thread, attempt int, maxRestarts int) (relaunchedThread, error) {
+	session, err := service.store.GetSessionByID(ctx, thread.SessionID)
+	if err != nil {
+		return relaunchedThread{}, err
+	}
+	workdir, err := service.resolveThreadWorkdir(ctx, session, thread.WorktreeID)
+	if err != nil {
+		return relaunchedThread{}, err
+	}
+	sessionName := strings.TrimSpace(valueOrEmpty(thread.TmuxSessionName))
+	if sessionName == "" {
+		sessionName = strings.TrimSpace(valueOrEmpty(session.TmuxSessionName))
+	}
+	if sessionName == "" {
+		return relaunchedThread{}, errors.New("thread has no tmux session to restart in")
+	}
+	windowName := normalizeWindowName(valueOrEmpty(thread.TmuxWindowName), defaultChildWindowName)
+	if _, _, err := service.ensureTmuxSession(ctx, sessionName, workdir, windowName); err != nil {
+		return relaunchedThread{}, err
+	}
+	paneID, err := service.createTmuxPane(ctx, fmt.Sprintf("%s:0", sessionName), workdir, "")
+	if err != nil {
+		return relaunchedThread{}, err
+	}
+
+	providerType := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+	if providerType == "" {
+		providerType = "codex"
+	}
+	if _, err := service.provider.Create(thread.ID, providerType); err != nil {
+		_ = service.tmux.KillPane(ctx, paneID)
+		return relaunchedThread{}, err
+	}
+
+	logFilePath := service.threadLogFilePath(thread)
+	if err := appendRestartAttemptMarker(logFilePath, attempt); err != nil {
+		service.provider.Remove(thread.ID)
+		_ = service.tmux.KillPane(ctx, paneID)
+		return relaunchedThread{}, err
+	}
+	if err := service.tmux.StartPipePane(ctx, paneID, logFilePath); err != nil {
+		service.provider.Remove(thread.ID)
+		_ = service.tmux.KillPane(ctx, paneID)
+		return relaunchedThread{}, fmt.Errorf("failed to start pipe-pane: %w", err)
+	}
+
+	prompt := service.restartPrompt(ctx, thread, attempt, maxRestarts)
+	launchCommand := service.agentLaunchCommand(workdir, thread.SessionID, thread, prompt)
+	if err := service.tmux.SendKeys(ctx, paneID, launchCommand); err != nil {
+		_ = service.tmux.StopPipePane(ctx, paneID)
+		service.provider.Remove(thread.ID)
+		_ = service.tmux.KillPane(ctx, paneID)
+		return relaunchedThread{}, fmt.Errorf("send launch command: %w", err)
+	}
+	return relaunchedThread{paneID: paneID, launchCommand: launchCommand, logFilePath: logFilePath}, nil
+}
+
+func (service *Service) relaunchHeadlessThread(ctx context.Context, thread store.Thread, attempt int, maxRestarts int) (relaunchedThread, error) {
+	session, err := service.store.GetSessionByID(ctx, thread.SessionID)
+	if err != nil {
+		return relaunchedThread{}, err
+	}
+	workdir, err := service.resolveThreadWorkdir(ctx, session, thread.WorktreeID)
+	if err != nil {
+		return relaunchedThread{}, err
+	}
+	providerType := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+	if providerType == "" {
+		providerType = "codex"
+	}
+	if _, err := service.provider.Create(thread.ID, providerType); err != nil {
+		return relaunchedThread{}, err
+	}
+
+	logFilePath := service.threadLogFilePath(thread)
+	if err := appendRestartAttemptMarker(logFilePath, attempt); err != nil {
+		service.provider.Remove(thread.ID)
+		return relaunchedThread{}, err
+	}
+	prompt := service.restartPrompt(ctx, thread, attempt, maxRestarts)
+	launchCommand := service.agentLaunchCommand(workdir, thread.SessionID, thread, prompt)
+	processInfo, err := service.headless.Start(headless.StartOptions{
+		ThreadID:    thread.ID,
+		Workdir:     workdir,
+		Command:     launchCommand,
+		LogFilePath: logFilePath,
+	})
+	if err != nil {
+		service.provider.Remove(thread.ID)
+		return relaunchedThread{}, err
+	}
+	return relaunchedThread{processID: int64(processInfo.PID), launchCommand: launchCommand, logFilePath: logFilePath}, nil
+}
+
+// restartPrompt rebuilds the worker's brief from its objective plus the latest
+// checkpoint of every scoped case, so the relaunched agent resumes instead of
+// starting over.
+func (service *Service) restartPrompt(ctx context.Context, thread store.Thread, attempt int, maxRestarts int) string {
+	lines := []string{
+		fmt.Sprintf("[codex-orchestrator] Thread %d was restarted by the supervisor after a crash (restart %d/%d).", thread.ID, attempt, maxRestarts),
+	}
+	if title := strings.TrimSpace(valueOrEmpty(thread.Title)); title != "" {
+		lines = append(lines, "Title: "+title)
+	}
+	if objective := strings.TrimSpace(valueOrEmpty(thread.Objective)); objective != "" {
+		lines = append(lines, "Objective: "+objective)
+	}
+
+	checkpointLines := make([]string, 0)
+	for _, caseID := range decodeInt64JSON(valueOrEmpty(thread.ScopeCaseIDsJSON)) {
+		checkpoint, err := service.store.GetLatestCheckpoint(ctx, caseID)
+		if err != nil || checkpoint == nil {
+			continue
+		}
+		checkpointLines = append(checkpointLines, fmt.Sprintf("- case %d, after step %q: %s", caseID, checkpoint.StepTitle, checkpoint.Snapshot))
+	}
+	if len(checkpointLines) > 0 {
+		lines = append(lines, "Last checkpoints:")
+		lines = append(lines, checkpointLines...)
+		lines = append(lines, "Resume from these checkpoints; do not redo steps that are already checked.")
+	} else {
+		lines = append(lines, "No checkpoint was recorded; call orch_task case.begin / step.check to rebuild state before editing.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// threadExitedCleanly reports whether an agent whose pane or process is gone
+// finished on its own: a headless process that exited with status 0, or a pane
+// whose last logged screen was the provider's ready or completed prompt. The
+// log also decides for a headless process started before a server restart,
+// whose exit status was lost.
+func (service *Service) threadExitedCleanly(thread store.Thread) bool {
+	if threadRunnerBackend(thread) == runnerBackendHeadless {
+		if info, ok := service.headless.Info(thread.ID); ok {
+			return !info.Running && info.ExitError == ""
+		}
+	}
+	p, ok := service.threadProvider(thread)
+	if !ok {
+		return false
+	}
+	switch loggedProviderStatus(thread, p) {
+	case provider.StatusIdle, provider.StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// threadProvider returns the thread's status parser, creating it from the
+// stored provider type after a server restart.
+func (service *Service) threadProvider(thread store.Thread) (provider.Provider, bool) {
+	if p, ok := service.provider.Get(thread.ID); ok {
+		return p, true
+	}
+	providerType := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+	if providerType == "" {
+		return nil, false
+	}
+	p, err := service.provider.Create(thread.ID, providerType)
+	return p, err == nil
+}
+
+// loggedProviderStatus classifies the tail of the thread log, which outlives
+// the pane; it is empty when nothing was logged.
+func loggedProviderStatus(thread store.Thread, p provider.Provider) provider.Status {
+	logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+	if logPath == "" {
+		return ""
+	}
+	logTail, err := readFileTail(logPath, 4096)
+	if err != nil || logTail == "" {
+		return ""
+	}
+	return p.GetStatus(logTail)
+}
+
+func (service *Service) observeProviderStatus(ctx context.Context, thread store.Thread, runner threadRunner) provider.Status {
+	p, ok := service.threadProvider(thread)
+	if !ok {
+		return ""
+	}
+	if status := loggedProviderStatus(thread, p); status != "" {
+		return status
+	}
+	captured, err := runner.Capture(ctx, thread, 200)
+	if err != nil || captured == "" {
+		return ""
+	}
+	return p.GetStatus(captured)
+}
+
+func (service *Service) notifyParentThread(ctx context.Context, thread store.Thread, message string) {
+	if thread.ParentThreadID == nil {
+		return
+	}
+	_, _ = service.store.CreateInboxMessage(ctx, store.InboxMessageCreateArgs{
+		SenderThreadID:   thread.ID,
+		ReceiverThreadID: *thread.ParentThreadID,
+		Message:          message,
+	})
+}
+
+func threadLastActivity(thread store.Thread) time.Time {
+	latest := time.Time{}
+	for _, candidate := range []*string{thread.HeartbeatAt, thread.LastRestartAt, thread.StartedAt, &thread.CreatedAt} {
+		if candidate == nil {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, *candidate)
+		if err == nil && parsed.After(latest) {
+			latest = parsed
+		}
+	}
+	return latest
+}
+
+func logFileModTime(thread store.Thread) (time.Time, bool) {
+	logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+	if logPath == "" {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(logPath)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
@@ -0,0 +1,288 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestClassifyThreadHealth(t *testing.T) {
+	policy := store.SupervisorPolicy{StallTimeoutSeconds: 60}
+	testCases := []struct {
+		name           string
+		current        string
+		paneExists     bool
+		exitedCleanly  bool
+		providerStatus provider.Status
+		idle           time.Duration
+		expected       string
+	}{
+		{"pane lost", "running", false, false, "", 0, "crashed"},
+		{"clean exit", "running", false, true, "", 0, "completed"},
+		{"provider error", "running", true, false, provider.StatusError, 0, "running"},
+		{"provider completed", "running", true, false, provider.StatusCompleted, 0, "running"},
+		{"stalled completed", "stalled", true, false, provider.StatusCompleted, time.Hour, "running"},
+		{"silent processing", "running", true, false, provider.StatusProcessing, 2 * time.Minute, "stalled"},
+		{"active processing", "running", true, false, provider.StatusProcessing, 10 * time.Second, "running"},
+		{"stall recovered", "stalled", true, false, provider.StatusProcessing, time.Second, "running"},
+		{"initializing ready", "initializing", true, false, provider.StatusIdle, 0, "running"},
+		{"unknown status", "initializing", true, false, "", time.Hour, "initializing"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			next, _ := classifyThreadHealth(testCase.current, testCase.paneExists, testCase.exitedCleanly, testCase.providerStatus, testCase.idle, policy)
+			if next != testCase.expected {
+				t.Fatalf("expected %s, got %s", testCase.expected, next)
+			}
+		})
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	policy := store.SupervisorPolicy{RestartBackoffSeconds: 30, MaxBackoffSeconds: 100}
+	for restartCount, expected := range []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second, 100 * time.Second} {
+		if backoff := restartBackoff(policy, restartCount); backoff != expected {
+			t.Fatalf("restart %d: expected %s, got %s", restartCount, expected, backoff)
+		}
+	}
+}
+
+func TestSuperviseThreadsMarksLostPanesAndExhaustsRestarts(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create worker thread: %v", err)
+	}
+	missingPane := "%999999"
+	if _, err := service.store.UpdateThread(ctx, worker.ID, store.ThreadUpdateArgs{TmuxPaneID: &missingPane}); err != nil {
+		t.Fatalf("failed to bind pane: %v", err)
+	}
+
+	sweep, err := service.superviseThreads(ctx)
+	if err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	if len(sweep.Transitions) != 1 || sweep.Transitions[0].ToStatus != "crashed" || len(sweep.Restarts) != 0 {
+		t.Fatalf("expected worker marked crashed without an immediate restart, got %+v", sweep)
+	}
+	crashed, err := service.store.GetThreadByID(ctx, worker.ID)
+	if err != nil {
+		t.Fatalf("failed to reload worker: %v", err)
+	}
+	if crashed.Status != "crashed" || crashed.StatusReason == nil || crashed.TmuxPaneID != nil {
+		t.Fatalf("unexpected crashed worker: %+v", crashed)
+	}
+	messages, err := service.store.ListInboxMessages(ctx, root.ID)
+	if err != nil || len(messages) != 1 || !strings.Contains(messages[0].Message, "crashed") {
+		t.Fatalf("expected crash notice in parent inbox, got %+v (%v)", messages, err)
+	}
+
+	// A provider error is posted to the parent once and the worker keeps running.
+	if err := service.reportProviderError(ctx, worker); err != nil {
+		t.Fatalf("failed to report provider error: %v", err)
+	}
+	reported, err := service.store.GetThreadByID(ctx, worker.ID)
+	if err != nil {
+		t.Fatalf("failed to reload worker: %v", err)
+	}
+	if err := service.reportProviderError(ctx, reported); err != nil {
+		t.Fatalf("failed to report provider error: %v", err)
+	}
+	if messages, err = service.store.ListInboxMessages(ctx, root.ID); err != nil || len(messages) != 2 || !strings.Contains(messages[1].Message, providerErrorReason) {
+		t.Fatalf("expected one provider error notice, got %+v (%v)", messages, err)
+	}
+
+	// Within the backoff window the supervisor leaves the crashed worker alone.
+	if sweep, err = service.superviseThreads(ctx); err != nil || len(sweep.Transitions) != 0 || len(sweep.Restarts) != 0 {
+		t.Fatalf("expected no action during backoff, got %+v (%v)", sweep, err)
+	}
+
+	if _, err := service.Handle(ctx, "thread.supervisor.update", []byte(`{"max_restarts":0}`)); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	sweep, err = service.superviseThreads(ctx)
+	if err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	if len(sweep.Transitions) != 1 || sweep.Transitions[0].ToStatus != "failed" {
+		t.Fatalf("expected exhausted restart budget to fail the worker, got %+v", sweep)
+	}
+
+	status, err := service.supervisorStatus(ctx)
+	if err != nil {
+		t.Fatalf("failed to get supervisor status: %v", err)
+	}
+	if status["running"] != false || status["last_sweep"].(*supervisorSweep).SweptAt != sweep.SweptAt {
+		t.Fatalf("unexpected supervisor status: %+v", status)
+	}
+}
+
+func TestSuperviseThreadsCompletesCleanExits(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	newWorker := func() store.Thread {
+		worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "running", ProviderType: "codex"})
+		if err != nil {
+			t.Fatalf("failed to create worker thread: %v", err)
+		}
+		logPath := service.threadLogFilePath(worker)
+		if worker, err = service.store.UpdateThread(ctx, worker.ID, store.ThreadUpdateArgs{LogFilePath: &logPath}); err != nil {
+			t.Fatalf("failed to bind log: %v", err)
+		}
+		return worker
+	}
+	startHeadless := func(worker store.Thread, command string) {
+		backend := runnerBackendHeadless
+		if _, err := service.store.UpdateThread(ctx, worker.ID, store.ThreadUpdateArgs{RunnerBackend: &backend}); err != nil {
+			t.Fatalf("failed to set backend: %v", err)
+		}
+		if _, err := service.headless.Start(headless.StartOptions{ThreadID: worker.ID, Command: command, LogFilePath: valueOrEmpty(worker.LogFilePath)}); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for service.headless.Alive(worker.ID) {
+			if time.Now().After(deadline) {
+				t.Fatalf("process for thread %d did not exit", worker.ID)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	finished := newWorker()
+	startHeadless(finished, "exit 0")
+	failed := newWorker()
+	startHeadless(failed, "exit 3")
+	// The pane is gone but its log ends at the ready prompt.
+	closed := newWorker()
+	missingPane := "%999999"
+	if _, err := service.store.UpdateThread(ctx, closed.ID, store.ThreadUpdateArgs{TmuxPaneID: &missingPane}); err != nil {
+		t.Fatalf("failed to bind pane: %v", err)
+	}
+	if err := os.WriteFile(valueOrEmpty(closed.LogFilePath), []byte("codex: all tests pass\n› "), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+
+	if _, err := service.superviseThreads(ctx); err != nil {
+		t.Fatalf("failed to sweep: %v", err)
+	}
+	for _, expected := range []struct {
+		thread store.Thread
+		status string
+	}{{finished, "completed"}, {failed, "crashed"}, {closed, "completed"}} {
+		reloaded, err := service.store.GetThreadByID(ctx, expected.thread.ID)
+		if err != nil || reloaded.Status != expected.status {
+			t.Fatalf("expected thread %d to be %s, got %+v (%v)", expected.thread.ID, expected.status, reloaded, err)
+		}
+	}
+
+	// Completed workers leave the sweep; only the crashed one waits for a restart.
+	sweep, err := service.superviseThreads(ctx)
+	if err != nil || sweep.Checked != 1 || len(sweep.Transitions) != 0 || len(sweep.Restarts) != 0 {
+		t.Fatalf("expected completed workers to be left alone, got %+v (%v)", sweep, err)
+	}
+}
+
+func TestSuperviseThreadsReconcilesStuckRestarts(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	newRestarting := func() store.Thread {
+		worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "restarting"})
+		if err != nil {
+			t.Fatalf("failed to create worker thread: %v", err)
+		}
+		return worker
+	}
+	policy, err := service.store.GetSupervisorPolicy(ctx)
+	if err != nil {
+		t.Fatalf("failed to get supervisor policy: %v", err)
+	}
+
+	// A restart still within the stall timeout may be another server's.
+	abandoned := newRestarting()
+	sweep, err := service.superviseThreads(ctx)
+	if err != nil || sweep.Checked != 1 || len(sweep.Transitions) != 0 {
+		t.Fatalf("expected a fresh restart to be left alone, got %+v (%v)", sweep, err)
+	}
+	transition, err := service.reconcileRestartingThread(ctx, abandoned, policy, time.Now().Add(time.Hour))
+	if err != nil || transition == nil || transition.ToStatus != "crashed" {
+		t.Fatalf("expected an abandoned restart to crash, got %+v (%v)", transition, err)
+	}
+
+	relaunched := newRestarting()
+	backend := runnerBackendHeadless
+	logPath := service.threadLogFilePath(relaunched)
+	if _, err := service.store.UpdateThread(ctx, relaunched.ID, store.ThreadUpdateArgs{RunnerBackend: &backend, LogFilePath: &logPath}); err != nil {
+		t.Fatalf("failed to set backend: %v", err)
+	}
+	if _, err := service.headless.Start(headless.StartOptions{ThreadID: relaunched.ID, Command: "exec cat", LogFilePath: logPath}); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	if sweep, err = service.superviseThreads(ctx); err != nil || len(sweep.Transitions) != 1 || sweep.Transitions[0].ToStatus != "running" {
+		t.Fatalf("expected the live relaunch to be running, got %+v (%v)", sweep, err)
+	}
+}
+
+func TestRestartPromptIncludesLastCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	objective := "add retries"
+	scope := "[42]"
+	prompt := service.restartPrompt(ctx, store.Thread{ID: 7, Objective: &objective, ScopeCaseIDsJSON: &scope}, 2, 3)
+	if !strings.Contains(prompt, "restart 2/3") || !strings.Contains(prompt, "Objective: add retries") || !strings.Contains(prompt, "No checkpoint was recorded") {
+		t.Fatalf("unexpected restart prompt: %s", prompt)
+	}
+}
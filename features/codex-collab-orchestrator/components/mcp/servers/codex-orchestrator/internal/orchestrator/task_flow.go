@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func (service *Service) cancelTask(ctx context.Context, input taskCancelInput) (map[string]any, error) {
+	task, affectedTaskIDs, err := service.store.CancelTask(ctx, input.TaskID, input.Reason)
+	if err != nil {
+		return nil, err
+	}
+	return service.closedTaskResult(ctx, task, affectedTaskIDs, "task_cancel"), nil
+}
+
+func (service *Service) deleteTask(ctx context.Context, input taskDeleteInput) (map[string]any, error) {
+	task, affectedTaskIDs, err := service.store.DeleteTask(ctx, input.TaskID, input.Reason, input.Force)
+	if err != nil {
+		return nil, err
+	}
+	return service.closedTaskResult(ctx, task, affectedTaskIDs, "task_delete"), nil
+}
+
+// blockTask and unblockTask carry the task's status over to the graph nodes
+// linked to it, as case.begin and case.complete do.
+func (service *Service) blockTask(ctx context.Context, input taskBlockInput) (store.Task, error) {
+	task, err := service.store.BlockTask(ctx, input.TaskID, input.Reason)
+	if err != nil {
+		return store.Task{}, err
+	}
+	service.syncLinkedGraphNodes(ctx, []int64{task.ID})
+	return task, nil
+}
+
+func (service *Service) unblockTask(ctx context.Context, input taskUnblockInput) (store.Task, error) {
+	task, err := service.store.UnblockTask(ctx, input.TaskID)
+	if err != nil {
+		return store.Task{}, err
+	}
+	service.syncLinkedGraphNodes(ctx, []int64{task.ID})
+	return task, nil
+}
+
+// closedTaskResult releases the locks of cancelled or deleted tasks and rolls
+// their linked graph nodes up. A lock release failure is reported in the
+// result; the tasks stay closed.
+func (service *Service) closedTaskResult(ctx context.Context, task store.Task, affectedTaskIDs []int64, reason string) map[string]any {
+	locksReleased, err := service.releaseTaskLocks(ctx, affectedTaskIDs, reason)
+	result := map[string]any{
+		"task":              task,
+		"affected_task_ids": affectedTaskIDs,
+		"locks_released":    locksReleased,
+		"nodes_updated":     service.syncLinkedGraphNodes(ctx, affectedTaskIDs),
+	}
+	if err != nil {
+		result["locks_release_error"] = err.Error()
+	}
+	return result
+}
+
+func (service *Service) releaseTaskLocks(ctx context.Context, taskIDs []int64, reason string) (int, error) {
+	releasedCount := 0
+	failures := make([]string, 0)
+	for _, taskID := range taskIDs {
+		caseID := taskID
+		released, err := service.store.ReleaseLocksByOwner(ctx, store.LockReleaseByOwnerArgs{
+			OwnerCaseID: &caseID,
+			Reason:      reason,
+		})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("task %d: %v", taskID, err))
+			continue
+		}
+		releasedCount += len(released)
+	}
+	if len(failures) > 0 {
+		return releasedCount, fmt.Errorf("failed to release locks: %s", strings.Join(failures, "; "))
+	}
+	return releasedCount, nil
+}
+
+func (service *Service) syncLinkedGraphNodes(ctx context.Context, taskIDs []int64) []store.GraphNode {
+	updatedNodes := make([]store.GraphNode, 0)
+	for _, taskID := range taskIDs {
+		changedNodes, err := service.store.SyncGraphNodesForTask(ctx, taskID)
+		if err != nil {
+			continue
+		}
+		updatedNodes = append(updatedNodes, changedNodes...)
+	}
+	return updatedNodes
+}
@@ -0,0 +1,198 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+// autoAnswerAnyRole holds rules applied to every role after its own rules.
+const autoAnswerAnyRole = "*"
+
+// autoAnswerPolicyFile is .codex-orch/auto-answer.yaml: per-role rules that
+// let the supervisor answer known-safe prompts without a human. A rule's
+// question must match the whole prompt question, so a pattern written for one
+// command cannot also approve a longer one that merely contains it.
+type autoAnswerPolicyFile struct {
+	Roles map[string][]autoAnswerRule `yaml:"roles"`
+}
+
+type autoAnswerRule struct {
+	Question string `yaml:"question"`
+	Answer   string `yaml:"answer"`
+
+	pattern *regexp.Regexp
+}
+
+// threadAutoAnswer records one prompt the supervisor answered.
+type threadAutoAnswer struct {
+	ThreadID int64                 `json:"thread_id"`
+	Question string                `json:"question"`
+	Option   provider.PromptOption `json:"option"`
+	Rule     string                `json:"rule"`
+}
+
+func (service *Service) autoAnswerPolicyPath() string {
+	return service.orchConfigPath("auto-answer.yaml")
+}
+
+// loadAutoAnswerPolicy compiles the auto-answer rules, rejecting rules with
+// no question or answer. Each sweep calls it, so rule edits apply without a
+// restart; without the file no prompt is answered automatically.
+func (service *Service) loadAutoAnswerPolicy() (autoAnswerPolicyFile, error) {
+	var policy autoAnswerPolicyFile
+	if err := service.loadOrchConfigYAML("auto-answer.yaml", &policy); err != nil {
+		return autoAnswerPolicyFile{}, err
+	}
+	for role, rules := range policy.Roles {
+		for index := range rules {
+			if strings.TrimSpace(rules[index].Answer) == "" {
+				return autoAnswerPolicyFile{}, fmt.Errorf("auto-answer rule %s[%d] has no answer", role, index)
+			}
+			if strings.TrimSpace(rules[index].Question) == "" {
+				return autoAnswerPolicyFile{}, fmt.Errorf("auto-answer rule %s[%d] has no question pattern", role, index)
+			}
+			pattern, err := regexp.Compile(`^(?:` + rules[index].Question + `)$`)
+			if err != nil {
+				return autoAnswerPolicyFile{}, fmt.Errorf("auto-answer rule %s[%d]: invalid question pattern: %w", role, index, err)
+			}
+			rules[index].pattern = pattern
+		}
+	}
+	return policy, nil
+}
+
+// match returns the first rule for the role (then for any role) whose
+// question pattern matches and whose answer is one of the prompt's options.
+func (policy autoAnswerPolicyFile) match(role string, prompt provider.Prompt) (autoAnswerRule, provider.PromptOption, bool) {
+	for _, rules := range [][]autoAnswerRule{policy.Roles[role], policy.Roles[autoAnswerAnyRole]} {
+		for _, rule := range rules {
+			if !rule.pattern.MatchString(prompt.Question) {
+				continue
+			}
+			if option, err := prompt.Option(rule.Answer); err == nil {
+				return rule, option, true
+			}
+		}
+	}
+	return autoAnswerRule{}, provider.PromptOption{}, false
+}
+
+func (service *Service) answerChildThread(ctx context.Context, input threadChildAnswerInput) (map[string]any, error) {
+	if input.ThreadID <= 0 {
+		return nil, errors.New("thread_id is required")
+	}
+	if strings.TrimSpace(input.Option) == "" {
+		return nil, errors.New("option is required")
+	}
+	thread, err := service.store.GetThreadByID(ctx, input.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread.ParentThreadID == nil {
+		return nil, fmt.Errorf("thread is not a child thread: %d", thread.ID)
+	}
+	runner, err := service.runnerFor(thread)
+	if err != nil {
+		return nil, err
+	}
+	captured, err := runner.Capture(ctx, thread, 200)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := service.pendingPrompt(thread, captured)
+	if err != nil {
+		return nil, err
+	}
+	option, err := prompt.Option(input.Option)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.sendPromptAnswer(ctx, thread, runner, prompt, option); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"thread_id": thread.ID,
+		"prompt":    prompt,
+		"answered":  option,
+	}, nil
+}
+
+// pendingPrompt parses the question from a capture, failing unless the
+// thread's provider reports it is waiting for an answer.
+func (service *Service) pendingPrompt(thread store.Thread, captured string) (provider.Prompt, error) {
+	p, ok := service.provider.Get(thread.ID)
+	if !ok {
+		providerType := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+		if providerType == "" {
+			providerType = defaultProviderType
+		}
+		var err error
+		if p, err = service.provider.Create(thread.ID, providerType); err != nil {
+			return provider.Prompt{}, err
+		}
+	}
+	captured = provider.StripEscapes(captured)
+	if status := p.GetStatus(captured); status != provider.StatusWaitingUserAnswer {
+		return provider.Prompt{}, fmt.Errorf("thread %d is not waiting for an answer (provider status %s)", thread.ID, status)
+	}
+	prompt, ok := provider.ParsePrompt(captured)
+	if !ok {
+		return provider.Prompt{}, fmt.Errorf("thread %d is waiting but its prompt could not be parsed", thread.ID)
+	}
+	return prompt, nil
+}
+
+func (service *Service) sendPromptAnswer(ctx context.Context, thread store.Thread, runner threadRunner, prompt provider.Prompt, option provider.PromptOption) error {
+	if err := runner.SendAnswer(ctx, thread, option.Key, prompt.Submit()); err != nil {
+		return err
+	}
+	heartbeatAt := time.Now().UTC().Format(time.RFC3339Nano)
+	_, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{HeartbeatAt: &heartbeatAt})
+	return err
+}
+
+// autoAnswerThread answers the pending prompt when the auto-answer policy has
+// a rule for it and tells the parent thread what was chosen. It returns nil
+// when no rule applies or the same screen was already answered.
+func (service *Service) autoAnswerThread(ctx context.Context, thread store.Thread, runner threadRunner) (*threadAutoAnswer, error) {
+	policy, err := service.loadAutoAnswerPolicy()
+	if err != nil || len(policy.Roles) == 0 {
+		return nil, err
+	}
+	captured, err := runner.Capture(ctx, thread, 200)
+	if err != nil {
+		return nil, err
+	}
+	service.supervisor.mu.Lock()
+	alreadyAnswered := service.supervisor.answered[thread.ID] == captured
+	service.supervisor.mu.Unlock()
+	if alreadyAnswered {
+		return nil, nil
+	}
+	prompt, err := service.pendingPrompt(thread, captured)
+	if err != nil {
+		return nil, nil
+	}
+	rule, option, ok := policy.match(thread.Role, prompt)
+	if !ok {
+		return nil, nil
+	}
+	if err := service.sendPromptAnswer(ctx, thread, runner, prompt, option); err != nil {
+		return nil, err
+	}
+	service.supervisor.mu.Lock()
+	if service.supervisor.answered == nil {
+		service.supervisor.answered = make(map[int64]string)
+	}
+	service.supervisor.answered[thread.ID] = captured
+	service.supervisor.mu.Unlock()
+	service.notifyParentThread(ctx, thread, fmt.Sprintf("[auto-answer] thread %d answered %q to %q", thread.ID, option.Label, prompt.Question))
+	return &threadAutoAnswer{ThreadID: thread.ID, Question: prompt.Question, Option: option, Rule: rule.Question}, nil
+}
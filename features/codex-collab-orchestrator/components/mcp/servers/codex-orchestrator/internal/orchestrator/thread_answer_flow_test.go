@@ -0,0 +1,157 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const askerProvidersYAML = `providers:
+  asker:
+    launch_command: 'printf "Allow command go test ./...? (y/n)\n"; read answer; printf "answer=%s\n> \n" "$answer"; cat'
+    idle_pattern: '^>\s*$'
+    waiting_pattern: '\(y/n\)'
+    tail_lines: 1
+`
+
+func spawnAskerThread(t *testing.T, ctx context.Context, service *Service, repoPath string) store.Thread {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(repoPath, ".codex-orch", "providers.yaml"), []byte(askerProvidersYAML), 0o644); err != nil {
+		t.Fatalf("failed to write providers config: %v", err)
+	}
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, _, _, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{
+		SessionID:      session.ID,
+		Role:           "worker",
+		Backend:        "headless",
+		ProviderType:   "asker",
+		SkipReadyCheck: pointerToBool(true),
+	})
+	if err != nil {
+		t.Fatalf("failed to spawn asker thread: %v", err)
+	}
+	waitForCondition(t, func() bool {
+		content, err := os.ReadFile(valueOrEmpty(thread.LogFilePath))
+		return err == nil && strings.Contains(string(content), "(y/n)")
+	})
+	return thread
+}
+
+func TestAnswerChildThreadPrompt(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+	thread := spawnAskerThread(t, ctx, service, repoPath)
+
+	status, err := service.childThreadStatus(ctx, threadChildStatusInput{ThreadID: thread.ID})
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	prompt, ok := status["pending_prompt"].(provider.Prompt)
+	if !ok || prompt.Kind != provider.PromptKindYesNo || prompt.Question != "Allow command go test ./...?" || len(prompt.Options) != 2 {
+		t.Fatalf("expected parsed y/n prompt in status, got %+v", status)
+	}
+
+	if _, err := service.answerChildThread(ctx, threadChildAnswerInput{ThreadID: thread.ID, Option: "maybe"}); err == nil || !strings.Contains(err.Error(), "y (Yes)") {
+		t.Fatalf("expected unknown option error listing choices, got %v", err)
+	}
+	answered, err := service.answerChildThread(ctx, threadChildAnswerInput{ThreadID: thread.ID, Option: "yes"})
+	if err != nil {
+		t.Fatalf("failed to answer: %v", err)
+	}
+	if option := answered["answered"].(provider.PromptOption); option.Key != "y" {
+		t.Fatalf("expected the yes option to be sent, got %+v", option)
+	}
+	waitForCondition(t, func() bool {
+		content, err := os.ReadFile(valueOrEmpty(thread.LogFilePath))
+		return err == nil && strings.Contains(string(content), "answer=y\n>")
+	})
+
+	if _, err := service.answerChildThread(ctx, threadChildAnswerInput{ThreadID: thread.ID, Option: "y"}); err == nil || !strings.Contains(err.Error(), "not waiting") {
+		t.Fatalf("expected answering an idle thread to fail, got %v", err)
+	}
+}
+
+func TestSupervisorAutoAnswersKnownSafePrompts(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	policy := `roles:
+  reviewer:
+    - question: 'Allow command .*'
+      answer: "n"
+  worker:
+    - question: 'Allow command go (build|vet) .*'
+      answer: "y"
+  "*":
+    - question: 'Allow command go test \S+\?'
+      answer: "Yes"
+`
+	if err := os.WriteFile(service.autoAnswerPolicyPath(), []byte(policy), 0o644); err != nil {
+		t.Fatalf("failed to write auto-answer policy: %v", err)
+	}
+	thread := spawnAskerThread(t, ctx, service, repoPath)
+
+	sweep, err := service.superviseThreads(ctx)
+	if err != nil {
+		t.Fatalf("sweep failed: %v", err)
+	}
+	if len(sweep.AutoAnswers) != 1 || sweep.AutoAnswers[0].Option.Key != "y" || sweep.AutoAnswers[0].Rule != `Allow command go test \S+\?` {
+		t.Fatalf("expected the any-role rule to answer yes, got %+v", sweep)
+	}
+	waitForCondition(t, func() bool {
+		content, err := os.ReadFile(valueOrEmpty(thread.LogFilePath))
+		return err == nil && strings.Contains(string(content), "answer=y\n>")
+	})
+	messages, err := service.store.ListInboxMessages(ctx, *thread.ParentThreadID)
+	if err != nil {
+		t.Fatalf("failed to list parent inbox: %v", err)
+	}
+	if len(messages) == 0 || !strings.Contains(messages[len(messages)-1].Message, "[auto-answer]") {
+		t.Fatalf("expected the parent to be told about the auto-answer, got %+v", messages)
+	}
+
+	if err := os.WriteFile(service.autoAnswerPolicyPath(), []byte("roles:\n  worker:\n    - question: '('\n      answer: y\n"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite auto-answer policy: %v", err)
+	}
+	if _, err := service.loadAutoAnswerPolicy(); err == nil || !strings.Contains(err.Error(), "invalid question pattern") {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+
+	if err := os.WriteFile(service.autoAnswerPolicyPath(), []byte("roles:\n  worker:\n    - question: ''\n      answer: y\n"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite auto-answer policy: %v", err)
+	}
+	if _, err := service.loadAutoAnswerPolicy(); err == nil || !strings.Contains(err.Error(), "has no question pattern") {
+		t.Fatalf("expected empty pattern error, got %v", err)
+	}
+
+	// Patterns match the whole question, not a substring of it.
+	if err := os.WriteFile(service.autoAnswerPolicyPath(), []byte("roles:\n  worker:\n    - question: 'Allow command go test'\n      answer: y\n"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite auto-answer policy: %v", err)
+	}
+	anchored, err := service.loadAutoAnswerPolicy()
+	if err != nil {
+		t.Fatalf("failed to load anchored policy: %v", err)
+	}
+	prompt := provider.Prompt{Kind: provider.PromptKindYesNo, Question: "Allow command go test ./... && rm -rf /?", Options: []provider.PromptOption{{Key: "y", Label: "Yes"}, {Key: "n", Label: "No"}}}
+	if _, _, ok := anchored.match("worker", prompt); ok {
+		t.Fatalf("expected a partial pattern not to match a longer question")
+	}
+}
@@ -0,0 +1,327 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/tmux"
+)
+
+const (
+	runnerBackendTmux     = "tmux"
+	runnerBackendHeadless = "headless"
+	runnerBackendAuto     = "auto"
+
+	headlessStopGrace = 5 * time.Second
+	// orphanCaptureBytes bounds the log tail an orphan's capture reads.
+	orphanCaptureBytes = 64 * 1024
+)
+
+// threadRunner is the runtime a child agent lives in. thread.child.directive,
+// interrupt, stop and status go through it so they behave the same whether
+// the agent sits in a tmux pane or in a headless subprocess.
+type threadRunner interface {
+	Backend() string
+	Alive(ctx context.Context, thread store.Thread) bool
+	SendText(ctx context.Context, thread store.Thread, text string) error
+	// SendAnswer types a prompt option key, followed by Enter when submit is
+	// set. Headless stdin is line based, so the key is always a full line.
+	SendAnswer(ctx context.Context, thread store.Thread, key string, submit bool) error
+	Interrupt(ctx context.Context, thread store.Thread) error
+	// ClearInput discards text typed at the prompt but not submitted.
+	ClearInput(ctx context.Context, thread store.Thread) error
+	// Stop ends the agent. terminate also tears down the tmux pane; headless
+	// processes are always reaped.
+	Stop(ctx context.Context, thread store.Thread, terminate bool) error
+	Capture(ctx context.Context, thread store.Thread, lines int) (string, error)
+}
+
+type tmuxThreadRunner struct {
+	client *tmux.Client
+}
+
+func (runner tmuxThreadRunner) Backend() string { return runnerBackendTmux }
+
+func (runner tmuxThreadRunner) Alive(ctx context.Context, thread store.Thread) bool {
+	return runner.client.PaneExists(ctx, threadPaneID(thread))
+}
+
+func (runner tmuxThreadRunner) SendText(ctx context.Context, thread store.Thread, text string) error {
+	return runner.client.SendKeys(ctx, threadPaneID(thread), text)
+}
+
+func (runner tmuxThreadRunner) SendAnswer(ctx context.Context, thread store.Thread, key string, submit bool) error {
+	if err := runner.client.SendKeysRaw(ctx, threadPaneID(thread), "-l", key); err != nil {
+		return err
+	}
+	if submit {
+		return runner.client.SendKeysRaw(ctx, threadPaneID(thread), "Enter")
+	}
+	return nil
+}
+
+func (runner tmuxThreadRunner) Interrupt(ctx context.Context, thread store.Thread) error {
+	return runner.client.SendKeysRaw(ctx, threadPaneID(thread), "C-c")
+}
+
+func (runner tmuxThreadRunner) ClearInput(ctx context.Context, thread store.Thread) error {
+	return runner.client.SendKeysRaw(ctx, threadPaneID(thread), "C-u")
+}
+
+func (runner tmuxThreadRunner) Stop(ctx context.Context, thread store.Thread, terminate bool) error {
+	paneID := threadPaneID(thread)
+	_ = runner.client.StopPipePane(ctx, paneID)
+	_ = runner.client.SendKeysRaw(ctx, paneID, "exit", "C-m")
+	if terminate {
+		return runner.client.KillPane(ctx, paneID)
+	}
+	return nil
+}
+
+func (runner tmuxThreadRunner) Capture(ctx context.Context, thread store.Thread, lines int) (string, error) {
+	return runner.client.CaptureHistory(ctx, threadPaneID(thread), lines)
+}
+
+type headlessThreadRunner struct {
+	runner *headless.Runner
+}
+
+func (runner headlessThreadRunner) Backend() string { return runnerBackendHeadless }
+
+func (runner headlessThreadRunner) Alive(_ context.Context, thread store.Thread) bool {
+	return runner.runner.Alive(thread.ID)
+}
+
+func (runner headlessThreadRunner) SendText(_ context.Context, thread store.Thread, text string) error {
+	return runner.runner.Send(thread.ID, text)
+}
+
+func (runner headlessThreadRunner) SendAnswer(_ context.Context, thread store.Thread, key string, _ bool) error {
+	return runner.runner.Send(thread.ID, key)
+}
+
+func (runner headlessThreadRunner) Interrupt(_ context.Context, thread store.Thread) error {
+	return runner.runner.Interrupt(thread.ID)
+}
+
+// ClearInput has nothing to clear: headless stdin is line based, so text is
+// never left half-typed at the prompt.
+func (runner headlessThreadRunner) ClearInput(context.Context, store.Thread) error {
+	return nil
+}
+
+func (runner headlessThreadRunner) Stop(_ context.Context, thread store.Thread, _ bool) error {
+	return runner.runner.Stop(thread.ID, headlessStopGrace)
+}
+
+func (runner headlessThreadRunner) Capture(_ context.Context, thread store.Thread, lines int) (string, error) {
+	return runner.runner.Capture(thread.ID, lines)
+}
+
+// orphanThreadRunner reaches a headless agent started by an earlier server
+// process, found by its stored pid. Its terminal went away with that server,
+// so it takes no input; it can still be watched through its log, interrupted
+// and stopped.
+type orphanThreadRunner struct {
+	pid int
+}
+
+func (runner orphanThreadRunner) Backend() string { return runnerBackendHeadless }
+
+func (runner orphanThreadRunner) Alive(context.Context, store.Thread) bool {
+	return headless.OrphanAlive(runner.pid)
+}
+
+func (runner orphanThreadRunner) SendText(context.Context, store.Thread, string) error {
+	return runner.noInput()
+}
+
+func (runner orphanThreadRunner) SendAnswer(context.Context, store.Thread, string, bool) error {
+	return runner.noInput()
+}
+
+func (runner orphanThreadRunner) ClearInput(context.Context, store.Thread) error {
+	return runner.noInput()
+}
+
+func (runner orphanThreadRunner) noInput() error {
+	return fmt.Errorf("headless process %d outlived a server restart and takes no input; stop the thread and spawn it again", runner.pid)
+}
+
+func (runner orphanThreadRunner) Interrupt(context.Context, store.Thread) error {
+	return headless.InterruptOrphan(runner.pid)
+}
+
+func (runner orphanThreadRunner) Stop(context.Context, store.Thread, bool) error {
+	return headless.StopOrphan(runner.pid, headlessStopGrace)
+}
+
+func (runner orphanThreadRunner) Capture(_ context.Context, thread store.Thread, lines int) (string, error) {
+	tail, err := readFileTail(valueOrEmpty(thread.LogFilePath), orphanCaptureBytes)
+	if err != nil {
+		return "", err
+	}
+	allLines := strings.Split(strings.TrimRight(tail, "\n"), "\n")
+	if lines > 0 && len(allLines) > lines {
+		allLines = allLines[len(allLines)-lines:]
+	}
+	return strings.Join(allLines, "\n"), nil
+}
+
+// runnerFor returns the runner a thread was launched with. A headless thread
+// this server did not start is reached by its stored pid while that process
+// group lives. Threads without a pane or process are reported as unbound so
+// callers keep their old errors.
+func (service *Service) runnerFor(thread store.Thread) (threadRunner, error) {
+	if threadRunnerBackend(thread) == runnerBackendHeadless {
+		if _, ok := service.headless.Info(thread.ID); ok {
+			return headlessThreadRunner{runner: service.headless}, nil
+		}
+		if thread.ProcessID != nil && headless.OrphanAlive(int(*thread.ProcessID)) {
+			return orphanThreadRunner{pid: int(*thread.ProcessID)}, nil
+		}
+		return nil, fmt.Errorf("thread has no headless process bound: %d", thread.ID)
+	}
+	if threadPaneID(thread) == "" {
+		return nil, fmt.Errorf("thread has no tmux pane bound: %d", thread.ID)
+	}
+	return tmuxThreadRunner{client: service.tmux}, nil
+}
+
+func threadRunnerBackend(thread store.Thread) string {
+	if backend := strings.TrimSpace(valueOrEmpty(thread.RunnerBackend)); backend != "" {
+		return backend
+	}
+	return runnerBackendTmux
+}
+
+func threadPaneID(thread store.Thread) string {
+	return strings.TrimSpace(valueOrEmpty(thread.TmuxPaneID))
+}
+
+// resolveRunnerBackend picks the backend for a new child: an explicit request
+// wins, then the server default; auto uses tmux when it is ready and falls
+// back to headless otherwise.
+func (service *Service) resolveRunnerBackend(requested string, tmuxReady bool) (string, error) {
+	backend := strings.ToLower(strings.TrimSpace(requested))
+	if backend == "" {
+		backend = service.defaultRunnerBackend
+	}
+	switch backend {
+	case "", runnerBackendTmux:
+		return runnerBackendTmux, nil
+	case runnerBackendHeadless:
+		return runnerBackendHeadless, nil
+	case runnerBackendAuto:
+		if tmuxReady {
+			return runnerBackendTmux, nil
+		}
+		return runnerBackendHeadless, nil
+	default:
+		return "", fmt.Errorf("backend must be one of: tmux, headless, auto (got %q)", requested)
+	}
+}
+
+// SetDefaultRunnerBackend sets the backend used when thread.child.spawn does
+// not pass one.
+func (service *Service) SetDefaultRunnerBackend(backend string) error {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	switch backend {
+	case "", runnerBackendTmux, runnerBackendHeadless, runnerBackendAuto:
+		service.defaultRunnerBackend = backend
+		return nil
+	default:
+		return fmt.Errorf("backend must be one of: tmux, headless, auto (got %q)", backend)
+	}
+}
+
+// launchHeadlessChild starts a spawned child as a subprocess instead of a tmux
+// pane. The runner gives it a pseudo-terminal, so the default interactive
+// launch (`codex --no-alt-screen`) runs as it would in a pane. Output goes to
+// the same .codex-orch/logs/thread_N.log the pane would pipe to, so provider
+// status detection works unchanged.
+func (service *Service) launchHeadlessChild(ctx context.Context, session store.Session, rootThread store.Thread, createdThread store.Thread, input threadChildSpawnInput, tmuxResult map[string]any) (store.Thread, map[string]any, map[string]any, error) {
+	markFailed := func() {
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cleanupCancel()
+		_ = service.headless.Stop(createdThread.ID, headlessStopGrace)
+		service.provider.Remove(createdThread.ID)
+		failedStatus := "failed"
+		_, _ = service.store.UpdateThread(cleanupCtx, createdThread.ID, store.ThreadUpdateArgs{
+			Status:    &failedStatus,
+			ProcessID: pointerToInt64(0),
+		})
+	}
+
+	workdir, err := service.resolveThreadWorkdir(ctx, session, input.WorktreeID)
+	if err != nil {
+		markFailed()
+		return store.Thread{}, nil, nil, err
+	}
+	providerType := strings.TrimSpace(valueOrEmpty(createdThread.ProviderType))
+	if providerType == "" {
+		providerType = "codex"
+	}
+	if _, err := service.provider.Create(createdThread.ID, providerType); err != nil {
+		markFailed()
+		return store.Thread{}, nil, nil, fmt.Errorf("failed to create provider %q: %w", providerType, err)
+	}
+
+	logFilePath := service.threadLogFilePath(createdThread)
+	launchCommand := service.childLaunchCommand(workdir, rootThread.ID, createdThread, input)
+	backend := runnerBackendHeadless
+	updatedThread, err := service.store.UpdateThread(ctx, createdThread.ID, store.ThreadUpdateArgs{
+		RunnerBackend: &backend,
+		LaunchCommand: &launchCommand,
+		LogFilePath:   &logFilePath,
+		ProviderType:  &providerType,
+	})
+	if err != nil {
+		markFailed()
+		return store.Thread{}, nil, nil, err
+	}
+	if launchCommand == "" {
+		// Nothing to run: keep the planned record, like a tmux pane with no
+		// launch command.
+		tmuxResult["headless"] = "skipped: no launch command"
+		attachInfo, attachErr := service.buildAttachInfo(ctx, session, &updatedThread)
+		if attachErr != nil {
+			return store.Thread{}, nil, nil, attachErr
+		}
+		return updatedThread, attachInfo, tmuxResult, nil
+	}
+
+	processInfo, err := service.headless.Start(headless.StartOptions{
+		ThreadID:    createdThread.ID,
+		Workdir:     workdir,
+		Command:     launchCommand,
+		LogFilePath: logFilePath,
+	})
+	if err != nil {
+		markFailed()
+		return store.Thread{}, nil, nil, err
+	}
+	tmuxResult["process"] = processInfo
+	processID := int64(processInfo.PID)
+	updatedThread, err = service.store.UpdateThread(ctx, createdThread.ID, store.ThreadUpdateArgs{ProcessID: &processID})
+	if err != nil {
+		markFailed()
+		return store.Thread{}, nil, nil, err
+	}
+	return service.finishChildLaunch(ctx, session, updatedThread, launchCommand, input.SkipReadyCheck, tmuxResult, markFailed)
+}
+
+func (service *Service) threadLogFilePath(thread store.Thread) string {
+	if logFilePath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath)); logFilePath != "" {
+		return logFilePath
+	}
+	logDir := filepath.Join(service.repoPath, ".codex-orch", "logs")
+	_ = os.MkdirAll(logDir, 0o755)
+	return filepath.Join(logDir, fmt.Sprintf("thread_%d.log", thread.ID))
+}
@@ -0,0 +1,85 @@
+//go:build linux || darwin
+
+package orchestrator
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestHeadlessOrphanSurvivesServerRestart(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create worker thread: %v", err)
+	}
+
+	// An agent left behind by an earlier server: its own session and process
+	// group, known to this server only by the stored pid.
+	orphan := exec.Command("sleep", "30")
+	orphan.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := orphan.Start(); err != nil {
+		t.Fatalf("failed to start orphan: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = orphan.Wait()
+		close(exited)
+	}()
+	defer func() { _ = orphan.Process.Kill() }()
+
+	backend := runnerBackendHeadless
+	pid := int64(orphan.Process.Pid)
+	logPath := service.threadLogFilePath(worker)
+	if _, err := service.store.UpdateThread(ctx, worker.ID, store.ThreadUpdateArgs{RunnerBackend: &backend, ProcessID: &pid, LogFilePath: &logPath}); err != nil {
+		t.Fatalf("failed to bind process: %v", err)
+	}
+	lock, err := service.acquireLock(ctx, lockAcquireInput{ScopeType: "file", ScopePath: "api.go", OwnerThreadID: &worker.ID})
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	if sweep, err := service.superviseThreads(ctx); err != nil || len(sweep.Transitions) != 0 {
+		t.Fatalf("expected the live orphan not to crash, got %+v (%v)", sweep, err)
+	}
+	status, err := service.childThreadStatus(ctx, threadChildStatusInput{ThreadID: worker.ID})
+	if err != nil || status["process_alive"] != true || status["orphaned"] != true {
+		t.Fatalf("expected a live orphan in status, got %+v (%v)", status, err)
+	}
+	if _, err := service.directiveChildThread(ctx, threadChildDirectiveInput{ThreadID: worker.ID, Directive: "hello", Mode: "queue"}); err == nil || !strings.Contains(err.Error(), "outlived a server restart") {
+		t.Fatalf("expected directives to an orphan to be refused, got %v", err)
+	}
+	if locks, err := service.store.ListLocks(ctx, store.LockFilter{State: "active", OwnerThreadID: &worker.ID}); err != nil || len(locks) != 1 || locks[0].ID != lock.ID {
+		t.Fatalf("expected the orphan to keep its lock, got %+v (%v)", locks, err)
+	}
+
+	stopped, err := service.stopChildThread(ctx, threadChildStopInput{ThreadID: worker.ID})
+	if err != nil || stopped["thread"].(store.Thread).Status != "stopped" {
+		t.Fatalf("expected the orphan to be stopped, got %+v (%v)", stopped, err)
+	}
+	<-exited
+	if headless.OrphanAlive(int(pid)) {
+		t.Fatalf("expected the orphan process group to be gone")
+	}
+}
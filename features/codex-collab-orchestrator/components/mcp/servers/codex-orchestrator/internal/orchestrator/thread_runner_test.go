@@ -0,0 +1,95 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestHeadlessChildThreadLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	if _, _, _, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{SessionID: session.ID, Backend: "screen"}); err == nil {
+		t.Fatalf("expected unknown backend to be rejected")
+	}
+
+	thread, _, runtimeResult, err := service.spawnChildThreadInternal(ctx, threadChildSpawnInput{
+		SessionID:      session.ID,
+		Role:           "worker",
+		Title:          "headless worker",
+		Backend:        "headless",
+		LaunchCommand:  "cat",
+		SkipReadyCheck: pointerToBool(true),
+	})
+	if err != nil {
+		t.Fatalf("failed to spawn headless child: %v", err)
+	}
+	if runtimeResult["runner_backend"] != runnerBackendHeadless || valueOrEmpty(thread.RunnerBackend) != runnerBackendHeadless {
+		t.Fatalf("expected headless backend, got %+v / %+v", runtimeResult, thread)
+	}
+	if thread.Status != "running" || thread.ProcessID == nil || thread.TmuxPaneID != nil {
+		t.Fatalf("unexpected headless thread record: %+v", thread)
+	}
+	if !strings.HasSuffix(valueOrEmpty(thread.LogFilePath), ".codex-orch/logs/thread_"+strconv.FormatInt(thread.ID, 10)+".log") {
+		t.Fatalf("expected thread log under .codex-orch/logs, got %v", thread.LogFilePath)
+	}
+
+	if _, err := service.directiveChildThread(ctx, threadChildDirectiveInput{ThreadID: thread.ID, Directive: "hello headless", Mode: "queue"}); err != nil {
+		t.Fatalf("failed to send directive: %v", err)
+	}
+	waitForCondition(t, func() bool {
+		content, err := os.ReadFile(valueOrEmpty(thread.LogFilePath))
+		return err == nil && strings.Contains(string(content), "hello headless")
+	})
+
+	status, err := service.childThreadStatus(ctx, threadChildStatusInput{ThreadID: thread.ID})
+	if err != nil {
+		t.Fatalf("failed to read status: %v", err)
+	}
+	if status["backend"] != runnerBackendHeadless || status["process_alive"] != true {
+		t.Fatalf("expected live headless process, got %+v", status)
+	}
+
+	if _, err := service.interruptChildThread(ctx, threadChildSignalInput{ThreadID: thread.ID}); err != nil {
+		t.Fatalf("failed to interrupt: %v", err)
+	}
+	waitForCondition(t, func() bool { return !service.headless.Alive(thread.ID) })
+
+	stopped, err := service.stopChildThread(ctx, threadChildStopInput{ThreadID: thread.ID})
+	if err != nil {
+		t.Fatalf("failed to stop: %v", err)
+	}
+	stoppedThread := stopped["thread"].(store.Thread)
+	if stoppedThread.Status != "stopped" || stoppedThread.ProcessID != nil {
+		t.Fatalf("unexpected stopped thread: %+v", stoppedThread)
+	}
+	if _, err := service.interruptChildThread(ctx, threadChildSignalInput{ThreadID: thread.ID}); err == nil {
+		t.Fatalf("expected interrupt on a stopped headless thread to fail")
+	}
+}
+
+func waitForCondition(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within 5s")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -166,20 +166,12 @@ func (service *Service) directiveChildThread(ctx context.Context, input threadCh
 	if thread.ParentThreadID == nil {
 		return nil, fmt.Errorf("thread is not a child thread: %d", thread.ID)
 	}
-	if thread.TmuxPaneID == nil || strings.TrimSpace(*thread.TmuxPaneID) == "" {
-		return nil, fmt.Errorf("thread has no tmux pane bound: %d", thread.ID)
-	}
-	paneID := strings.TrimSpace(*thread.TmuxPaneID)
-
-	sendDirective := func() error {
-		return service.tmux.SendKeys(ctx, paneID, directive)
+	runner, err := service.runnerFor(thread)
+	if err != nil {
+		return nil, err
 	}
 
 	switch mode {
-	case "queue":
-		if err := sendDirective(); err != nil {
-			return nil, err
-		}
 	case "restart":
 		_, _ = service.stopChildThread(ctx, threadChildStopInput{
 			ThreadID:      thread.ID,
@@ -195,39 +187,74 @@ func (service *Service) directiveChildThread(ctx context.Context, input threadCh
 			AgentGuidePath: strings.TrimSpace(valueOrEmpty(thread.AgentGuidePath)),
 			InitialPrompt:  directive,
 			LaunchCodex:    pointerToBool(true),
+			Backend:        runner.Backend(),
+			ProviderType:   valueOrEmpty(thread.ProviderType),
 		})
 		if spawnErr != nil {
 			return nil, spawnErr
 		}
+		// The respawned agent gets the directive as its launch prompt.
+		record, err := service.store.CreateThreadDirective(ctx, store.ThreadDirectiveCreateArgs{
+			ThreadID:  respawnedThread.ID,
+			SessionID: respawnedThread.SessionID,
+			Mode:      mode,
+			Directive: directive,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if record, err = service.store.AdvanceThreadDirective(ctx, record.ID, store.ThreadDirectiveAdvanceArgs{Status: store.DirectiveStatusSent, CountAttempt: true}); err != nil {
+			return nil, err
+		}
 		return map[string]any{
-			"result":      "respawned_with_directive",
-			"mode":        mode,
-			"thread":      respawnedThread,
-			"attach_info": attachInfo,
-			"tmux":        tmuxResult,
+			"result":           "respawned_with_directive",
+			"mode":             mode,
+			"thread":           respawnedThread,
+			"attach_info":      attachInfo,
+			"tmux":             tmuxResult,
+			"directive_record": record,
 		}, nil
 	default:
-		if err := service.tmux.SendKeysRaw(ctx, paneID, "C-c"); err != nil {
+		if mode != "queue" {
+			mode = "interrupt_patch"
+		}
+		confirmTimeout := defaultDirectiveConfirmTimeout
+		if input.ConfirmTimeoutSeconds != nil {
+			confirmTimeout = time.Duration(*input.ConfirmTimeoutSeconds) * time.Second
+		}
+		record, err := service.store.CreateThreadDirective(ctx, store.ThreadDirectiveCreateArgs{
+			ThreadID:  thread.ID,
+			SessionID: thread.SessionID,
+			Mode:      mode,
+			Directive: directive,
+		})
+		if err != nil {
 			return nil, err
 		}
-		if err := sendDirective(); err != nil {
+		record, err = service.deliverDirective(ctx, thread, runner, record, confirmTimeout)
+		if err != nil {
 			return nil, err
 		}
-		runningStatus := "running"
-		_, _ = service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{Status: &runningStatus})
-		mode = "interrupt_patch"
-	}
-
-	updatedThread, err := service.store.GetThreadByID(ctx, thread.ID)
-	if err != nil {
-		return nil, err
+		if mode == "interrupt_patch" {
+			runningStatus := "running"
+			_, _ = service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{Status: &runningStatus})
+		}
+		updatedThread, err := service.store.GetThreadByID(ctx, thread.ID)
+		if err != nil {
+			return nil, err
+		}
+		result := "directive_sent"
+		if record.Status == store.DirectiveStatusFailed {
+			result = "directive_failed"
+		}
+		return map[string]any{
+			"result":           result,
+			"mode":             mode,
+			"thread":           updatedThread,
+			"directive":        directive,
+			"directive_record": record,
+		}, nil
 	}
-	return map[string]any{
-		"result":    "directive_sent",
-		"mode":      mode,
-		"thread":    updatedThread,
-		"directive": directive,
-	}, nil
 }
 
 func (service *Service) interruptChildThread(ctx context.Context, input threadChildSignalInput) (map[string]any, error) {
@@ -241,11 +268,12 @@ func (service *Service) interruptChildThread(ctx context.Context, input threadCh
 	if thread.ParentThreadID == nil {
 		return nil, fmt.Errorf("thread is not a child thread: %d", thread.ID)
 	}
-	if thread.TmuxPaneID == nil || strings.TrimSpace(*thread.TmuxPaneID) == "" {
-		return nil, fmt.Errorf("thread has no tmux pane bound: %d", thread.ID)
+	runner, err := service.runnerFor(thread)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := service.tmux.SendKeysRaw(ctx, *thread.TmuxPaneID, "C-c"); err != nil {
+	if err := runner.Interrupt(ctx, thread); err != nil {
 		return nil, err
 	}
 	interruptedStatus := "interrupted"
@@ -273,16 +301,23 @@ func (service *Service) stopChildThread(ctx context.Context, input threadChildSt
 	if thread.ParentThreadID == nil {
 		return nil, fmt.Errorf("thread is not a child thread: %d", thread.ID)
 	}
-	if thread.TmuxPaneID == nil || strings.TrimSpace(*thread.TmuxPaneID) == "" {
-		return nil, fmt.Errorf("thread has no tmux pane bound: %d", thread.ID)
+	runner, err := service.runnerFor(thread)
+	if err != nil {
+		return nil, err
 	}
 
-	_ = service.tmux.StopPipePane(ctx, *thread.TmuxPaneID)
-	_ = service.tmux.SendKeysRaw(ctx, *thread.TmuxPaneID, "exit", "C-m")
+	terminatePane := boolValueOrDefault(input.TerminatePane, false)
+	if p, ok := service.provider.Get(thread.ID); ok && strings.TrimSpace(p.ExitCommand()) != "" {
+		_ = runner.SendText(ctx, thread, p.ExitCommand())
+	}
+	_ = runner.Stop(ctx, thread, terminatePane)
+	_, _ = service.recordThreadUsage(ctx, thread)
+	_, _ = service.refreshThreadTranscript(ctx, thread)
 	service.provider.Remove(thread.ID)
 	updateArgs := store.ThreadUpdateArgs{}
-	if boolValueOrDefault(input.TerminatePane, false) {
-		_ = service.tmux.KillPane(ctx, *thread.TmuxPaneID)
+	if runner.Backend() == runnerBackendHeadless {
+		updateArgs.ProcessID = pointerToInt64(0)
+	} else if terminatePane {
 		updateArgs.TmuxPaneID = pointerToString("")
 		updateArgs.TmuxWindowName = pointerToString("")
 	}
@@ -293,10 +328,16 @@ func (service *Service) stopChildThread(ctx context.Context, input threadChildSt
 	if err != nil {
 		return nil, err
 	}
-	return map[string]any{
-		"thread": updatedThread,
-		"result": "stopped",
-	}, nil
+	locksReleased, releaseErr := service.releaseThreadLocks(ctx, thread.ID, "thread_stop")
+	result := map[string]any{
+		"thread":         updatedThread,
+		"result":         "stopped",
+		"locks_released": locksReleased,
+	}
+	if releaseErr != nil {
+		result["locks_release_error"] = releaseErr.Error()
+	}
+	return result, nil
 }
 
 func (service *Service) childThreadStatus(ctx context.Context, input threadChildStatusInput) (map[string]any, error) {
@@ -314,14 +355,25 @@ func (service *Service) childThreadStatus(ctx context.Context, input threadChild
 	result := map[string]any{
 		"thread_id":   thread.ID,
 		"db_status":   thread.Status,
+		"backend":     threadRunnerBackend(thread),
 		"pane_exists": false,
 	}
 
-	paneID := strings.TrimSpace(valueOrEmpty(thread.TmuxPaneID))
-	if paneID == "" {
+	runner, err := service.runnerFor(thread)
+	if err != nil {
 		return result, nil
 	}
-	result["pane_exists"] = service.tmux.PaneExists(ctx, paneID)
+	paneExists := runner.Alive(ctx, thread)
+	result["pane_exists"] = paneExists
+	if runner.Backend() == runnerBackendHeadless {
+		result["process_alive"] = paneExists
+		if info, ok := service.headless.Info(thread.ID); ok {
+			result["process"] = info
+		}
+		if _, orphaned := runner.(orphanThreadRunner); orphaned {
+			result["orphaned"] = true
+		}
+	}
 
 	// Tier 1: Fast check via pipe-pane log tail.
 	providerTypeName := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
@@ -339,16 +391,17 @@ func (service *Service) childThreadStatus(ctx context.Context, input threadChild
 			result["provider_status"] = string(fastStatus)
 			result["detection_tier"] = "fast"
 			result["last_response"] = p.ExtractLastResponse(logTail)
+			addPendingPrompt(result, fastStatus, logTail)
 			return result, nil
 		}
 	}
 
-	// Tier 2: Full tmux capture-pane.
+	// Tier 2: Full capture (tmux capture-pane, or the log tail when headless).
 	captureLines := 200
 	if input.CaptureLines != nil && *input.CaptureLines > 0 {
 		captureLines = *input.CaptureLines
 	}
-	captured, captureErr := service.tmux.CaptureHistory(ctx, paneID, captureLines)
+	captured, captureErr := runner.Capture(ctx, thread, captureLines)
 	if captureErr != nil {
 		result["capture_error"] = captureErr.Error()
 		return result, nil
@@ -358,10 +411,22 @@ func (service *Service) childThreadStatus(ctx context.Context, input threadChild
 		result["provider_status"] = string(fullStatus)
 		result["detection_tier"] = "full"
 		result["last_response"] = p.ExtractLastResponse(captured)
+		addPendingPrompt(result, fullStatus, captured)
 	}
 	return result, nil
 }
 
+// addPendingPrompt exposes the question a waiting thread is blocked on so the
+// caller can pick an option for thread.child.answer.
+func addPendingPrompt(result map[string]any, status provider.Status, output string) {
+	if status != provider.StatusWaitingUserAnswer {
+		return
+	}
+	if prompt, ok := provider.ParsePrompt(provider.StripEscapes(output)); ok {
+		result["pending_prompt"] = prompt
+	}
+}
+
 func (service *Service) threadAttachInfo(ctx context.Context, input threadAttachInfoInput) (map[string]any, error) {
 	if input.SessionID <= 0 {
 		return nil, errors.New("session_id is required")
@@ -404,10 +469,11 @@ func (service *Service) requestAutoMergeReview(ctx context.Context, input mergeR
 	}
 
 	reviewJob, err := service.store.CreateReviewJob(ctx, store.ReviewJobCreateArgs{
-		MergeRequestID: input.MergeRequestID,
-		SessionID:      input.SessionID,
-		State:          "requested",
-		NotesJSON:      input.AgentOverride,
+		MergeRequestID:     input.MergeRequestID,
+		SessionID:          input.SessionID,
+		MainMergeRequestID: input.MainMergeRequestID,
+		State:              "requested",
+		NotesJSON:          input.AgentOverride,
 	})
 	if err != nil {
 		return nil, err
@@ -452,6 +518,11 @@ func (service *Service) requestAutoMergeReview(ctx context.Context, input mergeR
 	if err != nil {
 		return nil, err
 	}
+	if input.MainMergeRequestID != nil {
+		if _, err := service.store.UpdateMainMergeRequestState(ctx, *input.MainMergeRequestID, "reviewing"); err != nil {
+			return nil, err
+		}
+	}
 
 	return map[string]any{
 		"review_job":   reviewJob,
@@ -520,9 +591,16 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 	if err != nil {
 		return store.Thread{}, nil, nil, err
 	}
+	if err := service.checkSessionBudget(ctx, session.ID); err != nil {
+		return store.Thread{}, nil, nil, err
+	}
 
+	requestedBackend := strings.ToLower(strings.TrimSpace(input.Backend))
+	if requestedBackend == "" {
+		requestedBackend = service.defaultRunnerBackend
+	}
 	tmuxResult := map[string]any{"status": "skipped"}
-	ensureTmux := boolValueOrDefault(input.EnsureTmux, true)
+	ensureTmux := boolValueOrDefault(input.EnsureTmux, true) && requestedBackend != runnerBackendHeadless
 	if ensureTmux {
 		tmuxResult, err = service.ensureTmux(ctx, runtimeTmuxEnsureInput{
 			SessionID:   &session.ID,
@@ -532,6 +610,11 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 			return store.Thread{}, nil, nil, err
 		}
 	}
+	backend, err := service.resolveRunnerBackend(requestedBackend, isTmuxReady(tmuxResult))
+	if err != nil {
+		return store.Thread{}, nil, nil, err
+	}
+	tmuxResult["runner_backend"] = backend
 
 	parentThreadID := rootThread.ID
 	if input.ParentThreadID != nil && *input.ParentThreadID > 0 {
@@ -557,9 +640,9 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 	if interactionMode == "" {
 		interactionMode = defaultInteractionMode
 	}
-	providerType := strings.TrimSpace(input.ProviderType)
-	if providerType == "" {
-		providerType = "codex"
+	providerType, err := service.resolveProviderType(input.ProviderType)
+	if err != nil {
+		return store.Thread{}, nil, nil, err
 	}
 
 	resolvedGuidePath := service.resolveAgentGuidePathForRole(role, input.AgentGuidePath)
@@ -599,6 +682,9 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 		return store.Thread{}, nil, nil, attachErr
 	}
 
+	if backend == runnerBackendHeadless {
+		return service.launchHeadlessChild(ctx, session, rootThread, createdThread, input, tmuxResult)
+	}
 	if !isTmuxReady(tmuxResult) {
 		return createdThread, attachInfo, tmuxResult, nil
 	}
@@ -694,19 +780,7 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 		return store.Thread{}, nil, nil, fmt.Errorf("failed to start pipe-pane: %w", ppErr)
 	}
 
-	launchCodex := boolValueOrDefault(input.LaunchCodex, true)
-	launchCommand := strings.TrimSpace(input.LaunchCommand)
-	if launchCommand == "" && launchCodex {
-		initialPrompt := strings.TrimSpace(input.InitialPrompt)
-		if initialPrompt == "" {
-			initialPrompt = service.defaultChildPrompt(input.SessionID, rootThread.ID, createdThread, input)
-		}
-		if strings.TrimSpace(input.CodexCommand) != "" {
-			launchCommand = service.defaultCodexLaunchCommand(workdir, input.CodexCommand, resolvedGuidePath, initialPrompt)
-		} else {
-			launchCommand = service.defaultAgentsRunnerLaunchCommand(workdir, input.SessionID, createdThread, role, initialPrompt)
-		}
-	}
+	launchCommand := service.childLaunchCommand(workdir, rootThread.ID, createdThread, input)
 	// Persist pane/log metadata to DB BEFORE launching command or polling status.
 	// waitUntilStatus reads TmuxPaneID and LogFilePath from DB, so they must be
 	// written first to avoid a NULL-field race condition.
@@ -732,8 +806,32 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 		}
 	}
 
+	return service.finishChildLaunch(ctx, session, updatedThread, launchCommand, input.SkipReadyCheck, tmuxResult, cleanupOnFailure)
+}
+
+// childLaunchCommand returns the command that starts the child agent: the
+// explicit launch_command, else codex or the agents runner with the initial
+// prompt. It is empty when launch_codex is false and nothing was given.
+func (service *Service) childLaunchCommand(workdir string, rootThreadID int64, thread store.Thread, input threadChildSpawnInput) string {
+	launchCommand := strings.TrimSpace(input.LaunchCommand)
+	if launchCommand != "" || !boolValueOrDefault(input.LaunchCodex, true) {
+		return launchCommand
+	}
+	initialPrompt := strings.TrimSpace(input.InitialPrompt)
+	if initialPrompt == "" {
+		initialPrompt = service.defaultChildPrompt(input.SessionID, rootThreadID, thread, input)
+	}
+	if strings.TrimSpace(input.CodexCommand) != "" {
+		return service.defaultCodexLaunchCommand(workdir, input.CodexCommand, valueOrEmpty(thread.AgentGuidePath), initialPrompt)
+	}
+	return service.agentLaunchCommand(workdir, input.SessionID, thread, initialPrompt)
+}
+
+// finishChildLaunch waits for the launched agent to become ready and records
+// the resulting thread status.
+func (service *Service) finishChildLaunch(ctx context.Context, session store.Session, updatedThread store.Thread, launchCommand string, skipReadyCheck *bool, tmuxResult map[string]any, cleanupOnFailure func()) (store.Thread, map[string]any, map[string]any, error) {
 	readyCheckResult := "skipped"
-	if strings.TrimSpace(launchCommand) != "" && !boolValueOrDefault(input.SkipReadyCheck, false) {
+	if strings.TrimSpace(launchCommand) != "" && !boolValueOrDefault(skipReadyCheck, false) {
 		readyTimeout := 30 * time.Second
 		achievedStatus, _, waitErr := service.waitUntilStatus(ctx, updatedThread.ID, []provider.Status{
 			provider.StatusIdle,
@@ -765,7 +863,7 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 			threadStatus = "running"
 		}
 	}
-	updatedThread, err = service.store.UpdateThread(ctx, updatedThread.ID, store.ThreadUpdateArgs{
+	updatedThread, err := service.store.UpdateThread(ctx, updatedThread.ID, store.ThreadUpdateArgs{
 		Status: &threadStatus,
 	})
 	if err != nil {
@@ -773,7 +871,7 @@ func (service *Service) spawnChildThreadInternal(ctx context.Context, input thre
 		return store.Thread{}, nil, nil, err
 	}
 
-	attachInfo, err = service.buildAttachInfo(ctx, session, &updatedThread)
+	attachInfo, err := service.buildAttachInfo(ctx, session, &updatedThread)
 	if err != nil {
 		cleanupOnFailure()
 		return store.Thread{}, nil, nil, err
@@ -1000,6 +1098,9 @@ func (service *Service) ensureChildPaneCapacity(ctx context.Context, sessionID i
 				TmuxPaneID:     pointerToString(""),
 				TmuxWindowName: pointerToString(""),
 			})
+			if _, err := service.releaseThreadLocks(ctx, childThread.ID, "pane_lost"); err != nil {
+				return err
+			}
 			continue
 		}
 		occupied++
@@ -1380,7 +1481,25 @@ func normalizePathForThread(path string) string {
 	return filepath.Clean(trimmedPath)
 }
 
+// readFileTail returns the last maxBytes of a thread log, reaching back into
+// rotated segments when the live file is shorter.
 func readFileTail(path string, maxBytes int64) (string, error) {
+	tail, err := readLiveFileTail(path, maxBytes)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if int64(len(tail)) >= maxBytes {
+		return tail, nil
+	}
+	// Unreadable segments only shorten the tail.
+	older, _ := readLogSegmentsTail(path, maxBytes-int64(len(tail)))
+	if older == "" && err != nil {
+		return "", err
+	}
+	return older + tail, nil
+}
+
+func readLiveFileTail(path string, maxBytes int64) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
@@ -1431,9 +1550,9 @@ func (service *Service) waitUntilStatus(ctx context.Context, threadID int64, tar
 			return "", "", fmt.Errorf("failed to get thread %d: %w", threadID, err)
 		}
 
-		paneID := strings.TrimSpace(valueOrEmpty(thread.TmuxPaneID))
-		if paneID == "" {
-			return "", "", fmt.Errorf("thread %d has no tmux pane", threadID)
+		runner, err := service.runnerFor(thread)
+		if err != nil {
+			return "", "", err
 		}
 
 		providerTypeName := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
@@ -1456,7 +1575,7 @@ func (service *Service) waitUntilStatus(ctx context.Context, threadID int64, tar
 				}
 			}
 
-			captured, captureErr := service.tmux.CaptureHistory(timeoutCtx, paneID, 200)
+			captured, captureErr := runner.Capture(timeoutCtx, thread, 200)
 			if captureErr == nil && captured != "" {
 				fullStatus := p.GetStatus(captured)
 				if targetSet[fullStatus] {
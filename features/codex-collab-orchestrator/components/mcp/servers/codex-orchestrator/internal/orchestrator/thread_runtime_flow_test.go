@@ -1,9 +1,12 @@
 package orchestrator
 
 import (
+	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/headless"
 	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
 )
 
@@ -66,3 +69,56 @@ func TestIsChildThreadReusable(t *testing.T) {
 		t.Fatalf("expected running to not be reusable")
 	}
 }
+
+func TestChildThreadStatusLeavesLocksOfALostAgent(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	root, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "session-root", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create root thread: %v", err)
+	}
+	worker, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, ParentThreadID: &root.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create worker thread: %v", err)
+	}
+	backend := runnerBackendHeadless
+	if _, err := service.store.UpdateThread(ctx, worker.ID, store.ThreadUpdateArgs{RunnerBackend: &backend}); err != nil {
+		t.Fatalf("failed to set backend: %v", err)
+	}
+	if _, err := service.headless.Start(headless.StartOptions{ThreadID: worker.ID, Command: "true", LogFilePath: service.threadLogFilePath(worker)}); err != nil {
+		t.Fatalf("failed to start agent: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); service.headless.Alive(worker.ID); time.Sleep(20 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("agent did not exit")
+		}
+	}
+	if _, err := service.acquireLock(ctx, lockAcquireInput{ScopeType: "file", ScopePath: "api.go", OwnerSessionID: &session.ID, OwnerThreadID: &worker.ID}); err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	// Reading the status of an agent whose process is gone changes nothing;
+	// the supervisor or thread.child.stop release its locks.
+	status, err := service.childThreadStatus(ctx, threadChildStatusInput{ThreadID: worker.ID})
+	if err != nil || status["process_alive"] != false {
+		t.Fatalf("expected a lost agent, got %+v (%v)", status, err)
+	}
+	if locks, err := service.store.ListLocks(ctx, store.LockFilter{State: "active", OwnerThreadID: &worker.ID}); err != nil || len(locks) != 1 {
+		t.Fatalf("expected thread.child.status to leave the lock, got %+v (%v)", locks, err)
+	}
+
+	stopped, err := service.stopChildThread(ctx, threadChildStopInput{ThreadID: worker.ID})
+	if err != nil || stopped["locks_released"] != 1 || stopped["locks_release_error"] != nil {
+		t.Fatalf("expected thread.child.stop to release the lock, got %+v (%v)", stopped, err)
+	}
+}
@@ -0,0 +1,229 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/provider"
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+const (
+	// transcriptLogMaxBytes caps how much new log output is parsed in one
+	// step; a longer backlog is parsed in several.
+	transcriptLogMaxBytes = 16 * 1024 * 1024
+
+	defaultTranscriptPageSize = 50
+	maxTranscriptPageSize     = 500
+
+	transcriptFormatTurns = "turns"
+	transcriptFormatText  = "text"
+)
+
+// providerForThread returns the live provider of the thread or, for stopped
+// threads, a fresh one of the recorded type.
+func (service *Service) providerForThread(thread store.Thread) (provider.Provider, error) {
+	if p, ok := service.provider.Get(thread.ID); ok {
+		return p, nil
+	}
+	providerType := strings.TrimSpace(valueOrEmpty(thread.ProviderType))
+	if providerType == "" {
+		providerType = defaultProviderType
+	}
+	return service.provider.New(providerType)
+}
+
+// refreshThreadTranscript parses the log output written since the stored
+// cursor and appends it to the transcript, so turns survive log rotation and
+// retention. It reports whether the stored transcript changed.
+func (service *Service) refreshThreadTranscript(ctx context.Context, thread store.Thread) (bool, error) {
+	logPath := strings.TrimSpace(valueOrEmpty(thread.LogFilePath))
+	if logPath == "" {
+		return false, nil
+	}
+	p, err := service.providerForThread(thread)
+	if err != nil {
+		return false, err
+	}
+	cursor, err := service.store.GetThreadTranscriptCursor(ctx, thread.ID)
+	if err != nil {
+		return false, err
+	}
+	pieces, err := readLogFrom(logPath, cursor)
+	if err != nil {
+		return false, nil
+	}
+
+	changed := false
+	for {
+		content, next, rest, ok := takeLogLines(pieces, transcriptLogMaxBytes)
+		if !ok {
+			return changed, nil
+		}
+		parsed := provider.ParseTranscript(p, content)
+		turns := make([]store.TranscriptTurnArgs, 0, len(parsed))
+		for _, turn := range parsed {
+			turns = append(turns, store.TranscriptTurnArgs{Role: turn.Role, Content: turn.Content})
+		}
+		// Output before any turn marker carries on the turn that was still
+		// open when the previous chunk was parsed.
+		continueLast := cursor != (store.TranscriptCursor{}) && len(parsed) > 0 && parsed[0].Role == provider.TurnRoleOutput
+		appended, err := service.store.AppendThreadTranscript(ctx, store.TranscriptAppendArgs{
+			ThreadID:     thread.ID,
+			From:         cursor,
+			To:           next,
+			Turns:        turns,
+			ContinueLast: continueLast,
+		})
+		if err != nil || !appended {
+			return changed, err
+		}
+		changed = changed || len(turns) > 0
+		cursor = next
+		pieces = rest
+	}
+}
+
+// logPiece is the unread part of one piece of a thread log; see
+// store.TranscriptCursor for the numbering.
+type logPiece struct {
+	number int
+	start  int64
+	data   []byte
+}
+
+// readLogFrom returns what a thread log holds past the cursor: the rotated
+// segments from the cursor's piece on, then the live log. Segments already
+// removed by retention are skipped.
+func readLogFrom(logPath string, cursor store.TranscriptCursor) ([]logPiece, error) {
+	segments, err := listLogSegments(logPath)
+	if err != nil {
+		return nil, err
+	}
+	first := max(cursor.Segment, 1)
+	liveNumber := first
+	pieces := make([]logPiece, 0, len(segments)+1)
+	for _, segment := range segments {
+		liveNumber = max(liveNumber, segment.Number+1)
+		if segment.Number < first {
+			continue
+		}
+		data, err := readLogSegment(segment.Path)
+		if err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, logPiece{number: segment.Number, data: data})
+	}
+	live, err := os.ReadFile(logPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	pieces = append(pieces, logPiece{number: liveNumber, data: live})
+	if pieces[0].number == cursor.Segment {
+		skip := min(cursor.Offset, int64(len(pieces[0].data)))
+		pieces[0].start = skip
+		pieces[0].data = pieces[0].data[skip:]
+	}
+	return pieces, nil
+}
+
+// takeLogLines takes up to maxBytes of complete lines off the front of pieces
+// and returns them with the cursor just past them and the pieces left over.
+// A single line longer than maxBytes is cut. ok is false when no complete
+// line is left; a partial last line waits for the next refresh.
+func takeLogLines(pieces []logPiece, maxBytes int64) (string, store.TranscriptCursor, []logPiece, bool) {
+	var buffer bytes.Buffer
+	for _, piece := range pieces {
+		remaining := maxBytes - int64(buffer.Len())
+		if remaining <= 0 {
+			break
+		}
+		buffer.Write(piece.data[:min(int64(len(piece.data)), remaining)])
+	}
+	data := buffer.Bytes()
+	cut := bytes.LastIndexByte(data, '\n') + 1
+	if cut == 0 {
+		if int64(len(data)) < maxBytes {
+			return "", store.TranscriptCursor{}, pieces, false
+		}
+		cut = len(data)
+	}
+
+	consumed := int64(cut)
+	for len(pieces) > 1 && consumed >= int64(len(pieces[0].data)) {
+		consumed -= int64(len(pieces[0].data))
+		pieces = pieces[1:]
+	}
+	rest := append([]logPiece{{number: pieces[0].number, start: pieces[0].start + consumed, data: pieces[0].data[consumed:]}}, pieces[1:]...)
+	return string(data[:cut]), store.TranscriptCursor{Segment: rest[0].number, Offset: rest[0].start}, rest, true
+}
+
+func (service *Service) threadTranscript(ctx context.Context, input threadTranscriptGetInput) (map[string]any, error) {
+	if input.ThreadID <= 0 {
+		return nil, errors.New("thread_id is required")
+	}
+	format := strings.TrimSpace(input.Format)
+	if format == "" {
+		format = transcriptFormatTurns
+	}
+	if format != transcriptFormatTurns && format != transcriptFormatText {
+		return nil, fmt.Errorf("format must be %s or %s", transcriptFormatTurns, transcriptFormatText)
+	}
+	if input.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultTranscriptPageSize
+	}
+	if limit > maxTranscriptPageSize {
+		limit = maxTranscriptPageSize
+	}
+
+	thread, err := service.store.GetThreadByID(ctx, input.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if boolValueOrDefault(input.Refresh, true) {
+		if _, err := service.refreshThreadTranscript(ctx, thread); err != nil {
+			return nil, err
+		}
+	}
+	turns, total, err := service.store.ListThreadTranscript(ctx, thread.ID, input.Offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"thread_id": thread.ID,
+		"total":     total,
+		"offset":    input.Offset,
+		"limit":     limit,
+	}
+	if next := input.Offset + len(turns); next < total {
+		result["next_offset"] = next
+	}
+	if format == transcriptFormatText {
+		result["text"] = renderTranscript(turns)
+	} else {
+		result["turns"] = turns
+	}
+	return result, nil
+}
+
+// renderTranscript formats turns for reading: a `[seq] role` header above
+// each turn's content.
+func renderTranscript(turns []store.TranscriptTurn) string {
+	var builder strings.Builder
+	for index, turn := range turns {
+		if index > 0 {
+			builder.WriteString("\n\n")
+		}
+		fmt.Fprintf(&builder, "[%d] %s\n%s", turn.Seq, turn.Role, turn.Content)
+	}
+	return builder.String()
+}
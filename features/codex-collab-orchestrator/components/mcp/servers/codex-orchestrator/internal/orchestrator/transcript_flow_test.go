@@ -0,0 +1,100 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cayde/llm/features/codex-collab-orchestrator/components/mcp/servers/codex-orchestrator/internal/store"
+)
+
+func TestThreadTranscriptPagesParsedLog(t *testing.T) {
+	ctx := context.Background()
+	repoPath := t.TempDir()
+	service, err := NewService(repoPath)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	defer service.Close()
+
+	session, err := service.store.OpenSession(ctx, store.SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: repoPath})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := service.store.CreateThread(ctx, store.ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running", ProviderType: "claude_code"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	logPath := filepath.Join(repoPath, "thread_1.log")
+	log := "\x1b[2J> run the tests\r\n\x1b[32m⏺\x1b[0m Bash(go test ./...)\r\n  ⎿  ok\r\n⏺ Tests pass.\r\n> \r\n"
+	if err := os.WriteFile(logPath, []byte(log), 0o644); err != nil {
+		t.Fatalf("failed to write log: %v", err)
+	}
+	if _, err := service.store.UpdateThread(ctx, thread.ID, store.ThreadUpdateArgs{LogFilePath: &logPath}); err != nil {
+		t.Fatalf("failed to bind log: %v", err)
+	}
+
+	page, err := service.threadTranscript(ctx, threadTranscriptGetInput{ThreadID: thread.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("thread.transcript.get failed: %v", err)
+	}
+	turns := page["turns"].([]store.TranscriptTurn)
+	if page["total"] != 3 || page["next_offset"] != 2 || len(turns) != 2 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	if turns[0].Role != "user" || turns[0].Content != "run the tests" || turns[1].Role != "tool" {
+		t.Fatalf("unexpected turns: %+v", turns)
+	}
+
+	if err := os.WriteFile(logPath, []byte(log+"> thanks\r\n⏺ You're welcome.\r\n"), 0o644); err != nil {
+		t.Fatalf("failed to append log: %v", err)
+	}
+	text, err := service.threadTranscript(ctx, threadTranscriptGetInput{ThreadID: thread.ID, Offset: 2, Format: "text"})
+	if err != nil {
+		t.Fatalf("thread.transcript.get text failed: %v", err)
+	}
+	if _, ok := text["next_offset"]; ok || text["total"] != 5 {
+		t.Fatalf("expected last page of 5 turns, got %+v", text)
+	}
+	want := "[3] assistant\nTests pass.\n\n[4] user\nthanks\n\n[5] assistant\nYou're welcome."
+	if text["text"] != want {
+		t.Fatalf("unexpected text export:\n%s", text["text"])
+	}
+
+	// Output after a rotation is appended; turns parsed before it stay, and
+	// unmarked lines carry on the turn that was open. A partial last line
+	// waits for the next refresh.
+	appendLog := func(text string) {
+		t.Helper()
+		logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatalf("failed to open log: %v", err)
+		}
+		defer logFile.Close()
+		if _, err := logFile.WriteString(text); err != nil {
+			t.Fatalf("failed to append log: %v", err)
+		}
+	}
+	appendLog("> next task\r\n⏺ Step one\r\n")
+	if _, _, err := rotateLog(logPath); err != nil {
+		t.Fatalf("failed to rotate log: %v", err)
+	}
+	appendLog("  step two\r\n⏺ partial")
+	rotated, err := service.threadTranscript(ctx, threadTranscriptGetInput{ThreadID: thread.ID, Format: "text"})
+	if err != nil {
+		t.Fatalf("thread.transcript.get after rotation failed: %v", err)
+	}
+	if rotated["total"] != 7 || !strings.HasPrefix(rotated["text"].(string), "[1] user\nrun the tests") || !strings.HasSuffix(rotated["text"].(string), "[7] assistant\nStep one\nstep two") {
+		t.Fatalf("unexpected transcript after rotation: %+v", rotated)
+	}
+	appendLog(" answer\r\n")
+	if finished, err := service.threadTranscript(ctx, threadTranscriptGetInput{ThreadID: thread.ID, Offset: 7, Format: "text"}); err != nil || finished["text"] != "[8] assistant\npartial answer" {
+		t.Fatalf("expected the completed line as a new turn, got %+v (%v)", finished, err)
+	}
+
+	if _, err := service.threadTranscript(ctx, threadTranscriptGetInput{ThreadID: thread.ID, Format: "html"}); err == nil || !strings.Contains(err.Error(), "format") {
+		t.Fatalf("expected format error, got %v", err)
+	}
+}
@@ -76,7 +76,7 @@ func (p *CodexProvider) ExtractLastResponse(rawOutput string) string {
 	inResponse := false
 	for i := len(lines) - 1; i >= 0; i-- {
 		line := strings.TrimSpace(lines[i])
-		if codexIdlePrompt.MatchString(line) && line == strings.TrimSpace(codexIdlePrompt.ReplaceAllString(line, "")) {
+		if codexIdlePrompt.MatchString(line) && strings.TrimSpace(codexIdlePrompt.ReplaceAllString(line, "")) == "" {
 			continue
 		}
 		if codexAssistant.MatchString(line) {
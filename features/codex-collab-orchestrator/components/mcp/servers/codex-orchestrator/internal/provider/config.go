@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigTailLines = 15
+
+// ConfigFile is the shape of .codex-orch/providers.yaml.
+//
+//	providers:
+//	  aider:
+//	    launch_command: "aider --no-pretty --message {prompt}"
+//	    idle_pattern: '^>\s*$'
+//	    processing_pattern: 'Tokens:|Thinking'
+//	    waiting_pattern: '\(Y\)es/\(N\)o'
+//	    error_pattern: '^(?:Error|Traceback)'
+//	    response:
+//	      start_pattern: '^aider>'
+//	    exit_command: "/exit"
+type ConfigFile struct {
+	Providers map[string]Definition `yaml:"providers"`
+}
+
+// Definition declares a CLI agent without Go code. Patterns are Go regular
+// expressions matched against ANSI-stripped output with (?m) enabled.
+type Definition struct {
+	// LaunchCommand is run inside the thread workdir. Placeholders {prompt},
+	// {guide_path}, {workdir} and {role} are shell-quoted; {thread_id} and
+	// {session_id} are numbers.
+	LaunchCommand     string       `yaml:"launch_command" json:"launch_command,omitempty"`
+	IdlePattern       string       `yaml:"idle_pattern" json:"idle_pattern"`
+	ProcessingPattern string       `yaml:"processing_pattern" json:"processing_pattern,omitempty"`
+	WaitingPattern    string       `yaml:"waiting_pattern" json:"waiting_pattern,omitempty"`
+	ErrorPattern      string       `yaml:"error_pattern" json:"error_pattern,omitempty"`
+	CompletedPattern  string       `yaml:"completed_pattern" json:"completed_pattern,omitempty"`
+	Response          ResponseRule `yaml:"response" json:"response"`
+	ExitCommand       string       `yaml:"exit_command" json:"exit_command,omitempty"`
+	TailLines         int          `yaml:"tail_lines" json:"tail_lines,omitempty"`
+}
+
+// ResponseRule extracts the last answer: the text after the last line that
+// matches StartPattern, up to the first following line that matches
+// EndPattern (the idle prompt by default). StripPattern is removed from every
+// kept line.
+type ResponseRule struct {
+	StartPattern string `yaml:"start_pattern" json:"start_pattern,omitempty"`
+	EndPattern   string `yaml:"end_pattern" json:"end_pattern,omitempty"`
+	StripPattern string `yaml:"strip_pattern" json:"strip_pattern,omitempty"`
+}
+
+// LoadConfigFile parses a providers.yaml file and validates every definition.
+func LoadConfigFile(path string) (map[string]Definition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config ConfigFile
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	definitions := make(map[string]Definition, len(config.Providers))
+	names := make([]string, 0, len(config.Providers))
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		trimmedName := strings.TrimSpace(name)
+		if trimmedName == "" {
+			return nil, errors.New("provider name must not be empty")
+		}
+		definition := config.Providers[name]
+		if _, err := NewConfigProvider(trimmedName, definition); err != nil {
+			return nil, fmt.Errorf("provider %s: %w", trimmedName, err)
+		}
+		definitions[trimmedName] = definition
+	}
+	return definitions, nil
+}
+
+// ConfigProvider is a Provider built from a Definition.
+type ConfigProvider struct {
+	name       string
+	definition Definition
+	idle       *regexp.Regexp
+	processing *regexp.Regexp
+	waiting    *regexp.Regexp
+	errorLine  *regexp.Regexp
+	completed  *regexp.Regexp
+	start      *regexp.Regexp
+	end        *regexp.Regexp
+	strip      *regexp.Regexp
+}
+
+func NewConfigProvider(name string, definition Definition) (*ConfigProvider, error) {
+	if strings.TrimSpace(definition.IdlePattern) == "" {
+		return nil, errors.New("idle_pattern is required")
+	}
+	provider := &ConfigProvider{name: name, definition: definition}
+	patterns := []struct {
+		field  string
+		source string
+		target **regexp.Regexp
+	}{
+		{"idle_pattern", definition.IdlePattern, &provider.idle},
+		{"processing_pattern", definition.ProcessingPattern, &provider.processing},
+		{"waiting_pattern", definition.WaitingPattern, &provider.waiting},
+		{"error_pattern", definition.ErrorPattern, &provider.errorLine},
+		{"completed_pattern", definition.CompletedPattern, &provider.completed},
+		{"response.start_pattern", definition.Response.StartPattern, &provider.start},
+		{"response.end_pattern", definition.Response.EndPattern, &provider.end},
+		{"response.strip_pattern", definition.Response.StripPattern, &provider.strip},
+	}
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern.source) == "" {
+			continue
+		}
+		compiled, err := regexp.Compile("(?m)" + pattern.source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", pattern.field, err)
+		}
+		*pattern.target = compiled
+	}
+	if provider.end == nil {
+		provider.end = provider.idle
+	}
+	return provider, nil
+}
+
+func (p *ConfigProvider) Name() string { return p.name }
+
+func (p *ConfigProvider) GetIdlePatternForLog() string { return p.definition.IdlePattern }
+
+func (p *ConfigProvider) ExitCommand() string { return p.definition.ExitCommand }
+
+// LaunchCommand returns the configured launch template (may be empty).
+func (p *ConfigProvider) LaunchCommand() string { return p.definition.LaunchCommand }
+
+// GetStatus checks, in order: waiting and error patterns in the recent tail,
+// then the idle prompt on the last non-empty line (completed when
+// completed_pattern also matches the tail), then the processing pattern.
+// Anything else counts as processing.
+func (p *ConfigProvider) GetStatus(rawOutput string) Status {
+	lines := nonEmptyLines(ansiPattern.ReplaceAllString(rawOutput, ""))
+	if len(lines) == 0 {
+		return StatusProcessing
+	}
+	tailLines := p.definition.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultConfigTailLines
+	}
+	tail := lines
+	if len(tail) > tailLines {
+		tail = tail[len(tail)-tailLines:]
+	}
+	tailText := strings.Join(tail, "\n")
+	lastLine := lines[len(lines)-1]
+
+	if p.waiting != nil && p.waiting.MatchString(tailText) {
+		return StatusWaitingUserAnswer
+	}
+	if p.errorLine != nil && p.errorLine.MatchString(tailText) {
+		return StatusError
+	}
+	if p.idle.MatchString(lastLine) {
+		if p.completed != nil && p.completed.MatchString(tailText) {
+			return StatusCompleted
+		}
+		return StatusIdle
+	}
+	return StatusProcessing
+}
+
+func (p *ConfigProvider) ExtractLastResponse(rawOutput string) string {
+	lines := strings.Split(ansiPattern.ReplaceAllString(rawOutput, ""), "\n")
+
+	startIndex := -1
+	if p.start != nil {
+		for index := len(lines) - 1; index >= 0; index-- {
+			if p.start.MatchString(lines[index]) {
+				startIndex = index
+				break
+			}
+		}
+		if startIndex < 0 {
+			return ""
+		}
+	} else {
+		// Without a start rule the response is the block between the two
+		// most recent idle prompts.
+		idleSeen := 0
+		for index := len(lines) - 1; index >= 0; index-- {
+			if p.idle.MatchString(lines[index]) {
+				idleSeen++
+				if idleSeen == 2 {
+					startIndex = index
+					break
+				}
+			}
+		}
+	}
+
+	responseLines := make([]string, 0)
+	for index := startIndex + 1; index < len(lines); index++ {
+		if p.end.MatchString(lines[index]) {
+			break
+		}
+		responseLines = append(responseLines, lines[index])
+	}
+	if p.start != nil {
+		firstLine := strings.TrimSpace(p.start.ReplaceAllString(lines[startIndex], ""))
+		if firstLine != "" {
+			responseLines = append([]string{firstLine}, responseLines...)
+		}
+	}
+	if p.strip != nil {
+		for index, line := range responseLines {
+			responseLines[index] = p.strip.ReplaceAllString(line, "")
+		}
+	}
+	return strings.TrimSpace(strings.Join(responseLines, "\n"))
+}
+
+func nonEmptyLines(text string) []string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	return lines
+}
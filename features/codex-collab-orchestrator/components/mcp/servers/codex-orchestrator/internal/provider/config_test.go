@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProvidersYAML = `providers:
+  aider:
+    launch_command: "aider --message {prompt}"
+    idle_pattern: '^>\s*$'
+    waiting_pattern: '\(Y\)es/\(N\)o'
+    error_pattern: '^Traceback'
+    completed_pattern: '^Applied edit'
+    response:
+      start_pattern: '^aider:\s*'
+    exit_command: "/exit"
+`
+
+func TestConfigProviderStatusAndResponse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	if err := os.WriteFile(path, []byte(testProvidersYAML), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	manager := NewManager()
+	if err := manager.LoadConfig(path); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if types := manager.Types(); len(types) != 3 || types[0] != "aider" {
+		t.Fatalf("expected aider next to built-ins, got %v", types)
+	}
+
+	p, err := manager.Create(1, "aider")
+	if err != nil {
+		t.Fatalf("failed to create aider provider: %v", err)
+	}
+	if p.ExitCommand() != "/exit" {
+		t.Fatalf("unexpected exit command %q", p.ExitCommand())
+	}
+
+	testCases := []struct {
+		output string
+		want   Status
+	}{
+		{"> \n", StatusIdle},
+		{"> fix the bug\nsearching repo...\n", StatusProcessing},
+		{"> fix the bug\nCreate file? (Y)es/(N)o\n", StatusWaitingUserAnswer},
+		{"> fix the bug\nTraceback (most recent call last):\n", StatusError},
+		{"> fix the bug\naider: patched \x1b[1mmain.go\x1b[0m\nApplied edit to main.go\n> \n", StatusCompleted},
+	}
+	for _, testCase := range testCases {
+		if got := p.GetStatus(testCase.output); got != testCase.want {
+			t.Fatalf("GetStatus(%q) = %s, want %s", testCase.output, got, testCase.want)
+		}
+	}
+	response := p.ExtractLastResponse("> one\naider: old\n> two\naider: patched main.go\nApplied edit to main.go\n> \n")
+	if response != "patched main.go\nApplied edit to main.go" {
+		t.Fatalf("unexpected response %q", response)
+	}
+
+	if err := os.WriteFile(path, []byte("providers:\n  broken:\n    idle_pattern: '('\n"), 0o644); err != nil {
+		t.Fatalf("failed to rewrite config: %v", err)
+	}
+	if err := manager.LoadConfig(path); err == nil {
+		t.Fatalf("expected invalid regex to be rejected")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove config: %v", err)
+	}
+	if err := manager.LoadConfig(path); err != nil {
+		t.Fatalf("expected missing config to be fine: %v", err)
+	}
+	if _, err := manager.New("aider"); err == nil {
+		t.Fatalf("expected aider to be gone after the config was removed")
+	}
+}
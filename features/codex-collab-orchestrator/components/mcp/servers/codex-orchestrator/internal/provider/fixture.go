@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinFixtures is the corpus shipped with the server: one directory per
+// provider, one YAML file per recorded capture.
+//
+//go:embed fixtures
+var builtinFixtures embed.FS
+
+var fixtureNameSanitizer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Fixture is a recorded pane capture together with the status and last
+// response the provider is expected to derive from it. A nil Response means
+// only the status is checked (useful for idle or processing captures).
+type Fixture struct {
+	Name       string  `yaml:"-" json:"name"`
+	Path       string  `yaml:"-" json:"path,omitempty"`
+	Provider   string  `yaml:"provider" json:"provider"`
+	Status     Status  `yaml:"status" json:"status"`
+	Response   *string `yaml:"response,omitempty" json:"response,omitempty"`
+	Source     string  `yaml:"source,omitempty" json:"source,omitempty"`
+	RecordedAt string  `yaml:"recorded_at,omitempty" json:"recorded_at,omitempty"`
+	Capture    string  `yaml:"capture" json:"-"`
+}
+
+// FixtureResult reports one fixture run; Error is set when the provider could
+// not be built.
+type FixtureResult struct {
+	Name             string  `json:"name"`
+	Path             string  `json:"path,omitempty"`
+	Provider         string  `json:"provider"`
+	ExpectedStatus   Status  `json:"expected_status"`
+	Status           Status  `json:"status"`
+	ExpectedResponse *string `json:"expected_response,omitempty"`
+	Response         string  `json:"response"`
+	Pass             bool    `json:"pass"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// ProbeResult is what one provider derives from an unlabelled capture.
+type ProbeResult struct {
+	Provider string `json:"provider"`
+	Status   Status `json:"status,omitempty"`
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BuiltinFixtures returns the embedded corpus.
+func BuiltinFixtures() ([]Fixture, error) {
+	return loadFixtures(builtinFixtures, "fixtures", "builtin:")
+}
+
+// LoadFixtureDir reads a corpus laid out like the built-in one. A missing
+// directory is an empty corpus.
+func LoadFixtureDir(dir string) ([]Fixture, error) {
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	fixtures, err := loadFixtures(os.DirFS(dir), ".", "")
+	if err != nil {
+		return nil, err
+	}
+	for index := range fixtures {
+		fixtures[index].Path = filepath.Join(dir, filepath.FromSlash(fixtures[index].Path))
+	}
+	return fixtures, nil
+}
+
+func loadFixtures(fsys fs.FS, root string, pathPrefix string) ([]Fixture, error) {
+	fixtures := make([]Fixture, 0)
+	err := fs.WalkDir(fsys, root, func(filePath string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() || path.Ext(filePath) != ".yaml" {
+			return nil
+		}
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return err
+		}
+		var fixture Fixture
+		if err := yaml.Unmarshal(content, &fixture); err != nil {
+			return fmt.Errorf("failed to parse fixture %s: %w", filePath, err)
+		}
+		relativePath := strings.TrimPrefix(strings.TrimPrefix(filePath, root), "/")
+		fixture.Name = strings.TrimSuffix(relativePath, ".yaml")
+		fixture.Path = pathPrefix + relativePath
+		if strings.TrimSpace(fixture.Provider) == "" {
+			fixture.Provider = path.Dir(relativePath)
+		}
+		fixtures = append(fixtures, fixture)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(fixtures, func(left, right int) bool { return fixtures[left].Name < fixtures[right].Name })
+	return fixtures, nil
+}
+
+// WriteFixture stores a fixture as <dir>/<provider>/<name>.yaml and returns the
+// path. Existing fixtures are never overwritten.
+func WriteFixture(dir string, fixture Fixture) (string, error) {
+	providerName := fixtureNameSanitizer.ReplaceAllString(strings.TrimSpace(fixture.Provider), "_")
+	name := fixtureNameSanitizer.ReplaceAllString(strings.TrimSpace(fixture.Name), "_")
+	if providerName == "" || name == "" {
+		return "", errors.New("fixture provider and name are required")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, providerName), 0o755); err != nil {
+		return "", err
+	}
+	encoded, err := yaml.Marshal(fixture)
+	if err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(dir, providerName, name+".yaml")
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("fixture already exists: %s", filePath)
+		}
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(encoded); err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// CheckFixture runs the fixture's provider against its capture. Responses are
+// compared after trimming surrounding whitespace.
+func (m *Manager) CheckFixture(fixture Fixture) FixtureResult {
+	result := FixtureResult{
+		Name:           fixture.Name,
+		Path:           fixture.Path,
+		Provider:       fixture.Provider,
+		ExpectedStatus: fixture.Status,
+	}
+	if fixture.Response != nil {
+		expectedResponse := strings.TrimSpace(*fixture.Response)
+		result.ExpectedResponse = &expectedResponse
+	}
+	p, err := m.New(fixture.Provider)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Status = p.GetStatus(fixture.Capture)
+	result.Response = strings.TrimSpace(p.ExtractLastResponse(fixture.Capture))
+	result.Pass = result.Status == result.ExpectedStatus &&
+		(result.ExpectedResponse == nil || result.Response == *result.ExpectedResponse)
+	return result
+}
+
+// Probe runs every known provider against a capture, to see how a new TUI
+// build is read before labelling it.
+func (m *Manager) Probe(capture string) []ProbeResult {
+	results := make([]ProbeResult, 0)
+	for _, providerType := range m.Types() {
+		result := ProbeResult{Provider: providerType}
+		p, err := m.New(providerType)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Status = p.GetStatus(capture)
+			result.Response = strings.TrimSpace(p.ExtractLastResponse(capture))
+		}
+		results = append(results, result)
+	}
+	return results
+}
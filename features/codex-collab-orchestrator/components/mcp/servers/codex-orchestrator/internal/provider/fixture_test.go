@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinFixturesPass(t *testing.T) {
+	fixtures, err := BuiltinFixtures()
+	if err != nil {
+		t.Fatalf("failed to load built-in fixtures: %v", err)
+	}
+	providers := map[string]bool{}
+	manager := NewManager()
+	for _, fixture := range fixtures {
+		providers[fixture.Provider] = true
+		result := manager.CheckFixture(fixture)
+		if !result.Pass {
+			t.Errorf("fixture %s failed: status %q (expected %q), response %q, error %q", fixture.Name, result.Status, result.ExpectedStatus, result.Response, result.Error)
+		}
+	}
+	if !providers["codex"] || !providers["claude_code"] {
+		t.Fatalf("expected fixtures for both built-in providers, got %v", providers)
+	}
+}
+
+func TestWriteFixtureRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	response := "Done.\nAll tests pass."
+	fixture := Fixture{
+		Name:     "thread 7/log",
+		Provider: "codex",
+		Status:   StatusCompleted,
+		Response: &response,
+		Source:   "thread_7.log",
+		Capture:  "You\n  do it\n\ncodex: Done.\n  All tests pass.\n\n› \n",
+	}
+	path, err := WriteFixture(dir, fixture)
+	if err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if !strings.HasSuffix(path, "codex/thread_7_log.yaml") {
+		t.Fatalf("unexpected fixture path %s", path)
+	}
+	if _, err := WriteFixture(dir, fixture); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected duplicate fixture error, got %v", err)
+	}
+
+	loaded, err := LoadFixtureDir(dir)
+	if err != nil {
+		t.Fatalf("failed to load fixture dir: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Name != "codex/thread_7_log" || loaded[0].Path != path || loaded[0].Capture != fixture.Capture {
+		t.Fatalf("unexpected loaded fixtures: %+v", loaded)
+	}
+	if result := NewManager().CheckFixture(loaded[0]); !result.Pass {
+		t.Fatalf("expected round-tripped fixture to pass, got %+v", result)
+	}
+
+	if missing, err := LoadFixtureDir(dir + "/missing"); err != nil || len(missing) != 0 {
+		t.Fatalf("expected empty corpus for a missing dir, got %d (%v)", len(missing), err)
+	}
+}
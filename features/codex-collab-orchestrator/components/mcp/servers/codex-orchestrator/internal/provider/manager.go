@@ -1,20 +1,87 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"sync"
+	"time"
 )
 
-// Manager maps thread IDs to their Provider instances.
+// Manager maps thread IDs to their Provider instances and holds the
+// config-defined provider types loaded from providers.yaml.
 type Manager struct {
-	mu        sync.RWMutex
-	providers map[int64]Provider
+	mu          sync.RWMutex
+	providers   map[int64]Provider
+	definitions map[string]Definition
+
+	configPath    string
+	configModTime time.Time
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		providers: make(map[int64]Provider),
+		providers:   make(map[int64]Provider),
+		definitions: make(map[string]Definition),
+	}
+}
+
+// LoadConfig reads config-defined providers from path. A missing file clears
+// them; an unchanged file (same mtime) is not re-read.
+func (m *Manager) LoadConfig(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.definitions = make(map[string]Definition)
+		m.configPath = path
+		m.configModTime = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	unchanged := m.configPath == path && info.ModTime().Equal(m.configModTime)
+	m.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	definitions, err := LoadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.definitions = definitions
+	m.configPath = path
+	m.configModTime = info.ModTime()
+	return nil
+}
+
+// Definition returns the config definition for a provider type, if any.
+func (m *Manager) Definition(providerType string) (Definition, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	definition, ok := m.definitions[providerType]
+	return definition, ok
+}
+
+// Types lists every selectable provider type: built-ins plus config ones.
+func (m *Manager) Types() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	types := []string{"claude_code", "codex"}
+	for name := range m.definitions {
+		if name != "codex" && name != "claude_code" {
+			types = append(types, name)
+		}
 	}
+	sort.Strings(types)
+	return types
 }
 
 // Register binds a provider to a thread ID.
@@ -41,7 +108,7 @@ func (m *Manager) Remove(threadID int64) {
 
 // Create creates a new provider by type name and registers it.
 func (m *Manager) Create(threadID int64, providerType string) (Provider, error) {
-	p, err := NewByType(providerType)
+	p, err := m.New(providerType)
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +116,16 @@ func (m *Manager) Create(threadID int64, providerType string) (Provider, error)
 	return p, nil
 }
 
-// NewByType creates a Provider by its type name.
+// New builds a provider by type name. A config definition takes precedence
+// over a built-in of the same name so teams can retune codex or claude_code.
+func (m *Manager) New(providerType string) (Provider, error) {
+	if definition, ok := m.Definition(providerType); ok {
+		return NewConfigProvider(providerType, definition)
+	}
+	return NewByType(providerType)
+}
+
+// NewByType creates a built-in Provider by its type name.
 func NewByType(providerType string) (Provider, error) {
 	switch providerType {
 	case "codex":
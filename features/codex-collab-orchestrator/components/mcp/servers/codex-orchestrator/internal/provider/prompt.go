@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	PromptKindYesNo = "yes_no"
+	PromptKindMenu  = "menu"
+)
+
+var (
+	promptMenuOption = regexp.MustCompile(`^\s*[│|]?\s*([❯›>])?\s*(\d+)[.)]\s+(.+?)\s*[│|]?\s*$`)
+	promptYesNo      = regexp.MustCompile(`(?i)[(\[]?\s*\b(?:y/n|yes/no)\b\s*[)\]]?\s*[?:]?\s*$`)
+	promptFrameChars = regexp.MustCompile(`^[\s│|╭╮╰╯─]+|[\s│|╭╮╰╯─]+$`)
+)
+
+// Prompt is the question a waiting agent is blocked on, parsed from its pane.
+type Prompt struct {
+	Kind     string         `json:"kind"`
+	Question string         `json:"question"`
+	Options  []PromptOption `json:"options"`
+}
+
+// PromptOption is one answer. Key is what gets typed to choose it.
+type PromptOption struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Selected bool   `json:"selected,omitempty"`
+}
+
+// Submit reports whether the key must be followed by Enter: numbered menus
+// act on the digit alone, y/n prompts read a line.
+func (prompt Prompt) Submit() bool {
+	return prompt.Kind == PromptKindYesNo
+}
+
+// Option resolves an answer given as a key ("1", "y") or a label, matched
+// case-insensitively.
+func (prompt Prompt) Option(answer string) (PromptOption, error) {
+	answer = strings.TrimSpace(answer)
+	for _, option := range prompt.Options {
+		if option.Key == answer {
+			return option, nil
+		}
+	}
+	for _, option := range prompt.Options {
+		if strings.EqualFold(option.Label, answer) {
+			return option, nil
+		}
+	}
+	keys := make([]string, 0, len(prompt.Options))
+	for _, option := range prompt.Options {
+		keys = append(keys, fmt.Sprintf("%s (%s)", option.Key, option.Label))
+	}
+	return PromptOption{}, fmt.Errorf("%q is not an option of %q: %s", answer, prompt.Question, strings.Join(keys, ", "))
+}
+
+// ParsePrompt reads the pending question from a capture: the last numbered
+// menu (Claude Code `❯ 1.` lists, Codex approval menus) or the last y/n line
+// (Codex `Approve ... (y/n)`), whichever comes later.
+func ParsePrompt(output string) (Prompt, bool) {
+	lines := strings.Split(strings.ReplaceAll(ansiPattern.ReplaceAllString(output, ""), "\r", ""), "\n")
+	for index := len(lines) - 1; index >= 0; index-- {
+		line := lines[index]
+		if promptMenuOption.MatchString(line) {
+			return parseMenuPrompt(lines, index)
+		}
+		if promptYesNo.MatchString(line) {
+			question := cleanPromptLine(promptYesNo.ReplaceAllString(line, ""))
+			return Prompt{
+				Kind:     PromptKindYesNo,
+				Question: question,
+				Options:  []PromptOption{{Key: "y", Label: "Yes"}, {Key: "n", Label: "No"}},
+			}, question != ""
+		}
+	}
+	return Prompt{}, false
+}
+
+// parseMenuPrompt collects the option block ending at last and takes the
+// nearest non-blank line above it as the question.
+func parseMenuPrompt(lines []string, last int) (Prompt, bool) {
+	first := last
+	for first > 0 && promptMenuOption.MatchString(lines[first-1]) {
+		first--
+	}
+	prompt := Prompt{Kind: PromptKindMenu, Options: make([]PromptOption, 0, last-first+1)}
+	for _, line := range lines[first : last+1] {
+		match := promptMenuOption.FindStringSubmatch(line)
+		prompt.Options = append(prompt.Options, PromptOption{Key: match[2], Label: match[3], Selected: match[1] != ""})
+	}
+	for index := first - 1; index >= 0 && prompt.Question == ""; index-- {
+		prompt.Question = cleanPromptLine(lines[index])
+	}
+	return prompt, len(prompt.Options) > 0
+}
+
+func cleanPromptLine(line string) string {
+	return strings.TrimSpace(promptFrameChars.ReplaceAllString(line, ""))
+}
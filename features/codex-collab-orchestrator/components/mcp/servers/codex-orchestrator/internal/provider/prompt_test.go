@@ -0,0 +1,55 @@
+package provider
+
+import "testing"
+
+func TestParsePrompt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		kind     string
+		question string
+		keys     string
+		selected string
+	}{
+		{"codex y/n", "You\n  clean the build dir\n\nAllow command `rm -rf build`? (y/n)\n", PromptKindYesNo, "Allow command `rm -rf build`?", "yn", ""},
+		{"bracketed y/n", "Approve running go test? [y/n]", PromptKindYesNo, "Approve running go test?", "yn", ""},
+		{"claude menu", "⏺ Bash(rm -rf build)\n\n Do you want to proceed?\n ❯ 1. Yes\n   2. Yes, and don't ask again this session\n   3. No, and tell Claude what to do differently (esc)\n", PromptKindMenu, "Do you want to proceed?", "123", "1"},
+		{"boxed menu", "│ Do you want to make this edit? │\n│ \x1b[1m❯ 1. Yes\x1b[0m │\n│   2. No  │\n╰──────╯\n", PromptKindMenu, "Do you want to make this edit?", "12", "1"},
+	}
+	for _, testCase := range testCases {
+		prompt, ok := ParsePrompt(testCase.output)
+		if !ok {
+			t.Fatalf("%s: expected a prompt", testCase.name)
+		}
+		keys, selected := "", ""
+		for _, option := range prompt.Options {
+			keys += option.Key
+			if option.Selected {
+				selected += option.Key
+			}
+		}
+		if prompt.Kind != testCase.kind || prompt.Question != testCase.question || keys != testCase.keys || selected != testCase.selected {
+			t.Fatalf("%s: unexpected prompt %+v", testCase.name, prompt)
+		}
+	}
+
+	if _, ok := ParsePrompt("codex: all done\n› "); ok {
+		t.Fatalf("expected no prompt in an idle pane")
+	}
+}
+
+func TestPromptOption(t *testing.T) {
+	prompt, _ := ParsePrompt(" Do you want to proceed?\n ❯ 1. Yes\n   2. No\n")
+	if option, err := prompt.Option("no"); err != nil || option.Key != "2" {
+		t.Fatalf("expected label match to pick option 2, got %+v (%v)", option, err)
+	}
+	if option, err := prompt.Option("1"); err != nil || option.Label != "Yes" {
+		t.Fatalf("expected key match to pick option 1, got %+v (%v)", option, err)
+	}
+	if _, err := prompt.Option("3"); err == nil {
+		t.Fatalf("expected unknown option to be rejected")
+	}
+	if prompt.Submit() {
+		t.Fatalf("menus act on the key alone")
+	}
+}
@@ -0,0 +1,280 @@
+package provider
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	TurnRoleUser      = "user"
+	TurnRoleAssistant = "assistant"
+	TurnRoleTool      = "tool"
+	// TurnRoleOutput holds text no turn rule claimed, e.g. the whole log of a
+	// provider without transcript rules.
+	TurnRoleOutput = "output"
+)
+
+// maxRedrawBlockLines bounds how long a repainted block may be and still be
+// recognized as a duplicate of the block right before it.
+const maxRedrawBlockLines = 80
+
+var (
+	terminalEscapes = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]|\x1b[()][0-9A-Za-z]`)
+	controlChars    = regexp.MustCompile(`[\x00-\x07\x0b\x0c\x0e-\x1f\x7f]`)
+	redrawDigits    = regexp.MustCompile(`\d+`)
+	transcriptNoise = regexp.MustCompile(`^[\s─━═╭╮╰╯│┃]*$`)
+
+	codexToolLine = regexp.MustCompile(`^\s*(?:[•⏺]\s*)?(?:Ran|Running|Explored|Edited|Read|exec|\$)\s+\S`)
+	ccUserLine    = regexp.MustCompile(`^>[\s\xa0]+(\S.*)$`)
+	ccToolLine    = regexp.MustCompile(`^⏺\s+([A-Z][A-Za-z]*\(.*)$`)
+	ccResultLine  = regexp.MustCompile(`^\s*⎿\s*`)
+	ccMarkerLine  = regexp.MustCompile(`^⏺\s+(.*)$`)
+)
+
+// Turn is one entry of a transcript: a user directive, an assistant answer or
+// a tool call with its output.
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TranscriptParser is implemented by providers that can split their cleaned
+// terminal output into turns.
+type TranscriptParser interface {
+	ParseTranscript(clean string) []Turn
+}
+
+// StripEscapes removes CSI, OSC and charset escape sequences.
+func StripEscapes(text string) string {
+	return terminalEscapes.ReplaceAllString(text, "")
+}
+
+// CleanTerminalOutput turns a pipe-pane byte stream into readable text:
+// escapes are stripped, carriage-return and backspace overwrites are applied,
+// lines that only redraw a spinner or timer replace their predecessor, and a
+// block repainted right after itself is kept once.
+func CleanTerminalOutput(raw string) string {
+	text := StripEscapes(raw)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := make([]string, 0, strings.Count(text, "\n")+1)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(controlChars.ReplaceAllString(applyOverwrites(line), ""), " \t\u00a0")
+		if line == "" {
+			if len(lines) > 0 && lines[len(lines)-1] != "" {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		if len(lines) > 0 {
+			previous := lines[len(lines)-1]
+			if previous == line {
+				continue
+			}
+			if redrawDigits.MatchString(line) && redrawDigits.ReplaceAllString(previous, "#") == redrawDigits.ReplaceAllString(line, "#") {
+				lines[len(lines)-1] = line
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(dropRepaintedBlocks(lines), "\n"))
+}
+
+// applyOverwrites keeps what a terminal would show after \r returns to the
+// start of the line and \b erases the previous rune.
+func applyOverwrites(line string) string {
+	if index := strings.LastIndex(line, "\r"); index >= 0 {
+		after := line[index+1:]
+		if strings.TrimSpace(after) == "" {
+			after = strings.TrimRight(line[:index], "\r")
+			if cut := strings.LastIndex(after, "\r"); cut >= 0 {
+				after = after[cut+1:]
+			}
+		}
+		line = after
+	}
+	if !strings.Contains(line, "\b") {
+		return line
+	}
+	runes := make([]rune, 0, len(line))
+	for _, r := range line {
+		if r == '\b' {
+			if len(runes) > 0 {
+				runes = runes[:len(runes)-1]
+			}
+			continue
+		}
+		runes = append(runes, r)
+	}
+	return string(runes)
+}
+
+// dropRepaintedBlocks skips a run of two or more lines that repeats the run
+// directly before it, which is how full-screen TUIs repaint.
+func dropRepaintedBlocks(lines []string) []string {
+	kept := make([]string, 0, len(lines))
+	for index := 0; index < len(lines); {
+		skipped := 0
+		for size := min(maxRedrawBlockLines, len(kept), len(lines)-index); size >= 2; size-- {
+			if kept[len(kept)-size] != lines[index] {
+				continue
+			}
+			if equalLines(kept[len(kept)-size:], lines[index:index+size]) {
+				skipped = size
+				break
+			}
+		}
+		if skipped > 0 {
+			index += skipped
+			continue
+		}
+		kept = append(kept, lines[index])
+		index++
+	}
+	return kept
+}
+
+func equalLines(left, right []string) bool {
+	for index := range left {
+		if left[index] != right[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTranscript cleans raw terminal output and splits it with the
+// provider's transcript rules. Providers without rules yield a single output
+// turn holding the cleaned text.
+func ParseTranscript(p Provider, raw string) []Turn {
+	clean := CleanTerminalOutput(raw)
+	if clean == "" {
+		return nil
+	}
+	if parser, ok := p.(TranscriptParser); ok {
+		return parser.ParseTranscript(clean)
+	}
+	return []Turn{{Role: TurnRoleOutput, Content: clean}}
+}
+
+// turnLine classifies one line: start reports a new turn of role whose first
+// line is text; skip drops the line; otherwise text continues the open turn.
+type turnLine struct {
+	start bool
+	skip  bool
+	role  string
+	text  string
+}
+
+// buildTurns groups lines into turns. Lines before the first turn marker are
+// kept as an output turn.
+func buildTurns(clean string, classify func(line string) turnLine) []Turn {
+	turns := make([]Turn, 0)
+	var role string
+	var body []string
+	flush := func() {
+		content := strings.TrimSpace(strings.Join(body, "\n"))
+		if content != "" {
+			turns = append(turns, Turn{Role: role, Content: content})
+		}
+		body = body[:0]
+	}
+	role = TurnRoleOutput
+	for _, line := range strings.Split(clean, "\n") {
+		entry := classify(line)
+		if entry.skip {
+			continue
+		}
+		if entry.start {
+			flush()
+			role = entry.role
+		}
+		body = append(body, entry.text)
+	}
+	flush()
+	return turns
+}
+
+// ParseTranscript splits Codex output on `You` user blocks, `codex:` answers
+// and `Ran`/`$` command lines; the bare composer prompt is dropped.
+func (p *CodexProvider) ParseTranscript(clean string) []Turn {
+	return buildTurns(clean, func(line string) turnLine {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case transcriptNoise.MatchString(line) && trimmed != "":
+			return turnLine{skip: true}
+		case codexIdlePrompt.MatchString(trimmed) && strings.TrimSpace(codexIdlePrompt.ReplaceAllString(trimmed, "")) == "":
+			return turnLine{skip: true}
+		case codexUserPrefix.MatchString(line):
+			return turnLine{start: true, role: TurnRoleUser, text: strings.TrimLeft(strings.TrimPrefix(line, "You"), " :")}
+		case codexAssistant.MatchString(line):
+			return turnLine{start: true, role: TurnRoleAssistant, text: strings.TrimSpace(line[strings.Index(line, ":")+1:])}
+		case codexProcessing.MatchString(line) && strings.Contains(line, "esc to interrupt"):
+			return turnLine{skip: true}
+		case codexToolLine.MatchString(line):
+			return turnLine{start: true, role: TurnRoleTool, text: strings.TrimLeft(trimmed, "•⏺ ")}
+		}
+		return turnLine{text: line}
+	})
+}
+
+// ParseTranscript splits Claude Code output on `> ` user prompts and `⏺`
+// markers; a marker followed by `Name(...)` is a tool call whose `⎿` result
+// lines belong to it.
+func (p *ClaudeCodeProvider) ParseTranscript(clean string) []Turn {
+	return buildTurns(clean, func(line string) turnLine {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case transcriptNoise.MatchString(line) && trimmed != "", ccSeparator.MatchString(trimmed):
+			return turnLine{skip: true}
+		case ccProcessing.MatchString(line):
+			return turnLine{skip: true}
+		case ccUserLine.MatchString(trimmed):
+			return turnLine{start: true, role: TurnRoleUser, text: ccUserLine.FindStringSubmatch(trimmed)[1]}
+		case trimmed == ">":
+			return turnLine{skip: true}
+		case ccToolLine.MatchString(trimmed):
+			return turnLine{start: true, role: TurnRoleTool, text: ccToolLine.FindStringSubmatch(trimmed)[1]}
+		case ccMarkerLine.MatchString(trimmed):
+			return turnLine{start: true, role: TurnRoleAssistant, text: ccMarkerLine.FindStringSubmatch(trimmed)[1]}
+		case ccResultLine.MatchString(line):
+			return turnLine{text: ccResultLine.ReplaceAllString(line, "")}
+		}
+		return turnLine{text: trimmed}
+	})
+}
+
+// ParseTranscript uses the definition's patterns: an idle prompt line
+// followed by text opens a user turn and a response.start_pattern line opens
+// an assistant turn. Without a start pattern the output after a user line is
+// the assistant turn.
+func (p *ConfigProvider) ParseTranscript(clean string) []Turn {
+	afterUser := false
+	return buildTurns(clean, func(line string) turnLine {
+		if location := p.idle.FindStringIndex(line); location != nil && location[0] == 0 {
+			rest := strings.TrimSpace(line[location[1]:])
+			if rest == "" {
+				return turnLine{skip: true}
+			}
+			afterUser = true
+			return turnLine{start: true, role: TurnRoleUser, text: rest}
+		}
+		if p.start != nil && p.start.MatchString(line) {
+			afterUser = false
+			return turnLine{start: true, role: TurnRoleAssistant, text: p.stripLine(p.start.ReplaceAllString(line, ""))}
+		}
+		if afterUser && p.start == nil && strings.TrimSpace(line) != "" {
+			afterUser = false
+			return turnLine{start: true, role: TurnRoleAssistant, text: p.stripLine(line)}
+		}
+		return turnLine{text: p.stripLine(line)}
+	})
+}
+
+func (p *ConfigProvider) stripLine(line string) string {
+	if p.strip != nil {
+		return p.strip.ReplaceAllString(line, "")
+	}
+	return line
+}
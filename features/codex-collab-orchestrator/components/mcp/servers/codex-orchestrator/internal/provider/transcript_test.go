@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCleanTerminalOutput(t *testing.T) {
+	raw := "\x1b[?2004h\x1b]0;codex\x07\x1b[1mYou\x1b[0m\r\n" +
+		"fix it\r\n" +
+		"\r\n\r\n\r\n" +
+		"Working (1s)\rWorking (2s)\r\n" +
+		"Working (3s)\r\n" +
+		"Working (3s)\r\n" +
+		"typo\b\b\bpe\r\n" +
+		"line a\nline b\nline a\nline b\n" +
+		"done\n"
+	want := "You\nfix it\n\nWorking (3s)\ntpe\nline a\nline b\ndone"
+	if got := CleanTerminalOutput(raw); got != want {
+		t.Fatalf("unexpected clean output:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestParseTranscriptByProvider(t *testing.T) {
+	config, err := NewConfigProvider("aider", Definition{IdlePattern: `^>\s*`})
+	if err != nil {
+		t.Fatalf("failed to build config provider: %v", err)
+	}
+	testCases := []struct {
+		name     string
+		provider Provider
+		raw      string
+		want     []Turn
+	}{
+		{
+			"codex",
+			NewCodexProvider(),
+			"banner\nYou\nfix the test\n• Working (2s • esc to interrupt)\n• Ran go test ./...\n  ok\ncodex: Fixed the test.\nAll green.\n› \n",
+			[]Turn{
+				{Role: TurnRoleOutput, Content: "banner"},
+				{Role: TurnRoleUser, Content: "fix the test"},
+				{Role: TurnRoleTool, Content: "Ran go test ./...\n  ok"},
+				{Role: TurnRoleAssistant, Content: "Fixed the test.\nAll green."},
+			},
+		},
+		{
+			"claude_code",
+			NewClaudeCodeProvider(),
+			"> list files\n\x1b[32m⏺\x1b[0m Bash(ls)\n  ⎿  a.go\n     b.go\n⏺ There are two files.\n✻ Thinking… (esc to interrupt)\n────────\n> \n",
+			[]Turn{
+				{Role: TurnRoleUser, Content: "list files"},
+				{Role: TurnRoleTool, Content: "Bash(ls)\na.go\nb.go"},
+				{Role: TurnRoleAssistant, Content: "There are two files."},
+			},
+		},
+		{
+			"config",
+			config,
+			"> add a flag\nAdded --verbose.\n> \n",
+			[]Turn{
+				{Role: TurnRoleUser, Content: "add a flag"},
+				{Role: TurnRoleAssistant, Content: "Added --verbose."},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		if got := ParseTranscript(testCase.provider, testCase.raw); !reflect.DeepEqual(got, testCase.want) {
+			t.Fatalf("%s: got %+v, want %+v", testCase.name, got, testCase.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	usageNumber     = `(\d[\d,]*(?:\.\d+)?[kKmM]?)`
+	codexTokenUsage = regexp.MustCompile(`(?i)token usage:\s*total=` + usageNumber + `\s+input=` + usageNumber + `(?:\s*\(\+\s*` + usageNumber + `\s+cached\))?\s+output=` + usageNumber)
+	codexTokensUsed = regexp.MustCompile(`(?i)` + usageNumber + `\s+tokens used|tokens used:?\s*` + usageNumber)
+	ccTotalCost     = regexp.MustCompile(`(?i)total cost:\s*\$(\d+(?:\.\d+)?)`)
+	ccUsageByModel  = regexp.MustCompile(`(?i)usage by model:`)
+	ccModelUsage    = regexp.MustCompile(`(?i)` + usageNumber + `\s+input,\s*` + usageNumber + `\s+output(?:,\s*` + usageNumber + `\s+cache read)?(?:,\s*` + usageNumber + `\s+cache write)?`)
+)
+
+// Usage is what an agent reports about its own consumption. Counts are
+// cumulative for the agent process; CostUSD is nil when the CLI prints none.
+type Usage struct {
+	InputTokens       int64    `json:"input_tokens"`
+	CachedInputTokens int64    `json:"cached_input_tokens"`
+	OutputTokens      int64    `json:"output_tokens"`
+	TotalTokens       int64    `json:"total_tokens"`
+	CostUSD           *float64 `json:"cost_usd,omitempty"`
+}
+
+// ParseUsage reads the most recent usage report from agent output: the Codex
+// exit summary (`Token usage: total=... input=... output=...`) or its
+// `tokens used` footer, and the Claude Code `/cost` block (`Total cost: $...`
+// plus per-model `N input, N output, N cache read` lines).
+func ParseUsage(output string) (Usage, bool) {
+	clean := ansiPattern.ReplaceAllString(output, "")
+	usage := Usage{}
+	found := false
+
+	if matches := codexTokenUsage.FindAllStringSubmatch(clean, -1); len(matches) > 0 {
+		match := matches[len(matches)-1]
+		usage.TotalTokens = parseUsageNumber(match[1])
+		usage.InputTokens = parseUsageNumber(match[2])
+		usage.CachedInputTokens = parseUsageNumber(match[3])
+		usage.OutputTokens = parseUsageNumber(match[4])
+		found = true
+	} else if matches := codexTokensUsed.FindAllStringSubmatch(clean, -1); len(matches) > 0 {
+		match := matches[len(matches)-1]
+		usage.TotalTokens = parseUsageNumber(match[1] + match[2])
+		found = true
+	}
+
+	if locations := ccUsageByModel.FindAllStringIndex(clean, -1); len(locations) > 0 {
+		block := clean[locations[len(locations)-1][1]:]
+		modelLines := 0
+		for _, line := range strings.Split(strings.TrimLeft(block, "\n"), "\n") {
+			match := ccModelUsage.FindStringSubmatch(line)
+			if match == nil {
+				break
+			}
+			usage.InputTokens += parseUsageNumber(match[1])
+			usage.OutputTokens += parseUsageNumber(match[2])
+			usage.CachedInputTokens += parseUsageNumber(match[3]) + parseUsageNumber(match[4])
+			modelLines++
+		}
+		if modelLines > 0 && usage.TotalTokens == 0 {
+			usage.TotalTokens = usage.InputTokens + usage.CachedInputTokens + usage.OutputTokens
+			found = true
+		}
+	}
+
+	if matches := ccTotalCost.FindAllStringSubmatch(clean, -1); len(matches) > 0 {
+		if cost, err := strconv.ParseFloat(matches[len(matches)-1][1], 64); err == nil {
+			usage.CostUSD = &cost
+			found = true
+		}
+	}
+	return usage, found
+}
+
+// parseUsageNumber accepts "12,345", "1.2k" and "3M"; anything else is 0.
+func parseUsageNumber(text string) int64 {
+	text = strings.ReplaceAll(strings.TrimSpace(text), ",", "")
+	if text == "" {
+		return 0
+	}
+	multiplier := 1.0
+	switch text[len(text)-1] {
+	case 'k', 'K':
+		multiplier = 1e3
+		text = text[:len(text)-1]
+	case 'm', 'M':
+		multiplier = 1e6
+		text = text[:len(text)-1]
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(value*multiplier + 0.5)
+}
@@ -0,0 +1,45 @@
+package provider
+
+import "testing"
+
+func TestParseUsage(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+		want   Usage
+		cost   float64
+	}{
+		{
+			"codex exit summary",
+			"codex: done\n› /exit\nToken usage: total=1,250 input=1,000 (+ 4,096 cached) output=250 (reasoning 100)\n",
+			Usage{TotalTokens: 1250, InputTokens: 1000, CachedInputTokens: 4096, OutputTokens: 250}, -1,
+		},
+		{
+			"codex footer",
+			"• Working (3s • esc to interrupt)\n  12.5K tokens used · 80% context left\n",
+			Usage{TotalTokens: 12500}, -1,
+		},
+		{
+			"claude cost block",
+			"> /cost\n  ⎿  Total cost:            $0.1234\n     Total duration (API):  6.1s\n     Usage by model:\n         claude-sonnet:  1.2k input, 345 output, 10.5k cache read, 200 cache write\n          claude-haiku:  100 input, 20 output\n\n> \n",
+			Usage{InputTokens: 1300, OutputTokens: 365, CachedInputTokens: 10700, TotalTokens: 12365}, 0.1234,
+		},
+	}
+	for _, testCase := range testCases {
+		usage, ok := ParseUsage(testCase.output)
+		if !ok {
+			t.Fatalf("%s: expected usage", testCase.name)
+		}
+		cost := usage.CostUSD
+		usage.CostUSD = nil
+		if usage != testCase.want {
+			t.Fatalf("%s: got %+v, want %+v", testCase.name, usage, testCase.want)
+		}
+		if (testCase.cost < 0) != (cost == nil) || cost != nil && *cost != testCase.cost {
+			t.Fatalf("%s: unexpected cost %v", testCase.name, cost)
+		}
+	}
+	if _, ok := ParseUsage("codex: all done\n› "); ok {
+		t.Fatalf("expected no usage in plain output")
+	}
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// AnalyzePlan computes effort and scheduling figures for the slices under a
+// plan node. Cancelled slices are ignored; done slices count toward the token
+// budget but drop out of the critical path, waves and conflict checks, which
+// describe the remaining work only.
+func (store *Store) AnalyzePlan(ctx context.Context, planNodeID int64, riskThreshold int) (PlanAnalysis, error) {
+	if _, err := store.GetGraphNodeByID(ctx, planNodeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PlanAnalysis{}, fmt.Errorf("graph node not found: %d", planNodeID)
+		}
+		return PlanAnalysis{}, err
+	}
+
+	allSlices, err := store.ListGraphNodes(ctx, GraphNodeFilter{NodeType: "slice", ParentID: &planNodeID})
+	if err != nil {
+		return PlanAnalysis{}, err
+	}
+	dependentsByNode, err := loadDependentsByNode(ctx, store.database)
+	if err != nil {
+		return PlanAnalysis{}, err
+	}
+
+	analysis := PlanAnalysis{
+		PlanNodeID:    planNodeID,
+		CriticalPath:  PlanCriticalPath{NodeIDs: make([]int64, 0)},
+		Tokens:        PlanTokenBudget{UnestimatedSlices: make([]int64, 0)},
+		Waves:         make([]PlanWave, 0),
+		CriticalRisks: make([]PlanRiskNode, 0),
+		FileConflicts: make([]PlanFileConflict, 0),
+	}
+
+	remainingByID := make(map[int64]GraphNode)
+	for _, slice := range allSlices {
+		if slice.Status == "cancelled" {
+			continue
+		}
+		analysis.SliceCount++
+		tokens := 0
+		if slice.TokenEstimate != nil {
+			tokens = *slice.TokenEstimate
+		} else {
+			analysis.Tokens.UnestimatedSlices = append(analysis.Tokens.UnestimatedSlices, slice.ID)
+		}
+		analysis.Tokens.Total += tokens
+		if slice.Status == "done" {
+			analysis.Tokens.Done += tokens
+			continue
+		}
+		analysis.Tokens.Remaining += tokens
+		remainingByID[slice.ID] = slice
+	}
+	analysis.RemainingSlices = len(remainingByID)
+	if len(remainingByID) == 0 {
+		return analysis, nil
+	}
+
+	remainingIDs := make([]int64, 0, len(remainingByID))
+	for nodeID := range remainingByID {
+		remainingIDs = append(remainingIDs, nodeID)
+	}
+	sort.Slice(remainingIDs, func(left, right int) bool { return remainingIDs[left] < remainingIDs[right] })
+
+	prerequisitesByNode := make(map[int64][]int64)
+	for _, prerequisiteID := range remainingIDs {
+		for _, dependentID := range dependentsByNode[prerequisiteID] {
+			if _, ok := remainingByID[dependentID]; ok {
+				prerequisitesByNode[dependentID] = append(prerequisitesByNode[dependentID], prerequisiteID)
+			}
+		}
+	}
+
+	order, err := topologicalOrder(remainingIDs, prerequisitesByNode)
+	if err != nil {
+		return PlanAnalysis{}, err
+	}
+
+	// Longest token-weighted chain ending at each node; ties prefer the
+	// longer chain, then the lower prerequisite ID (prerequisites are in ID order).
+	pathTokens := make(map[int64]int, len(order))
+	pathLength := make(map[int64]int, len(order))
+	previousOnPath := make(map[int64]int64, len(order))
+	waveByNode := make(map[int64]int, len(order))
+	for _, nodeID := range order {
+		bestTokens, bestLength, bestPrevious := 0, 0, int64(0)
+		wave := 0
+		for _, prerequisiteID := range prerequisitesByNode[nodeID] {
+			if waveByNode[prerequisiteID]+1 > wave {
+				wave = waveByNode[prerequisiteID] + 1
+			}
+			if pathTokens[prerequisiteID] > bestTokens ||
+				(pathTokens[prerequisiteID] == bestTokens && pathLength[prerequisiteID] > bestLength) {
+				bestTokens, bestLength, bestPrevious = pathTokens[prerequisiteID], pathLength[prerequisiteID], prerequisiteID
+			}
+		}
+		pathTokens[nodeID] = bestTokens + valueOrZero(remainingByID[nodeID].TokenEstimate)
+		pathLength[nodeID] = bestLength + 1
+		if bestPrevious != 0 {
+			previousOnPath[nodeID] = bestPrevious
+		}
+		waveByNode[nodeID] = wave
+	}
+
+	endNodeID := int64(0)
+	for _, nodeID := range remainingIDs {
+		if endNodeID == 0 ||
+			pathTokens[nodeID] > pathTokens[endNodeID] ||
+			(pathTokens[nodeID] == pathTokens[endNodeID] && pathLength[nodeID] > pathLength[endNodeID]) {
+			endNodeID = nodeID
+		}
+	}
+	for nodeID := endNodeID; nodeID != 0; nodeID = previousOnPath[nodeID] {
+		analysis.CriticalPath.NodeIDs = append([]int64{nodeID}, analysis.CriticalPath.NodeIDs...)
+	}
+	analysis.CriticalPath.Tokens = pathTokens[endNodeID]
+
+	for _, nodeID := range analysis.CriticalPath.NodeIDs {
+		node := remainingByID[nodeID]
+		if node.RiskLevel != nil && *node.RiskLevel >= riskThreshold {
+			analysis.CriticalRisks = append(analysis.CriticalRisks, PlanRiskNode{
+				NodeID:    node.ID,
+				Title:     node.Title,
+				Status:    node.Status,
+				RiskLevel: *node.RiskLevel,
+			})
+		}
+	}
+	sort.SliceStable(analysis.CriticalRisks, func(left, right int) bool {
+		return analysis.CriticalRisks[left].RiskLevel > analysis.CriticalRisks[right].RiskLevel
+	})
+
+	for _, nodeID := range remainingIDs {
+		wave := waveByNode[nodeID]
+		for len(analysis.Waves) <= wave {
+			analysis.Waves = append(analysis.Waves, PlanWave{Index: len(analysis.Waves), NodeIDs: make([]int64, 0)})
+		}
+		analysis.Waves[wave].NodeIDs = append(analysis.Waves[wave].NodeIDs, nodeID)
+		analysis.Waves[wave].Tokens += valueOrZero(remainingByID[nodeID].TokenEstimate)
+	}
+	for _, wave := range analysis.Waves {
+		if len(wave.NodeIDs) > analysis.MaxParallelism {
+			analysis.MaxParallelism = len(wave.NodeIDs)
+		}
+	}
+	if analysis.CriticalPath.Tokens > 0 {
+		ratio := float64(analysis.Tokens.Remaining) / float64(analysis.CriticalPath.Tokens)
+		analysis.AverageParallelism = math.Round(ratio*100) / 100
+	}
+
+	analysis.FileConflicts = findSliceFileConflicts(remainingIDs, remainingByID, dependentsByNode, waveByNode)
+	return analysis, nil
+}
+
+// topologicalOrder sorts nodeIDs so every prerequisite precedes its
+// dependents, keeping ID order among independent nodes.
+func topologicalOrder(nodeIDs []int64, prerequisitesByNode map[int64][]int64) ([]int64, error) {
+	pending := make(map[int64]int, len(nodeIDs))
+	dependentsByNode := make(map[int64][]int64)
+	for _, nodeID := range nodeIDs {
+		pending[nodeID] = len(prerequisitesByNode[nodeID])
+		for _, prerequisiteID := range prerequisitesByNode[nodeID] {
+			dependentsByNode[prerequisiteID] = append(dependentsByNode[prerequisiteID], nodeID)
+		}
+	}
+
+	order := make([]int64, 0, len(nodeIDs))
+	placed := make(map[int64]bool, len(nodeIDs))
+	for len(order) < len(nodeIDs) {
+		progressed := false
+		for _, nodeID := range nodeIDs {
+			if placed[nodeID] || pending[nodeID] > 0 {
+				continue
+			}
+			placed[nodeID] = true
+			order = append(order, nodeID)
+			for _, dependentID := range dependentsByNode[nodeID] {
+				pending[dependentID]--
+			}
+			progressed = true
+		}
+		if !progressed {
+			return nil, errors.New("dependency cycle detected among plan slices")
+		}
+	}
+	return order, nil
+}
+
+// findSliceFileConflicts reports slice pairs that touch the same files while
+// no dependency orders them, so running them concurrently would collide.
+// Paths are normalized and a directory overlaps every file under it, as with
+// prefix locks; the shared entry is the deeper of the two paths.
+func findSliceFileConflicts(nodeIDs []int64, nodesByID map[int64]GraphNode, dependentsByNode map[int64][]int64, waveByNode map[int64]int) []PlanFileConflict {
+	filesByNode := make(map[int64][]string, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		files := make([]string, 0)
+		seen := make(map[string]bool)
+		if raw := nodesByID[nodeID].AffectedFilesJSON; raw != nil {
+			var decoded []string
+			if err := json.Unmarshal([]byte(*raw), &decoded); err == nil {
+				for _, file := range decoded {
+					if normalized := normalizeScopePath(file); normalized != "" && !seen[normalized] {
+						seen[normalized] = true
+						files = append(files, normalized)
+					}
+				}
+			}
+		}
+		filesByNode[nodeID] = files
+	}
+
+	conflicts := make([]PlanFileConflict, 0)
+	for leftIndex, leftID := range nodeIDs {
+		for _, rightID := range nodeIDs[leftIndex+1:] {
+			shared := make(map[string]bool)
+			for _, leftFile := range filesByNode[leftID] {
+				for _, rightFile := range filesByNode[rightID] {
+					switch {
+					case hasPathPrefix(leftFile, rightFile):
+						shared[leftFile] = true
+					case hasPathPrefix(rightFile, leftFile):
+						shared[rightFile] = true
+					}
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+			sharedFiles := make([]string, 0, len(shared))
+			for file := range shared {
+				sharedFiles = append(sharedFiles, file)
+			}
+			if findDependencyPath(dependentsByNode, leftID, rightID) != nil || findDependencyPath(dependentsByNode, rightID, leftID) != nil {
+				continue
+			}
+			sort.Strings(sharedFiles)
+			conflicts = append(conflicts, PlanFileConflict{
+				NodeIDs:     []int64{leftID, rightID},
+				SharedFiles: sharedFiles,
+				SameWave:    waveByNode[leftID] == waveByNode[rightID],
+			})
+		}
+	}
+	return conflicts
+}
+
+func valueOrZero(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAnalyzePlanCriticalPathWavesAndConflicts(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	planNode, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "in_progress"})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	createSlice := func(title string, status string, tokens int, risk int, files string) GraphNode {
+		slice, err := store.CreateGraphNode(context, GraphNodeCreateArgs{
+			NodeType:          "slice",
+			Facet:             "planning",
+			Title:             title,
+			Status:            status,
+			ParentID:          &planNode.ID,
+			TokenEstimate:     &tokens,
+			RiskLevel:         &risk,
+			AffectedFilesJSON: files,
+		})
+		if err != nil {
+			t.Fatalf("failed to create slice %s: %v", title, err)
+		}
+		return slice
+	}
+
+	// done -> schema -> api -> ui, with docs independent and overlapping ui
+	// through a differently spelled file and a directory.
+	done := createSlice("done", "done", 1000, 1, `["go.mod"]`)
+	schema := createSlice("schema", "todo", 4000, 4, `["db/schema.sql"]`)
+	api := createSlice("api", "todo", 6000, 2, `["api/handler.go"]`)
+	ui := createSlice("ui", "todo", 3000, 5, `["web/app.ts","README.md"]`)
+	docs := createSlice("docs", "todo", 2000, 1, `["./README.md","web/"]`)
+	for _, edge := range []GraphEdgeCreateArgs{
+		{FromNodeID: schema.ID, ToNodeID: done.ID, EdgeType: EdgeTypeDependsOn},
+		{FromNodeID: schema.ID, ToNodeID: api.ID, EdgeType: EdgeTypeBlocks},
+		{FromNodeID: ui.ID, ToNodeID: api.ID, EdgeType: EdgeTypeDependsOn},
+	} {
+		if _, err := store.CreateGraphEdge(context, edge); err != nil {
+			t.Fatalf("failed to create edge %+v: %v", edge, err)
+		}
+	}
+
+	analysis, err := store.AnalyzePlan(context, planNode.ID, 3)
+	if err != nil {
+		t.Fatalf("failed to analyze plan: %v", err)
+	}
+	if analysis.SliceCount != 5 || analysis.RemainingSlices != 4 {
+		t.Fatalf("unexpected slice counts: %+v", analysis)
+	}
+	if analysis.Tokens.Total != 16000 || analysis.Tokens.Done != 1000 || analysis.Tokens.Remaining != 15000 {
+		t.Fatalf("unexpected token budget: %+v", analysis.Tokens)
+	}
+
+	criticalPath := analysis.CriticalPath.NodeIDs
+	if len(criticalPath) != 3 || criticalPath[0] != schema.ID || criticalPath[1] != api.ID || criticalPath[2] != ui.ID || analysis.CriticalPath.Tokens != 13000 {
+		t.Fatalf("unexpected critical path: %+v", analysis.CriticalPath)
+	}
+	if len(analysis.CriticalRisks) != 2 || analysis.CriticalRisks[0].NodeID != ui.ID || analysis.CriticalRisks[1].NodeID != schema.ID {
+		t.Fatalf("unexpected critical risks: %+v", analysis.CriticalRisks)
+	}
+
+	if len(analysis.Waves) != 3 || len(analysis.Waves[0].NodeIDs) != 2 || analysis.MaxParallelism != 2 {
+		t.Fatalf("unexpected waves: %+v", analysis.Waves)
+	}
+	if analysis.AverageParallelism != 1.15 {
+		t.Fatalf("unexpected average parallelism: %v", analysis.AverageParallelism)
+	}
+
+	if len(analysis.FileConflicts) != 1 {
+		t.Fatalf("expected one file conflict, got %+v", analysis.FileConflicts)
+	}
+	conflict := analysis.FileConflicts[0]
+	if conflict.NodeIDs[0] != ui.ID || conflict.NodeIDs[1] != docs.ID || len(conflict.SharedFiles) != 2 || conflict.SharedFiles[0] != "README.md" || conflict.SharedFiles[1] != "web/app.ts" || conflict.SameWave {
+		t.Fatalf("unexpected file conflict: %+v", conflict)
+	}
+}
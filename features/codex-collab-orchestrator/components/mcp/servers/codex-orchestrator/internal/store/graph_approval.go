@@ -0,0 +1,429 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ApprovalDecisionApprove = "approve"
+	ApprovalDecisionReject  = "reject"
+
+	// ApprovalPolicyMergeAgentRequired is the seeded default: one approval,
+	// and it must come from a merge-reviewer.
+	ApprovalPolicyMergeAgentRequired = "merge-agent-required"
+
+	requiredChangesFacet = "review"
+)
+
+const nodeApprovalSelectColumns = `id, node_id, decision, approver, role, comment, created_at, superseded_at`
+
+// ParseApprovalPolicy understands the named presets ("none",
+// "merge-agent-required") and the key=value form
+// "approvals=2;roles=merge-reviewer,plan-architect".
+func ParseApprovalPolicy(raw string) (ApprovalPolicy, error) {
+	trimmed := strings.TrimSpace(raw)
+	policy := ApprovalPolicy{Raw: trimmed, MinApprovals: 1, RequiredRoles: make([]string, 0)}
+	switch trimmed {
+	case "", "none":
+		return policy, nil
+	case ApprovalPolicyMergeAgentRequired:
+		policy.RequiredRoles = []string{"merge-reviewer"}
+		return policy, nil
+	}
+
+	for _, clause := range strings.Split(trimmed, ";") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+		key, value, found := strings.Cut(clause, "=")
+		if !found {
+			return ApprovalPolicy{}, fmt.Errorf("invalid approval_policy clause %q (expected key=value)", clause)
+		}
+		switch strings.TrimSpace(key) {
+		case "approvals":
+			minApprovals, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || minApprovals <= 0 {
+				return ApprovalPolicy{}, fmt.Errorf("invalid approval_policy approvals: %q", value)
+			}
+			policy.MinApprovals = minApprovals
+		case "roles":
+			for _, role := range strings.Split(value, ",") {
+				if role = strings.TrimSpace(role); role != "" {
+					policy.RequiredRoles = append(policy.RequiredRoles, role)
+				}
+			}
+		default:
+			return ApprovalPolicy{}, fmt.Errorf("unknown approval_policy key: %s", key)
+		}
+	}
+	return policy, nil
+}
+
+// EvaluateApprovalPolicy checks the active approve decisions against policy.
+func EvaluateApprovalPolicy(policy ApprovalPolicy, approvals []NodeApproval) ApprovalPolicyStatus {
+	status := ApprovalPolicyStatus{
+		Policy:        policy,
+		ApprovedRoles: make([]string, 0),
+		MissingRoles:  make([]string, 0),
+	}
+	approvedRoles := make(map[string]bool)
+	for _, approval := range approvals {
+		if approval.Decision != ApprovalDecisionApprove || approval.SupersededAt != nil {
+			continue
+		}
+		status.Approvals++
+		approvedRoles[approval.Role] = true
+	}
+	for role := range approvedRoles {
+		status.ApprovedRoles = append(status.ApprovedRoles, role)
+	}
+	sort.Strings(status.ApprovedRoles)
+	for _, role := range policy.RequiredRoles {
+		if !approvedRoles[role] {
+			status.MissingRoles = append(status.MissingRoles, role)
+		}
+	}
+	status.Satisfied = status.Approvals >= policy.MinApprovals && len(status.MissingRoles) == 0
+	return status
+}
+
+// ApproveNode records an approve decision on a node awaiting approval and,
+// once the planning approval policy is satisfied, marks it approved/done and
+// rolls the change up to its parent.
+func (store *Store) ApproveNode(ctx context.Context, args NodeApprovalArgs) (NodeApprovalResult, error) {
+	transaction, node, err := store.beginNodeDecisionTx(ctx, args)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	defer transaction.Rollback()
+
+	approval, err := insertNodeApprovalTx(ctx, transaction, args, ApprovalDecisionApprove)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+
+	policy, err := loadApprovalPolicyTx(ctx, transaction)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	activeApprovals, err := listNodeApprovals(ctx, transaction, node.ID, false)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	status := EvaluateApprovalPolicy(policy, activeApprovals)
+	if status.Satisfied {
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE graph_nodes SET approval_state = 'approved', status = 'done', updated_at = ? WHERE id = ?`,
+			nowTimestamp(),
+			node.ID,
+		); err != nil {
+			return NodeApprovalResult{}, err
+		}
+		// The approved node may complete its parent, which then waits for
+		// its own approval.
+		if node.ParentID != nil {
+			siblingStatuses, err := queryStringColumnTx(ctx, transaction, `SELECT status FROM graph_nodes WHERE parent_id = ? AND deleted_at IS NULL`, *node.ParentID)
+			if err != nil {
+				return NodeApprovalResult{}, err
+			}
+			if _, err := store.rollUpGraphNodeTx(ctx, transaction, *node.ParentID, siblingStatuses); err != nil {
+				return NodeApprovalResult{}, err
+			}
+		}
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return NodeApprovalResult{}, err
+	}
+	node, err = store.getGraphNodeByIDTx(ctx, transaction, node.ID)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return NodeApprovalResult{}, err
+	}
+	return NodeApprovalResult{Approval: approval, Node: node, Status: status}, nil
+}
+
+// RejectNode records a reject decision, marks the node rejected/blocked and
+// appends each required change to the node checklist under the review facet.
+func (store *Store) RejectNode(ctx context.Context, args NodeApprovalArgs) (NodeApprovalResult, error) {
+	transaction, node, err := store.beginNodeDecisionTx(ctx, args)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	defer transaction.Rollback()
+
+	approval, err := insertNodeApprovalTx(ctx, transaction, args, ApprovalDecisionReject)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+
+	timestamp := nowTimestamp()
+	var maxOrderNo sql.NullInt64
+	if err := transaction.QueryRowContext(ctx, `SELECT MAX(order_no) FROM node_checklists WHERE node_id = ?`, node.ID).Scan(&maxOrderNo); err != nil {
+		return NodeApprovalResult{}, err
+	}
+	nextOrderNo := maxOrderNo.Int64 + 1
+	checklistIDs := make([]int64, 0, len(args.RequiredChanges))
+	for _, requiredChange := range args.RequiredChanges {
+		requiredChange = strings.TrimSpace(requiredChange)
+		if requiredChange == "" {
+			continue
+		}
+		result, err := transaction.ExecContext(
+			ctx,
+			`INSERT INTO node_checklists(node_id, item_text, status, order_no, facet, created_at, updated_at)
+			 VALUES(?, ?, 'todo', ?, ?, ?, ?)`,
+			node.ID,
+			requiredChange,
+			nextOrderNo,
+			requiredChangesFacet,
+			timestamp,
+			timestamp,
+		)
+		if err != nil {
+			return NodeApprovalResult{}, err
+		}
+		checklistID, err := result.LastInsertId()
+		if err != nil {
+			return NodeApprovalResult{}, err
+		}
+		checklistIDs = append(checklistIDs, checklistID)
+		nextOrderNo++
+	}
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE graph_nodes SET approval_state = 'rejected', status = 'blocked', updated_at = ? WHERE id = ?`,
+		timestamp,
+		node.ID,
+	); err != nil {
+		return NodeApprovalResult{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return NodeApprovalResult{}, err
+	}
+
+	checklistItems := make([]NodeChecklistItem, 0, len(checklistIDs))
+	for _, checklistID := range checklistIDs {
+		item, err := scanNodeChecklistItem(transaction.QueryRowContext(
+			ctx,
+			`SELECT id, node_id, item_text, status, order_no, facet, created_at, updated_at
+			 FROM node_checklists
+			 WHERE id = ?`,
+			checklistID,
+		))
+		if err != nil {
+			return NodeApprovalResult{}, err
+		}
+		checklistItems = append(checklistItems, item)
+	}
+	policy, err := loadApprovalPolicyTx(ctx, transaction)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	activeApprovals, err := listNodeApprovals(ctx, transaction, node.ID, false)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	node, err = store.getGraphNodeByIDTx(ctx, transaction, node.ID)
+	if err != nil {
+		return NodeApprovalResult{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return NodeApprovalResult{}, err
+	}
+	return NodeApprovalResult{
+		Approval:       approval,
+		Node:           node,
+		Status:         EvaluateApprovalPolicy(policy, activeApprovals),
+		ChecklistItems: checklistItems,
+	}, nil
+}
+
+// ResetNodeApprovals supersedes every active decision on the node so a
+// re-submitted rollup starts a fresh approval round.
+func (store *Store) ResetNodeApprovals(ctx context.Context, nodeID int64) (int, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		`UPDATE node_approvals SET superseded_at = ? WHERE node_id = ? AND superseded_at IS NULL`,
+		nowTimestamp(),
+		nodeID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	changedRows, _ := result.RowsAffected()
+	if changedRows == 0 {
+		return 0, nil
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return 0, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return 0, err
+	}
+	return int(changedRows), nil
+}
+
+func (store *Store) ListNodeApprovals(ctx context.Context, nodeID int64, includeSuperseded bool) ([]NodeApproval, error) {
+	return listNodeApprovals(ctx, store.database, nodeID, includeSuperseded)
+}
+
+// GetNodeApprovalStatus evaluates the node's active decisions against the
+// current planning approval policy.
+func (store *Store) GetNodeApprovalStatus(ctx context.Context, nodeID int64) (ApprovalPolicyStatus, error) {
+	rule, err := store.GetPlanningRule(ctx)
+	if err != nil {
+		return ApprovalPolicyStatus{}, err
+	}
+	policy, err := ParseApprovalPolicy(rule.ApprovalPolicy)
+	if err != nil {
+		return ApprovalPolicyStatus{}, err
+	}
+	activeApprovals, err := store.ListNodeApprovals(ctx, nodeID, false)
+	if err != nil {
+		return ApprovalPolicyStatus{}, err
+	}
+	return EvaluateApprovalPolicy(policy, activeApprovals), nil
+}
+
+func (store *Store) beginNodeDecisionTx(ctx context.Context, args NodeApprovalArgs) (*sql.Tx, GraphNode, error) {
+	if args.NodeID <= 0 {
+		return nil, GraphNode{}, errors.New("node_id is required")
+	}
+	if strings.TrimSpace(args.Approver) == "" {
+		return nil, GraphNode{}, errors.New("approver is required")
+	}
+	if strings.TrimSpace(args.Role) == "" {
+		return nil, GraphNode{}, errors.New("role is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, GraphNode{}, err
+	}
+	node, err := store.getGraphNodeByIDTx(ctx, transaction, args.NodeID)
+	if err != nil {
+		transaction.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, GraphNode{}, fmt.Errorf("graph node not found: %d", args.NodeID)
+		}
+		return nil, GraphNode{}, err
+	}
+	if node.ApprovalState != "pending" {
+		transaction.Rollback()
+		return nil, GraphNode{}, fmt.Errorf("graph node %d is not awaiting approval (approval_state=%s)", node.ID, node.ApprovalState)
+	}
+
+	var existingDecisions int
+	if err := transaction.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FROM node_approvals WHERE node_id = ? AND approver = ? AND superseded_at IS NULL`,
+		node.ID,
+		strings.TrimSpace(args.Approver),
+	).Scan(&existingDecisions); err != nil {
+		transaction.Rollback()
+		return nil, GraphNode{}, err
+	}
+	if existingDecisions > 0 {
+		transaction.Rollback()
+		return nil, GraphNode{}, fmt.Errorf("approver %s already decided on graph node %d in this round", strings.TrimSpace(args.Approver), node.ID)
+	}
+	return transaction, node, nil
+}
+
+func insertNodeApprovalTx(ctx context.Context, transaction *sql.Tx, args NodeApprovalArgs, decision string) (NodeApproval, error) {
+	result, err := transaction.ExecContext(
+		ctx,
+		`INSERT INTO node_approvals(node_id, decision, approver, role, comment, created_at)
+		 VALUES(?, ?, ?, ?, ?, ?)`,
+		args.NodeID,
+		decision,
+		strings.TrimSpace(args.Approver),
+		strings.TrimSpace(args.Role),
+		nullableText(args.Comment),
+		nowTimestamp(),
+	)
+	if err != nil {
+		return NodeApproval{}, err
+	}
+	approvalID, err := result.LastInsertId()
+	if err != nil {
+		return NodeApproval{}, err
+	}
+	return scanNodeApproval(transaction.QueryRowContext(ctx, `SELECT `+nodeApprovalSelectColumns+` FROM node_approvals WHERE id = ?`, approvalID))
+}
+
+func loadApprovalPolicyTx(ctx context.Context, transaction *sql.Tx) (ApprovalPolicy, error) {
+	var rawPolicy string
+	if err := transaction.QueryRowContext(ctx, `SELECT approval_policy FROM planning_rules WHERE id = 1`).Scan(&rawPolicy); err != nil {
+		return ApprovalPolicy{}, err
+	}
+	return ParseApprovalPolicy(rawPolicy)
+}
+
+func listNodeApprovals(ctx context.Context, queryer rowQueryer, nodeID int64, includeSuperseded bool) ([]NodeApproval, error) {
+	query := `SELECT ` + nodeApprovalSelectColumns + ` FROM node_approvals WHERE node_id = ?`
+	if !includeSuperseded {
+		query += " AND superseded_at IS NULL"
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := queryer.QueryContext(ctx, query, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := make([]NodeApproval, 0)
+	for rows.Next() {
+		approval, scanErr := scanNodeApproval(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		approvals = append(approvals, approval)
+	}
+	return approvals, rows.Err()
+}
+
+func scanNodeApproval(scanner rowScanner) (NodeApproval, error) {
+	var approval NodeApproval
+	var comment sql.NullString
+	var supersededAt sql.NullString
+	if err := scanner.Scan(
+		&approval.ID,
+		&approval.NodeID,
+		&approval.Decision,
+		&approval.Approver,
+		&approval.Role,
+		&comment,
+		&approval.CreatedAt,
+		&supersededAt,
+	); err != nil {
+		return NodeApproval{}, err
+	}
+	if comment.Valid {
+		approval.Comment = &comment.String
+	}
+	if supersededAt.Valid {
+		approval.SupersededAt = &supersededAt.String
+	}
+	return approval, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseApprovalPolicy(t *testing.T) {
+	policy, err := ParseApprovalPolicy(ApprovalPolicyMergeAgentRequired)
+	if err != nil || policy.MinApprovals != 1 || len(policy.RequiredRoles) != 1 || policy.RequiredRoles[0] != "merge-reviewer" {
+		t.Fatalf("unexpected merge-agent-required policy: %+v (%v)", policy, err)
+	}
+	policy, err = ParseApprovalPolicy("approvals=2; roles=merge-reviewer, plan-architect")
+	if err != nil || policy.MinApprovals != 2 || len(policy.RequiredRoles) != 2 || policy.RequiredRoles[1] != "plan-architect" {
+		t.Fatalf("unexpected key=value policy: %+v (%v)", policy, err)
+	}
+	for _, invalid := range []string{"approvals=0", "quorum=2", "reviewer-optional"} {
+		if _, err := ParseApprovalPolicy(invalid); err == nil {
+			t.Fatalf("expected policy %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestApprovalWorkflowEnforcesPolicyAndResetsOnResubmit(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	policy := "approvals=2;roles=merge-reviewer"
+	if _, err := store.UpdatePlanningRule(context, PlanningRuleUpdateArgs{ApprovalPolicy: &policy}); err != nil {
+		t.Fatalf("failed to set approval policy: %v", err)
+	}
+	node, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "in_progress"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+
+	if _, err := store.ApproveNode(context, NodeApprovalArgs{NodeID: node.ID, Approver: "alice", Role: "plan-architect"}); err == nil {
+		t.Fatalf("expected approval before submit to fail")
+	}
+	if _, err := store.UpdateGraphNodeApprovalState(context, node.ID, "pending", "in_review"); err != nil {
+		t.Fatalf("failed to submit node: %v", err)
+	}
+
+	first, err := store.ApproveNode(context, NodeApprovalArgs{NodeID: node.ID, Approver: "alice", Role: "plan-architect", Comment: "looks fine"})
+	if err != nil {
+		t.Fatalf("failed to record first approval: %v", err)
+	}
+	if first.Status.Satisfied || first.Node.ApprovalState != "pending" || len(first.Status.MissingRoles) != 1 {
+		t.Fatalf("expected policy to remain unsatisfied, got %+v", first)
+	}
+	if _, err := store.ApproveNode(context, NodeApprovalArgs{NodeID: node.ID, Approver: "alice", Role: "plan-architect"}); err == nil {
+		t.Fatalf("expected duplicate approver to be rejected")
+	}
+
+	rejected, err := store.RejectNode(context, NodeApprovalArgs{NodeID: node.ID, Approver: "bob", Role: "merge-reviewer", RequiredChanges: []string{"add tests", " ", "split slice 3"}})
+	if err != nil {
+		t.Fatalf("failed to reject: %v", err)
+	}
+	if rejected.Node.ApprovalState != "rejected" || rejected.Node.Status != "blocked" {
+		t.Fatalf("unexpected node after rejection: %+v", rejected.Node)
+	}
+	if len(rejected.ChecklistItems) != 2 || rejected.ChecklistItems[0].Facet != "review" || rejected.ChecklistItems[1].ItemText != "split slice 3" {
+		t.Fatalf("unexpected required-change checklist items: %+v", rejected.ChecklistItems)
+	}
+
+	resetCount, err := store.ResetNodeApprovals(context, node.ID)
+	if err != nil || resetCount != 2 {
+		t.Fatalf("expected 2 decisions reset, got %d (%v)", resetCount, err)
+	}
+	if _, err := store.UpdateGraphNodeApprovalState(context, node.ID, "pending", "in_review"); err != nil {
+		t.Fatalf("failed to resubmit node: %v", err)
+	}
+
+	var final NodeApprovalResult
+	for _, approver := range []NodeApprovalArgs{
+		{NodeID: node.ID, Approver: "alice", Role: "plan-architect"},
+		{NodeID: node.ID, Approver: "bob", Role: "merge-reviewer"},
+	} {
+		result, err := store.ApproveNode(context, approver)
+		if err != nil {
+			t.Fatalf("failed to approve as %s: %v", approver.Approver, err)
+		}
+		final = result
+	}
+	if !final.Status.Satisfied || final.Node.ApprovalState != "approved" || final.Node.Status != "done" {
+		t.Fatalf("expected node approved after policy satisfied, got %+v", final)
+	}
+
+	allApprovals, err := store.ListNodeApprovals(context, node.ID, true)
+	if err != nil {
+		t.Fatalf("failed to list approvals: %v", err)
+	}
+	if len(allApprovals) != 4 || allApprovals[0].SupersededAt == nil || allApprovals[3].SupersededAt != nil {
+		t.Fatalf("expected superseded history plus active round, got %+v", allApprovals)
+	}
+}
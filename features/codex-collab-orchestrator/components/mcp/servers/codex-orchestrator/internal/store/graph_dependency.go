@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	EdgeTypeDependsOn = "depends_on"
+	EdgeTypeBlocks    = "blocks"
+)
+
+type rowQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// dependencyDirection maps a dependency edge onto prerequisite -> dependent.
+// "A depends_on B" means B must finish first; "A blocks B" means A must.
+func dependencyDirection(fromNodeID int64, toNodeID int64, edgeType string) (int64, int64, bool) {
+	switch edgeType {
+	case EdgeTypeDependsOn:
+		return toNodeID, fromNodeID, true
+	case EdgeTypeBlocks:
+		return fromNodeID, toNodeID, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// loadDependentsByNode returns prerequisite node ID -> dependent node IDs for
+// every depends_on/blocks edge.
+func loadDependentsByNode(ctx context.Context, queryer rowQueryer) (map[int64][]int64, error) {
+	rows, err := queryer.QueryContext(
+		ctx,
+		`SELECT from_node_id, to_node_id, edge_type
+		 FROM graph_edges
+		 WHERE edge_type IN (?, ?)
+		 ORDER BY id ASC`,
+		EdgeTypeDependsOn,
+		EdgeTypeBlocks,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dependentsByNode := make(map[int64][]int64)
+	for rows.Next() {
+		var fromNodeID int64
+		var toNodeID int64
+		var edgeType string
+		if err := rows.Scan(&fromNodeID, &toNodeID, &edgeType); err != nil {
+			return nil, err
+		}
+		prerequisiteID, dependentID, _ := dependencyDirection(fromNodeID, toNodeID, edgeType)
+		dependentsByNode[prerequisiteID] = append(dependentsByNode[prerequisiteID], dependentID)
+	}
+	return dependentsByNode, rows.Err()
+}
+
+// findDependencyPath returns the chain from startNodeID to targetNodeID along
+// dependent links, or nil when the target is unreachable.
+func findDependencyPath(dependentsByNode map[int64][]int64, startNodeID int64, targetNodeID int64) []int64 {
+	visited := map[int64]bool{startNodeID: true}
+	previous := make(map[int64]int64)
+	queue := []int64{startNodeID}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		if nodeID == targetNodeID {
+			path := []int64{nodeID}
+			for nodeID != startNodeID {
+				nodeID = previous[nodeID]
+				path = append([]int64{nodeID}, path...)
+			}
+			return path
+		}
+		for _, dependentID := range dependentsByNode[nodeID] {
+			if visited[dependentID] {
+				continue
+			}
+			visited[dependentID] = true
+			previous[dependentID] = nodeID
+			queue = append(queue, dependentID)
+		}
+	}
+	return nil
+}
+
+func (store *Store) checkDependencyCycleTx(ctx context.Context, transaction *sql.Tx, args GraphEdgeCreateArgs) error {
+	prerequisiteID, dependentID, ok := dependencyDirection(args.FromNodeID, args.ToNodeID, args.EdgeType)
+	if !ok {
+		return nil
+	}
+	dependentsByNode, err := loadDependentsByNode(ctx, transaction)
+	if err != nil {
+		return err
+	}
+	if cyclePath := findDependencyPath(dependentsByNode, dependentID, prerequisiteID); cyclePath != nil {
+		return fmt.Errorf("%s edge %d -> %d would create a dependency cycle: %v", args.EdgeType, args.FromNodeID, args.ToNodeID, append(cyclePath, dependentID))
+	}
+	return nil
+}
+
+func (store *Store) PlanReadySet(ctx context.Context, planNodeID int64) (PlanReadySet, error) {
+	if _, err := store.GetGraphNodeByID(ctx, planNodeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PlanReadySet{}, fmt.Errorf("graph node not found: %d", planNodeID)
+		}
+		return PlanReadySet{}, err
+	}
+
+	slices, err := store.ListGraphNodes(ctx, GraphNodeFilter{NodeType: "slice", ParentID: &planNodeID})
+	if err != nil {
+		return PlanReadySet{}, err
+	}
+	dependentsByNode, err := loadDependentsByNode(ctx, store.database)
+	if err != nil {
+		return PlanReadySet{}, err
+	}
+
+	prerequisitesByNode := make(map[int64][]int64)
+	for prerequisiteID, dependentIDs := range dependentsByNode {
+		for _, dependentID := range dependentIDs {
+			prerequisitesByNode[dependentID] = append(prerequisitesByNode[dependentID], prerequisiteID)
+		}
+	}
+
+	statusByNode := make(map[int64]string)
+	for _, slice := range slices {
+		statusByNode[slice.ID] = slice.Status
+	}
+	nodeStatus := func(nodeID int64) string {
+		if status, ok := statusByNode[nodeID]; ok {
+			return status
+		}
+		node, err := store.GetGraphNodeByID(ctx, nodeID)
+		if err != nil {
+			statusByNode[nodeID] = ""
+			return ""
+		}
+		statusByNode[nodeID] = node.Status
+		return node.Status
+	}
+
+	criticalPathByNode := make(map[int64]int)
+	var criticalPath func(nodeID int64) int
+	criticalPath = func(nodeID int64) int {
+		if length, ok := criticalPathByNode[nodeID]; ok {
+			return length
+		}
+		criticalPathByNode[nodeID] = 1
+		longest := 0
+		for _, dependentID := range dependentsByNode[nodeID] {
+			if nodeStatus(dependentID) == "done" {
+				continue
+			}
+			if length := criticalPath(dependentID); length > longest {
+				longest = length
+			}
+		}
+		criticalPathByNode[nodeID] = longest + 1
+		return longest + 1
+	}
+
+	readySet := PlanReadySet{
+		PlanNodeID: planNodeID,
+		Ready:      make([]ReadyGraphNode, 0),
+		Waiting:    make([]ReadyGraphNode, 0),
+	}
+	for _, slice := range slices {
+		if slice.Status != "todo" {
+			continue
+		}
+		entry := ReadyGraphNode{
+			Node:               slice,
+			DependsOn:          make([]int64, 0),
+			WaitingOn:          make([]int64, 0),
+			CriticalPathLength: criticalPath(slice.ID),
+		}
+		for _, prerequisiteID := range prerequisitesByNode[slice.ID] {
+			entry.DependsOn = append(entry.DependsOn, prerequisiteID)
+			if nodeStatus(prerequisiteID) != "done" {
+				entry.WaitingOn = append(entry.WaitingOn, prerequisiteID)
+			}
+		}
+		sort.Slice(entry.DependsOn, func(left, right int) bool { return entry.DependsOn[left] < entry.DependsOn[right] })
+		sort.Slice(entry.WaitingOn, func(left, right int) bool { return entry.WaitingOn[left] < entry.WaitingOn[right] })
+		if len(entry.WaitingOn) == 0 {
+			readySet.Ready = append(readySet.Ready, entry)
+		} else {
+			readySet.Waiting = append(readySet.Waiting, entry)
+		}
+	}
+
+	sortReadyGraphNodes(readySet.Ready)
+	sortReadyGraphNodes(readySet.Waiting)
+	return readySet, nil
+}
+
+func sortReadyGraphNodes(entries []ReadyGraphNode) {
+	sort.SliceStable(entries, func(left, right int) bool {
+		if entries[left].Node.Priority != entries[right].Node.Priority {
+			return entries[left].Node.Priority > entries[right].Node.Priority
+		}
+		if entries[left].CriticalPathLength != entries[right].CriticalPathLength {
+			return entries[left].CriticalPathLength > entries[right].CriticalPathLength
+		}
+		return entries[left].Node.ID < entries[right].Node.ID
+	})
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func createTestSlices(t *testing.T, store *Store, context context.Context, titles ...string) (GraphNode, []GraphNode) {
+	t.Helper()
+
+	planNode, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "todo"})
+	if err != nil {
+		t.Fatalf("failed to create plan node: %v", err)
+	}
+	slices := make([]GraphNode, 0, len(titles))
+	for _, title := range titles {
+		slice, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: title, Status: "todo", ParentID: &planNode.ID})
+		if err != nil {
+			t.Fatalf("failed to create slice %s: %v", title, err)
+		}
+		slices = append(slices, slice)
+	}
+	return planNode, slices
+}
+
+func TestCreateGraphEdgeRejectsDependencyCycle(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	_, slices := createTestSlices(t, store, context, "a", "b", "c")
+	if _, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: slices[1].ID, ToNodeID: slices[0].ID, EdgeType: "depends_on"}); err != nil {
+		t.Fatalf("failed to create b depends_on a: %v", err)
+	}
+	if _, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: slices[1].ID, ToNodeID: slices[2].ID, EdgeType: "blocks"}); err != nil {
+		t.Fatalf("failed to create b blocks c: %v", err)
+	}
+
+	_, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: slices[0].ID, ToNodeID: slices[2].ID, EdgeType: "depends_on"})
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle") {
+		t.Fatalf("expected cycle to be rejected, got %v", err)
+	}
+	if _, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: slices[0].ID, ToNodeID: slices[0].ID, EdgeType: "blocks"}); err == nil {
+		t.Fatalf("expected self dependency to be rejected")
+	}
+	if _, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: slices[2].ID, ToNodeID: slices[0].ID, EdgeType: "contains"}); err != nil {
+		t.Fatalf("expected non-dependency edge to skip cycle check: %v", err)
+	}
+}
+
+func TestPlanReadySetOrdersByPriorityAndCriticalPath(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	planNode, slices := createTestSlices(t, store, context, "foundation", "api", "ui", "docs")
+	// api depends on foundation; ui depends on api; docs is independent.
+	for _, edge := range []GraphEdgeCreateArgs{
+		{FromNodeID: slices[1].ID, ToNodeID: slices[0].ID, EdgeType: "depends_on"},
+		{FromNodeID: slices[1].ID, ToNodeID: slices[2].ID, EdgeType: "blocks"},
+	} {
+		if _, err := store.CreateGraphEdge(context, edge); err != nil {
+			t.Fatalf("failed to create edge: %v", err)
+		}
+	}
+
+	readySet, err := store.PlanReadySet(context, planNode.ID)
+	if err != nil {
+		t.Fatalf("failed to compute ready set: %v", err)
+	}
+	if len(readySet.Ready) != 2 || readySet.Ready[0].Node.ID != slices[0].ID || readySet.Ready[1].Node.ID != slices[3].ID {
+		t.Fatalf("expected foundation then docs ready, got %+v", readySet.Ready)
+	}
+	if readySet.Ready[0].CriticalPathLength != 3 {
+		t.Fatalf("expected foundation critical path 3, got %d", readySet.Ready[0].CriticalPathLength)
+	}
+	if len(readySet.Waiting) != 2 {
+		t.Fatalf("expected api and ui waiting, got %+v", readySet.Waiting)
+	}
+
+	if _, err := store.UpdateGraphNodeApprovalState(context, slices[0].ID, "approved", "done"); err != nil {
+		t.Fatalf("failed to finish foundation: %v", err)
+	}
+	readySet, err = store.PlanReadySet(context, planNode.ID)
+	if err != nil {
+		t.Fatalf("failed to recompute ready set: %v", err)
+	}
+	if len(readySet.Ready) != 2 || readySet.Ready[0].Node.ID != slices[1].ID {
+		t.Fatalf("expected api to lead ready set after foundation is done, got %+v", readySet.Ready)
+	}
+}
@@ -0,0 +1,407 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// graphNodeStatuses are the statuses a planning node can take.
+var graphNodeStatuses = map[string]bool{
+	"todo":        true,
+	"in_progress": true,
+	"in_review":   true,
+	"blocked":     true,
+	"done":        true,
+	"cancelled":   true,
+}
+
+func (store *Store) UpdateGraphNode(ctx context.Context, nodeID int64, args GraphNodeUpdateArgs) (GraphNode, error) {
+	setClauses := make([]string, 0, 9)
+	params := make([]any, 0, 10)
+
+	if args.Title != nil {
+		title := strings.TrimSpace(*args.Title)
+		if title == "" {
+			return GraphNode{}, errors.New("title cannot be empty")
+		}
+		setClauses = append(setClauses, "title = ?")
+		params = append(params, title)
+	}
+	if args.Status != nil {
+		status := strings.TrimSpace(*args.Status)
+		if !graphNodeStatuses[status] {
+			return GraphNode{}, fmt.Errorf("invalid graph node status: %q (want todo, in_progress, in_review, blocked, done or cancelled)", status)
+		}
+		setClauses = append(setClauses, "status = ?")
+		params = append(params, status)
+	}
+	if args.Priority != nil {
+		setClauses = append(setClauses, "priority = ?")
+		params = append(params, *args.Priority)
+	}
+	if args.Summary != nil {
+		setClauses = append(setClauses, "summary = ?")
+		params = append(params, nullableText(*args.Summary))
+	}
+	if args.RiskLevel != nil {
+		setClauses = append(setClauses, "risk_level = ?")
+		params = append(params, *args.RiskLevel)
+	}
+	if args.TokenEstimate != nil {
+		setClauses = append(setClauses, "token_estimate = ?")
+		params = append(params, *args.TokenEstimate)
+	}
+	if args.AffectedFilesJSON != nil {
+		setClauses = append(setClauses, "affected_files_json = ?")
+		params = append(params, nullableText(*args.AffectedFilesJSON))
+	}
+	if args.OwnerSessionID != nil {
+		setClauses = append(setClauses, "owner_session_id = ?")
+		params = append(params, *args.OwnerSessionID)
+	}
+	if len(setClauses) == 0 {
+		return GraphNode{}, errors.New("no graph node fields to update")
+	}
+	setClauses = append(setClauses, "updated_at = ?")
+	params = append(params, nowTimestamp(), nodeID)
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	defer transaction.Rollback()
+
+	previousNode, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphNode{}, fmt.Errorf("graph node not found: %d", nodeID)
+		}
+		return GraphNode{}, err
+	}
+	if previousNode.DeletedAt != nil {
+		return GraphNode{}, fmt.Errorf("graph node is deleted: %d", nodeID)
+	}
+	if err := store.insertNodeRevisionTx(ctx, transaction, previousNode, "revision", args.Reason, "graph.node.update"); err != nil {
+		return GraphNode{}, err
+	}
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE graph_nodes SET %s WHERE id = ?", strings.Join(setClauses, ", ")),
+		params...,
+	); err != nil {
+		return GraphNode{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphNode{}, err
+	}
+
+	node, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphNode{}, err
+	}
+	return node, nil
+}
+
+// DeleteGraphNode soft-deletes a node (and, with cascade, its descendants),
+// snapshots each removed node and drops every edge and task link that touches
+// them.
+func (store *Store) DeleteGraphNode(ctx context.Context, nodeID int64, reason string, cascade bool) (GraphNodeDeleteResult, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphNodeDeleteResult{}, err
+	}
+	defer transaction.Rollback()
+
+	node, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphNodeDeleteResult{}, fmt.Errorf("graph node not found: %d", nodeID)
+		}
+		return GraphNodeDeleteResult{}, err
+	}
+	if node.DeletedAt != nil {
+		return GraphNodeDeleteResult{}, fmt.Errorf("graph node is deleted: %d", nodeID)
+	}
+
+	subtreeIDs, err := queryInt64ColumnTx(
+		ctx,
+		transaction,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM graph_nodes WHERE id = ?
+			UNION
+			SELECT graph_nodes.id FROM graph_nodes JOIN subtree ON graph_nodes.parent_id = subtree.id
+			WHERE graph_nodes.deleted_at IS NULL
+		)
+		SELECT id FROM subtree ORDER BY id ASC`,
+		nodeID,
+	)
+	if err != nil {
+		return GraphNodeDeleteResult{}, err
+	}
+	if len(subtreeIDs) > 1 && !cascade {
+		return GraphNodeDeleteResult{}, fmt.Errorf("graph node %d has %d descendant(s); pass cascade=true to delete them", nodeID, len(subtreeIDs)-1)
+	}
+
+	timestamp := nowTimestamp()
+	edgesRemoved := int64(0)
+	for _, subtreeID := range subtreeIDs {
+		subtreeNode, err := store.getGraphNodeByIDTx(ctx, transaction, subtreeID)
+		if err != nil {
+			return GraphNodeDeleteResult{}, err
+		}
+		if err := store.insertNodeRevisionTx(ctx, transaction, subtreeNode, "delete", reason, "graph.node.delete"); err != nil {
+			return GraphNodeDeleteResult{}, err
+		}
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE graph_nodes SET deleted_at = ?, updated_at = ? WHERE id = ?`,
+			timestamp,
+			timestamp,
+			subtreeID,
+		); err != nil {
+			return GraphNodeDeleteResult{}, err
+		}
+		result, err := transaction.ExecContext(ctx, `DELETE FROM graph_edges WHERE from_node_id = ? OR to_node_id = ?`, subtreeID, subtreeID)
+		if err != nil {
+			return GraphNodeDeleteResult{}, err
+		}
+		changedRows, _ := result.RowsAffected()
+		edgesRemoved += changedRows
+		if _, err := transaction.ExecContext(ctx, `DELETE FROM graph_node_tasks WHERE node_id = ?`, subtreeID); err != nil {
+			return GraphNodeDeleteResult{}, err
+		}
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphNodeDeleteResult{}, err
+	}
+
+	deletedNode, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID)
+	if err != nil {
+		return GraphNodeDeleteResult{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphNodeDeleteResult{}, err
+	}
+	return GraphNodeDeleteResult{
+		Node:           deletedNode,
+		DeletedNodeIDs: subtreeIDs,
+		EdgesRemoved:   int(edgesRemoved),
+	}, nil
+}
+
+func (store *Store) ListNodeSnapshots(ctx context.Context, nodeID int64) ([]NodeSnapshot, error) {
+	rows, err := store.database.QueryContext(
+		ctx,
+		`SELECT `+nodeSnapshotSelectColumns+`
+		 FROM node_snapshots
+		 WHERE node_id = ?
+		 ORDER BY id ASC`,
+		nodeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]NodeSnapshot, 0)
+	for rows.Next() {
+		snapshot, scanErr := scanNodeSnapshot(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+func (store *Store) DeleteGraphEdge(ctx context.Context, edgeID int64) (GraphEdge, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphEdge{}, err
+	}
+	defer transaction.Rollback()
+
+	row := transaction.QueryRowContext(
+		ctx,
+		`SELECT id, from_node_id, to_node_id, edge_type, created_at
+		 FROM graph_edges
+		 WHERE id = ?`,
+		edgeID,
+	)
+	edge, err := scanGraphEdge(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphEdge{}, fmt.Errorf("graph edge not found: %d", edgeID)
+		}
+		return GraphEdge{}, err
+	}
+	if _, err := transaction.ExecContext(ctx, `DELETE FROM graph_edges WHERE id = ?`, edgeID); err != nil {
+		return GraphEdge{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphEdge{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphEdge{}, err
+	}
+	return edge, nil
+}
+
+// ReorderNodeChecklist renumbers a node's checklist items in the given order.
+// Items not listed keep their relative order after the listed ones.
+func (store *Store) ReorderNodeChecklist(ctx context.Context, nodeID int64, itemIDs []int64) ([]NodeChecklistItem, error) {
+	if nodeID <= 0 {
+		return nil, errors.New("node_id is required")
+	}
+	if len(itemIDs) == 0 {
+		return nil, errors.New("item_ids is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	existingIDs, err := queryInt64ColumnTx(ctx, transaction, `SELECT id FROM node_checklists WHERE node_id = ? ORDER BY order_no ASC, id ASC`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	belongsToNode := make(map[int64]bool, len(existingIDs))
+	for _, existingID := range existingIDs {
+		belongsToNode[existingID] = true
+	}
+
+	orderedIDs := make([]int64, 0, len(existingIDs))
+	listed := make(map[int64]bool, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if !belongsToNode[itemID] {
+			return nil, fmt.Errorf("checklist item %d does not belong to node %d", itemID, nodeID)
+		}
+		if listed[itemID] {
+			return nil, fmt.Errorf("checklist item %d is listed more than once", itemID)
+		}
+		listed[itemID] = true
+		orderedIDs = append(orderedIDs, itemID)
+	}
+	for _, existingID := range existingIDs {
+		if !listed[existingID] {
+			orderedIDs = append(orderedIDs, existingID)
+		}
+	}
+
+	timestamp := nowTimestamp()
+	for index, itemID := range orderedIDs {
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE node_checklists SET order_no = ?, updated_at = ? WHERE id = ?`,
+			index+1,
+			timestamp,
+			itemID,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	rows, err := transaction.QueryContext(
+		ctx,
+		`SELECT id, node_id, item_text, status, order_no, facet, created_at, updated_at
+		 FROM node_checklists
+		 WHERE node_id = ?
+		 ORDER BY order_no ASC, id ASC`,
+		nodeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]NodeChecklistItem, 0, len(orderedIDs))
+	for rows.Next() {
+		item, scanErr := scanNodeChecklistItem(rows)
+		if scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (store *Store) DeleteNodeChecklistItem(ctx context.Context, itemID int64) (NodeChecklistItem, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return NodeChecklistItem{}, err
+	}
+	defer transaction.Rollback()
+
+	row := transaction.QueryRowContext(
+		ctx,
+		`SELECT id, node_id, item_text, status, order_no, facet, created_at, updated_at
+		 FROM node_checklists
+		 WHERE id = ?`,
+		itemID,
+	)
+	item, err := scanNodeChecklistItem(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NodeChecklistItem{}, fmt.Errorf("checklist item not found: %d", itemID)
+		}
+		return NodeChecklistItem{}, err
+	}
+	if _, err := transaction.ExecContext(ctx, `DELETE FROM node_checklists WHERE id = ?`, itemID); err != nil {
+		return NodeChecklistItem{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return NodeChecklistItem{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return NodeChecklistItem{}, err
+	}
+	return item, nil
+}
+
+func (store *Store) insertNodeRevisionTx(ctx context.Context, transaction *sql.Tx, node GraphNode, snapshotType string, reason string, event string) error {
+	payloadBytes, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	summary := strings.TrimSpace(reason)
+	if summary == "" {
+		summary = event
+	}
+	_, err = transaction.ExecContext(
+		ctx,
+		`INSERT INTO node_snapshots(node_id, snapshot_type, summary, affected_files_json, next_action, created_at, payload_json)
+		 VALUES(?, ?, ?, ?, NULL, ?, ?)`,
+		node.ID,
+		snapshotType,
+		summary,
+		node.AffectedFilesJSON,
+		nowTimestamp(),
+		string(payloadBytes),
+	)
+	return err
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestUpdateAndDeleteGraphNodeKeepHistory(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	planNode, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "todo"})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	first, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "first", Status: "todo", ParentID: &planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to create first slice: %v", err)
+	}
+	second, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "second", Status: "todo", ParentID: &planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to create second slice: %v", err)
+	}
+	edge, err := store.CreateGraphEdge(context, GraphEdgeCreateArgs{FromNodeID: second.ID, ToNodeID: first.ID, EdgeType: EdgeTypeDependsOn})
+	if err != nil {
+		t.Fatalf("failed to create edge: %v", err)
+	}
+
+	status := "in_progress"
+	priority := 5
+	summary := "narrowed scope"
+	updated, err := store.UpdateGraphNode(context, first.ID, GraphNodeUpdateArgs{Status: &status, Priority: &priority, Summary: &summary, Reason: "scope change"})
+	if err != nil {
+		t.Fatalf("failed to update node: %v", err)
+	}
+	if updated.Status != "in_progress" || updated.Priority != 5 || updated.Summary == nil || *updated.Summary != "narrowed scope" {
+		t.Fatalf("unexpected updated node: %+v", updated)
+	}
+
+	invalidStatus := "finished"
+	if _, err := store.UpdateGraphNode(context, first.ID, GraphNodeUpdateArgs{Status: &invalidStatus}); err == nil || !strings.Contains(err.Error(), "invalid graph node status") {
+		t.Fatalf("expected unknown status to be rejected, got %v", err)
+	}
+	caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "first case"})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if _, err := store.LinkGraphNodeTask(context, first.ID, caseTask.ID); err != nil {
+		t.Fatalf("failed to link task: %v", err)
+	}
+
+	snapshots, err := store.ListNodeSnapshots(context, first.ID)
+	if err != nil {
+		t.Fatalf("failed to list snapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].SnapshotType != "revision" || snapshots[0].PayloadJSON == nil || !strings.Contains(*snapshots[0].PayloadJSON, `"status":"todo"`) {
+		t.Fatalf("expected one revision snapshot holding the old status, got %+v", snapshots)
+	}
+
+	if _, err := store.DeleteGraphNode(context, planNode.ID, "drop plan", false); err == nil {
+		t.Fatalf("expected delete without cascade to fail on a node with children")
+	}
+
+	deleteResult, err := store.DeleteGraphNode(context, first.ID, "superseded", false)
+	if err != nil {
+		t.Fatalf("failed to delete node: %v", err)
+	}
+	if deleteResult.Node.DeletedAt == nil || deleteResult.EdgesRemoved != 1 || len(deleteResult.DeletedNodeIDs) != 1 {
+		t.Fatalf("unexpected delete result: %+v", deleteResult)
+	}
+	if _, err := store.DeleteGraphEdge(context, edge.ID); err == nil {
+		t.Fatalf("expected edge to be removed with its node")
+	}
+	if links, err := store.ListGraphNodeTaskLinks(context, GraphNodeTaskLinkFilter{TaskID: &caseTask.ID}); err != nil || len(links) != 0 {
+		t.Fatalf("expected task links to be removed with their node, got %+v (%v)", links, err)
+	}
+
+	remaining, err := store.ListGraphNodes(context, GraphNodeFilter{ParentID: &planNode.ID})
+	if err != nil {
+		t.Fatalf("failed to list nodes: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != second.ID {
+		t.Fatalf("expected deleted node to be hidden, got %+v", remaining)
+	}
+	withDeleted, err := store.ListGraphNodes(context, GraphNodeFilter{ParentID: &planNode.ID, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("failed to list nodes with deleted: %v", err)
+	}
+	if len(withDeleted) != 2 {
+		t.Fatalf("expected deleted node with include_deleted, got %+v", withDeleted)
+	}
+
+	cascadeResult, err := store.DeleteGraphNode(context, planNode.ID, "drop plan", true)
+	if err != nil {
+		t.Fatalf("failed to cascade delete plan: %v", err)
+	}
+	if len(cascadeResult.DeletedNodeIDs) != 2 {
+		t.Fatalf("expected plan and live slice deleted, got %+v", cascadeResult.DeletedNodeIDs)
+	}
+}
+
+func TestReorderAndDeleteNodeChecklist(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	node, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "slice", Status: "todo"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	itemIDs := make([]int64, 0, 3)
+	for index, text := range []string{"a", "b", "c"} {
+		item, err := store.UpsertNodeChecklistItem(context, NodeChecklistUpsertArgs{NodeID: node.ID, ItemText: text, OrderNo: int64(index + 1)})
+		if err != nil {
+			t.Fatalf("failed to add checklist item %s: %v", text, err)
+		}
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	reordered, err := store.ReorderNodeChecklist(context, node.ID, []int64{itemIDs[2], itemIDs[0], itemIDs[1]})
+	if err != nil {
+		t.Fatalf("failed to reorder checklist: %v", err)
+	}
+	if len(reordered) != 3 || reordered[0].ItemText != "c" || reordered[1].ItemText != "a" || reordered[2].ItemText != "b" {
+		t.Fatalf("unexpected reordered checklist: %+v", reordered)
+	}
+	partial, err := store.ReorderNodeChecklist(context, node.ID, []int64{itemIDs[1]})
+	if err != nil {
+		t.Fatalf("failed to reorder partial checklist: %v", err)
+	}
+	if partial[0].ItemText != "b" || partial[1].ItemText != "c" || partial[2].ItemText != "a" {
+		t.Fatalf("expected unlisted items to keep their order after listed ones, got %+v", partial)
+	}
+	if _, err := store.ReorderNodeChecklist(context, node.ID, []int64{itemIDs[0] + 100}); err == nil {
+		t.Fatalf("expected reorder with a foreign item to fail")
+	}
+
+	deleted, err := store.DeleteNodeChecklistItem(context, itemIDs[0])
+	if err != nil {
+		t.Fatalf("failed to delete checklist item: %v", err)
+	}
+	if deleted.ItemText != "a" {
+		t.Fatalf("unexpected deleted item: %+v", deleted)
+	}
+	if _, err := store.DeleteNodeChecklistItem(context, itemIDs[0]); err == nil {
+		t.Fatalf("expected second delete to fail")
+	}
+}
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func (store *Store) LinkGraphNodeTask(ctx context.Context, nodeID int64, taskID int64) (GraphNodeTaskLink, error) {
+	if nodeID <= 0 || taskID <= 0 {
+		return GraphNodeTaskLink{}, errors.New("node_id and task_id are required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphNodeTaskLink{}, err
+	}
+	defer transaction.Rollback()
+
+	if _, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphNodeTaskLink{}, fmt.Errorf("graph node not found: %d", nodeID)
+		}
+		return GraphNodeTaskLink{}, err
+	}
+	task, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphNodeTaskLink{}, fmt.Errorf("task not found: %d", taskID)
+		}
+		return GraphNodeTaskLink{}, err
+	}
+	if task.DeletedAt != nil {
+		return GraphNodeTaskLink{}, fmt.Errorf("task is deleted: %d", taskID)
+	}
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		`INSERT OR IGNORE INTO graph_node_tasks(node_id, task_id, created_at) VALUES(?, ?, ?)`,
+		nodeID,
+		taskID,
+		nowTimestamp(),
+	); err != nil {
+		return GraphNodeTaskLink{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphNodeTaskLink{}, err
+	}
+
+	var link GraphNodeTaskLink
+	if err := transaction.QueryRowContext(
+		ctx,
+		`SELECT node_id, task_id, created_at FROM graph_node_tasks WHERE node_id = ? AND task_id = ?`,
+		nodeID,
+		taskID,
+	).Scan(&link.NodeID, &link.TaskID, &link.CreatedAt); err != nil {
+		return GraphNodeTaskLink{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphNodeTaskLink{}, err
+	}
+	return link, nil
+}
+
+func (store *Store) UnlinkGraphNodeTask(ctx context.Context, nodeID int64, taskID int64) error {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(ctx, `DELETE FROM graph_node_tasks WHERE node_id = ? AND task_id = ?`, nodeID, taskID)
+	if err != nil {
+		return err
+	}
+	if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+		return fmt.Errorf("graph node %d is not linked to task %d", nodeID, taskID)
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return err
+	}
+	return transaction.Commit()
+}
+
+func (store *Store) ListGraphNodeTaskLinks(ctx context.Context, filter GraphNodeTaskLinkFilter) ([]GraphNodeTaskLink, error) {
+	query := `SELECT node_id, task_id, created_at FROM graph_node_tasks`
+	whereClauses := make([]string, 0, 2)
+	parameters := make([]any, 0, 2)
+	if filter.NodeID != nil {
+		whereClauses = append(whereClauses, "node_id = ?")
+		parameters = append(parameters, *filter.NodeID)
+	}
+	if filter.TaskID != nil {
+		whereClauses = append(whereClauses, "task_id = ?")
+		parameters = append(parameters, *filter.TaskID)
+	}
+	if len(whereClauses) > 0 {
+		query += " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+	query += " ORDER BY node_id ASC, task_id ASC"
+
+	rows, err := store.database.QueryContext(ctx, query, parameters...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]GraphNodeTaskLink, 0)
+	for rows.Next() {
+		var link GraphNodeTaskLink
+		if err := rows.Scan(&link.NodeID, &link.TaskID, &link.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// SyncGraphNodesForTask recomputes the status of every graph node linked to
+// the task from its linked tasks, then rolls the change up the parent chain
+// (slice -> plan -> initiative). It returns the nodes whose status changed.
+func (store *Store) SyncGraphNodesForTask(ctx context.Context, taskID int64) ([]GraphNode, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	linkedNodeIDs, err := queryInt64ColumnTx(ctx, transaction, `SELECT node_id FROM graph_node_tasks WHERE task_id = ? ORDER BY node_id ASC`, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	changedNodes := make([]GraphNode, 0)
+	for _, nodeID := range linkedNodeIDs {
+		taskStatuses, err := queryStringColumnTx(
+			ctx,
+			transaction,
+			`SELECT tasks.status
+			 FROM graph_node_tasks
+			 JOIN tasks ON tasks.id = graph_node_tasks.task_id
+			 WHERE graph_node_tasks.node_id = ? AND tasks.deleted_at IS NULL`,
+			nodeID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		changed, err := store.rollUpGraphNodeTx(ctx, transaction, nodeID, taskStatuses)
+		if err != nil {
+			return nil, err
+		}
+		changedNodes = append(changedNodes, changed...)
+	}
+
+	if len(changedNodes) == 0 {
+		return changedNodes, nil
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return changedNodes, nil
+}
+
+// rollUpGraphNodeTx sets nodeID's status from its children's statuses and
+// walks on up the parent chain while statuses change. A node with child nodes
+// is rolled-up work under the approval policy: instead of done it goes to
+// in_review awaiting approval, and the walk stops there until ApproveNode
+// marks it done.
+func (store *Store) rollUpGraphNodeTx(ctx context.Context, transaction *sql.Tx, nodeID int64, childStatuses []string) ([]GraphNode, error) {
+	changedNodes := make([]GraphNode, 0)
+	currentNodeID := nodeID
+	for {
+		node, err := store.getGraphNodeByIDTx(ctx, transaction, currentNodeID)
+		if err != nil {
+			return nil, err
+		}
+		nextStatus := rollUpTaskStatus(node.Status, childStatuses)
+		// A rollup never pulls a started node back to todo, but it does lift
+		// a block it propagated once the children are unblocked. A rejected
+		// node stays blocked until it is resubmitted.
+		if nextStatus == "todo" && (node.Status != "blocked" || node.ApprovalState == "rejected") {
+			nextStatus = node.Status
+		}
+		approvalState := node.ApprovalState
+		awaitingApproval := false
+		if nextStatus == "done" && approvalState != "approved" {
+			var childCount int
+			if err := transaction.QueryRowContext(ctx, `SELECT COUNT(*) FROM graph_nodes WHERE parent_id = ? AND deleted_at IS NULL`, node.ID).Scan(&childCount); err != nil {
+				return nil, err
+			}
+			awaitingApproval = childCount > 0
+		}
+		if awaitingApproval {
+			nextStatus = "in_review"
+			if approvalState == "none" {
+				approvalState = "pending"
+			}
+		}
+		if nextStatus == node.Status && approvalState == node.ApprovalState {
+			break
+		}
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE graph_nodes SET status = ?, approval_state = ?, updated_at = ? WHERE id = ?`,
+			nextStatus,
+			approvalState,
+			nowTimestamp(),
+			node.ID,
+		); err != nil {
+			return nil, err
+		}
+		node.Status = nextStatus
+		node.ApprovalState = approvalState
+		changedNodes = append(changedNodes, node)
+
+		if awaitingApproval || node.ParentID == nil {
+			break
+		}
+		currentNodeID = *node.ParentID
+		childStatuses, err = queryStringColumnTx(ctx, transaction, `SELECT status FROM graph_nodes WHERE parent_id = ? AND deleted_at IS NULL`, currentNodeID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changedNodes, nil
+}
+
+func (store *Store) getGraphNodeByIDTx(ctx context.Context, transaction *sql.Tx, nodeID int64) (GraphNode, error) {
+	row := transaction.QueryRowContext(ctx, `SELECT `+graphNodeSelectColumns+` FROM graph_nodes WHERE id = ?`, nodeID)
+	return scanGraphNode(row)
+}
+
+func queryInt64ColumnTx(ctx context.Context, transaction *sql.Tx, query string, args ...any) ([]int64, error) {
+	rows, err := transaction.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]int64, 0)
+	for rows.Next() {
+		var value int64
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func queryStringColumnTx(ctx context.Context, transaction *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := transaction.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
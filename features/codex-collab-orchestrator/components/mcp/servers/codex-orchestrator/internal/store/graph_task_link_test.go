@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncGraphNodesForTaskRollsUpToInitiative(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	initiative, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "initiative", Facet: "planning", Title: "initiative", Status: "in_progress"})
+	if err != nil {
+		t.Fatalf("failed to create initiative: %v", err)
+	}
+	planNode, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "plan", Facet: "planning", Title: "plan", Status: "todo", ParentID: &initiative.ID})
+	if err != nil {
+		t.Fatalf("failed to create plan: %v", err)
+	}
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+
+	riskLevel := 3
+	sliceIDs := make([]int64, 0, 2)
+	caseIDs := make([]int64, 0, 2)
+	for _, title := range []string{"a", "b"} {
+		tokenEstimate := 1000
+		slice, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: title, Status: "todo", ParentID: &planNode.ID, TokenEstimate: &tokenEstimate, RiskLevel: &riskLevel})
+		if err != nil {
+			t.Fatalf("failed to create slice %s: %v", title, err)
+		}
+		caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: title, ParentID: &feature.ID})
+		if err != nil {
+			t.Fatalf("failed to create case %s: %v", title, err)
+		}
+		if _, err := store.LinkGraphNodeTask(context, slice.ID, caseTask.ID); err != nil {
+			t.Fatalf("failed to link slice %s: %v", title, err)
+		}
+		sliceIDs = append(sliceIDs, slice.ID)
+		caseIDs = append(caseIDs, caseTask.ID)
+	}
+
+	if _, err := store.CompleteCase(context, CaseCompleteArgs{TaskID: caseIDs[0]}); err != nil {
+		t.Fatalf("failed to complete case a: %v", err)
+	}
+	changedNodes, err := store.SyncGraphNodesForTask(context, caseIDs[0])
+	if err != nil {
+		t.Fatalf("failed to sync nodes for case a: %v", err)
+	}
+	if len(changedNodes) != 2 || changedNodes[0].ID != sliceIDs[0] || changedNodes[0].Status != "done" || changedNodes[1].ID != planNode.ID || changedNodes[1].Status != "in_progress" {
+		t.Fatalf("expected slice a done and plan in_progress, got %+v", changedNodes)
+	}
+
+	preview, err := store.RollupPreview(context, initiative.ID)
+	if err != nil {
+		t.Fatalf("failed to build rollup preview: %v", err)
+	}
+	progress := preview["progress"].(map[string]int)
+	tokens := preview["tokens"].(map[string]int)
+	risk := preview["risk"].(map[string]int)
+	linkedTasks := preview["linked_tasks"].(map[string]int)
+	if progress["total_leaves"] != 2 || progress["done_leaves"] != 1 || progress["percent"] != 50 {
+		t.Fatalf("unexpected recursive progress: %+v", progress)
+	}
+	if tokens["done_estimate"] != 1000 || tokens["remaining"] != 1000 {
+		t.Fatalf("unexpected token rollup: %+v", tokens)
+	}
+	if risk["open_risky_nodes"] != 1 || risk["total_remaining"] != 3 {
+		t.Fatalf("unexpected risk rollup: %+v", risk)
+	}
+	if linkedTasks["total"] != 2 || linkedTasks["done"] != 1 {
+		t.Fatalf("unexpected linked task rollup: %+v", linkedTasks)
+	}
+
+	if _, err := store.CompleteCase(context, CaseCompleteArgs{TaskID: caseIDs[1]}); err != nil {
+		t.Fatalf("failed to complete case b: %v", err)
+	}
+	if _, err := store.SyncGraphNodesForTask(context, caseIDs[1]); err != nil {
+		t.Fatalf("failed to sync nodes for case b: %v", err)
+	}
+	updatedPlan, err := store.GetGraphNodeByID(context, planNode.ID)
+	if err != nil {
+		t.Fatalf("failed to reload plan: %v", err)
+	}
+	if updatedPlan.Status != "in_review" || updatedPlan.ApprovalState != "pending" {
+		t.Fatalf("expected plan held for approval after all slices complete, got %s/%s", updatedPlan.Status, updatedPlan.ApprovalState)
+	}
+	updatedInitiative, err := store.GetGraphNodeByID(context, initiative.ID)
+	if err != nil {
+		t.Fatalf("failed to reload initiative: %v", err)
+	}
+	if updatedInitiative.Status != "in_progress" {
+		t.Fatalf("expected initiative to wait for the plan approval, got %s", updatedInitiative.Status)
+	}
+
+	approved, err := store.ApproveNode(context, NodeApprovalArgs{NodeID: planNode.ID, Approver: "bob", Role: "merge-reviewer"})
+	if err != nil {
+		t.Fatalf("failed to approve plan: %v", err)
+	}
+	if approved.Node.Status != "done" {
+		t.Fatalf("expected approved plan done, got %s", approved.Node.Status)
+	}
+	updatedInitiative, err = store.GetGraphNodeByID(context, initiative.ID)
+	if err != nil {
+		t.Fatalf("failed to reload initiative: %v", err)
+	}
+	if updatedInitiative.Status != "in_review" || updatedInitiative.ApprovalState != "pending" {
+		t.Fatalf("expected initiative held for approval once its plan is done, got %s/%s", updatedInitiative.Status, updatedInitiative.ApprovalState)
+	}
+}
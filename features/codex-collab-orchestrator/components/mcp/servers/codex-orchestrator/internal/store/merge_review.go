@@ -0,0 +1,320 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ReviewVerdictApprove        = "approve"
+	ReviewVerdictRequestChanges = "request_changes"
+	ReviewVerdictReject         = "reject"
+)
+
+var reviewFindingSeverities = map[string]bool{
+	"info":     true,
+	"minor":    true,
+	"major":    true,
+	"critical": true,
+}
+
+// reviewVerdictOutcomes maps a verdict to the merge request status and the
+// state of the linked main merge queue item.
+var reviewVerdictOutcomes = map[string]struct {
+	mergeRequestStatus string
+	mainQueueState     string
+}{
+	ReviewVerdictApprove:        {"approved", "approved"},
+	ReviewVerdictRequestChanges: {"changes_requested", "changes_requested"},
+	ReviewVerdictReject:         {"rejected", "rejected"},
+}
+
+// SubmitReview records a reviewer verdict with its findings, completes the
+// review job, and moves the merge request and any linked main merge queue item
+// to the matching state in one transaction.
+func (store *Store) SubmitReview(ctx context.Context, args ReviewSubmitArgs) (ReviewSubmission, error) {
+	if args.ReviewJobID <= 0 {
+		return ReviewSubmission{}, errors.New("review_job_id is required")
+	}
+	verdict := strings.ToLower(strings.TrimSpace(args.Verdict))
+	outcome, ok := reviewVerdictOutcomes[verdict]
+	if !ok {
+		return ReviewSubmission{}, fmt.Errorf("verdict must be one of: approve, request_changes, reject (got %q)", args.Verdict)
+	}
+
+	findings := make([]ReviewFindingArgs, 0, len(args.Findings))
+	for index, finding := range args.Findings {
+		finding.FilePath = strings.TrimSpace(finding.FilePath)
+		finding.Severity = strings.ToLower(strings.TrimSpace(finding.Severity))
+		finding.Message = strings.TrimSpace(finding.Message)
+		if finding.FilePath == "" {
+			return ReviewSubmission{}, fmt.Errorf("findings[%d].file is required", index)
+		}
+		if finding.Message == "" {
+			return ReviewSubmission{}, fmt.Errorf("findings[%d].message is required", index)
+		}
+		if !reviewFindingSeverities[finding.Severity] {
+			return ReviewSubmission{}, fmt.Errorf("findings[%d].severity must be one of: info, minor, major, critical (got %q)", index, finding.Severity)
+		}
+		if finding.Line != nil && *finding.Line <= 0 {
+			return ReviewSubmission{}, fmt.Errorf("findings[%d].line must be positive", index)
+		}
+		if verdict == ReviewVerdictApprove && finding.Severity == "critical" {
+			return ReviewSubmission{}, errors.New("cannot approve with critical findings")
+		}
+		findings = append(findings, finding)
+	}
+	if verdict != ReviewVerdictApprove && len(findings) == 0 && strings.TrimSpace(args.Summary) == "" {
+		return ReviewSubmission{}, fmt.Errorf("%s requires findings or a summary", verdict)
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return ReviewSubmission{}, err
+	}
+	defer transaction.Rollback()
+
+	reviewJob, err := scanReviewJob(transaction.QueryRowContext(
+		ctx,
+		`SELECT `+reviewJobSelectColumns+`
+		   FROM review_jobs
+		  WHERE id = ?`,
+		args.ReviewJobID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ReviewSubmission{}, fmt.Errorf("review job not found: %d", args.ReviewJobID)
+		}
+		return ReviewSubmission{}, err
+	}
+	if reviewJob.State != "requested" && reviewJob.State != "running" {
+		return ReviewSubmission{}, fmt.Errorf("review job %d is already %s", reviewJob.ID, reviewJob.State)
+	}
+
+	now := nowTimestamp()
+	for _, finding := range findings {
+		if _, err := transaction.ExecContext(
+			ctx,
+			`INSERT INTO review_findings(review_job_id, file_path, line, severity, message, created_at)
+			 VALUES(?, ?, ?, ?, ?, ?)`,
+			reviewJob.ID,
+			finding.FilePath,
+			finding.Line,
+			finding.Severity,
+			finding.Message,
+			now,
+		); err != nil {
+			return ReviewSubmission{}, err
+		}
+	}
+
+	notesJSON, err := mergeReviewSummaryIntoNotes(reviewJob.NotesJSON, args.Summary)
+	if err != nil {
+		return ReviewSubmission{}, err
+	}
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE review_jobs
+		 SET state = 'completed', verdict = ?, notes_json = ?, completed_at = COALESCE(completed_at, ?), updated_at = ?
+		 WHERE id = ?`,
+		verdict,
+		notesJSON,
+		now,
+		now,
+		reviewJob.ID,
+	); err != nil {
+		return ReviewSubmission{}, err
+	}
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE merge_requests SET status = ?, updated_at = ? WHERE id = ?`,
+		outcome.mergeRequestStatus,
+		now,
+		reviewJob.MergeRequestID,
+	); err != nil {
+		return ReviewSubmission{}, err
+	}
+
+	var mainMergeRequest *MainMergeQueueItem
+	if reviewJob.MainMergeRequestID != nil {
+		var errorMessage any
+		var completedAt any
+		if verdict != ReviewVerdictApprove {
+			errorMessage = nullableText(strings.TrimSpace(args.Summary))
+		}
+		if verdict == ReviewVerdictReject {
+			completedAt = now
+		}
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE merge_main_queue
+			 SET state = ?, error_message = ?, completed_at = COALESCE(completed_at, ?), updated_at = ?
+			 WHERE id = ?`,
+			outcome.mainQueueState,
+			errorMessage,
+			completedAt,
+			now,
+			*reviewJob.MainMergeRequestID,
+		); err != nil {
+			return ReviewSubmission{}, err
+		}
+		item, err := scanMainMergeQueueItem(transaction.QueryRowContext(
+			ctx,
+			`SELECT id, session_id, from_worktree_id, target_branch, state, started_at, completed_at, error_message, created_at, updated_at
+			 FROM merge_main_queue
+			 WHERE id = ?`,
+			*reviewJob.MainMergeRequestID,
+		))
+		if err != nil {
+			return ReviewSubmission{}, err
+		}
+		mainMergeRequest = &item
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return ReviewSubmission{}, err
+	}
+
+	reviewJob, err = scanReviewJob(transaction.QueryRowContext(
+		ctx,
+		`SELECT `+reviewJobSelectColumns+`
+		   FROM review_jobs
+		  WHERE id = ?`,
+		reviewJob.ID,
+	))
+	if err != nil {
+		return ReviewSubmission{}, err
+	}
+	mergeRequest, err := scanMergeRequest(transaction.QueryRowContext(
+		ctx,
+		`SELECT id, feature_task_id, status, reviewer_session, notes_json, created_at, updated_at
+		 FROM merge_requests WHERE id = ?`,
+		reviewJob.MergeRequestID,
+	))
+	if err != nil {
+		return ReviewSubmission{}, err
+	}
+	storedFindings, err := listReviewFindings(ctx, transaction, reviewJob.ID)
+	if err != nil {
+		return ReviewSubmission{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return ReviewSubmission{}, err
+	}
+	return ReviewSubmission{
+		ReviewJob:        reviewJob,
+		Findings:         storedFindings,
+		MergeRequest:     mergeRequest,
+		MainMergeRequest: mainMergeRequest,
+	}, nil
+}
+
+func (store *Store) ListReviewFindings(ctx context.Context, reviewJobID int64) ([]ReviewFinding, error) {
+	return listReviewFindings(ctx, store.database, reviewJobID)
+}
+
+// UpdateMainMergeRequestState moves a main merge queue item, e.g. to
+// "reviewing" while an auto review is in flight.
+func (store *Store) UpdateMainMergeRequestState(ctx context.Context, requestID int64, state string) (MainMergeQueueItem, error) {
+	state = strings.TrimSpace(state)
+	if state == "" {
+		return MainMergeQueueItem{}, errors.New("state is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return MainMergeQueueItem{}, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		`UPDATE merge_main_queue SET state = ?, updated_at = ? WHERE id = ?`,
+		state,
+		nowTimestamp(),
+		requestID,
+	)
+	if err != nil {
+		return MainMergeQueueItem{}, err
+	}
+	if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+		return MainMergeQueueItem{}, fmt.Errorf("main merge request not found: %d", requestID)
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return MainMergeQueueItem{}, err
+	}
+
+	item, err := scanMainMergeQueueItem(transaction.QueryRowContext(
+		ctx,
+		`SELECT id, session_id, from_worktree_id, target_branch, state, started_at, completed_at, error_message, created_at, updated_at
+		 FROM merge_main_queue
+		 WHERE id = ?`,
+		requestID,
+	))
+	if err != nil {
+		return MainMergeQueueItem{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return MainMergeQueueItem{}, err
+	}
+	return item, nil
+}
+
+func listReviewFindings(ctx context.Context, queryer rowQueryer, reviewJobID int64) ([]ReviewFinding, error) {
+	rows, err := queryer.QueryContext(
+		ctx,
+		`SELECT id, review_job_id, file_path, line, severity, message, created_at
+		 FROM review_findings
+		 WHERE review_job_id = ?
+		 ORDER BY id ASC`,
+		reviewJobID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	findings := make([]ReviewFinding, 0)
+	for rows.Next() {
+		var finding ReviewFinding
+		var line sql.NullInt64
+		if err := rows.Scan(&finding.ID, &finding.ReviewJobID, &finding.FilePath, &line, &finding.Severity, &finding.Message, &finding.CreatedAt); err != nil {
+			return nil, err
+		}
+		if line.Valid {
+			value := int(line.Int64)
+			finding.Line = &value
+		}
+		findings = append(findings, finding)
+	}
+	return findings, rows.Err()
+}
+
+// mergeReviewSummaryIntoNotes keeps whatever the job was created with (the
+// reviewer agent override) and adds the verdict summary under "summary".
+func mergeReviewSummaryIntoNotes(existing *string, summary string) (any, error) {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		if existing == nil {
+			return nil, nil
+		}
+		return *existing, nil
+	}
+	notes := map[string]any{}
+	if existing != nil && strings.TrimSpace(*existing) != "" {
+		if err := json.Unmarshal([]byte(*existing), &notes); err != nil {
+			notes = map[string]any{"request": json.RawMessage(*existing)}
+		}
+	}
+	notes["summary"] = summary
+	encoded, err := json.Marshal(notes)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSubmitReviewDrivesMergeQueue(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "epic", Title: "checkout"})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	mergeRequest, err := store.CreateMergeRequest(context, MergeRequestArgs{FeatureTaskID: feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create merge request: %v", err)
+	}
+	queueItem, err := store.EnqueueMainMergeRequest(context, MainMergeRequestArgs{SessionID: 1, FromWorktreeID: 1})
+	if err != nil {
+		t.Fatalf("failed to enqueue main merge: %v", err)
+	}
+	if _, err := store.UpdateMainMergeRequestState(context, queueItem.ID, "reviewing"); err != nil {
+		t.Fatalf("failed to mark queue item reviewing: %v", err)
+	}
+	if next, err := store.NextMainMergeRequest(context); err != nil || next != nil {
+		t.Fatalf("expected reviewing item to be skipped by next, got %+v (%v)", next, err)
+	}
+
+	firstJob, err := store.CreateReviewJob(context, ReviewJobCreateArgs{MergeRequestID: mergeRequest.ID, SessionID: 1, MainMergeRequestID: &queueItem.ID, State: "running"})
+	if err != nil {
+		t.Fatalf("failed to create review job: %v", err)
+	}
+	line := 42
+	if _, err := store.SubmitReview(context, ReviewSubmitArgs{ReviewJobID: firstJob.ID, Verdict: "request_changes", Findings: []ReviewFindingArgs{{FilePath: "api/pay.go", Severity: "blocker", Message: "x"}}}); err == nil {
+		t.Fatalf("expected unknown severity to be rejected")
+	}
+	submission, err := store.SubmitReview(context, ReviewSubmitArgs{
+		ReviewJobID: firstJob.ID,
+		Verdict:     "request_changes",
+		Summary:     "retry loop is unbounded",
+		Findings:    []ReviewFindingArgs{{FilePath: "api/pay.go", Line: &line, Severity: "Major", Message: "cap retries"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to submit review: %v", err)
+	}
+	if submission.ReviewJob.State != "completed" || *submission.ReviewJob.Verdict != "request_changes" || submission.MergeRequest.Status != "changes_requested" {
+		t.Fatalf("unexpected submission: %+v", submission)
+	}
+	if submission.MainMergeRequest == nil || submission.MainMergeRequest.State != "changes_requested" || *submission.MainMergeRequest.ErrorMessage != "retry loop is unbounded" {
+		t.Fatalf("unexpected queue item after request_changes: %+v", submission.MainMergeRequest)
+	}
+	if len(submission.Findings) != 1 || submission.Findings[0].Severity != "major" || *submission.Findings[0].Line != 42 {
+		t.Fatalf("unexpected findings: %+v", submission.Findings)
+	}
+	if _, err := store.SubmitReview(context, ReviewSubmitArgs{ReviewJobID: firstJob.ID, Verdict: "approve"}); err == nil {
+		t.Fatalf("expected second verdict on a completed job to be rejected")
+	}
+
+	secondJob, err := store.CreateReviewJob(context, ReviewJobCreateArgs{MergeRequestID: mergeRequest.ID, SessionID: 1, MainMergeRequestID: &queueItem.ID, State: "running"})
+	if err != nil {
+		t.Fatalf("failed to create second review job: %v", err)
+	}
+	if _, err := store.SubmitReview(context, ReviewSubmitArgs{ReviewJobID: secondJob.ID, Verdict: "approve", Findings: []ReviewFindingArgs{{FilePath: "a.go", Severity: "critical", Message: "data loss"}}}); err == nil {
+		t.Fatalf("expected approval with critical findings to be rejected")
+	}
+	approved, err := store.SubmitReview(context, ReviewSubmitArgs{ReviewJobID: secondJob.ID, Verdict: "approve", Findings: []ReviewFindingArgs{{FilePath: "a.go", Severity: "info", Message: "nit"}}})
+	if err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+	if approved.MergeRequest.Status != "approved" || approved.MainMergeRequest.State != "approved" {
+		t.Fatalf("unexpected approval outcome: %+v", approved)
+	}
+	next, err := store.NextMainMergeRequest(context)
+	if err != nil || next == nil || next.ID != queueItem.ID {
+		t.Fatalf("expected approved item to be next, got %+v (%v)", next, err)
+	}
+}
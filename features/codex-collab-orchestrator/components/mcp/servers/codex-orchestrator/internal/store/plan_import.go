@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ImportPlanOutline creates the plan, its slices, checklists, contains edges
+// and dependency edges in one transaction, so a failure part-way (a missing
+// node or a dependency cycle) leaves no half-imported graph behind.
+func (store *Store) ImportPlanOutline(ctx context.Context, args PlanImportArgs) (PlanImportResult, error) {
+	if len(args.Slices) == 0 {
+		return PlanImportResult{}, errors.New("at least one slice is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return PlanImportResult{}, err
+	}
+	defer transaction.Rollback()
+
+	result := PlanImportResult{
+		Slices:          make([]GraphNode, 0, len(args.Slices)),
+		DependencyEdges: make([]GraphEdge, 0),
+	}
+	if args.PlanNodeID > 0 {
+		result.Plan, err = store.getGraphNodeByIDTx(ctx, transaction, args.PlanNodeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return PlanImportResult{}, fmt.Errorf("graph node not found: %d", args.PlanNodeID)
+		}
+		if err != nil {
+			return PlanImportResult{}, err
+		}
+	} else {
+		initiativeID := args.InitiativeID
+		if initiativeID <= 0 {
+			initiative, err := store.insertGraphNodeTx(ctx, transaction, GraphNodeCreateArgs{
+				NodeType:       "initiative",
+				Facet:          "planning",
+				Title:          args.InitiativeTitle,
+				Status:         "in_progress",
+				Priority:       args.Priority,
+				OwnerSessionID: args.OwnerSessionID,
+			})
+			if err != nil {
+				return PlanImportResult{}, err
+			}
+			result.Initiative = &initiative
+			initiativeID = initiative.ID
+		}
+		result.Plan, err = store.insertGraphNodeTx(ctx, transaction, GraphNodeCreateArgs{
+			NodeType:       "plan",
+			Facet:          "planning",
+			Title:          args.PlanTitle,
+			Status:         "todo",
+			Priority:       args.Priority,
+			ParentID:       &initiativeID,
+			OwnerSessionID: args.OwnerSessionID,
+			Summary:        args.PlanSummary,
+		})
+		if err != nil {
+			return PlanImportResult{}, err
+		}
+		if _, err := store.insertGraphEdgeTx(ctx, transaction, GraphEdgeCreateArgs{FromNodeID: initiativeID, ToNodeID: result.Plan.ID, EdgeType: "contains"}); err != nil {
+			return PlanImportResult{}, err
+		}
+		if err := insertImportedChecklistTx(ctx, transaction, result.Plan.ID, args.PlanChecklist); err != nil {
+			return PlanImportResult{}, err
+		}
+	}
+
+	for _, slice := range args.Slices {
+		sliceNode, err := store.insertGraphNodeTx(ctx, transaction, GraphNodeCreateArgs{
+			NodeType:          "slice",
+			Facet:             "planning",
+			Title:             slice.Title,
+			Status:            "todo",
+			Priority:          slice.Priority,
+			ParentID:          &result.Plan.ID,
+			OwnerSessionID:    args.OwnerSessionID,
+			Summary:           slice.Summary,
+			RiskLevel:         slice.RiskLevel,
+			TokenEstimate:     slice.TokenEstimate,
+			AffectedFilesJSON: slice.AffectedFilesJSON,
+		})
+		if err != nil {
+			return PlanImportResult{}, err
+		}
+		if _, err := store.insertGraphEdgeTx(ctx, transaction, GraphEdgeCreateArgs{FromNodeID: result.Plan.ID, ToNodeID: sliceNode.ID, EdgeType: "contains"}); err != nil {
+			return PlanImportResult{}, err
+		}
+		if err := insertImportedChecklistTx(ctx, transaction, sliceNode.ID, slice.Checklist); err != nil {
+			return PlanImportResult{}, err
+		}
+		result.Slices = append(result.Slices, sliceNode)
+	}
+
+	for index, slice := range args.Slices {
+		prerequisiteIDs := make([]int64, 0, len(slice.AfterSlices)+len(slice.AfterNodeIDs))
+		for _, sliceIndex := range slice.AfterSlices {
+			if sliceIndex < 0 || sliceIndex >= len(result.Slices) {
+				return PlanImportResult{}, fmt.Errorf("slice %q: dependency index %d out of range", slice.Title, sliceIndex)
+			}
+			prerequisiteIDs = append(prerequisiteIDs, result.Slices[sliceIndex].ID)
+		}
+		for _, nodeID := range slice.AfterNodeIDs {
+			if _, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID); errors.Is(err, sql.ErrNoRows) {
+				return PlanImportResult{}, fmt.Errorf("slice %q: graph node not found: %d", slice.Title, nodeID)
+			} else if err != nil {
+				return PlanImportResult{}, err
+			}
+			prerequisiteIDs = append(prerequisiteIDs, nodeID)
+		}
+		for _, prerequisiteID := range prerequisiteIDs {
+			edge, err := store.insertGraphEdgeTx(ctx, transaction, GraphEdgeCreateArgs{
+				FromNodeID: result.Slices[index].ID,
+				ToNodeID:   prerequisiteID,
+				EdgeType:   EdgeTypeDependsOn,
+			})
+			if err != nil {
+				return PlanImportResult{}, err
+			}
+			result.DependencyEdges = append(result.DependencyEdges, edge)
+		}
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return PlanImportResult{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return PlanImportResult{}, err
+	}
+	return result, nil
+}
+
+func insertImportedChecklistTx(ctx context.Context, transaction *sql.Tx, nodeID int64, items []PlanImportChecklistItem) error {
+	for index, item := range items {
+		if strings.TrimSpace(item.ItemText) == "" {
+			return errors.New("item_text is required")
+		}
+		status := strings.TrimSpace(item.Status)
+		if status == "" {
+			status = "todo"
+		}
+		if _, err := upsertNodeChecklistItemTx(ctx, transaction, NodeChecklistUpsertArgs{
+			NodeID:   nodeID,
+			ItemText: item.ItemText,
+			Status:   status,
+			OrderNo:  int64(index + 1),
+			Facet:    "planning",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
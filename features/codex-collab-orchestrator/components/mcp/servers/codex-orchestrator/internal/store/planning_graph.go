@@ -3,11 +3,41 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 )
 
+const graphNodeSelectColumns = `id, node_type, facet, title, status, priority, parent_id, worktree_id, owner_session_id, summary, risk_level, token_estimate, affected_files_json, approval_state, created_at, updated_at, deleted_at`
+
+// ErrGraphNodeClaimed reports a slice that is no longer todo, usually because
+// a concurrent plan.dispatch claimed it first.
+var ErrGraphNodeClaimed = errors.New("graph node is not todo")
+
+const nodeSnapshotSelectColumns = `id, node_id, snapshot_type, summary, affected_files_json, next_action, created_at, payload_json`
+
 func (store *Store) CreateGraphNode(ctx context.Context, args GraphNodeCreateArgs) (GraphNode, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	defer transaction.Rollback()
+
+	node, err := store.insertGraphNodeTx(ctx, transaction, args)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphNode{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphNode{}, err
+	}
+	return node, nil
+}
+
+func (store *Store) insertGraphNodeTx(ctx context.Context, transaction *sql.Tx, args GraphNodeCreateArgs) (GraphNode, error) {
 	if strings.TrimSpace(args.NodeType) == "" {
 		return GraphNode{}, errors.New("node_type is required")
 	}
@@ -27,12 +57,6 @@ func (store *Store) CreateGraphNode(ctx context.Context, args GraphNodeCreateArg
 		approvalState = "none"
 	}
 
-	transaction, err := store.database.BeginTx(ctx, nil)
-	if err != nil {
-		return GraphNode{}, err
-	}
-	defer transaction.Rollback()
-
 	now := nowTimestamp()
 	result, err := transaction.ExecContext(
 		ctx,
@@ -61,34 +85,18 @@ func (store *Store) CreateGraphNode(ctx context.Context, args GraphNodeCreateArg
 	if err != nil {
 		return GraphNode{}, err
 	}
-
-	if err := store.bumpVersionTx(ctx, transaction); err != nil {
-		return GraphNode{}, err
-	}
-
-	row := transaction.QueryRowContext(
-		ctx,
-		`SELECT id, node_type, facet, title, status, priority, parent_id, worktree_id, owner_session_id, summary, risk_level, token_estimate, affected_files_json, approval_state, created_at, updated_at
-		 FROM graph_nodes
-		 WHERE id = ?`,
-		nodeID,
-	)
-	node, err := scanGraphNode(row)
-	if err != nil {
-		return GraphNode{}, err
-	}
-	if err := transaction.Commit(); err != nil {
-		return GraphNode{}, err
-	}
-	return node, nil
+	return store.getGraphNodeByIDTx(ctx, transaction, nodeID)
 }
 
 func (store *Store) ListGraphNodes(ctx context.Context, filter GraphNodeFilter) ([]GraphNode, error) {
-	query := `SELECT id, node_type, facet, title, status, priority, parent_id, worktree_id, owner_session_id, summary, risk_level, token_estimate, affected_files_json, approval_state, created_at, updated_at
+	query := `SELECT ` + graphNodeSelectColumns + `
 		FROM graph_nodes`
-	whereClauses := make([]string, 0, 4)
+	whereClauses := make([]string, 0, 5)
 	parameters := make([]any, 0, 4)
 
+	if !filter.IncludeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
 	if strings.TrimSpace(filter.NodeType) != "" {
 		whereClauses = append(whereClauses, "node_type = ?")
 		parameters = append(parameters, filter.NodeType)
@@ -130,7 +138,7 @@ func (store *Store) ListGraphNodes(ctx context.Context, filter GraphNodeFilter)
 func (store *Store) GetGraphNodeByID(ctx context.Context, nodeID int64) (GraphNode, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, node_type, facet, title, status, priority, parent_id, worktree_id, owner_session_id, summary, risk_level, token_estimate, affected_files_json, approval_state, created_at, updated_at
+		`SELECT `+graphNodeSelectColumns+`
 		 FROM graph_nodes
 		 WHERE id = ?`,
 		nodeID,
@@ -181,7 +189,7 @@ func (store *Store) UpdateGraphNodeApprovalState(ctx context.Context, nodeID int
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, node_type, facet, title, status, priority, parent_id, worktree_id, owner_session_id, summary, risk_level, token_estimate, affected_files_json, approval_state, created_at, updated_at
+		`SELECT `+graphNodeSelectColumns+`
 		 FROM graph_nodes
 		 WHERE id = ?`,
 		nodeID,
@@ -196,7 +204,87 @@ func (store *Store) UpdateGraphNodeApprovalState(ctx context.Context, nodeID int
 	return node, nil
 }
 
+// ClaimGraphNodeForDispatch moves a todo slice to in_progress. Only one
+// caller wins the claim; the others get ErrGraphNodeClaimed.
+func (store *Store) ClaimGraphNodeForDispatch(ctx context.Context, nodeID int64) (GraphNode, error) {
+	return store.transitionGraphNodeDispatch(ctx, nodeID, "todo", "in_progress", nil)
+}
+
+// ReleaseGraphNodeClaim returns a claimed slice to todo after a failed
+// dispatch.
+func (store *Store) ReleaseGraphNodeClaim(ctx context.Context, nodeID int64) (GraphNode, error) {
+	return store.transitionGraphNodeDispatch(ctx, nodeID, "in_progress", "todo", nil)
+}
+
+// MarkGraphNodeDispatched records the worktree of a claimed slice.
+func (store *Store) MarkGraphNodeDispatched(ctx context.Context, nodeID int64, worktreeID *int64) (GraphNode, error) {
+	return store.transitionGraphNodeDispatch(ctx, nodeID, "in_progress", "in_progress", worktreeID)
+}
+
+func (store *Store) transitionGraphNodeDispatch(ctx context.Context, nodeID int64, fromStatus string, toStatus string, worktreeID *int64) (GraphNode, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		`UPDATE graph_nodes
+		 SET status = ?, worktree_id = COALESCE(?, worktree_id), updated_at = ?
+		 WHERE id = ? AND status = ?`,
+		toStatus,
+		worktreeID,
+		nowTimestamp(),
+		nodeID,
+		fromStatus,
+	)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+		if fromStatus == "todo" {
+			return GraphNode{}, fmt.Errorf("%w: %d", ErrGraphNodeClaimed, nodeID)
+		}
+		return GraphNode{}, fmt.Errorf("graph node is not %s: %d", fromStatus, nodeID)
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphNode{}, err
+	}
+	node, err := store.getGraphNodeByIDTx(ctx, transaction, nodeID)
+	if err != nil {
+		return GraphNode{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphNode{}, err
+	}
+	return node, nil
+}
+
 func (store *Store) CreateGraphEdge(ctx context.Context, args GraphEdgeCreateArgs) (GraphEdge, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return GraphEdge{}, err
+	}
+	defer transaction.Rollback()
+
+	edge, err := store.insertGraphEdgeTx(ctx, transaction, args)
+	if err != nil {
+		return GraphEdge{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return GraphEdge{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return GraphEdge{}, err
+	}
+	return edge, nil
+}
+
+// insertGraphEdgeTx inserts an edge after checking it does not close a
+// dependency cycle.
+func (store *Store) insertGraphEdgeTx(ctx context.Context, transaction *sql.Tx, args GraphEdgeCreateArgs) (GraphEdge, error) {
 	if args.FromNodeID <= 0 || args.ToNodeID <= 0 {
 		return GraphEdge{}, errors.New("from_node_id and to_node_id are required")
 	}
@@ -205,11 +293,13 @@ func (store *Store) CreateGraphEdge(ctx context.Context, args GraphEdgeCreateArg
 		return GraphEdge{}, errors.New("edge_type is required")
 	}
 
-	transaction, err := store.database.BeginTx(ctx, nil)
-	if err != nil {
+	if err := store.checkDependencyCycleTx(ctx, transaction, GraphEdgeCreateArgs{
+		FromNodeID: args.FromNodeID,
+		ToNodeID:   args.ToNodeID,
+		EdgeType:   edgeType,
+	}); err != nil {
 		return GraphEdge{}, err
 	}
-	defer transaction.Rollback()
 
 	result, err := transaction.ExecContext(
 		ctx,
@@ -228,10 +318,6 @@ func (store *Store) CreateGraphEdge(ctx context.Context, args GraphEdgeCreateArg
 		return GraphEdge{}, err
 	}
 
-	if err := store.bumpVersionTx(ctx, transaction); err != nil {
-		return GraphEdge{}, err
-	}
-
 	row := transaction.QueryRowContext(
 		ctx,
 		`SELECT id, from_node_id, to_node_id, edge_type, created_at
@@ -239,14 +325,7 @@ func (store *Store) CreateGraphEdge(ctx context.Context, args GraphEdgeCreateArg
 		 WHERE id = ?`,
 		edgeID,
 	)
-	edge, err := scanGraphEdge(row)
-	if err != nil {
-		return GraphEdge{}, err
-	}
-	if err := transaction.Commit(); err != nil {
-		return GraphEdge{}, err
-	}
-	return edge, nil
+	return scanGraphEdge(row)
 }
 
 func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeChecklistUpsertArgs) (NodeChecklistItem, error) {
@@ -267,6 +346,8 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 	if facet == "" {
 		facet = "planning"
 	}
+	args.Status = status
+	args.Facet = facet
 
 	transaction, err := store.database.BeginTx(ctx, nil)
 	if err != nil {
@@ -274,6 +355,23 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 	}
 	defer transaction.Rollback()
 
+	checklist, err := upsertNodeChecklistItemTx(ctx, transaction, args)
+	if err != nil {
+		return NodeChecklistItem{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return NodeChecklistItem{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return NodeChecklistItem{}, err
+	}
+	return checklist, nil
+}
+
+// upsertNodeChecklistItemTx writes the item at args.OrderNo, replacing the
+// text and status of an existing item there. Args must be normalized.
+func upsertNodeChecklistItemTx(ctx context.Context, transaction *sql.Tx, args NodeChecklistUpsertArgs) (NodeChecklistItem, error) {
+	var err error
 	row := transaction.QueryRowContext(
 		ctx,
 		`SELECT id
@@ -282,7 +380,7 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 		 LIMIT 1`,
 		args.NodeID,
 		args.OrderNo,
-		facet,
+		args.Facet,
 	)
 	var checklistID int64
 	queryErr := row.Scan(&checklistID)
@@ -294,7 +392,7 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 			 SET item_text = ?, status = ?, updated_at = ?
 			 WHERE id = ?`,
 			args.ItemText,
-			status,
+			args.Status,
 			nowTimestamp(),
 			checklistID,
 		)
@@ -308,9 +406,9 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 			 VALUES(?, ?, ?, ?, ?, ?, ?)`,
 			args.NodeID,
 			args.ItemText,
-			status,
+			args.Status,
 			args.OrderNo,
-			facet,
+			args.Facet,
 			nowTimestamp(),
 			nowTimestamp(),
 		)
@@ -325,10 +423,6 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 		return NodeChecklistItem{}, queryErr
 	}
 
-	if err := store.bumpVersionTx(ctx, transaction); err != nil {
-		return NodeChecklistItem{}, err
-	}
-
 	refRow := transaction.QueryRowContext(
 		ctx,
 		`SELECT id, node_id, item_text, status, order_no, facet, created_at, updated_at
@@ -336,15 +430,7 @@ func (store *Store) UpsertNodeChecklistItem(ctx context.Context, args NodeCheckl
 		 WHERE id = ?`,
 		checklistID,
 	)
-	checklist, err := scanNodeChecklistItem(refRow)
-	if err != nil {
-		return NodeChecklistItem{}, err
-	}
-
-	if err := transaction.Commit(); err != nil {
-		return NodeChecklistItem{}, err
-	}
-	return checklist, nil
+	return scanNodeChecklistItem(refRow)
 }
 
 func (store *Store) CreateNodeSnapshot(ctx context.Context, args NodeSnapshotCreateArgs) (NodeSnapshot, error) {
@@ -386,7 +472,7 @@ func (store *Store) CreateNodeSnapshot(ctx context.Context, args NodeSnapshotCre
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, node_id, snapshot_type, summary, affected_files_json, next_action, created_at
+		`SELECT `+nodeSnapshotSelectColumns+`
 		 FROM node_snapshots
 		 WHERE id = ?`,
 		snapshotID,
@@ -416,6 +502,82 @@ func (store *Store) GetPlanningRule(ctx context.Context) (PlanningRule, error) {
 	return rule, nil
 }
 
+func (store *Store) UpdatePlanningRule(ctx context.Context, args PlanningRuleUpdateArgs) (PlanningRule, error) {
+	setClauses := make([]string, 0, 5)
+	params := make([]any, 0, 5)
+
+	if args.MaxTokenPerSlice != nil {
+		if *args.MaxTokenPerSlice <= 0 {
+			return PlanningRule{}, errors.New("max_token_per_slice must be positive")
+		}
+		setClauses = append(setClauses, "max_token_per_slice = ?")
+		params = append(params, *args.MaxTokenPerSlice)
+	}
+	if args.MaxFilesPerSlice != nil {
+		if *args.MaxFilesPerSlice <= 0 {
+			return PlanningRule{}, errors.New("max_files_per_slice must be positive")
+		}
+		setClauses = append(setClauses, "max_files_per_slice = ?")
+		params = append(params, *args.MaxFilesPerSlice)
+	}
+	if args.ReplanTriggers != nil {
+		triggersJSON, err := json.Marshal(args.ReplanTriggers)
+		if err != nil {
+			return PlanningRule{}, err
+		}
+		setClauses = append(setClauses, "replan_triggers_json = ?")
+		params = append(params, string(triggersJSON))
+	}
+	if args.ApprovalPolicy != nil {
+		approvalPolicy := strings.TrimSpace(*args.ApprovalPolicy)
+		if approvalPolicy == "" {
+			return PlanningRule{}, errors.New("approval_policy cannot be empty")
+		}
+		if _, err := ParseApprovalPolicy(approvalPolicy); err != nil {
+			return PlanningRule{}, err
+		}
+		setClauses = append(setClauses, "approval_policy = ?")
+		params = append(params, approvalPolicy)
+	}
+	if len(setClauses) == 0 {
+		return PlanningRule{}, errors.New("no planning rule fields to update")
+	}
+	setClauses = append(setClauses, "updated_at = ?")
+	params = append(params, nowTimestamp())
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return PlanningRule{}, err
+	}
+	defer transaction.Rollback()
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE planning_rules SET %s WHERE id = 1", strings.Join(setClauses, ", ")),
+		params...,
+	); err != nil {
+		return PlanningRule{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return PlanningRule{}, err
+	}
+
+	var rule PlanningRule
+	if err := transaction.QueryRowContext(
+		ctx,
+		`SELECT max_token_per_slice, max_files_per_slice, replan_triggers_json, approval_policy, updated_at
+		 FROM planning_rules
+		 WHERE id = 1`,
+	).Scan(&rule.MaxTokenPerSlice, &rule.MaxFilesPerSlice, &rule.ReplanTriggersJSON, &rule.ApprovalPolicy, &rule.UpdatedAt); err != nil {
+		return PlanningRule{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return PlanningRule{}, err
+	}
+	return rule, nil
+}
+
 func (store *Store) RollupPreview(ctx context.Context, parentNodeID int64) (map[string]any, error) {
 	nodes, err := store.ListGraphNodes(ctx, GraphNodeFilter{
 		ParentID: &parentNodeID,
@@ -428,14 +590,195 @@ func (store *Store) RollupPreview(ctx context.Context, parentNodeID int64) (map[
 	for _, node := range nodes {
 		statusCounts[node.Status]++
 	}
+
+	descendants, err := store.listGraphDescendants(ctx, parentNodeID)
+	if err != nil {
+		return nil, err
+	}
+	hasChildren := make(map[int64]bool)
+	for _, descendant := range descendants {
+		if descendant.ParentID != nil {
+			hasChildren[*descendant.ParentID] = true
+		}
+	}
+
+	recursiveStatusCounts := map[string]int{}
+	totalLeaves := 0
+	doneLeaves := 0
+	estimatedTokens := 0
+	doneEstimateTokens := 0
+	riskyNodes := 0
+	maxRemainingRisk := 0
+	totalRemainingRisk := 0
+	for _, descendant := range descendants {
+		recursiveStatusCounts[descendant.Status]++
+		if descendant.Status == "cancelled" {
+			continue
+		}
+		if descendant.Status != "done" && descendant.RiskLevel != nil && *descendant.RiskLevel > 0 {
+			riskyNodes++
+			totalRemainingRisk += *descendant.RiskLevel
+			if *descendant.RiskLevel > maxRemainingRisk {
+				maxRemainingRisk = *descendant.RiskLevel
+			}
+		}
+		if hasChildren[descendant.ID] {
+			continue
+		}
+		totalLeaves++
+		tokenEstimate := 0
+		if descendant.TokenEstimate != nil {
+			tokenEstimate = *descendant.TokenEstimate
+		}
+		estimatedTokens += tokenEstimate
+		if descendant.Status == "done" {
+			doneLeaves++
+			doneEstimateTokens += tokenEstimate
+		}
+	}
+	progressPercent := 0
+	if totalLeaves > 0 {
+		progressPercent = doneLeaves * 100 / totalLeaves
+	}
+
+	linkedTaskCount := 0
+	linkedDoneCount := 0
+	if err := store.database.QueryRowContext(
+		ctx,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM graph_nodes WHERE id = ?
+			UNION
+			SELECT graph_nodes.id FROM graph_nodes JOIN subtree ON graph_nodes.parent_id = subtree.id
+			WHERE graph_nodes.deleted_at IS NULL
+		)
+		SELECT COUNT(DISTINCT tasks.id), COUNT(DISTINCT CASE WHEN tasks.status = 'done' THEN tasks.id END)
+		FROM graph_node_tasks
+		JOIN tasks ON tasks.id = graph_node_tasks.task_id
+		WHERE graph_node_tasks.node_id IN (SELECT id FROM subtree)
+		  AND tasks.deleted_at IS NULL AND tasks.status != 'cancelled'`,
+		parentNodeID,
+	).Scan(&linkedTaskCount, &linkedDoneCount); err != nil {
+		return nil, err
+	}
+
 	return map[string]any{
-		"parent_node_id": parentNodeID,
-		"child_count":    len(nodes),
-		"status_counts":  statusCounts,
-		"children":       nodes,
+		"parent_node_id":          parentNodeID,
+		"child_count":             len(nodes),
+		"status_counts":           statusCounts,
+		"children":                nodes,
+		"descendant_count":        len(descendants),
+		"recursive_status_counts": recursiveStatusCounts,
+		"progress": map[string]int{
+			"total_leaves": totalLeaves,
+			"done_leaves":  doneLeaves,
+			"percent":      progressPercent,
+		},
+		"tokens": map[string]int{
+			"estimated":     estimatedTokens,
+			"done_estimate": doneEstimateTokens,
+			"remaining":     estimatedTokens - doneEstimateTokens,
+		},
+		"risk": map[string]int{
+			"open_risky_nodes": riskyNodes,
+			"max_remaining":    maxRemainingRisk,
+			"total_remaining":  totalRemainingRisk,
+		},
+		"linked_tasks": map[string]int{
+			"total": linkedTaskCount,
+			"done":  linkedDoneCount,
+		},
 	}, nil
 }
 
+// GetGraphSubtree returns the live subtree rooted at rootNodeID (root first)
+// together with every edge whose endpoints both lie inside it.
+func (store *Store) GetGraphSubtree(ctx context.Context, rootNodeID int64) (GraphSubtree, error) {
+	root, err := store.GetGraphNodeByID(ctx, rootNodeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return GraphSubtree{}, fmt.Errorf("graph node not found: %d", rootNodeID)
+		}
+		return GraphSubtree{}, err
+	}
+	if root.DeletedAt != nil {
+		return GraphSubtree{}, fmt.Errorf("graph node is deleted: %d", rootNodeID)
+	}
+
+	descendants, err := store.listGraphDescendants(ctx, rootNodeID)
+	if err != nil {
+		return GraphSubtree{}, err
+	}
+	nodes := append([]GraphNode{root}, descendants...)
+
+	rows, err := store.database.QueryContext(
+		ctx,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM graph_nodes WHERE id = ?
+			UNION
+			SELECT graph_nodes.id FROM graph_nodes JOIN subtree ON graph_nodes.parent_id = subtree.id
+			WHERE graph_nodes.deleted_at IS NULL
+		)
+		SELECT id, from_node_id, to_node_id, edge_type, created_at
+		FROM graph_edges
+		WHERE from_node_id IN (SELECT id FROM subtree) AND to_node_id IN (SELECT id FROM subtree)
+		ORDER BY id ASC`,
+		rootNodeID,
+	)
+	if err != nil {
+		return GraphSubtree{}, err
+	}
+	defer rows.Close()
+
+	edges := make([]GraphEdge, 0)
+	for rows.Next() {
+		edge, scanErr := scanGraphEdge(rows)
+		if scanErr != nil {
+			return GraphSubtree{}, scanErr
+		}
+		edges = append(edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return GraphSubtree{}, err
+	}
+
+	return GraphSubtree{
+		Root:  root,
+		Nodes: nodes,
+		Edges: edges,
+	}, nil
+}
+
+func (store *Store) listGraphDescendants(ctx context.Context, rootNodeID int64) ([]GraphNode, error) {
+	rows, err := store.database.QueryContext(
+		ctx,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM graph_nodes WHERE id = ?
+			UNION
+			SELECT graph_nodes.id FROM graph_nodes JOIN subtree ON graph_nodes.parent_id = subtree.id
+		)
+		SELECT `+graphNodeSelectColumns+`
+		FROM graph_nodes
+		WHERE id IN (SELECT id FROM subtree) AND id != ? AND deleted_at IS NULL
+		ORDER BY id ASC`,
+		rootNodeID,
+		rootNodeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := make([]GraphNode, 0)
+	for rows.Next() {
+		node, scanErr := scanGraphNode(rows)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
 func scanGraphNode(scanner rowScanner) (GraphNode, error) {
 	var node GraphNode
 	var parentID sql.NullInt64
@@ -445,6 +788,7 @@ func scanGraphNode(scanner rowScanner) (GraphNode, error) {
 	var riskLevel sql.NullInt64
 	var tokenEstimate sql.NullInt64
 	var affectedFilesJSON sql.NullString
+	var deletedAt sql.NullString
 	err := scanner.Scan(
 		&node.ID,
 		&node.NodeType,
@@ -462,6 +806,7 @@ func scanGraphNode(scanner rowScanner) (GraphNode, error) {
 		&node.ApprovalState,
 		&node.CreatedAt,
 		&node.UpdatedAt,
+		&deletedAt,
 	)
 	if err != nil {
 		return GraphNode{}, err
@@ -489,6 +834,9 @@ func scanGraphNode(scanner rowScanner) (GraphNode, error) {
 	if affectedFilesJSON.Valid {
 		node.AffectedFilesJSON = &affectedFilesJSON.String
 	}
+	if deletedAt.Valid {
+		node.DeletedAt = &deletedAt.String
+	}
 	return node, nil
 }
 
@@ -530,6 +878,7 @@ func scanNodeSnapshot(scanner rowScanner) (NodeSnapshot, error) {
 	var summary sql.NullString
 	var affectedFilesJSON sql.NullString
 	var nextAction sql.NullString
+	var payloadJSON sql.NullString
 	err := scanner.Scan(
 		&snapshot.ID,
 		&snapshot.NodeID,
@@ -538,6 +887,7 @@ func scanNodeSnapshot(scanner rowScanner) (NodeSnapshot, error) {
 		&affectedFilesJSON,
 		&nextAction,
 		&snapshot.CreatedAt,
+		&payloadJSON,
 	)
 	if err != nil {
 		return NodeSnapshot{}, err
@@ -551,5 +901,8 @@ func scanNodeSnapshot(scanner rowScanner) (NodeSnapshot, error) {
 	if nextAction.Valid {
 		snapshot.NextAction = &nextAction.String
 	}
+	if payloadJSON.Valid {
+		snapshot.PayloadJSON = &payloadJSON.String
+	}
 	return snapshot, nil
 }
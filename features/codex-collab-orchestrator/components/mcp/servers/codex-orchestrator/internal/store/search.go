@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	SearchEntityTask         = "task"
+	SearchEntityGraphNode    = "graph_node"
+	SearchEntityNodeSnapshot = "node_snapshot"
+	SearchEntityStep         = "step"
+	SearchEntityCheckpoint   = "checkpoint"
+	SearchEntityInboxMessage = "inbox_message"
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	// searchRowIDStride packs (entity_id, source) into the FTS rowid so the
+	// triggers can replace an entry without scanning the index.
+	searchRowIDStride = 8
+)
+
+// searchSource describes how one table feeds search_index. Expressions use
+// {row} for the row alias (NEW in triggers, the table name in backfills).
+type searchSource struct {
+	entityType string
+	code       int
+	table      string
+	parentExpr string
+	titleExpr  string
+	bodyExpr   string
+	liveExpr   string
+}
+
+var searchSources = []searchSource{
+	{
+		entityType: SearchEntityTask,
+		code:       1,
+		table:      "tasks",
+		parentExpr: "{row}.parent_id",
+		titleExpr:  "{row}.title",
+		bodyExpr:   "coalesce({row}.next_action, '') || ' ' || coalesce({row}.status_reason, '')",
+		liveExpr:   "{row}.deleted_at IS NULL",
+	},
+	{
+		entityType: SearchEntityGraphNode,
+		code:       2,
+		table:      "graph_nodes",
+		parentExpr: "{row}.parent_id",
+		titleExpr:  "{row}.title",
+		bodyExpr:   "coalesce({row}.summary, '')",
+		liveExpr:   "{row}.deleted_at IS NULL",
+	},
+	{
+		entityType: SearchEntityNodeSnapshot,
+		code:       3,
+		table:      "node_snapshots",
+		parentExpr: "{row}.node_id",
+		titleExpr:  "{row}.snapshot_type",
+		bodyExpr:   "coalesce({row}.summary, '') || ' ' || coalesce({row}.next_action, '')",
+		liveExpr:   "1",
+	},
+	{
+		entityType: SearchEntityStep,
+		code:       4,
+		table:      "steps",
+		parentExpr: "{row}.task_id",
+		titleExpr:  "{row}.title",
+		bodyExpr:   "{row}.evidence_json",
+		liveExpr:   "1",
+	},
+	{
+		entityType: SearchEntityCheckpoint,
+		code:       5,
+		table:      "checkpoints",
+		parentExpr: "{row}.task_id",
+		titleExpr:  "{row}.step_title",
+		bodyExpr:   "{row}.snapshot_json",
+		liveExpr:   "1",
+	},
+	{
+		entityType: SearchEntityInboxMessage,
+		code:       6,
+		table:      "inbox_messages",
+		parentExpr: "{row}.receiver_thread_id",
+		titleExpr:  "''",
+		bodyExpr:   "{row}.message",
+		liveExpr:   "1",
+	},
+}
+
+func (source searchSource) expression(template string, rowAlias string) string {
+	return strings.ReplaceAll(template, "{row}", rowAlias)
+}
+
+func (source searchSource) selectSQL(rowAlias string) string {
+	return fmt.Sprintf(
+		`SELECT %s.id * %d + %d, '%s', %s.id, %s, %s, %s WHERE %s`,
+		rowAlias,
+		searchRowIDStride,
+		source.code,
+		source.entityType,
+		rowAlias,
+		source.expression(source.parentExpr, rowAlias),
+		source.expression(source.titleExpr, rowAlias),
+		source.expression(source.bodyExpr, rowAlias),
+		source.expression(source.liveExpr, rowAlias),
+	)
+}
+
+// searchIndexMigrations creates the FTS5 index and the triggers that keep it
+// in sync with every searchable table.
+func searchIndexMigrations() []string {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+			entity_type UNINDEXED,
+			entity_id UNINDEXED,
+			parent_id UNINDEXED,
+			title,
+			body,
+			tokenize = 'unicode61'
+		);`,
+	}
+	for _, source := range searchSources {
+		insertSQL := `INSERT INTO search_index(rowid, entity_type, entity_id, parent_id, title, body) ` + source.selectSQL("NEW") + `;`
+		deleteSQL := fmt.Sprintf(`DELETE FROM search_index WHERE rowid = OLD.id * %d + %d;`, searchRowIDStride, source.code)
+		statements = append(statements,
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS search_index_%s_ai AFTER INSERT ON %s BEGIN %s END;`, source.table, source.table, insertSQL),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS search_index_%s_au AFTER UPDATE ON %s BEGIN %s %s END;`, source.table, source.table, deleteSQL, insertSQL),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS search_index_%s_ad AFTER DELETE ON %s BEGIN %s END;`, source.table, source.table, deleteSQL),
+		)
+	}
+	return statements
+}
+
+// backfillSearchIndex indexes rows written before the search index existed.
+func (store *Store) backfillSearchIndex(ctx context.Context) error {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	for _, source := range searchSources {
+		selectSQL := strings.Replace(source.selectSQL(source.table), " WHERE ", " FROM "+source.table+" WHERE ", 1)
+		if _, err := transaction.ExecContext(ctx, `INSERT INTO search_index(rowid, entity_type, entity_id, parent_id, title, body) `+selectSQL); err != nil {
+			return fmt.Errorf("failed to backfill search index from %s: %w", source.table, err)
+		}
+	}
+	return transaction.Commit()
+}
+
+func (store *Store) Search(ctx context.Context, args SearchArgs) ([]SearchResult, error) {
+	matchExpression := strings.TrimSpace(args.Query)
+	if matchExpression == "" {
+		return nil, errors.New("query is required")
+	}
+	if !args.Raw {
+		matchExpression = buildSearchMatchExpression(matchExpression, args.MatchAny)
+		if matchExpression == "" {
+			return nil, errors.New("query has no searchable terms")
+		}
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	query := `SELECT entity_type, entity_id, parent_id, title,
+		snippet(search_index, 4, '[', ']', '…', 16),
+		bm25(search_index, 0.0, 0.0, 0.0, 4.0, 1.0)
+		FROM search_index
+		WHERE search_index MATCH ?`
+	parameters := []any{matchExpression}
+	if len(args.EntityTypes) > 0 {
+		knownTypes := make(map[string]bool, len(searchSources))
+		for _, source := range searchSources {
+			knownTypes[source.entityType] = true
+		}
+		placeholders := make([]string, 0, len(args.EntityTypes))
+		for _, entityType := range args.EntityTypes {
+			if !knownTypes[entityType] {
+				return nil, fmt.Errorf("unknown search entity type: %s", entityType)
+			}
+			placeholders = append(placeholders, "?")
+			parameters = append(parameters, entityType)
+		}
+		query += " AND entity_type IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	query += " ORDER BY bm25(search_index, 0.0, 0.0, 0.0, 4.0, 1.0) ASC LIMIT ?"
+	parameters = append(parameters, limit)
+
+	rows, err := store.database.QueryContext(ctx, query, parameters...)
+	if err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			return nil, fmt.Errorf("invalid search query: %w", err)
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0)
+	for rows.Next() {
+		var result SearchResult
+		var parentID *int64
+		var rank float64
+		if err := rows.Scan(&result.EntityType, &result.EntityID, &parentID, &result.Title, &result.Snippet, &rank); err != nil {
+			return nil, err
+		}
+		result.ParentID = parentID
+		// bm25 is lower-is-better; flip it so larger scores rank higher.
+		result.Score = -rank
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// buildSearchMatchExpression quotes each word of a free-text query so FTS5
+// punctuation ("?", "-", ":") is matched literally, then joins them with AND
+// (default) or OR.
+func buildSearchMatchExpression(query string, matchAny bool) string {
+	terms := strings.FieldsFunc(query, func(character rune) bool {
+		return !unicode.IsLetter(character) && !unicode.IsDigit(character) && character != '_'
+	})
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"`)
+	}
+	separator := " "
+	if matchAny {
+		separator = " OR "
+	}
+	return strings.Join(quoted, separator)
+}
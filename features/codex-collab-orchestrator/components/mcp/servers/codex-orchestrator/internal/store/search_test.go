@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchIndexTracksWritesAndRanks(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	task, err := store.CreateTask(context, TaskCreateArgs{Level: "epic", Title: "Payments ledger migration"})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	nextAction := "backfill ledger rows"
+	if _, err := store.UpdateTask(context, task.ID, TaskUpdateArgs{NextAction: &nextAction}); err != nil {
+		t.Fatalf("failed to update task: %v", err)
+	}
+	node, err := store.CreateGraphNode(context, GraphNodeCreateArgs{NodeType: "slice", Facet: "planning", Title: "Checkout UI", Summary: "render ledger totals"})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	if _, err := store.CreateInboxMessage(context, InboxMessageCreateArgs{SenderThreadID: 1, ReceiverThreadID: 2, Message: "ledger schema is frozen"}); err != nil {
+		t.Fatalf("failed to create inbox message: %v", err)
+	}
+
+	results, err := store.Search(context, SearchArgs{Query: "ledger"})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(results) != 3 || results[0].EntityType != SearchEntityTask || results[0].EntityID != task.ID {
+		t.Fatalf("expected title match to rank first across 3 entities, got %+v", results)
+	}
+
+	nodeOnly, err := store.Search(context, SearchArgs{Query: "ledger totals", EntityTypes: []string{SearchEntityGraphNode}})
+	if err != nil {
+		t.Fatalf("failed to search nodes: %v", err)
+	}
+	if len(nodeOnly) != 1 || nodeOnly[0].EntityID != node.ID || nodeOnly[0].Snippet != "render [ledger] [totals]" {
+		t.Fatalf("unexpected node search result: %+v", nodeOnly)
+	}
+
+	if results, err := store.Search(context, SearchArgs{Query: "payments frozen"}); err != nil || len(results) != 0 {
+		t.Fatalf("expected AND semantics to match nothing, got %+v (%v)", results, err)
+	}
+	if results, err := store.Search(context, SearchArgs{Query: "payments frozen", MatchAny: true}); err != nil || len(results) != 2 {
+		t.Fatalf("expected match_any to return 2 results, got %+v (%v)", results, err)
+	}
+	if results, err := store.Search(context, SearchArgs{Query: "ledg*", Raw: true}); err != nil || len(results) != 3 {
+		t.Fatalf("expected raw prefix query to return 3 results, got %+v (%v)", results, err)
+	}
+	if _, err := store.Search(context, SearchArgs{Query: "ledger", EntityTypes: []string{"lock"}}); err == nil {
+		t.Fatalf("expected unknown entity type to be rejected")
+	}
+
+	if _, _, err := store.DeleteTask(context, task.ID, "obsolete", false); err != nil {
+		t.Fatalf("failed to delete task: %v", err)
+	}
+	if results, err := store.Search(context, SearchArgs{Query: "payments"}); err != nil || len(results) != 0 {
+		t.Fatalf("expected deleted task to leave the index, got %+v (%v)", results, err)
+	}
+}
+
+func TestSearchIndexBackfillsExistingRows(t *testing.T) {
+	context := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+	store, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	if _, err := store.CreateTask(context, TaskCreateArgs{Level: "epic", Title: "Legacy importer"}); err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if _, err := store.database.ExecContext(context, `DROP TABLE search_index`); err != nil {
+		t.Fatalf("failed to drop search index: %v", err)
+	}
+	store.Close()
+
+	store, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	if results, err := store.Search(context, SearchArgs{Query: "importer"}); err != nil || len(results) != 1 {
+		t.Fatalf("expected backfilled task, got %+v (%v)", results, err)
+	}
+}
@@ -275,7 +275,7 @@ func (store *Store) GetPendingDelegationSession(ctx context.Context) (*Session,
 func (store *Store) CreateOrGetMainWorktree(ctx context.Context, repoPath string, branch string) (Worktree, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
 		 FROM worktrees
 		 WHERE kind = 'main' AND path = ?
 		 ORDER BY id DESC
@@ -420,7 +420,7 @@ func (store *Store) ListResumeCandidates(ctx context.Context, repoPath string, r
 func (store *Store) GetWorktreeByID(ctx context.Context, worktreeID int64) (Worktree, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
 		 FROM worktrees WHERE id = ?`,
 		worktreeID,
 	)
@@ -778,7 +778,7 @@ func (store *Store) NextMainMergeRequest(ctx context.Context) (*MainMergeQueueIt
 		ctx,
 		`SELECT id, session_id, from_worktree_id, target_branch, state, started_at, completed_at, error_message, created_at, updated_at
 		 FROM merge_main_queue
-		 WHERE state = 'queued'
+		 WHERE state IN ('queued', 'approved')
 		 ORDER BY id ASC
 		 LIMIT 1`,
 	)
@@ -939,7 +939,51 @@ func (store *Store) MarkWorktreeMergedToParent(ctx context.Context, worktreeID i
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
+		 FROM worktrees WHERE id = ?`,
+		worktreeID,
+	)
+	worktree, err := scanWorktree(row)
+	if err != nil {
+		return Worktree{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return Worktree{}, err
+	}
+	return worktree, nil
+}
+
+// AbandonWorktree closes a worktree that was created for work that never
+// started, such as a failed dispatch.
+func (store *Store) AbandonWorktree(ctx context.Context, worktreeID int64) (Worktree, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Worktree{}, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		`UPDATE worktrees
+		 SET merge_state = 'abandoned',
+		     status = 'closed'
+		 WHERE id = ?`,
+		worktreeID,
+	)
+	if err != nil {
+		return Worktree{}, err
+	}
+	if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+		return Worktree{}, fmt.Errorf("worktree not found: %d", worktreeID)
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Worktree{}, err
+	}
+
+	row := transaction.QueryRowContext(
+		ctx,
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
 		 FROM worktrees WHERE id = ?`,
 		worktreeID,
 	)
@@ -18,6 +18,10 @@ const (
 	defaultLockTTLSeconds = 600
 )
 
+const taskSelectColumns = `id, level, parent_id, title, status, priority, assignee_session, input_contract, fixtures, next_action, status_reason, deleted_at, created_at, updated_at`
+
+const lockSelectColumns = `id, scope_type, scope_path, owner_session, owner_session_id, owner_thread_id, owner_case_id, lease_until, heartbeat_at, state, release_reason`
+
 type Store struct {
 	database *sql.DB
 	dbPath   string
@@ -111,6 +115,7 @@ func (store *Store) migrate(ctx context.Context) error {
 		`ALTER TABLE worktrees ADD COLUMN parent_worktree_id INTEGER NULL;`,
 		`ALTER TABLE worktrees ADD COLUMN owner_session_id INTEGER NULL;`,
 		`ALTER TABLE worktrees ADD COLUMN merge_state TEXT NULL;`,
+		`ALTER TABLE worktrees ADD COLUMN base_ref TEXT NULL;`,
 		`CREATE TABLE IF NOT EXISTS merge_requests (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			feature_task_id INTEGER NOT NULL,
@@ -315,6 +320,123 @@ func (store *Store) migrate(ctx context.Context) error {
 			delivered_at TEXT NULL
 		);`,
 		`CREATE INDEX IF NOT EXISTS idx_inbox_receiver_status ON inbox_messages(receiver_thread_id, status);`,
+		`ALTER TABLE locks ADD COLUMN owner_session_id INTEGER NULL;`,
+		`ALTER TABLE locks ADD COLUMN owner_thread_id INTEGER NULL;`,
+		`ALTER TABLE locks ADD COLUMN release_reason TEXT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_locks_owner ON locks(state, owner_session_id, owner_thread_id);`,
+		`ALTER TABLE locks ADD COLUMN owner_case_id INTEGER NULL;`,
+		`ALTER TABLE tasks ADD COLUMN status_reason TEXT NULL;`,
+		`ALTER TABLE tasks ADD COLUMN deleted_at TEXT NULL;`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_id, deleted_at);`,
+		`CREATE TABLE IF NOT EXISTS graph_node_tasks (
+			node_id INTEGER NOT NULL,
+			task_id INTEGER NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (node_id, task_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_graph_node_tasks_task ON graph_node_tasks(task_id);`,
+		`CREATE INDEX IF NOT EXISTS idx_graph_nodes_parent ON graph_nodes(parent_id);`,
+		`ALTER TABLE graph_nodes ADD COLUMN deleted_at TEXT NULL;`,
+		`ALTER TABLE node_snapshots ADD COLUMN payload_json TEXT NULL;`,
+		`CREATE TABLE IF NOT EXISTS node_approvals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			node_id INTEGER NOT NULL,
+			decision TEXT NOT NULL,
+			approver TEXT NOT NULL,
+			role TEXT NOT NULL,
+			comment TEXT NULL,
+			created_at TEXT NOT NULL,
+			superseded_at TEXT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_node_approvals_node ON node_approvals(node_id, superseded_at);`,
+		`ALTER TABLE threads ADD COLUMN heartbeat_at TEXT NULL;`,
+		`ALTER TABLE threads ADD COLUMN restart_count INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE threads ADD COLUMN last_restart_at TEXT NULL;`,
+		`ALTER TABLE threads ADD COLUMN status_reason TEXT NULL;`,
+		`ALTER TABLE threads ADD COLUMN runner_backend TEXT NULL;`,
+		`ALTER TABLE threads ADD COLUMN process_id INTEGER NULL;`,
+		`CREATE TABLE IF NOT EXISTS supervisor_policy (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			enabled INTEGER NOT NULL,
+			interval_seconds INTEGER NOT NULL,
+			stall_timeout_seconds INTEGER NOT NULL,
+			max_restarts INTEGER NOT NULL,
+			restart_backoff_seconds INTEGER NOT NULL,
+			max_backoff_seconds INTEGER NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`INSERT OR IGNORE INTO supervisor_policy(id, enabled, interval_seconds, stall_timeout_seconds, max_restarts, restart_backoff_seconds, max_backoff_seconds, updated_at)
+		 VALUES(1, 1, 15, 600, 3, 30, 600, strftime('%Y-%m-%dT%H:%M:%fZ','now'));`,
+		`ALTER TABLE review_jobs ADD COLUMN main_merge_request_id INTEGER NULL;`,
+		`ALTER TABLE review_jobs ADD COLUMN verdict TEXT NULL;`,
+		`CREATE TABLE IF NOT EXISTS review_findings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			review_job_id INTEGER NOT NULL,
+			file_path TEXT NOT NULL,
+			line INTEGER NULL,
+			severity TEXT NOT NULL,
+			message TEXT NOT NULL,
+			created_at TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_review_findings_job ON review_findings(review_job_id, id);`,
+		`CREATE TABLE IF NOT EXISTS thread_usage (
+			thread_id INTEGER NOT NULL,
+			attempt INTEGER NOT NULL,
+			session_id INTEGER NOT NULL,
+			provider_type TEXT NULL,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			cached_input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			total_tokens INTEGER NOT NULL DEFAULT 0,
+			cost_usd REAL NULL,
+			source TEXT NOT NULL,
+			recorded_at TEXT NOT NULL,
+			PRIMARY KEY (thread_id, attempt)
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_thread_usage_session ON thread_usage(session_id);`,
+		`CREATE TABLE IF NOT EXISTS session_budgets (
+			session_id INTEGER PRIMARY KEY,
+			token_budget INTEGER NULL,
+			cost_budget_usd REAL NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS thread_directives (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			thread_id INTEGER NOT NULL,
+			session_id INTEGER NOT NULL,
+			mode TEXT NOT NULL,
+			directive TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NULL,
+			created_at TEXT NOT NULL,
+			sent_at TEXT NULL,
+			acknowledged_at TEXT NULL,
+			applied_at TEXT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_thread_directives_thread ON thread_directives(thread_id, id);`,
+		`ALTER TABLE thread_directives ADD COLUMN delivered_at TEXT NULL;`,
+		`CREATE TABLE IF NOT EXISTS thread_transcript_turns (
+			thread_id INTEGER NOT NULL,
+			seq INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			content TEXT NOT NULL,
+			created_at TEXT NOT NULL,
+			PRIMARY KEY (thread_id, seq)
+		);`,
+		`CREATE TABLE IF NOT EXISTS thread_transcript_cursors (
+			thread_id INTEGER PRIMARY KEY,
+			log_segment INTEGER NOT NULL,
+			log_offset INTEGER NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+	}
+	statements = append(statements, searchIndexMigrations()...)
+
+	var searchIndexExists int
+	if err := store.database.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'search_index'`).Scan(&searchIndexExists); err != nil {
+		return err
 	}
 
 	for _, statement := range statements {
@@ -325,12 +447,16 @@ func (store *Store) migrate(ctx context.Context) error {
 			return fmt.Errorf("migration failed: %w", err)
 		}
 	}
+	if searchIndexExists == 0 {
+		return store.backfillSearchIndex(ctx)
+	}
 	return nil
 }
 
 func (store *Store) CreateTask(ctx context.Context, args TaskCreateArgs) (Task, error) {
-	if strings.TrimSpace(args.Level) == "" {
-		return Task{}, errors.New("level is required")
+	level, err := NormalizeTaskLevel(args.Level)
+	if err != nil {
+		return Task{}, err
 	}
 	if strings.TrimSpace(args.Title) == "" {
 		return Task{}, errors.New("title is required")
@@ -342,12 +468,16 @@ func (store *Store) CreateTask(ctx context.Context, args TaskCreateArgs) (Task,
 	}
 	defer transaction.Rollback()
 
+	if err := store.validateTaskParentTx(ctx, transaction, level, args.ParentID); err != nil {
+		return Task{}, err
+	}
+
 	timestamp := nowTimestamp()
 	result, err := transaction.ExecContext(
 		ctx,
 		`INSERT INTO tasks(level, parent_id, title, status, priority, assignee_session, created_at, updated_at)
 		 VALUES(?, ?, ?, 'todo', ?, ?, ?, ?)`,
-		strings.TrimSpace(args.Level),
+		level,
 		args.ParentID,
 		args.Title,
 		args.Priority,
@@ -380,9 +510,9 @@ func (store *Store) CreateTask(ctx context.Context, args TaskCreateArgs) (Task,
 }
 
 func (store *Store) ListTasks(ctx context.Context, filter TaskFilter) ([]Task, error) {
-	baseQuery := `SELECT id, level, parent_id, title, status, priority, assignee_session, input_contract, fixtures, next_action, created_at, updated_at
+	baseQuery := `SELECT ` + taskSelectColumns + `
 		FROM tasks`
-	whereClauses := make([]string, 0, 3)
+	whereClauses := make([]string, 0, 4)
 	parameters := make([]any, 0, 3)
 
 	if strings.TrimSpace(filter.Level) != "" {
@@ -397,6 +527,9 @@ func (store *Store) ListTasks(ctx context.Context, filter TaskFilter) ([]Task, e
 		whereClauses = append(whereClauses, "parent_id = ?")
 		parameters = append(parameters, *filter.ParentID)
 	}
+	if !filter.IncludeDeleted {
+		whereClauses = append(whereClauses, "deleted_at IS NULL")
+	}
 
 	if len(whereClauses) > 0 {
 		baseQuery += " WHERE " + strings.Join(whereClauses, " AND ")
@@ -424,7 +557,7 @@ func (store *Store) ListTasks(ctx context.Context, filter TaskFilter) ([]Task, e
 func (store *Store) GetTaskByID(ctx context.Context, taskID int64) (Task, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, level, parent_id, title, status, priority, assignee_session, input_contract, fixtures, next_action, created_at, updated_at
+		`SELECT `+taskSelectColumns+`
 		 FROM tasks WHERE id = ?`,
 		taskID,
 	)
@@ -434,7 +567,7 @@ func (store *Store) GetTaskByID(ctx context.Context, taskID int64) (Task, error)
 func (store *Store) getTaskByIDTx(ctx context.Context, transaction *sql.Tx, taskID int64) (Task, error) {
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, level, parent_id, title, status, priority, assignee_session, input_contract, fixtures, next_action, created_at, updated_at
+		`SELECT `+taskSelectColumns+`
 		 FROM tasks WHERE id = ?`,
 		taskID,
 	)
@@ -465,7 +598,7 @@ func (store *Store) BeginCase(ctx context.Context, args CaseBeginArgs) (Task, er
 		     input_contract = ?,
 		     fixtures = ?,
 		     updated_at = ?
-		 WHERE id = ? AND level = 'case'`,
+		 WHERE id = ? AND level = 'case' AND deleted_at IS NULL`,
 		inputContractText,
 		string(fixturesBytes),
 		timestamp,
@@ -596,7 +729,7 @@ func (store *Store) CompleteCase(ctx context.Context, args CaseCompleteArgs) (Ta
 		 SET status = 'done',
 		     next_action = ?,
 		     updated_at = ?
-		 WHERE id = ? AND level = 'case'`,
+		 WHERE id = ? AND level = 'case' AND deleted_at IS NULL`,
 		nullableText(args.NextAction),
 		timestamp,
 		args.TaskID,
@@ -638,9 +771,9 @@ func (store *Store) CompleteCase(ctx context.Context, args CaseCompleteArgs) (Ta
 func (store *Store) ResumeNextCase(ctx context.Context) (ResumeState, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, level, parent_id, title, status, priority, assignee_session, input_contract, fixtures, next_action, created_at, updated_at
+		`SELECT `+taskSelectColumns+`
 		 FROM tasks
-		 WHERE level = 'case' AND status IN ('in_progress', 'blocked', 'todo')
+		 WHERE level = 'case' AND deleted_at IS NULL AND status IN ('in_progress', 'blocked', 'todo')
 		 ORDER BY
 		 	CASE status
 				WHEN 'in_progress' THEN 0
@@ -705,45 +838,158 @@ func (store *Store) insertCheckpointTx(ctx context.Context, transaction *sql.Tx,
 }
 
 func (store *Store) AcquireLock(ctx context.Context, args LockAcquireArgs) (Lock, error) {
-	scopeType := strings.TrimSpace(strings.ToLower(args.ScopeType))
-	scopePath := normalizeScopePath(args.ScopePath)
-	ownerSession := strings.TrimSpace(args.OwnerSession)
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Lock{}, err
+	}
+	defer transaction.Rollback()
 
-	if scopeType != "prefix" && scopeType != "file" {
-		return Lock{}, errors.New("scope_type must be one of: prefix, file")
+	if err := store.expireLocksTx(ctx, transaction); err != nil {
+		return Lock{}, err
 	}
-	if scopePath == "" {
-		return Lock{}, errors.New("scope_path is required")
+	lock, err := store.acquireLockTx(ctx, transaction, args)
+	if err != nil {
+		return Lock{}, err
 	}
-	if ownerSession == "" {
-		return Lock{}, errors.New("owner_session is required")
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Lock{}, err
 	}
-	if args.TTLSeconds <= 0 {
-		args.TTLSeconds = defaultLockTTLSeconds
+	if err := transaction.Commit(); err != nil {
+		return Lock{}, err
+	}
+	return lock, nil
+}
+
+func (store *Store) ClaimCaseLocks(ctx context.Context, args CaseLockClaimArgs) ([]Lock, error) {
+	if args.CaseID <= 0 {
+		return nil, errors.New("case_id is required")
+	}
+
+	scopePaths := make([]string, 0, len(args.Paths))
+	seenPaths := make(map[string]bool, len(args.Paths))
+	for _, path := range args.Paths {
+		scopePath := normalizeScopePath(path)
+		if scopePath == "" || seenPaths[scopePath] {
+			continue
+		}
+		seenPaths[scopePath] = true
+		scopePaths = append(scopePaths, scopePath)
+	}
+	claimed := make([]Lock, 0, len(scopePaths))
+	if len(scopePaths) == 0 {
+		return claimed, nil
 	}
 
 	transaction, err := store.database.BeginTx(ctx, nil)
 	if err != nil {
-		return Lock{}, err
+		return nil, err
 	}
 	defer transaction.Rollback()
 
-	now := nowTimestamp()
-	_, err = transaction.ExecContext(
+	if err := store.expireLocksTx(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	caseID := args.CaseID
+	conflicts := make([]string, 0)
+	for _, scopePath := range scopePaths {
+		existing, err := store.findCaseLockTx(ctx, transaction, caseID, scopePath)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			claimed = append(claimed, *existing)
+			continue
+		}
+		lock, err := store.acquireLockTx(ctx, transaction, LockAcquireArgs{
+			ScopeType:      "file",
+			ScopePath:      scopePath,
+			OwnerSession:   args.OwnerSession,
+			OwnerSessionID: args.OwnerSessionID,
+			OwnerThreadID:  args.OwnerThreadID,
+			OwnerCaseID:    &caseID,
+			TTLSeconds:     args.TTLSeconds,
+		})
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v", scopePath, err))
+			continue
+		}
+		claimed = append(claimed, lock)
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("case %d cannot claim locks: %s", caseID, strings.Join(conflicts, "; "))
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+func (store *Store) expireLocksTx(ctx context.Context, transaction *sql.Tx) error {
+	_, err := transaction.ExecContext(
 		ctx,
 		`UPDATE locks
 		 SET state = 'expired'
 		 WHERE state = 'active'
 		   AND lease_until < ?`,
-		now,
+		nowTimestamp(),
 	)
+	return err
+}
+
+func (store *Store) findCaseLockTx(ctx context.Context, transaction *sql.Tx, caseID int64, scopePath string) (*Lock, error) {
+	row := transaction.QueryRowContext(
+		ctx,
+		`SELECT `+lockSelectColumns+`
+		 FROM locks
+		 WHERE state = 'active'
+		   AND owner_case_id = ?
+		   AND scope_type = 'file'
+		   AND scope_path = ?
+		 ORDER BY id DESC
+		 LIMIT 1`,
+		caseID,
+		scopePath,
+	)
+	lock, err := scanLock(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
-		return Lock{}, err
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (store *Store) acquireLockTx(ctx context.Context, transaction *sql.Tx, args LockAcquireArgs) (Lock, error) {
+	scopeType := strings.TrimSpace(strings.ToLower(args.ScopeType))
+	scopePath := normalizeScopePath(args.ScopePath)
+	ownerSession := strings.TrimSpace(args.OwnerSession)
+
+	if scopeType != "prefix" && scopeType != "file" {
+		return Lock{}, errors.New("scope_type must be one of: prefix, file")
+	}
+	if scopePath == "" {
+		return Lock{}, errors.New("scope_path is required")
+	}
+	if ownerSession == "" && args.OwnerSessionID != nil {
+		ownerSession = fmt.Sprintf("session:%d", *args.OwnerSessionID)
+	}
+	if ownerSession == "" {
+		return Lock{}, errors.New("owner_session or owner_session_id is required")
+	}
+	if args.TTLSeconds <= 0 {
+		args.TTLSeconds = defaultLockTTLSeconds
 	}
 
 	rows, err := transaction.QueryContext(
 		ctx,
-		`SELECT id, scope_type, scope_path, owner_session, lease_until, heartbeat_at, state
+		`SELECT `+lockSelectColumns+`
 		 FROM locks
 		 WHERE state = 'active'`,
 	)
@@ -757,22 +1003,33 @@ func (store *Store) AcquireLock(ctx context.Context, args LockAcquireArgs) (Lock
 		if scanErr != nil {
 			return Lock{}, scanErr
 		}
+		if args.OwnerCaseID != nil && activeLock.OwnerCaseID != nil && *activeLock.OwnerCaseID == *args.OwnerCaseID {
+			continue
+		}
 		if scopesConflict(scopeType, scopePath, activeLock.ScopeType, activeLock.ScopePath) {
+			if activeLock.OwnerCaseID != nil {
+				return Lock{}, fmt.Errorf("lock conflict with #%d (%s:%s) held by case %d", activeLock.ID, activeLock.ScopeType, activeLock.ScopePath, *activeLock.OwnerCaseID)
+			}
 			return Lock{}, fmt.Errorf("lock conflict with #%d (%s:%s)", activeLock.ID, activeLock.ScopeType, activeLock.ScopePath)
 		}
 	}
 	if err := rows.Err(); err != nil {
 		return Lock{}, err
 	}
+	rows.Close()
 
+	now := nowTimestamp()
 	leaseUntil := time.Now().UTC().Add(time.Duration(args.TTLSeconds) * time.Second).Format(time.RFC3339Nano)
 	result, err := transaction.ExecContext(
 		ctx,
-		`INSERT INTO locks(scope_type, scope_path, owner_session, lease_until, heartbeat_at, state)
-		 VALUES(?, ?, ?, ?, ?, 'active')`,
+		`INSERT INTO locks(scope_type, scope_path, owner_session, owner_session_id, owner_thread_id, owner_case_id, lease_until, heartbeat_at, state)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, 'active')`,
 		scopeType,
 		scopePath,
 		ownerSession,
+		args.OwnerSessionID,
+		args.OwnerThreadID,
+		args.OwnerCaseID,
 		leaseUntil,
 		now,
 	)
@@ -785,21 +1042,17 @@ func (store *Store) AcquireLock(ctx context.Context, args LockAcquireArgs) (Lock
 		return Lock{}, err
 	}
 
-	if err := store.bumpVersionTx(ctx, transaction); err != nil {
-		return Lock{}, err
-	}
-	if err := transaction.Commit(); err != nil {
-		return Lock{}, err
-	}
-
 	return Lock{
-		ID:           lockID,
-		ScopeType:    scopeType,
-		ScopePath:    scopePath,
-		OwnerSession: ownerSession,
-		LeaseUntil:   leaseUntil,
-		HeartbeatAt:  now,
-		State:        "active",
+		ID:             lockID,
+		ScopeType:      scopeType,
+		ScopePath:      scopePath,
+		OwnerSession:   ownerSession,
+		OwnerSessionID: args.OwnerSessionID,
+		OwnerThreadID:  args.OwnerThreadID,
+		OwnerCaseID:    args.OwnerCaseID,
+		LeaseUntil:     leaseUntil,
+		HeartbeatAt:    now,
+		State:          "active",
 	}, nil
 }
 
@@ -838,7 +1091,7 @@ func (store *Store) HeartbeatLock(ctx context.Context, lockID int64, ttlSeconds
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, scope_type, scope_path, owner_session, lease_until, heartbeat_at, state
+		`SELECT `+lockSelectColumns+`
 		 FROM locks WHERE id = ?`,
 		lockID,
 	)
@@ -866,7 +1119,8 @@ func (store *Store) ReleaseLock(ctx context.Context, lockID int64) (Lock, error)
 		`UPDATE locks
 		 SET state = 'released',
 		     heartbeat_at = ?,
-		     lease_until = ?
+		     lease_until = ?,
+		     release_reason = 'manual'
 		 WHERE id = ? AND state = 'active'`,
 		now,
 		now,
@@ -885,7 +1139,7 @@ func (store *Store) ReleaseLock(ctx context.Context, lockID int64) (Lock, error)
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, scope_type, scope_path, owner_session, lease_until, heartbeat_at, state
+		`SELECT `+lockSelectColumns+`
 		 FROM locks WHERE id = ?`,
 		lockID,
 	)
@@ -901,6 +1155,10 @@ func (store *Store) ReleaseLock(ctx context.Context, lockID int64) (Lock, error)
 }
 
 func (store *Store) ListActiveLocks(ctx context.Context) ([]Lock, error) {
+	return store.ListLocks(ctx, LockFilter{State: "active"})
+}
+
+func (store *Store) ListLocks(ctx context.Context, filter LockFilter) ([]Lock, error) {
 	now := nowTimestamp()
 	_, _ = store.database.ExecContext(
 		ctx,
@@ -911,13 +1169,35 @@ func (store *Store) ListActiveLocks(ctx context.Context) ([]Lock, error) {
 		now,
 	)
 
-	rows, err := store.database.QueryContext(
-		ctx,
-		`SELECT id, scope_type, scope_path, owner_session, lease_until, heartbeat_at, state
+	query := strings.Builder{}
+	query.WriteString(`SELECT ` + lockSelectColumns + `
 		 FROM locks
-		 WHERE state = 'active'
-		 ORDER BY id ASC`,
-	)
+		 WHERE 1=1`)
+	params := make([]any, 0, 4)
+
+	if strings.TrimSpace(filter.State) != "" {
+		query.WriteString(" AND state = ?")
+		params = append(params, strings.TrimSpace(filter.State))
+	}
+	if strings.TrimSpace(filter.OwnerSession) != "" {
+		query.WriteString(" AND owner_session = ?")
+		params = append(params, strings.TrimSpace(filter.OwnerSession))
+	}
+	if filter.OwnerSessionID != nil {
+		query.WriteString(" AND owner_session_id = ?")
+		params = append(params, *filter.OwnerSessionID)
+	}
+	if filter.OwnerThreadID != nil {
+		query.WriteString(" AND owner_thread_id = ?")
+		params = append(params, *filter.OwnerThreadID)
+	}
+	if filter.OwnerCaseID != nil {
+		query.WriteString(" AND owner_case_id = ?")
+		params = append(params, *filter.OwnerCaseID)
+	}
+	query.WriteString(" ORDER BY id ASC")
+
+	rows, err := store.database.QueryContext(ctx, query.String(), params...)
 	if err != nil {
 		return nil, err
 	}
@@ -934,6 +1214,115 @@ func (store *Store) ListActiveLocks(ctx context.Context) ([]Lock, error) {
 	return locks, rows.Err()
 }
 
+func (store *Store) ReleaseLocksByOwner(ctx context.Context, args LockReleaseByOwnerArgs) ([]Lock, error) {
+	if args.OwnerSessionID == nil && args.OwnerThreadID == nil && args.OwnerCaseID == nil {
+		return nil, errors.New("owner_session_id, owner_thread_id or owner_case_id is required")
+	}
+	reason := strings.TrimSpace(args.Reason)
+	if reason == "" {
+		reason = "owner_released"
+	}
+
+	conditions := make([]string, 0, 4)
+	params := make([]any, 0, 3+len(args.LockIDs))
+	if args.OwnerSessionID != nil {
+		conditions = append(conditions, "owner_session_id = ?")
+		params = append(params, *args.OwnerSessionID)
+	}
+	if args.OwnerThreadID != nil {
+		conditions = append(conditions, "owner_thread_id = ?")
+		params = append(params, *args.OwnerThreadID)
+	}
+	if args.OwnerCaseID != nil {
+		conditions = append(conditions, "owner_case_id = ?")
+		params = append(params, *args.OwnerCaseID)
+	}
+	if len(args.LockIDs) > 0 {
+		placeholders := make([]string, 0, len(args.LockIDs))
+		for _, lockID := range args.LockIDs {
+			placeholders = append(placeholders, "?")
+			params = append(params, lockID)
+		}
+		conditions = append(conditions, "id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	whereClause := "state = 'active' AND " + strings.Join(conditions, " AND ")
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	rows, err := transaction.QueryContext(
+		ctx,
+		`SELECT `+lockSelectColumns+`
+		 FROM locks
+		 WHERE `+whereClause+`
+		 ORDER BY id ASC`,
+		params...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	releasedIDs := make([]int64, 0)
+	for rows.Next() {
+		lock, scanErr := scanLock(rows)
+		if scanErr != nil {
+			rows.Close()
+			return nil, scanErr
+		}
+		releasedIDs = append(releasedIDs, lock.ID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	released := make([]Lock, 0, len(releasedIDs))
+	if len(releasedIDs) == 0 {
+		return released, nil
+	}
+
+	now := nowTimestamp()
+	updateParams := append([]any{now, now, reason}, params...)
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE locks
+		 SET state = 'released',
+		     heartbeat_at = ?,
+		     lease_until = ?,
+		     release_reason = ?
+		 WHERE `+whereClause,
+		updateParams...,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	for _, lockID := range releasedIDs {
+		row := transaction.QueryRowContext(
+			ctx,
+			`SELECT `+lockSelectColumns+`
+			 FROM locks WHERE id = ?`,
+			lockID,
+		)
+		lock, scanErr := scanLock(row)
+		if scanErr != nil {
+			return nil, scanErr
+		}
+		released = append(released, lock)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
 func (store *Store) CreateWorktreeRecord(ctx context.Context, args WorktreeCreateArgs) (Worktree, error) {
 	if strings.TrimSpace(args.Path) == "" {
 		return Worktree{}, errors.New("path is required")
@@ -960,8 +1349,8 @@ func (store *Store) CreateWorktreeRecord(ctx context.Context, args WorktreeCreat
 	timestamp := nowTimestamp()
 	result, err := transaction.ExecContext(
 		ctx,
-		`INSERT INTO worktrees(task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)`,
+		`INSERT INTO worktrees(task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)`,
 		args.TaskID,
 		args.Path,
 		args.Branch,
@@ -970,6 +1359,7 @@ func (store *Store) CreateWorktreeRecord(ctx context.Context, args WorktreeCreat
 		args.ParentWorktree,
 		args.OwnerSessionID,
 		args.MergeState,
+		nullableText(args.BaseRef),
 		timestamp,
 	)
 	if err != nil {
@@ -985,7 +1375,7 @@ func (store *Store) CreateWorktreeRecord(ctx context.Context, args WorktreeCreat
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
 		 FROM worktrees
 		 WHERE id = ?`,
 		worktreeID,
@@ -1003,7 +1393,7 @@ func (store *Store) CreateWorktreeRecord(ctx context.Context, args WorktreeCreat
 func (store *Store) ListWorktrees(ctx context.Context) ([]Worktree, error) {
 	rows, err := store.database.QueryContext(
 		ctx,
-		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, created_at, merged_at
+		`SELECT id, task_id, path, branch, status, kind, parent_worktree_id, owner_session_id, merge_state, base_ref, created_at, merged_at
 		 FROM worktrees
 		 ORDER BY id DESC`,
 	)
@@ -1216,6 +1606,10 @@ func scopesConflict(newScopeType string, newScopePath string, existingScopeType
 	return false
 }
 
+func LockCoversPath(lock Lock, path string) bool {
+	return scopesConflict("file", normalizeScopePath(path), lock.ScopeType, lock.ScopePath)
+}
+
 func samePath(leftPath string, rightPath string) bool {
 	return normalizeScopePath(leftPath) == normalizeScopePath(rightPath)
 }
@@ -1243,6 +1637,8 @@ func scanTask(scanner rowScanner) (Task, error) {
 	var inputContract sql.NullString
 	var fixtures sql.NullString
 	var nextAction sql.NullString
+	var statusReason sql.NullString
+	var deletedAt sql.NullString
 
 	err := scanner.Scan(
 		&task.ID,
@@ -1255,6 +1651,8 @@ func scanTask(scanner rowScanner) (Task, error) {
 		&inputContract,
 		&fixtures,
 		&nextAction,
+		&statusReason,
+		&deletedAt,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
@@ -1277,6 +1675,12 @@ func scanTask(scanner rowScanner) (Task, error) {
 	if nextAction.Valid {
 		task.NextAction = &nextAction.String
 	}
+	if statusReason.Valid {
+		task.StatusReason = &statusReason.String
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.String
+	}
 	return task, nil
 }
 
@@ -1297,18 +1701,38 @@ func scanCheckpoint(scanner rowScanner) (Checkpoint, error) {
 
 func scanLock(scanner rowScanner) (Lock, error) {
 	var lock Lock
+	var ownerSessionID sql.NullInt64
+	var ownerThreadID sql.NullInt64
+	var ownerCaseID sql.NullInt64
+	var releaseReason sql.NullString
 	err := scanner.Scan(
 		&lock.ID,
 		&lock.ScopeType,
 		&lock.ScopePath,
 		&lock.OwnerSession,
+		&ownerSessionID,
+		&ownerThreadID,
+		&ownerCaseID,
 		&lock.LeaseUntil,
 		&lock.HeartbeatAt,
 		&lock.State,
+		&releaseReason,
 	)
 	if err != nil {
 		return Lock{}, err
 	}
+	if ownerSessionID.Valid {
+		lock.OwnerSessionID = &ownerSessionID.Int64
+	}
+	if ownerThreadID.Valid {
+		lock.OwnerThreadID = &ownerThreadID.Int64
+	}
+	if ownerCaseID.Valid {
+		lock.OwnerCaseID = &ownerCaseID.Int64
+	}
+	if releaseReason.Valid {
+		lock.ReleaseReason = &releaseReason.String
+	}
 	return lock, nil
 }
 
@@ -1318,6 +1742,7 @@ func scanWorktree(scanner rowScanner) (Worktree, error) {
 	var parentWorktree sql.NullInt64
 	var ownerSessionID sql.NullInt64
 	var mergeState sql.NullString
+	var baseRef sql.NullString
 	var mergedAt sql.NullString
 	err := scanner.Scan(
 		&worktree.ID,
@@ -1329,6 +1754,7 @@ func scanWorktree(scanner rowScanner) (Worktree, error) {
 		&parentWorktree,
 		&ownerSessionID,
 		&mergeState,
+		&baseRef,
 		&worktree.CreatedAt,
 		&mergedAt,
 	)
@@ -1347,6 +1773,9 @@ func scanWorktree(scanner rowScanner) (Worktree, error) {
 	if mergeState.Valid {
 		worktree.MergeState = &mergeState.String
 	}
+	if baseRef.Valid {
+		worktree.BaseRef = &baseRef.String
+	}
 	if mergedAt.Valid {
 		worktree.MergedAt = mergedAt.String
 	}
@@ -1,6 +1,10 @@
 package store
 
-import "testing"
+import (
+	"context"
+	"strings"
+	"testing"
+)
 
 func TestScopesConflict(t *testing.T) {
 	testCases := []struct {
@@ -67,3 +71,185 @@ func TestScopesConflict(t *testing.T) {
 		})
 	}
 }
+
+func TestReleaseLocksByOwner(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	ownerSessionID := int64(7)
+	firstThreadID := int64(11)
+	secondThreadID := int64(12)
+
+	firstLock, err := store.AcquireLock(context, LockAcquireArgs{
+		ScopeType:      "file",
+		ScopePath:      "src/api/users.go",
+		OwnerSessionID: &ownerSessionID,
+		OwnerThreadID:  &firstThreadID,
+	})
+	if err != nil {
+		t.Fatalf("failed to acquire first lock: %v", err)
+	}
+	if firstLock.OwnerSession != "session:7" {
+		t.Fatalf("expected derived owner_session, got %s", firstLock.OwnerSession)
+	}
+	if _, err := store.AcquireLock(context, LockAcquireArgs{
+		ScopeType:      "prefix",
+		ScopePath:      "src/ui",
+		OwnerSessionID: &ownerSessionID,
+		OwnerThreadID:  &secondThreadID,
+	}); err != nil {
+		t.Fatalf("failed to acquire second lock: %v", err)
+	}
+
+	threadLocks, err := store.ListLocks(context, LockFilter{State: "active", OwnerThreadID: &firstThreadID})
+	if err != nil {
+		t.Fatalf("failed to list thread locks: %v", err)
+	}
+	if len(threadLocks) != 1 || threadLocks[0].ID != firstLock.ID {
+		t.Fatalf("expected only first lock for thread filter, got %+v", threadLocks)
+	}
+
+	released, err := store.ReleaseLocksByOwner(context, LockReleaseByOwnerArgs{
+		OwnerThreadID: &firstThreadID,
+		Reason:        "thread_stop",
+	})
+	if err != nil {
+		t.Fatalf("failed to release thread locks: %v", err)
+	}
+	if len(released) != 1 || released[0].State != "released" {
+		t.Fatalf("expected one released lock, got %+v", released)
+	}
+	if released[0].ReleaseReason == nil || *released[0].ReleaseReason != "thread_stop" {
+		t.Fatalf("expected release_reason=thread_stop, got %+v", released[0].ReleaseReason)
+	}
+
+	released, err = store.ReleaseLocksByOwner(context, LockReleaseByOwnerArgs{
+		OwnerSessionID: &ownerSessionID,
+		Reason:         "session_close",
+	})
+	if err != nil {
+		t.Fatalf("failed to release session locks: %v", err)
+	}
+	if len(released) != 1 {
+		t.Fatalf("expected remaining session lock to be released, got %+v", released)
+	}
+
+	activeLocks, err := store.ListActiveLocks(context)
+	if err != nil {
+		t.Fatalf("failed to list active locks: %v", err)
+	}
+	if len(activeLocks) != 0 {
+		t.Fatalf("expected no active locks, got %+v", activeLocks)
+	}
+}
+
+func TestClaimCaseLocksReportsHoldingCase(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	firstClaim, err := store.ClaimCaseLocks(context, CaseLockClaimArgs{
+		CaseID:       3,
+		Paths:        []string{"src/api/users.go", "./src/api/users.go", "src/api/posts.go"},
+		OwnerSession: "case:3",
+	})
+	if err != nil {
+		t.Fatalf("failed to claim locks for first case: %v", err)
+	}
+	if len(firstClaim) != 2 {
+		t.Fatalf("expected deduplicated claim of 2 locks, got %+v", firstClaim)
+	}
+
+	reclaimed, err := store.ClaimCaseLocks(context, CaseLockClaimArgs{
+		CaseID:       3,
+		Paths:        []string{"src/api/users.go"},
+		OwnerSession: "case:3",
+	})
+	if err != nil {
+		t.Fatalf("expected re-claim by the same case to succeed: %v", err)
+	}
+	if len(reclaimed) != 1 || reclaimed[0].ID != firstClaim[0].ID {
+		t.Fatalf("expected re-claim to reuse lock #%d, got %+v", firstClaim[0].ID, reclaimed)
+	}
+
+	_, err = store.ClaimCaseLocks(context, CaseLockClaimArgs{
+		CaseID:       4,
+		Paths:        []string{"src/ui/app.go", "src/api/posts.go"},
+		OwnerSession: "case:4",
+	})
+	if err == nil || !strings.Contains(err.Error(), "held by case 3") {
+		t.Fatalf("expected conflict naming case 3, got %v", err)
+	}
+
+	secondCaseID := int64(4)
+	secondCaseLocks, err := store.ListLocks(context, LockFilter{State: "active", OwnerCaseID: &secondCaseID})
+	if err != nil {
+		t.Fatalf("failed to list second case locks: %v", err)
+	}
+	if len(secondCaseLocks) != 0 {
+		t.Fatalf("expected failed claim to be atomic, got %+v", secondCaseLocks)
+	}
+
+	firstCaseID := int64(3)
+	released, err := store.ReleaseLocksByOwner(context, LockReleaseByOwnerArgs{OwnerCaseID: &firstCaseID, Reason: "case_complete"})
+	if err != nil {
+		t.Fatalf("failed to release case locks: %v", err)
+	}
+	if len(released) != 2 {
+		t.Fatalf("expected 2 released locks, got %+v", released)
+	}
+}
+
+func TestLockCoversPath(t *testing.T) {
+	prefixLock := Lock{ScopeType: "prefix", ScopePath: "src/api"}
+	fileLock := Lock{ScopeType: "file", ScopePath: "src/ui/app.go"}
+
+	if !LockCoversPath(prefixLock, "src/api/v1/users.go") {
+		t.Fatalf("expected prefix lock to cover nested file")
+	}
+	if LockCoversPath(prefixLock, "src/apiv2/users.go") {
+		t.Fatalf("expected prefix lock not to cover sibling prefix")
+	}
+	if !LockCoversPath(fileLock, "./src/ui/app.go") {
+		t.Fatalf("expected file lock to cover normalized path")
+	}
+	if LockCoversPath(fileLock, "src/ui/other.go") {
+		t.Fatalf("expected file lock not to cover other file")
+	}
+}
+
+func TestUpdatePlanningRule(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	maxFiles := 20
+	policy := "approvals=2;roles=merge-reviewer"
+	rule, err := store.UpdatePlanningRule(context, PlanningRuleUpdateArgs{
+		MaxFilesPerSlice: &maxFiles,
+		ReplanTriggers:   []string{"scope_change"},
+		ApprovalPolicy:   &policy,
+	})
+	if err != nil {
+		t.Fatalf("failed to update planning rule: %v", err)
+	}
+	if rule.MaxFilesPerSlice != 20 || rule.MaxTokenPerSlice != 18000 {
+		t.Fatalf("unexpected limits after update: %+v", rule)
+	}
+	if rule.ReplanTriggersJSON != `["scope_change"]` || rule.ApprovalPolicy != policy {
+		t.Fatalf("unexpected triggers/policy after update: %+v", rule)
+	}
+
+	invalid := 0
+	if _, err := store.UpdatePlanningRule(context, PlanningRuleUpdateArgs{MaxTokenPerSlice: &invalid}); err == nil {
+		t.Fatalf("expected non-positive limit to be rejected")
+	}
+	if _, err := store.UpdatePlanningRule(context, PlanningRuleUpdateArgs{}); err == nil {
+		t.Fatalf("expected empty update to be rejected")
+	}
+	unknownPolicy := "reviewer-optional"
+	if _, err := store.UpdatePlanningRule(context, PlanningRuleUpdateArgs{ApprovalPolicy: &unknownPolicy}); err == nil {
+		t.Fatalf("expected unparsable approval policy to be rejected")
+	}
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// taskLevelParents lists the parent levels each task level may hang under.
+// An empty string means the level may be created without a parent.
+var taskLevelParents = map[string][]string{
+	"epic":       {""},
+	"feature":    {"", "epic"},
+	"test_group": {"feature"},
+	"case":       {"feature", "test_group"},
+}
+
+func NormalizeTaskLevel(level string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(level))
+	normalized = strings.NewReplacer("-", "_", " ", "_").Replace(normalized)
+	if normalized == "testgroup" {
+		normalized = "test_group"
+	}
+	if normalized == "" {
+		return "", errors.New("level is required")
+	}
+	if _, ok := taskLevelParents[normalized]; !ok {
+		return "", fmt.Errorf("level must be one of: epic, feature, test_group, case (got %q)", level)
+	}
+	return normalized, nil
+}
+
+func (store *Store) validateTaskParentTx(ctx context.Context, transaction *sql.Tx, level string, parentID *int64) error {
+	parentLevel := ""
+	if parentID != nil {
+		parentTask, err := store.getTaskByIDTx(ctx, transaction, *parentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("parent task not found: %d", *parentID)
+			}
+			return err
+		}
+		if parentTask.DeletedAt != nil {
+			return fmt.Errorf("parent task is deleted: %d", parentTask.ID)
+		}
+		parentLevel = parentTask.Level
+	}
+
+	allowedParents := taskLevelParents[level]
+	for _, allowedParent := range allowedParents {
+		if allowedParent == parentLevel {
+			return nil
+		}
+	}
+	if parentLevel == "" {
+		return fmt.Errorf("%s requires a parent of level: %s", level, strings.Join(allowedParents, ", "))
+	}
+	return fmt.Errorf("%s cannot be placed under %s", level, parentLevel)
+}
+
+func (store *Store) BuildTaskTree(ctx context.Context, rootTaskID int64) (TaskTreeNode, error) {
+	rootTask, err := store.GetTaskByID(ctx, rootTaskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TaskTreeNode{}, fmt.Errorf("task not found: %d", rootTaskID)
+		}
+		return TaskTreeNode{}, err
+	}
+
+	rows, err := store.database.QueryContext(
+		ctx,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM tasks WHERE id = ?
+			UNION
+			SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+		)
+		SELECT `+taskSelectColumns+`
+		FROM tasks
+		WHERE id IN (SELECT id FROM subtree) AND id != ? AND deleted_at IS NULL
+		ORDER BY priority DESC, id ASC`,
+		rootTaskID,
+		rootTaskID,
+	)
+	if err != nil {
+		return TaskTreeNode{}, err
+	}
+	defer rows.Close()
+
+	childrenByParent := make(map[int64][]Task)
+	for rows.Next() {
+		task, scanErr := scanTask(rows)
+		if scanErr != nil {
+			return TaskTreeNode{}, scanErr
+		}
+		if task.ParentID != nil {
+			childrenByParent[*task.ParentID] = append(childrenByParent[*task.ParentID], task)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return TaskTreeNode{}, err
+	}
+
+	return buildTaskTreeNode(rootTask, childrenByParent), nil
+}
+
+func buildTaskTreeNode(task Task, childrenByParent map[int64][]Task) TaskTreeNode {
+	node := TaskTreeNode{
+		Task:     task,
+		Children: make([]TaskTreeNode, 0, len(childrenByParent[task.ID])),
+	}
+
+	if task.Level == "case" {
+		if task.Status != "cancelled" && task.Status != "deleted" {
+			node.TotalCases = 1
+			if task.Status == "done" {
+				node.DoneCases = 1
+			}
+		}
+	}
+
+	childStatuses := make([]string, 0, len(childrenByParent[task.ID]))
+	for _, childTask := range childrenByParent[task.ID] {
+		childNode := buildTaskTreeNode(childTask, childrenByParent)
+		node.TotalCases += childNode.TotalCases
+		node.DoneCases += childNode.DoneCases
+		node.Children = append(node.Children, childNode)
+		childStatuses = append(childStatuses, childNode.RolledUpStatus)
+	}
+
+	if node.TotalCases > 0 {
+		node.ProgressPercent = node.DoneCases * 100 / node.TotalCases
+	}
+	node.RolledUpStatus = rollUpTaskStatus(task.Status, childStatuses)
+	return node
+}
+
+func rollUpTaskStatus(ownStatus string, childStatuses []string) string {
+	if ownStatus == "cancelled" || ownStatus == "deleted" || len(childStatuses) == 0 {
+		return ownStatus
+	}
+
+	counted := 0
+	doneCount := 0
+	startedCount := 0
+	blockedCount := 0
+	for _, childStatus := range childStatuses {
+		switch childStatus {
+		case "cancelled", "deleted":
+			continue
+		case "done":
+			doneCount++
+		case "blocked":
+			blockedCount++
+		case "in_progress":
+			startedCount++
+		}
+		counted++
+	}
+
+	switch {
+	case counted == 0:
+		return ownStatus
+	case doneCount == counted:
+		return "done"
+	case blockedCount > 0:
+		return "blocked"
+	case startedCount > 0 || doneCount > 0:
+		return "in_progress"
+	default:
+		return "todo"
+	}
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeTaskLevel(t *testing.T) {
+	testCases := map[string]string{
+		"Epic":       "epic",
+		"feature":    "feature",
+		"TestGroup":  "test_group",
+		"test-group": "test_group",
+		" case ":     "case",
+	}
+	for input, expected := range testCases {
+		level, err := NormalizeTaskLevel(input)
+		if err != nil {
+			t.Fatalf("expected %q to normalize, got error: %v", input, err)
+		}
+		if level != expected {
+			t.Fatalf("expected %q -> %s, got %s", input, expected, level)
+		}
+	}
+	if _, err := NormalizeTaskLevel("story"); err == nil {
+		t.Fatalf("expected unknown level to be rejected")
+	}
+}
+
+func TestCreateTaskEnforcesParentLevels(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	epic, err := store.CreateTask(context, TaskCreateArgs{Level: "epic", Title: "epic"})
+	if err != nil {
+		t.Fatalf("failed to create epic: %v", err)
+	}
+	if _, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "orphan case", ParentID: &epic.ID}); err == nil {
+		t.Fatalf("expected case under epic to be rejected")
+	}
+	if _, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "root case"}); err == nil {
+		t.Fatalf("expected case without parent to be rejected")
+	}
+
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "feature", ParentID: &epic.ID})
+	if err != nil {
+		t.Fatalf("failed to create feature under epic: %v", err)
+	}
+	caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create case under feature: %v", err)
+	}
+	if _, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "nested", ParentID: &caseTask.ID}); err == nil {
+		t.Fatalf("expected feature under case to be rejected")
+	}
+}
+
+func TestBuildTaskTreeRollsUpProgress(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	epic, err := store.CreateTask(context, TaskCreateArgs{Level: "epic", Title: "epic"})
+	if err != nil {
+		t.Fatalf("failed to create epic: %v", err)
+	}
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "feature", ParentID: &epic.ID})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	testGroup, err := store.CreateTask(context, TaskCreateArgs{Level: "test_group", Title: "group", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create test group: %v", err)
+	}
+	caseIDs := make([]int64, 0, 4)
+	for _, title := range []string{"a", "b", "c", "d"} {
+		caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: title, ParentID: &testGroup.ID})
+		if err != nil {
+			t.Fatalf("failed to create case %s: %v", title, err)
+		}
+		caseIDs = append(caseIDs, caseTask.ID)
+	}
+	if _, err := store.CompleteCase(context, CaseCompleteArgs{TaskID: caseIDs[0]}); err != nil {
+		t.Fatalf("failed to complete case: %v", err)
+	}
+	if _, _, err := store.CancelTask(context, caseIDs[3], "dropped"); err != nil {
+		t.Fatalf("failed to cancel case: %v", err)
+	}
+
+	tree, err := store.BuildTaskTree(context, epic.ID)
+	if err != nil {
+		t.Fatalf("failed to build task tree: %v", err)
+	}
+	if tree.TotalCases != 3 || tree.DoneCases != 1 || tree.ProgressPercent != 33 {
+		t.Fatalf("unexpected epic rollup: total=%d done=%d progress=%d", tree.TotalCases, tree.DoneCases, tree.ProgressPercent)
+	}
+	if tree.RolledUpStatus != "in_progress" {
+		t.Fatalf("expected epic rolled up to in_progress, got %s", tree.RolledUpStatus)
+	}
+	if len(tree.Children) != 1 || len(tree.Children[0].Children) != 1 || len(tree.Children[0].Children[0].Children) != 4 {
+		t.Fatalf("unexpected tree shape: %+v", tree)
+	}
+}
@@ -0,0 +1,361 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func (store *Store) UpdateTask(ctx context.Context, taskID int64, args TaskUpdateArgs) (Task, error) {
+	setClauses := make([]string, 0, 5)
+	params := make([]any, 0, 6)
+
+	if args.Title != nil {
+		title := strings.TrimSpace(*args.Title)
+		if title == "" {
+			return Task{}, errors.New("title cannot be empty")
+		}
+		setClauses = append(setClauses, "title = ?")
+		params = append(params, title)
+	}
+	if args.Priority != nil {
+		setClauses = append(setClauses, "priority = ?")
+		params = append(params, *args.Priority)
+	}
+	if args.AssigneeSession != nil {
+		setClauses = append(setClauses, "assignee_session = ?")
+		params = append(params, nullableText(*args.AssigneeSession))
+	}
+	if args.NextAction != nil {
+		setClauses = append(setClauses, "next_action = ?")
+		params = append(params, nullableText(*args.NextAction))
+	}
+	if len(setClauses) == 0 {
+		return Task{}, errors.New("no task fields to update")
+	}
+	setClauses = append(setClauses, "updated_at = ?")
+	params = append(params, nowTimestamp(), taskID)
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE tasks SET %s WHERE id = ? AND deleted_at IS NULL", strings.Join(setClauses, ", ")),
+		params...,
+	)
+	if err != nil {
+		return Task{}, err
+	}
+	if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+		return Task{}, fmt.Errorf("task not found: %d", taskID)
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Task{}, err
+	}
+
+	task, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+func (store *Store) BlockTask(ctx context.Context, taskID int64, reason string) (Task, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return Task{}, errors.New("reason is required")
+	}
+	return store.transitionTaskStatus(ctx, taskID, "task.block", reason, func(_ *sql.Tx, task Task) (string, error) {
+		if isTaskClosedStatus(task.Status) {
+			return "", fmt.Errorf("cannot block %s task: %d", task.Status, task.ID)
+		}
+		return "blocked", nil
+	})
+}
+
+func (store *Store) UnblockTask(ctx context.Context, taskID int64) (Task, error) {
+	return store.transitionTaskStatus(ctx, taskID, "task.unblock", "", func(transaction *sql.Tx, task Task) (string, error) {
+		if task.Status != "blocked" {
+			return "", fmt.Errorf("task is not blocked: %d", task.ID)
+		}
+		var startedCount int64
+		if err := transaction.QueryRowContext(
+			ctx,
+			`SELECT COUNT(*) FROM checkpoints WHERE task_id = ? AND step_title NOT LIKE 'task.%'`,
+			task.ID,
+		).Scan(&startedCount); err != nil {
+			return "", err
+		}
+		if startedCount > 0 {
+			return "in_progress", nil
+		}
+		return "todo", nil
+	})
+}
+
+func (store *Store) CancelTask(ctx context.Context, taskID int64, reason string) (Task, []int64, error) {
+	return store.cascadeTaskStatus(ctx, taskID, "cancelled", "task.cancel", reason, true)
+}
+
+func (store *Store) DeleteTask(ctx context.Context, taskID int64, reason string, force bool) (Task, []int64, error) {
+	return store.cascadeTaskStatus(ctx, taskID, "deleted", "task.delete", reason, force)
+}
+
+func (store *Store) MoveTask(ctx context.Context, taskID int64, parentID *int64) (Task, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, err
+	}
+	defer transaction.Rollback()
+
+	task, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, fmt.Errorf("task not found: %d", taskID)
+		}
+		return Task{}, err
+	}
+	if task.DeletedAt != nil {
+		return Task{}, fmt.Errorf("task is deleted: %d", taskID)
+	}
+
+	if err := store.validateTaskParentTx(ctx, transaction, task.Level, parentID); err != nil {
+		return Task{}, err
+	}
+	if parentID != nil {
+		subtreeIDs, err := store.collectTaskSubtreeTx(ctx, transaction, taskID)
+		if err != nil {
+			return Task{}, err
+		}
+		for _, subtreeID := range subtreeIDs {
+			if subtreeID == *parentID {
+				return Task{}, fmt.Errorf("cannot move task %d under its own descendant %d", taskID, *parentID)
+			}
+		}
+	}
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE tasks SET parent_id = ?, updated_at = ? WHERE id = ?`,
+		parentID,
+		nowTimestamp(),
+		taskID,
+	); err != nil {
+		return Task{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Task{}, err
+	}
+
+	movedTask, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return Task{}, err
+	}
+	return movedTask, nil
+}
+
+func (store *Store) transitionTaskStatus(ctx context.Context, taskID int64, event string, reason string, nextStatus func(*sql.Tx, Task) (string, error)) (Task, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, err
+	}
+	defer transaction.Rollback()
+
+	task, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, fmt.Errorf("task not found: %d", taskID)
+		}
+		return Task{}, err
+	}
+	if task.DeletedAt != nil {
+		return Task{}, fmt.Errorf("task is deleted: %d", taskID)
+	}
+	status, err := nextStatus(transaction, task)
+	if err != nil {
+		return Task{}, err
+	}
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		`UPDATE tasks SET status = ?, status_reason = ?, updated_at = ? WHERE id = ?`,
+		status,
+		nullableText(reason),
+		nowTimestamp(),
+		taskID,
+	); err != nil {
+		return Task{}, err
+	}
+	if err := store.insertTaskEventCheckpointTx(ctx, transaction, taskID, event, reason); err != nil {
+		return Task{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Task{}, err
+	}
+
+	updatedTask, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		return Task{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return Task{}, err
+	}
+	return updatedTask, nil
+}
+
+// cascadeTaskStatus applies cancel/delete to a task and all of its
+// descendants. Done descendants keep their status on cancel; delete marks the
+// whole subtree deleted and voids recorded steps while keeping checkpoints.
+// Without allowActive, a subtree with in_progress tasks is rejected.
+func (store *Store) cascadeTaskStatus(ctx context.Context, taskID int64, status string, event string, reason string, allowActive bool) (Task, []int64, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	defer transaction.Rollback()
+
+	task, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, nil, fmt.Errorf("task not found: %d", taskID)
+		}
+		return Task{}, nil, err
+	}
+	if task.DeletedAt != nil {
+		return Task{}, nil, fmt.Errorf("task is deleted: %d", taskID)
+	}
+
+	subtreeIDs, err := store.collectTaskSubtreeTx(ctx, transaction, taskID)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	if !allowActive {
+		for _, subtreeID := range subtreeIDs {
+			subtreeTask, err := store.getTaskByIDTx(ctx, transaction, subtreeID)
+			if err != nil {
+				return Task{}, nil, err
+			}
+			if subtreeTask.Status == "in_progress" {
+				return Task{}, nil, fmt.Errorf("task %d is in_progress; cancel it first or pass force=true", subtreeTask.ID)
+			}
+		}
+	}
+
+	timestamp := nowTimestamp()
+	affectedIDs := make([]int64, 0, len(subtreeIDs))
+	for _, subtreeID := range subtreeIDs {
+		var result sql.Result
+		if status == "deleted" {
+			result, err = transaction.ExecContext(
+				ctx,
+				`UPDATE tasks
+				 SET status = 'deleted', status_reason = ?, deleted_at = ?, updated_at = ?
+				 WHERE id = ? AND deleted_at IS NULL`,
+				nullableText(reason),
+				timestamp,
+				timestamp,
+				subtreeID,
+			)
+			if err == nil {
+				_, err = transaction.ExecContext(ctx, `UPDATE steps SET status = 'deleted' WHERE task_id = ?`, subtreeID)
+			}
+		} else {
+			result, err = transaction.ExecContext(
+				ctx,
+				`UPDATE tasks
+				 SET status = ?, status_reason = ?, updated_at = ?
+				 WHERE id = ? AND deleted_at IS NULL AND status NOT IN ('done', 'cancelled')`,
+				status,
+				nullableText(reason),
+				timestamp,
+				subtreeID,
+			)
+		}
+		if err != nil {
+			return Task{}, nil, err
+		}
+		if changedRows, _ := result.RowsAffected(); changedRows == 0 {
+			continue
+		}
+		affectedIDs = append(affectedIDs, subtreeID)
+		if err := store.insertTaskEventCheckpointTx(ctx, transaction, subtreeID, event, reason); err != nil {
+			return Task{}, nil, err
+		}
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return Task{}, nil, err
+	}
+
+	updatedTask, err := store.getTaskByIDTx(ctx, transaction, taskID)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return Task{}, nil, err
+	}
+	return updatedTask, affectedIDs, nil
+}
+
+func (store *Store) collectTaskSubtreeTx(ctx context.Context, transaction *sql.Tx, rootTaskID int64) ([]int64, error) {
+	rows, err := transaction.QueryContext(
+		ctx,
+		`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM tasks WHERE id = ?
+			UNION
+			SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+		)
+		SELECT id FROM subtree ORDER BY id ASC`,
+		rootTaskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taskIDs := make([]int64, 0)
+	for rows.Next() {
+		var taskID int64
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, err
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+	return taskIDs, rows.Err()
+}
+
+func (store *Store) insertTaskEventCheckpointTx(ctx context.Context, transaction *sql.Tx, taskID int64, event string, reason string) error {
+	snapshotBytes, err := json.Marshal(map[string]any{
+		"event":  event,
+		"reason": reason,
+	})
+	if err != nil {
+		return err
+	}
+	return store.insertCheckpointTx(ctx, transaction, taskID, event, string(snapshotBytes))
+}
+
+func isTaskClosedStatus(status string) bool {
+	switch strings.TrimSpace(status) {
+	case "done", "cancelled", "deleted":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCancelTaskCascadesAndKeepsDoneChildren(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "gallery"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	doneCase, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "done case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create done case: %v", err)
+	}
+	openCase, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "open case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create open case: %v", err)
+	}
+	if _, err := store.CompleteCase(context, CaseCompleteArgs{TaskID: doneCase.ID, Summary: "ok"}); err != nil {
+		t.Fatalf("failed to complete case: %v", err)
+	}
+
+	cancelled, affectedIDs, err := store.CancelTask(context, feature.ID, "scope dropped")
+	if err != nil {
+		t.Fatalf("failed to cancel feature: %v", err)
+	}
+	if cancelled.Status != "cancelled" || cancelled.StatusReason == nil || *cancelled.StatusReason != "scope dropped" {
+		t.Fatalf("unexpected cancelled feature: %+v", cancelled)
+	}
+	if len(affectedIDs) != 2 || affectedIDs[0] != feature.ID || affectedIDs[1] != openCase.ID {
+		t.Fatalf("expected feature and open case to be affected, got %+v", affectedIDs)
+	}
+
+	reloadedDoneCase, err := store.GetTaskByID(context, doneCase.ID)
+	if err != nil {
+		t.Fatalf("failed to reload done case: %v", err)
+	}
+	if reloadedDoneCase.Status != "done" {
+		t.Fatalf("expected done case to keep status, got %s", reloadedDoneCase.Status)
+	}
+}
+
+func TestBlockUnblockAndMoveTask(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	otherFeature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "other feature"})
+	if err != nil {
+		t.Fatalf("failed to create other feature: %v", err)
+	}
+	caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create case: %v", err)
+	}
+
+	blocked, err := store.BlockTask(context, caseTask.ID, "waiting for api")
+	if err != nil {
+		t.Fatalf("failed to block case: %v", err)
+	}
+	if blocked.Status != "blocked" {
+		t.Fatalf("expected blocked status, got %s", blocked.Status)
+	}
+	unblocked, err := store.UnblockTask(context, caseTask.ID)
+	if err != nil {
+		t.Fatalf("failed to unblock case: %v", err)
+	}
+	if unblocked.Status != "todo" {
+		t.Fatalf("expected unstarted case to return to todo, got %s", unblocked.Status)
+	}
+
+	moved, err := store.MoveTask(context, caseTask.ID, &otherFeature.ID)
+	if err != nil {
+		t.Fatalf("failed to move case: %v", err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != otherFeature.ID {
+		t.Fatalf("expected case under feature %d, got %+v", otherFeature.ID, moved.ParentID)
+	}
+	if _, err := store.MoveTask(context, otherFeature.ID, &caseTask.ID); err == nil {
+		t.Fatalf("expected moving a task under its descendant to fail")
+	}
+}
+
+func TestDeleteTaskIsSoftAndGuardsActiveWork(t *testing.T) {
+	context := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	feature, err := store.CreateTask(context, TaskCreateArgs{Level: "feature", Title: "feature"})
+	if err != nil {
+		t.Fatalf("failed to create feature: %v", err)
+	}
+	caseTask, err := store.CreateTask(context, TaskCreateArgs{Level: "case", Title: "case", ParentID: &feature.ID})
+	if err != nil {
+		t.Fatalf("failed to create case: %v", err)
+	}
+	if _, err := store.BeginCase(context, CaseBeginArgs{TaskID: caseTask.ID}); err != nil {
+		t.Fatalf("failed to begin case: %v", err)
+	}
+
+	if _, _, err := store.DeleteTask(context, feature.ID, "obsolete", false); err == nil {
+		t.Fatalf("expected delete of in_progress subtree to require force")
+	}
+	if _, _, err := store.DeleteTask(context, feature.ID, "obsolete", true); err != nil {
+		t.Fatalf("failed to force delete feature: %v", err)
+	}
+
+	visibleTasks, err := store.ListTasks(context, TaskFilter{})
+	if err != nil {
+		t.Fatalf("failed to list tasks: %v", err)
+	}
+	if len(visibleTasks) != 0 {
+		t.Fatalf("expected deleted tasks to be hidden, got %+v", visibleTasks)
+	}
+	allTasks, err := store.ListTasks(context, TaskFilter{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("failed to list tasks with deleted: %v", err)
+	}
+	if len(allTasks) != 2 || allTasks[0].DeletedAt == nil {
+		t.Fatalf("expected soft-deleted tasks to remain, got %+v", allTasks)
+	}
+	if _, err := store.CompleteCase(context, CaseCompleteArgs{TaskID: caseTask.ID}); err == nil {
+		t.Fatalf("expected completing a deleted case to fail")
+	}
+}
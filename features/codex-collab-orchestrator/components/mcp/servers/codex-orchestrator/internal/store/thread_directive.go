@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	DirectiveStatusPending      = "pending"
+	DirectiveStatusSent         = "sent"
+	DirectiveStatusDelivered    = "delivered"
+	DirectiveStatusAcknowledged = "acknowledged"
+	DirectiveStatusApplied      = "applied"
+	DirectiveStatusFailed       = "failed"
+)
+
+const threadDirectiveSelectColumns = `id, thread_id, session_id, mode, directive, status, attempts, last_error, created_at, sent_at, delivered_at, acknowledged_at, applied_at, updated_at`
+
+// directiveStatusRank orders statuses so a directive never moves backwards.
+// failed ranks with sent: a worker acknowledging a directive whose delivery
+// could not be confirmed still moves it on. delivered only means the pane
+// showed the agent taking the text up; acknowledged and applied come from the
+// worker itself.
+var directiveStatusRank = map[string]int{
+	DirectiveStatusPending:      0,
+	DirectiveStatusSent:         1,
+	DirectiveStatusFailed:       1,
+	DirectiveStatusDelivered:    2,
+	DirectiveStatusAcknowledged: 3,
+	DirectiveStatusApplied:      4,
+}
+
+func (store *Store) CreateThreadDirective(ctx context.Context, args ThreadDirectiveCreateArgs) (ThreadDirective, error) {
+	if args.ThreadID <= 0 || args.SessionID <= 0 {
+		return ThreadDirective{}, errors.New("thread_id and session_id are required")
+	}
+	if strings.TrimSpace(args.Directive) == "" {
+		return ThreadDirective{}, errors.New("directive is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	defer transaction.Rollback()
+
+	now := nowTimestamp()
+	result, err := transaction.ExecContext(
+		ctx,
+		`INSERT INTO thread_directives(thread_id, session_id, mode, directive, status, created_at, updated_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?)`,
+		args.ThreadID,
+		args.SessionID,
+		strings.TrimSpace(args.Mode),
+		args.Directive,
+		DirectiveStatusPending,
+		now,
+		now,
+	)
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	directiveID, err := result.LastInsertId()
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return ThreadDirective{}, err
+	}
+
+	directive, err := scanThreadDirective(transaction.QueryRowContext(ctx, `SELECT `+threadDirectiveSelectColumns+` FROM thread_directives WHERE id = ?`, directiveID))
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return ThreadDirective{}, err
+	}
+	return directive, nil
+}
+
+func (store *Store) GetThreadDirective(ctx context.Context, directiveID int64) (ThreadDirective, error) {
+	directive, err := scanThreadDirective(store.database.QueryRowContext(ctx, `SELECT `+threadDirectiveSelectColumns+` FROM thread_directives WHERE id = ?`, directiveID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ThreadDirective{}, fmt.Errorf("directive not found: %d", directiveID)
+	}
+	return directive, err
+}
+
+// AdvanceThreadDirective sets a later status and stamps its time. A status
+// that ranks below the current one leaves the status alone, so late or
+// repeated reports are harmless; attempts and errors are still recorded.
+func (store *Store) AdvanceThreadDirective(ctx context.Context, directiveID int64, args ThreadDirectiveAdvanceArgs) (ThreadDirective, error) {
+	nextRank, ok := directiveStatusRank[args.Status]
+	if !ok {
+		return ThreadDirective{}, fmt.Errorf("unknown directive status: %s", args.Status)
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	defer transaction.Rollback()
+
+	current, err := scanThreadDirective(transaction.QueryRowContext(ctx, `SELECT `+threadDirectiveSelectColumns+` FROM thread_directives WHERE id = ?`, directiveID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ThreadDirective{}, fmt.Errorf("directive not found: %d", directiveID)
+	}
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+
+	now := nowTimestamp()
+	setClauses := []string{"updated_at = ?"}
+	params := []any{now}
+	if nextRank >= directiveStatusRank[current.Status] {
+		setClauses = append(setClauses, "status = ?")
+		params = append(params, args.Status)
+		switch args.Status {
+		case DirectiveStatusSent:
+			setClauses = append(setClauses, "sent_at = ?")
+			params = append(params, now)
+		case DirectiveStatusDelivered:
+			setClauses = append(setClauses, "delivered_at = COALESCE(delivered_at, ?)")
+			params = append(params, now)
+		case DirectiveStatusAcknowledged:
+			setClauses = append(setClauses, "acknowledged_at = COALESCE(acknowledged_at, ?)")
+			params = append(params, now)
+		case DirectiveStatusApplied:
+			setClauses = append(setClauses, "acknowledged_at = COALESCE(acknowledged_at, ?)", "applied_at = COALESCE(applied_at, ?)")
+			params = append(params, now, now)
+		}
+	}
+	if args.CountAttempt {
+		setClauses = append(setClauses, "attempts = attempts + 1")
+	}
+	if args.LastError != nil {
+		setClauses = append(setClauses, "last_error = ?")
+		params = append(params, nullableText(*args.LastError))
+	}
+	params = append(params, directiveID)
+	if _, err := transaction.ExecContext(ctx, `UPDATE thread_directives SET `+strings.Join(setClauses, ", ")+` WHERE id = ?`, params...); err != nil {
+		return ThreadDirective{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return ThreadDirective{}, err
+	}
+
+	directive, err := scanThreadDirective(transaction.QueryRowContext(ctx, `SELECT `+threadDirectiveSelectColumns+` FROM thread_directives WHERE id = ?`, directiveID))
+	if err != nil {
+		return ThreadDirective{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return ThreadDirective{}, err
+	}
+	return directive, nil
+}
+
+func (store *Store) ListThreadDirectives(ctx context.Context, filter ThreadDirectiveFilter) ([]ThreadDirective, error) {
+	query := strings.Builder{}
+	query.WriteString(`SELECT ` + threadDirectiveSelectColumns + `
+		                 FROM thread_directives
+		                WHERE 1=1`)
+	params := make([]any, 0, 3)
+	if filter.ThreadID > 0 {
+		query.WriteString(" AND thread_id = ?")
+		params = append(params, filter.ThreadID)
+	}
+	if filter.SessionID > 0 {
+		query.WriteString(" AND session_id = ?")
+		params = append(params, filter.SessionID)
+	}
+	if strings.TrimSpace(filter.Status) != "" {
+		query.WriteString(" AND status = ?")
+		params = append(params, strings.TrimSpace(filter.Status))
+	}
+	query.WriteString(" ORDER BY id ASC")
+
+	rows, err := store.database.QueryContext(ctx, query.String(), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	directives := make([]ThreadDirective, 0)
+	for rows.Next() {
+		directive, err := scanThreadDirective(rows)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive)
+	}
+	return directives, rows.Err()
+}
+
+func scanThreadDirective(scanner rowScanner) (ThreadDirective, error) {
+	var directive ThreadDirective
+	var lastError, sentAt, deliveredAt, acknowledgedAt, appliedAt sql.NullString
+	if err := scanner.Scan(
+		&directive.ID,
+		&directive.ThreadID,
+		&directive.SessionID,
+		&directive.Mode,
+		&directive.Directive,
+		&directive.Status,
+		&directive.Attempts,
+		&lastError,
+		&directive.CreatedAt,
+		&sentAt,
+		&deliveredAt,
+		&acknowledgedAt,
+		&appliedAt,
+		&directive.UpdatedAt,
+	); err != nil {
+		return ThreadDirective{}, err
+	}
+	if lastError.Valid {
+		directive.LastError = &lastError.String
+	}
+	if sentAt.Valid {
+		directive.SentAt = &sentAt.String
+	}
+	if deliveredAt.Valid {
+		directive.DeliveredAt = &deliveredAt.String
+	}
+	if acknowledgedAt.Valid {
+		directive.AcknowledgedAt = &acknowledgedAt.String
+	}
+	if appliedAt.Valid {
+		directive.AppliedAt = &appliedAt.String
+	}
+	return directive, nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAdvanceThreadDirectiveNeverMovesBackwards(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	session, err := store.OpenSession(ctx, SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := store.CreateThread(ctx, ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	directive, err := store.CreateThreadDirective(ctx, ThreadDirectiveCreateArgs{ThreadID: thread.ID, SessionID: session.ID, Mode: "interrupt_patch", Directive: "switch to the v2 API"})
+	if err != nil || directive.Status != DirectiveStatusPending {
+		t.Fatalf("unexpected created directive: %+v (%v)", directive, err)
+	}
+
+	notEchoed := "directive was not echoed"
+	for range 2 {
+		if directive, err = store.AdvanceThreadDirective(ctx, directive.ID, ThreadDirectiveAdvanceArgs{Status: DirectiveStatusSent, CountAttempt: true, LastError: &notEchoed}); err != nil {
+			t.Fatalf("failed to mark sent: %v", err)
+		}
+	}
+	if directive, err = store.AdvanceThreadDirective(ctx, directive.ID, ThreadDirectiveAdvanceArgs{Status: DirectiveStatusFailed}); err != nil {
+		t.Fatalf("failed to mark failed: %v", err)
+	}
+	if directive.Status != DirectiveStatusFailed || directive.Attempts != 2 || directive.SentAt == nil || directive.LastError == nil || *directive.LastError != notEchoed {
+		t.Fatalf("unexpected failed directive: %+v", directive)
+	}
+
+	if directive, err = store.AdvanceThreadDirective(ctx, directive.ID, ThreadDirectiveAdvanceArgs{Status: DirectiveStatusApplied}); err != nil {
+		t.Fatalf("failed to mark applied: %v", err)
+	}
+	if directive.Status != DirectiveStatusApplied || directive.AcknowledgedAt == nil || directive.AppliedAt == nil {
+		t.Fatalf("expected a late worker report to apply the directive, got %+v", directive)
+	}
+	for _, late := range []string{DirectiveStatusDelivered, DirectiveStatusAcknowledged} {
+		if directive, err = store.AdvanceThreadDirective(ctx, directive.ID, ThreadDirectiveAdvanceArgs{Status: late}); err != nil || directive.Status != DirectiveStatusApplied {
+			t.Fatalf("expected status to stay applied after %s, got %+v (%v)", late, directive, err)
+		}
+	}
+	if _, err := store.AdvanceThreadDirective(ctx, directive.ID, ThreadDirectiveAdvanceArgs{Status: "done"}); err == nil {
+		t.Fatal("expected unknown status to fail")
+	}
+
+	listed, err := store.ListThreadDirectives(ctx, ThreadDirectiveFilter{ThreadID: thread.ID, Status: DirectiveStatusApplied})
+	if err != nil || len(listed) != 1 || listed[0].ID != directive.ID {
+		t.Fatalf("unexpected directive list: %+v (%v)", listed, err)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 )
 
-const threadSelectColumns = `id, session_id, parent_thread_id, role, status, title, objective, worktree_id, agent_guide_path, agent_override, task_spec_json, scope_task_ids_json, scope_case_ids_json, scope_node_ids_json, tmux_session_name, tmux_window_name, tmux_pane_id, launch_command, log_file_path, provider_type, created_at, started_at, completed_at, updated_at`
+const reviewJobSelectColumns = `id, merge_request_id, session_id, reviewer_thread_id, main_merge_request_id, state, verdict, notes_json, created_at, updated_at, completed_at`
+
+const threadSelectColumns = `id, session_id, parent_thread_id, role, status, title, objective, worktree_id, agent_guide_path, agent_override, task_spec_json, scope_task_ids_json, scope_case_ids_json, scope_node_ids_json, tmux_session_name, tmux_window_name, tmux_pane_id, launch_command, log_file_path, provider_type, heartbeat_at, restart_count, last_restart_at, status_reason, runner_backend, process_id, created_at, started_at, completed_at, updated_at`
 
 func (store *Store) CreateThread(ctx context.Context, args ThreadCreateArgs) (Thread, error) {
 	if args.SessionID <= 0 {
@@ -226,6 +228,34 @@ func (store *Store) UpdateThread(ctx context.Context, threadID int64, args Threa
 		setClauses = append(setClauses, "scope_node_ids_json = ?")
 		params = append(params, nullableText(*args.ScopeNodeIDsJSON))
 	}
+	if args.HeartbeatAt != nil {
+		setClauses = append(setClauses, "heartbeat_at = ?")
+		params = append(params, nullableText(*args.HeartbeatAt))
+	}
+	if args.RestartCount != nil {
+		setClauses = append(setClauses, "restart_count = ?")
+		params = append(params, *args.RestartCount)
+	}
+	if args.LastRestartAt != nil {
+		setClauses = append(setClauses, "last_restart_at = ?")
+		params = append(params, nullableText(*args.LastRestartAt))
+	}
+	if args.StatusReason != nil {
+		setClauses = append(setClauses, "status_reason = ?")
+		params = append(params, nullableText(*args.StatusReason))
+	}
+	if args.RunnerBackend != nil {
+		setClauses = append(setClauses, "runner_backend = ?")
+		params = append(params, nullableText(*args.RunnerBackend))
+	}
+	if args.ProcessID != nil {
+		setClauses = append(setClauses, "process_id = ?")
+		if *args.ProcessID > 0 {
+			params = append(params, *args.ProcessID)
+		} else {
+			params = append(params, nil)
+		}
+	}
 	if len(setClauses) == 0 {
 		return store.GetThreadByID(ctx, threadID)
 	}
@@ -291,11 +321,12 @@ func (store *Store) CreateReviewJob(ctx context.Context, args ReviewJobCreateArg
 	now := nowTimestamp()
 	result, err := transaction.ExecContext(
 		ctx,
-		`INSERT INTO review_jobs(merge_request_id, session_id, reviewer_thread_id, state, notes_json, created_at, updated_at, completed_at)
-		 VALUES(?, ?, ?, ?, ?, ?, ?, NULL)`,
+		`INSERT INTO review_jobs(merge_request_id, session_id, reviewer_thread_id, main_merge_request_id, state, notes_json, created_at, updated_at, completed_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, NULL)`,
 		args.MergeRequestID,
 		args.SessionID,
 		args.ReviewerThreadID,
+		args.MainMergeRequestID,
 		state,
 		nullableJSON(args.NotesJSON),
 		now,
@@ -315,7 +346,7 @@ func (store *Store) CreateReviewJob(ctx context.Context, args ReviewJobCreateArg
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, merge_request_id, session_id, reviewer_thread_id, state, notes_json, created_at, updated_at, completed_at
+		`SELECT `+reviewJobSelectColumns+`
 		   FROM review_jobs
 		  WHERE id = ?`,
 		reviewJobID,
@@ -382,7 +413,7 @@ func (store *Store) UpdateReviewJob(ctx context.Context, reviewJobID int64, args
 
 	row := transaction.QueryRowContext(
 		ctx,
-		`SELECT id, merge_request_id, session_id, reviewer_thread_id, state, notes_json, created_at, updated_at, completed_at
+		`SELECT `+reviewJobSelectColumns+`
 		   FROM review_jobs
 		  WHERE id = ?`,
 		reviewJobID,
@@ -400,7 +431,7 @@ func (store *Store) UpdateReviewJob(ctx context.Context, reviewJobID int64, args
 func (store *Store) GetReviewJobByID(ctx context.Context, reviewJobID int64) (ReviewJob, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, merge_request_id, session_id, reviewer_thread_id, state, notes_json, created_at, updated_at, completed_at
+		`SELECT `+reviewJobSelectColumns+`
 		   FROM review_jobs
 		  WHERE id = ?`,
 		reviewJobID,
@@ -411,7 +442,7 @@ func (store *Store) GetReviewJobByID(ctx context.Context, reviewJobID int64) (Re
 func (store *Store) GetLatestReviewJobByMergeRequest(ctx context.Context, mergeRequestID int64) (*ReviewJob, error) {
 	row := store.database.QueryRowContext(
 		ctx,
-		`SELECT id, merge_request_id, session_id, reviewer_thread_id, state, notes_json, created_at, updated_at, completed_at
+		`SELECT `+reviewJobSelectColumns+`
 		   FROM review_jobs
 		  WHERE merge_request_id = ?
 		  ORDER BY id DESC
@@ -501,6 +532,11 @@ func scanThread(scanner rowScanner) (Thread, error) {
 	var launchCommand sql.NullString
 	var logFilePath sql.NullString
 	var providerType sql.NullString
+	var heartbeatAt sql.NullString
+	var lastRestartAt sql.NullString
+	var statusReason sql.NullString
+	var runnerBackend sql.NullString
+	var processID sql.NullInt64
 	var startedAt sql.NullString
 	var completedAt sql.NullString
 	err := scanner.Scan(
@@ -524,6 +560,12 @@ func scanThread(scanner rowScanner) (Thread, error) {
 		&launchCommand,
 		&logFilePath,
 		&providerType,
+		&heartbeatAt,
+		&thread.RestartCount,
+		&lastRestartAt,
+		&statusReason,
+		&runnerBackend,
+		&processID,
 		&thread.CreatedAt,
 		&startedAt,
 		&completedAt,
@@ -581,6 +623,21 @@ func scanThread(scanner rowScanner) (Thread, error) {
 	if providerType.Valid {
 		thread.ProviderType = &providerType.String
 	}
+	if heartbeatAt.Valid {
+		thread.HeartbeatAt = &heartbeatAt.String
+	}
+	if lastRestartAt.Valid {
+		thread.LastRestartAt = &lastRestartAt.String
+	}
+	if statusReason.Valid {
+		thread.StatusReason = &statusReason.String
+	}
+	if runnerBackend.Valid {
+		thread.RunnerBackend = &runnerBackend.String
+	}
+	if processID.Valid {
+		thread.ProcessID = &processID.Int64
+	}
 	if startedAt.Valid {
 		thread.StartedAt = &startedAt.String
 	}
@@ -593,6 +650,8 @@ func scanThread(scanner rowScanner) (Thread, error) {
 func scanReviewJob(scanner rowScanner) (ReviewJob, error) {
 	var reviewJob ReviewJob
 	var reviewerThreadID sql.NullInt64
+	var mainMergeRequestID sql.NullInt64
+	var verdict sql.NullString
 	var notesJSON sql.NullString
 	var completedAt sql.NullString
 	err := scanner.Scan(
@@ -600,7 +659,9 @@ func scanReviewJob(scanner rowScanner) (ReviewJob, error) {
 		&reviewJob.MergeRequestID,
 		&reviewJob.SessionID,
 		&reviewerThreadID,
+		&mainMergeRequestID,
 		&reviewJob.State,
+		&verdict,
 		&notesJSON,
 		&reviewJob.CreatedAt,
 		&reviewJob.UpdatedAt,
@@ -612,6 +673,12 @@ func scanReviewJob(scanner rowScanner) (ReviewJob, error) {
 	if reviewerThreadID.Valid {
 		reviewJob.ReviewerThreadID = &reviewerThreadID.Int64
 	}
+	if mainMergeRequestID.Valid {
+		reviewJob.MainMergeRequestID = &mainMergeRequestID.Int64
+	}
+	if verdict.Valid {
+		reviewJob.Verdict = &verdict.String
+	}
 	if notesJSON.Valid {
 		reviewJob.NotesJSON = &notesJSON.String
 	}
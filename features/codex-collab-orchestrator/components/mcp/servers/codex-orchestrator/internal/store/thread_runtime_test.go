@@ -266,3 +266,72 @@ func openThreadTestStore(t *testing.T) *Store {
 	}
 	return store
 }
+
+func TestUpdateSupervisorPolicy(t *testing.T) {
+	context := context.Background()
+	store := openThreadTestStore(t)
+	defer store.Close()
+
+	policy, err := store.GetSupervisorPolicy(context)
+	if err != nil {
+		t.Fatalf("failed to get supervisor policy: %v", err)
+	}
+	if !policy.Enabled || policy.MaxRestarts != 3 || policy.RestartBackoffSeconds != 30 {
+		t.Fatalf("unexpected default supervisor policy: %+v", policy)
+	}
+
+	disabled := false
+	maxRestarts := 0
+	policy, err = store.UpdateSupervisorPolicy(context, SupervisorPolicyUpdateArgs{Enabled: &disabled, MaxRestarts: &maxRestarts})
+	if err != nil {
+		t.Fatalf("failed to update supervisor policy: %v", err)
+	}
+	if policy.Enabled || policy.MaxRestarts != 0 {
+		t.Fatalf("unexpected supervisor policy after update: %+v", policy)
+	}
+
+	zero := 0
+	if _, err := store.UpdateSupervisorPolicy(context, SupervisorPolicyUpdateArgs{IntervalSeconds: &zero}); err == nil {
+		t.Fatalf("expected non-positive interval to be rejected")
+	}
+	smallCap := 5
+	if _, err := store.UpdateSupervisorPolicy(context, SupervisorPolicyUpdateArgs{MaxBackoffSeconds: &smallCap}); err == nil {
+		t.Fatalf("expected max backoff below base backoff to be rejected")
+	}
+	if _, err := store.UpdateSupervisorPolicy(context, SupervisorPolicyUpdateArgs{}); err == nil {
+		t.Fatalf("expected empty update to be rejected")
+	}
+}
+
+func TestClaimCrashedThreadForRestart(t *testing.T) {
+	context := context.Background()
+	store := openThreadTestStore(t)
+	defer store.Close()
+
+	session, err := store.OpenSession(context, SessionOpenArgs{AgentRole: "codex", Owner: "owner-a", RepoPath: "/tmp/repo-a"})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := store.CreateThread(context, ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+	if claimed, err := store.ClaimCrashedThreadForRestart(context, thread.ID); err != nil || claimed {
+		t.Fatalf("expected running thread not to be claimable, got %v (%v)", claimed, err)
+	}
+
+	crashedStatus := "crashed"
+	if _, err := store.UpdateThread(context, thread.ID, ThreadUpdateArgs{Status: &crashedStatus}); err != nil {
+		t.Fatalf("failed to mark thread crashed: %v", err)
+	}
+	if claimed, err := store.ClaimCrashedThreadForRestart(context, thread.ID); err != nil || !claimed {
+		t.Fatalf("expected first claim to win, got %v (%v)", claimed, err)
+	}
+	if claimed, err := store.ClaimCrashedThreadForRestart(context, thread.ID); err != nil || claimed {
+		t.Fatalf("expected second claim to lose, got %v (%v)", claimed, err)
+	}
+	reloaded, err := store.GetThreadByID(context, thread.ID)
+	if err != nil || reloaded.Status != "restarting" {
+		t.Fatalf("expected restarting thread, got %+v (%v)", reloaded, err)
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const supervisorPolicySelectColumns = `enabled, interval_seconds, stall_timeout_seconds, max_restarts, restart_backoff_seconds, max_backoff_seconds, updated_at`
+
+func (store *Store) GetSupervisorPolicy(ctx context.Context) (SupervisorPolicy, error) {
+	row := store.database.QueryRowContext(
+		ctx,
+		`SELECT `+supervisorPolicySelectColumns+`
+		 FROM supervisor_policy
+		 WHERE id = 1`,
+	)
+	return scanSupervisorPolicy(row)
+}
+
+func (store *Store) UpdateSupervisorPolicy(ctx context.Context, args SupervisorPolicyUpdateArgs) (SupervisorPolicy, error) {
+	setClauses := make([]string, 0, 7)
+	params := make([]any, 0, 7)
+
+	if args.Enabled != nil {
+		setClauses = append(setClauses, "enabled = ?")
+		params = append(params, *args.Enabled)
+	}
+	positiveFields := []struct {
+		column string
+		value  *int
+	}{
+		{"interval_seconds", args.IntervalSeconds},
+		{"stall_timeout_seconds", args.StallTimeoutSeconds},
+		{"restart_backoff_seconds", args.RestartBackoffSeconds},
+		{"max_backoff_seconds", args.MaxBackoffSeconds},
+	}
+	for _, field := range positiveFields {
+		if field.value == nil {
+			continue
+		}
+		if *field.value <= 0 {
+			return SupervisorPolicy{}, fmt.Errorf("%s must be positive", field.column)
+		}
+		setClauses = append(setClauses, field.column+" = ?")
+		params = append(params, *field.value)
+	}
+	if args.MaxRestarts != nil {
+		if *args.MaxRestarts < 0 {
+			return SupervisorPolicy{}, errors.New("max_restarts cannot be negative")
+		}
+		setClauses = append(setClauses, "max_restarts = ?")
+		params = append(params, *args.MaxRestarts)
+	}
+	if len(setClauses) == 0 {
+		return SupervisorPolicy{}, errors.New("no supervisor policy fields to update")
+	}
+	setClauses = append(setClauses, "updated_at = ?")
+	params = append(params, nowTimestamp())
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return SupervisorPolicy{}, err
+	}
+	defer transaction.Rollback()
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE supervisor_policy SET %s WHERE id = 1", strings.Join(setClauses, ", ")),
+		params...,
+	); err != nil {
+		return SupervisorPolicy{}, err
+	}
+
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return SupervisorPolicy{}, err
+	}
+
+	policy, err := scanSupervisorPolicy(transaction.QueryRowContext(
+		ctx,
+		`SELECT `+supervisorPolicySelectColumns+`
+		 FROM supervisor_policy
+		 WHERE id = 1`,
+	))
+	if err != nil {
+		return SupervisorPolicy{}, err
+	}
+	if policy.MaxBackoffSeconds < policy.RestartBackoffSeconds {
+		return SupervisorPolicy{}, errors.New("max_backoff_seconds cannot be less than restart_backoff_seconds")
+	}
+	if err := transaction.Commit(); err != nil {
+		return SupervisorPolicy{}, err
+	}
+	return policy, nil
+}
+
+func scanSupervisorPolicy(scanner rowScanner) (SupervisorPolicy, error) {
+	var policy SupervisorPolicy
+	var enabled int
+	if err := scanner.Scan(
+		&enabled,
+		&policy.IntervalSeconds,
+		&policy.StallTimeoutSeconds,
+		&policy.MaxRestarts,
+		&policy.RestartBackoffSeconds,
+		&policy.MaxBackoffSeconds,
+		&policy.UpdatedAt,
+	); err != nil {
+		return SupervisorPolicy{}, err
+	}
+	policy.Enabled = enabled != 0
+	return policy, nil
+}
+
+// ClaimCrashedThreadForRestart moves a crashed thread to restarting. Only one
+// caller wins the claim, so concurrent supervisors never relaunch the same
+// thread twice; the others get false.
+func (store *Store) ClaimCrashedThreadForRestart(ctx context.Context, threadID int64) (bool, error) {
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer transaction.Rollback()
+
+	result, err := transaction.ExecContext(
+		ctx,
+		`UPDATE threads
+		    SET status = 'restarting',
+		        updated_at = ?
+		  WHERE id = ?
+		    AND status = 'crashed'`,
+		nowTimestamp(),
+		threadID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if changedRows, err := result.RowsAffected(); err != nil || changedRows != 1 {
+		return false, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return false, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetThreadTranscriptCursor returns how far the thread log has been parsed;
+// the zero cursor means nothing has been.
+func (store *Store) GetThreadTranscriptCursor(ctx context.Context, threadID int64) (TranscriptCursor, error) {
+	return scanTranscriptCursor(store.database.QueryRowContext(ctx, transcriptCursorQuery, threadID))
+}
+
+// AppendThreadTranscript stores the turns parsed from the log between
+// args.From and args.To and moves the cursor to args.To. Stored turns are
+// never deleted or renumbered; a continued turn only grows. It reports false
+// without writing when the cursor is no longer at args.From, because another
+// refresh already stored that part of the log.
+func (store *Store) AppendThreadTranscript(ctx context.Context, args TranscriptAppendArgs) (bool, error) {
+	if args.ThreadID <= 0 {
+		return false, errors.New("thread_id is required")
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer transaction.Rollback()
+
+	cursor, err := getThreadTranscriptCursorTx(ctx, transaction, args.ThreadID)
+	if err != nil {
+		return false, err
+	}
+	if cursor != args.From {
+		return false, nil
+	}
+
+	var lastSeq int
+	if err := transaction.QueryRowContext(
+		ctx,
+		`SELECT COALESCE(MAX(seq), 0) FROM thread_transcript_turns WHERE thread_id = ?`,
+		args.ThreadID,
+	).Scan(&lastSeq); err != nil {
+		return false, err
+	}
+
+	turns := args.Turns
+	if args.ContinueLast && lastSeq > 0 && len(turns) > 0 {
+		if _, err := transaction.ExecContext(
+			ctx,
+			`UPDATE thread_transcript_turns SET content = content || ? WHERE thread_id = ? AND seq = ?`,
+			"\n"+turns[0].Content,
+			args.ThreadID,
+			lastSeq,
+		); err != nil {
+			return false, err
+		}
+		turns = turns[1:]
+	}
+	now := nowTimestamp()
+	for _, turn := range turns {
+		lastSeq++
+		if _, err := transaction.ExecContext(
+			ctx,
+			`INSERT INTO thread_transcript_turns(thread_id, seq, role, content, created_at) VALUES(?, ?, ?, ?, ?)`,
+			args.ThreadID,
+			lastSeq,
+			turn.Role,
+			turn.Content,
+			now,
+		); err != nil {
+			return false, err
+		}
+	}
+	if _, err := transaction.ExecContext(
+		ctx,
+		`INSERT INTO thread_transcript_cursors(thread_id, log_segment, log_offset, updated_at)
+		 VALUES(?, ?, ?, ?)
+		 ON CONFLICT(thread_id) DO UPDATE SET
+			log_segment = excluded.log_segment,
+			log_offset = excluded.log_offset,
+			updated_at = excluded.updated_at`,
+		args.ThreadID,
+		args.To.Segment,
+		args.To.Offset,
+		now,
+	); err != nil {
+		return false, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return false, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func getThreadTranscriptCursorTx(ctx context.Context, transaction *sql.Tx, threadID int64) (TranscriptCursor, error) {
+	return scanTranscriptCursor(transaction.QueryRowContext(ctx, transcriptCursorQuery, threadID))
+}
+
+const transcriptCursorQuery = `SELECT log_segment, log_offset FROM thread_transcript_cursors WHERE thread_id = ?`
+
+func scanTranscriptCursor(row *sql.Row) (TranscriptCursor, error) {
+	var cursor TranscriptCursor
+	err := row.Scan(&cursor.Segment, &cursor.Offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TranscriptCursor{}, nil
+	}
+	return cursor, err
+}
+
+// ListThreadTranscript returns one page of turns in seq order together with
+// the thread's total turn count.
+func (store *Store) ListThreadTranscript(ctx context.Context, threadID int64, offset int, limit int) ([]TranscriptTurn, int, error) {
+	var total int
+	if err := store.database.QueryRowContext(ctx, `SELECT COUNT(*) FROM thread_transcript_turns WHERE thread_id = ?`, threadID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := store.database.QueryContext(
+		ctx,
+		`SELECT thread_id, seq, role, content, created_at
+		 FROM thread_transcript_turns
+		 WHERE thread_id = ?
+		 ORDER BY seq ASC
+		 LIMIT ? OFFSET ?`,
+		threadID,
+		limit,
+		offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	turns := make([]TranscriptTurn, 0)
+	for rows.Next() {
+		var turn TranscriptTurn
+		if err := rows.Scan(&turn.ThreadID, &turn.Seq, &turn.Role, &turn.Content, &turn.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		turns = append(turns, turn)
+	}
+	return turns, total, rows.Err()
+}
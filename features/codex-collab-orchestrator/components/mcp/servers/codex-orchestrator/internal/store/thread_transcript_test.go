@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAppendThreadTranscriptNeverRewritesStoredTurns(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	session, err := store.OpenSession(ctx, SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := store.CreateThread(ctx, ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+
+	if cursor, err := store.GetThreadTranscriptCursor(ctx, thread.ID); err != nil || cursor != (TranscriptCursor{}) {
+		t.Fatalf("expected a zero cursor before parsing, got %+v (%v)", cursor, err)
+	}
+	firstCursor := TranscriptCursor{Segment: 1, Offset: 40}
+	if appended, err := store.AppendThreadTranscript(ctx, TranscriptAppendArgs{
+		ThreadID: thread.ID,
+		To:       firstCursor,
+		Turns:    []TranscriptTurnArgs{{Role: "user", Content: "fix the parser"}, {Role: "assistant", Content: "Looking"}},
+	}); err != nil || !appended {
+		t.Fatalf("expected first turns to be stored, appended=%v err=%v", appended, err)
+	}
+	if appended, err := store.AppendThreadTranscript(ctx, TranscriptAppendArgs{
+		ThreadID: thread.ID,
+		To:       firstCursor,
+		Turns:    []TranscriptTurnArgs{{Role: "user", Content: "fix the parser"}},
+	}); err != nil || appended {
+		t.Fatalf("expected a stale cursor to be ignored, appended=%v err=%v", appended, err)
+	}
+
+	secondCursor := TranscriptCursor{Segment: 2, Offset: 12}
+	if appended, err := store.AppendThreadTranscript(ctx, TranscriptAppendArgs{
+		ThreadID:     thread.ID,
+		From:         firstCursor,
+		To:           secondCursor,
+		Turns:        []TranscriptTurnArgs{{Role: "output", Content: "at the parser now."}, {Role: "tool", Content: "Bash(go test ./...)"}},
+		ContinueLast: true,
+	}); err != nil || !appended {
+		t.Fatalf("expected continued turns to be stored, appended=%v err=%v", appended, err)
+	}
+	if cursor, err := store.GetThreadTranscriptCursor(ctx, thread.ID); err != nil || cursor != secondCursor {
+		t.Fatalf("expected the cursor to move, got %+v (%v)", cursor, err)
+	}
+
+	turns, total, err := store.ListThreadTranscript(ctx, thread.ID, 1, 5)
+	if err != nil {
+		t.Fatalf("failed to list transcript: %v", err)
+	}
+	if total != 3 || len(turns) != 2 {
+		t.Fatalf("expected 2 of 3 turns, got %d of %d", len(turns), total)
+	}
+	if turns[0].Seq != 2 || turns[0].Content != "Looking\nat the parser now." || turns[1].Seq != 3 || turns[1].Role != "tool" {
+		t.Fatalf("unexpected transcript page: %+v", turns)
+	}
+}
@@ -0,0 +1,254 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+const threadUsageSelectColumns = `thread_id, attempt, session_id, provider_type, input_tokens, cached_input_tokens, output_tokens, total_tokens, cost_usd, source, recorded_at`
+
+// RecordThreadUsage stores the latest usage report of one agent process.
+// Reports are cumulative, so each (thread, attempt) row is replaced rather
+// than added to; a restart starts a new attempt.
+func (store *Store) RecordThreadUsage(ctx context.Context, args ThreadUsageArgs) (ThreadUsage, error) {
+	if args.ThreadID <= 0 || args.SessionID <= 0 {
+		return ThreadUsage{}, errors.New("thread_id and session_id are required")
+	}
+	source := strings.TrimSpace(args.Source)
+	if source == "" {
+		source = "log"
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return ThreadUsage{}, err
+	}
+	defer transaction.Rollback()
+
+	if _, err := transaction.ExecContext(
+		ctx,
+		`INSERT INTO thread_usage(thread_id, attempt, session_id, provider_type, input_tokens, cached_input_tokens, output_tokens, total_tokens, cost_usd, source, recorded_at)
+		 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(thread_id, attempt) DO UPDATE SET
+			provider_type = excluded.provider_type,
+			input_tokens = excluded.input_tokens,
+			cached_input_tokens = excluded.cached_input_tokens,
+			output_tokens = excluded.output_tokens,
+			total_tokens = excluded.total_tokens,
+			cost_usd = excluded.cost_usd,
+			source = excluded.source,
+			recorded_at = excluded.recorded_at`,
+		args.ThreadID,
+		args.Attempt,
+		args.SessionID,
+		nullableText(args.ProviderType),
+		args.InputTokens,
+		args.CachedInputTokens,
+		args.OutputTokens,
+		args.TotalTokens,
+		args.CostUSD,
+		source,
+		nowTimestamp(),
+	); err != nil {
+		return ThreadUsage{}, err
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return ThreadUsage{}, err
+	}
+
+	usage, err := scanThreadUsage(transaction.QueryRowContext(
+		ctx,
+		`SELECT `+threadUsageSelectColumns+`
+		 FROM thread_usage
+		 WHERE thread_id = ? AND attempt = ?`,
+		args.ThreadID,
+		args.Attempt,
+	))
+	if err != nil {
+		return ThreadUsage{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return ThreadUsage{}, err
+	}
+	return usage, nil
+}
+
+// ListThreadUsageTotals sums usage per thread, optionally for one session.
+func (store *Store) ListThreadUsageTotals(ctx context.Context, sessionID int64) ([]ThreadUsageTotal, error) {
+	rows, err := store.database.QueryContext(
+		ctx,
+		`SELECT u.thread_id, u.session_id, t.role, t.scope_node_ids_json, COUNT(*),
+		        SUM(u.input_tokens), SUM(u.cached_input_tokens), SUM(u.output_tokens), SUM(u.total_tokens), SUM(u.cost_usd)
+		   FROM thread_usage u
+		   JOIN threads t ON t.id = u.thread_id
+		  WHERE ? = 0 OR u.session_id = ?
+		  GROUP BY u.thread_id, u.session_id, t.role, t.scope_node_ids_json
+		  ORDER BY u.thread_id ASC`,
+		sessionID,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make([]ThreadUsageTotal, 0)
+	for rows.Next() {
+		var total ThreadUsageTotal
+		var scopeNodeIDsJSON sql.NullString
+		var costUSD sql.NullFloat64
+		if err := rows.Scan(
+			&total.ThreadID,
+			&total.SessionID,
+			&total.Role,
+			&scopeNodeIDsJSON,
+			&total.Attempts,
+			&total.InputTokens,
+			&total.CachedInputTokens,
+			&total.OutputTokens,
+			&total.TotalTokens,
+			&costUSD,
+		); err != nil {
+			return nil, err
+		}
+		if scopeNodeIDsJSON.Valid {
+			total.ScopeNodeIDsJSON = &scopeNodeIDsJSON.String
+		}
+		if costUSD.Valid {
+			total.CostUSD = &costUSD.Float64
+		}
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// GetSessionBudget returns nil when the session has no budget.
+func (store *Store) GetSessionBudget(ctx context.Context, sessionID int64) (*SessionBudget, error) {
+	budget, err := scanSessionBudget(store.database.QueryRowContext(
+		ctx,
+		`SELECT session_id, token_budget, cost_budget_usd, updated_at
+		 FROM session_budgets
+		 WHERE session_id = ?`,
+		sessionID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (store *Store) SetSessionBudget(ctx context.Context, args SessionBudgetArgs) (SessionBudget, error) {
+	if args.SessionID <= 0 {
+		return SessionBudget{}, errors.New("session_id is required")
+	}
+	if args.TokenBudget == nil && args.CostBudgetUSD == nil {
+		return SessionBudget{}, errors.New("token_budget or cost_budget_usd is required")
+	}
+	if args.TokenBudget != nil && *args.TokenBudget < 0 || args.CostBudgetUSD != nil && *args.CostBudgetUSD < 0 {
+		return SessionBudget{}, errors.New("budgets cannot be negative")
+	}
+	if _, err := store.GetSessionByID(ctx, args.SessionID); err != nil {
+		return SessionBudget{}, err
+	}
+
+	transaction, err := store.database.BeginTx(ctx, nil)
+	if err != nil {
+		return SessionBudget{}, err
+	}
+	defer transaction.Rollback()
+
+	now := nowTimestamp()
+	if _, err := transaction.ExecContext(
+		ctx,
+		`INSERT OR IGNORE INTO session_budgets(session_id, updated_at) VALUES(?, ?)`,
+		args.SessionID,
+		now,
+	); err != nil {
+		return SessionBudget{}, err
+	}
+	if args.TokenBudget != nil {
+		var tokenBudget any
+		if *args.TokenBudget > 0 {
+			tokenBudget = *args.TokenBudget
+		}
+		if _, err := transaction.ExecContext(ctx, `UPDATE session_budgets SET token_budget = ?, updated_at = ? WHERE session_id = ?`, tokenBudget, now, args.SessionID); err != nil {
+			return SessionBudget{}, err
+		}
+	}
+	if args.CostBudgetUSD != nil {
+		var costBudget any
+		if *args.CostBudgetUSD > 0 {
+			costBudget = *args.CostBudgetUSD
+		}
+		if _, err := transaction.ExecContext(ctx, `UPDATE session_budgets SET cost_budget_usd = ?, updated_at = ? WHERE session_id = ?`, costBudget, now, args.SessionID); err != nil {
+			return SessionBudget{}, err
+		}
+	}
+	if err := store.bumpVersionTx(ctx, transaction); err != nil {
+		return SessionBudget{}, err
+	}
+
+	budget, err := scanSessionBudget(transaction.QueryRowContext(
+		ctx,
+		`SELECT session_id, token_budget, cost_budget_usd, updated_at
+		 FROM session_budgets
+		 WHERE session_id = ?`,
+		args.SessionID,
+	))
+	if err != nil {
+		return SessionBudget{}, err
+	}
+	if err := transaction.Commit(); err != nil {
+		return SessionBudget{}, err
+	}
+	return budget, nil
+}
+
+func scanThreadUsage(scanner rowScanner) (ThreadUsage, error) {
+	var usage ThreadUsage
+	var providerType sql.NullString
+	var costUSD sql.NullFloat64
+	if err := scanner.Scan(
+		&usage.ThreadID,
+		&usage.Attempt,
+		&usage.SessionID,
+		&providerType,
+		&usage.InputTokens,
+		&usage.CachedInputTokens,
+		&usage.OutputTokens,
+		&usage.TotalTokens,
+		&costUSD,
+		&usage.Source,
+		&usage.RecordedAt,
+	); err != nil {
+		return ThreadUsage{}, err
+	}
+	if providerType.Valid {
+		usage.ProviderType = &providerType.String
+	}
+	if costUSD.Valid {
+		usage.CostUSD = &costUSD.Float64
+	}
+	return usage, nil
+}
+
+func scanSessionBudget(scanner rowScanner) (SessionBudget, error) {
+	var budget SessionBudget
+	var tokenBudget sql.NullInt64
+	var costBudget sql.NullFloat64
+	if err := scanner.Scan(&budget.SessionID, &tokenBudget, &costBudget, &budget.UpdatedAt); err != nil {
+		return SessionBudget{}, err
+	}
+	if tokenBudget.Valid {
+		budget.TokenBudget = &tokenBudget.Int64
+	}
+	if costBudget.Valid {
+		budget.CostBudgetUSD = &costBudget.Float64
+	}
+	return budget, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRecordThreadUsageReplacesPerAttempt(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	defer store.Close()
+
+	session, err := store.OpenSession(ctx, SessionOpenArgs{AgentRole: "root", Owner: "test", RepoPath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	thread, err := store.CreateThread(ctx, ThreadCreateArgs{SessionID: session.ID, Role: "worker", Status: "running"})
+	if err != nil {
+		t.Fatalf("failed to create thread: %v", err)
+	}
+
+	cost := 0.25
+	for _, args := range []ThreadUsageArgs{
+		{ThreadID: thread.ID, SessionID: session.ID, InputTokens: 100, OutputTokens: 10, TotalTokens: 110},
+		{ThreadID: thread.ID, SessionID: session.ID, InputTokens: 400, OutputTokens: 40, TotalTokens: 440, CostUSD: &cost},
+		{ThreadID: thread.ID, Attempt: 1, SessionID: session.ID, TotalTokens: 60},
+	} {
+		if _, err := store.RecordThreadUsage(ctx, args); err != nil {
+			t.Fatalf("failed to record usage: %v", err)
+		}
+	}
+
+	totals, err := store.ListThreadUsageTotals(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("failed to list usage totals: %v", err)
+	}
+	if len(totals) != 1 || totals[0].Attempts != 2 || totals[0].TotalTokens != 500 || totals[0].InputTokens != 400 || totals[0].CostUSD == nil || *totals[0].CostUSD != 0.25 {
+		t.Fatalf("unexpected usage totals: %+v", totals)
+	}
+
+	if budget, err := store.GetSessionBudget(ctx, session.ID); err != nil || budget != nil {
+		t.Fatalf("expected no budget, got %+v (%v)", budget, err)
+	}
+	tokenBudget := int64(1000)
+	budget, err := store.SetSessionBudget(ctx, SessionBudgetArgs{SessionID: session.ID, TokenBudget: &tokenBudget})
+	if err != nil || budget.TokenBudget == nil || *budget.TokenBudget != 1000 || budget.CostBudgetUSD != nil {
+		t.Fatalf("unexpected budget: %+v (%v)", budget, err)
+	}
+	costBudget := 2.5
+	tokenBudget = 0
+	if budget, err = store.SetSessionBudget(ctx, SessionBudgetArgs{SessionID: session.ID, TokenBudget: &tokenBudget, CostBudgetUSD: &costBudget}); err != nil || budget.TokenBudget != nil || *budget.CostBudgetUSD != 2.5 {
+		t.Fatalf("expected token budget cleared and cost budget set, got %+v (%v)", budget, err)
+	}
+}
@@ -13,6 +13,8 @@ type Task struct {
 	InputContract   *string `json:"input_contract,omitempty"`
 	Fixtures        *string `json:"fixtures,omitempty"`
 	NextAction      *string `json:"next_action,omitempty"`
+	StatusReason    *string `json:"status_reason,omitempty"`
+	DeletedAt       *string `json:"deleted_at,omitempty"`
 	CreatedAt       string  `json:"created_at"`
 	UpdatedAt       string  `json:"updated_at"`
 }
@@ -36,13 +38,17 @@ type Checkpoint struct {
 }
 
 type Lock struct {
-	ID           int64  `json:"id"`
-	ScopeType    string `json:"scope_type"`
-	ScopePath    string `json:"scope_path"`
-	OwnerSession string `json:"owner_session"`
-	LeaseUntil   string `json:"lease_until"`
-	HeartbeatAt  string `json:"heartbeat_at"`
-	State        string `json:"state"`
+	ID             int64   `json:"id"`
+	ScopeType      string  `json:"scope_type"`
+	ScopePath      string  `json:"scope_path"`
+	OwnerSession   string  `json:"owner_session"`
+	OwnerSessionID *int64  `json:"owner_session_id,omitempty"`
+	OwnerThreadID  *int64  `json:"owner_thread_id,omitempty"`
+	OwnerCaseID    *int64  `json:"owner_case_id,omitempty"`
+	LeaseUntil     string  `json:"lease_until"`
+	HeartbeatAt    string  `json:"heartbeat_at"`
+	State          string  `json:"state"`
+	ReleaseReason  *string `json:"release_reason,omitempty"`
 }
 
 type Worktree struct {
@@ -55,6 +61,7 @@ type Worktree struct {
 	ParentWorktree *int64  `json:"parent_worktree_id,omitempty"`
 	OwnerSessionID *int64  `json:"owner_session_id,omitempty"`
 	MergeState     *string `json:"merge_state,omitempty"`
+	BaseRef        *string `json:"base_ref,omitempty"`
 	CreatedAt      string  `json:"created_at"`
 	MergedAt       string  `json:"merged_at,omitempty"`
 }
@@ -175,6 +182,12 @@ type Thread struct {
 	LaunchCommand    *string `json:"launch_command,omitempty"`
 	LogFilePath      *string `json:"log_file_path,omitempty"`
 	ProviderType     *string `json:"provider_type,omitempty"`
+	HeartbeatAt      *string `json:"heartbeat_at,omitempty"`
+	RestartCount     int     `json:"restart_count"`
+	LastRestartAt    *string `json:"last_restart_at,omitempty"`
+	StatusReason     *string `json:"status_reason,omitempty"`
+	RunnerBackend    *string `json:"runner_backend,omitempty"`
+	ProcessID        *int64  `json:"process_id,omitempty"`
 	CreatedAt        string  `json:"created_at"`
 	StartedAt        *string `json:"started_at,omitempty"`
 	CompletedAt      *string `json:"completed_at,omitempty"`
@@ -182,15 +195,17 @@ type Thread struct {
 }
 
 type ReviewJob struct {
-	ID               int64   `json:"id"`
-	MergeRequestID   int64   `json:"merge_request_id"`
-	SessionID        int64   `json:"session_id"`
-	ReviewerThreadID *int64  `json:"reviewer_thread_id,omitempty"`
-	State            string  `json:"state"`
-	NotesJSON        *string `json:"notes_json,omitempty"`
-	CreatedAt        string  `json:"created_at"`
-	UpdatedAt        string  `json:"updated_at"`
-	CompletedAt      *string `json:"completed_at,omitempty"`
+	ID                 int64   `json:"id"`
+	MergeRequestID     int64   `json:"merge_request_id"`
+	SessionID          int64   `json:"session_id"`
+	ReviewerThreadID   *int64  `json:"reviewer_thread_id,omitempty"`
+	MainMergeRequestID *int64  `json:"main_merge_request_id,omitempty"`
+	State              string  `json:"state"`
+	Verdict            *string `json:"verdict,omitempty"`
+	NotesJSON          *string `json:"notes_json,omitempty"`
+	CreatedAt          string  `json:"created_at"`
+	UpdatedAt          string  `json:"updated_at"`
+	CompletedAt        *string `json:"completed_at,omitempty"`
 }
 
 type RuntimePrereqEvent struct {
@@ -219,6 +234,7 @@ type GraphNode struct {
 	ApprovalState     string  `json:"approval_state"`
 	CreatedAt         string  `json:"created_at"`
 	UpdatedAt         string  `json:"updated_at"`
+	DeletedAt         *string `json:"deleted_at,omitempty"`
 }
 
 type GraphEdge struct {
@@ -229,6 +245,75 @@ type GraphEdge struct {
 	CreatedAt  string `json:"created_at"`
 }
 
+type GraphNodeTaskLink struct {
+	NodeID    int64  `json:"node_id"`
+	TaskID    int64  `json:"task_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+type ReadyGraphNode struct {
+	Node               GraphNode `json:"node"`
+	DependsOn          []int64   `json:"depends_on"`
+	WaitingOn          []int64   `json:"waiting_on"`
+	CriticalPathLength int       `json:"critical_path_length"`
+}
+
+type PlanCriticalPath struct {
+	NodeIDs []int64 `json:"node_ids"`
+	Tokens  int     `json:"tokens"`
+}
+
+type PlanTokenBudget struct {
+	Total             int     `json:"total"`
+	Done              int     `json:"done"`
+	Remaining         int     `json:"remaining"`
+	UnestimatedSlices []int64 `json:"unestimated_slices"`
+}
+
+type PlanWave struct {
+	Index   int     `json:"index"`
+	NodeIDs []int64 `json:"node_ids"`
+	Tokens  int     `json:"tokens"`
+}
+
+type PlanRiskNode struct {
+	NodeID    int64  `json:"node_id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	RiskLevel int    `json:"risk_level"`
+}
+
+type PlanFileConflict struct {
+	NodeIDs     []int64  `json:"node_ids"`
+	SharedFiles []string `json:"shared_files"`
+	SameWave    bool     `json:"same_wave"`
+}
+
+type PlanAnalysis struct {
+	PlanNodeID         int64              `json:"plan_node_id"`
+	SliceCount         int                `json:"slice_count"`
+	RemainingSlices    int                `json:"remaining_slices"`
+	CriticalPath       PlanCriticalPath   `json:"critical_path"`
+	Tokens             PlanTokenBudget    `json:"tokens"`
+	Waves              []PlanWave         `json:"waves"`
+	MaxParallelism     int                `json:"max_parallelism"`
+	AverageParallelism float64            `json:"average_parallelism"`
+	CriticalRisks      []PlanRiskNode     `json:"critical_risks"`
+	FileConflicts      []PlanFileConflict `json:"file_conflicts"`
+}
+
+type GraphSubtree struct {
+	Root  GraphNode   `json:"root"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type PlanReadySet struct {
+	PlanNodeID int64            `json:"plan_node_id"`
+	Ready      []ReadyGraphNode `json:"ready"`
+	Waiting    []ReadyGraphNode `json:"waiting"`
+}
+
 type NodeChecklistItem struct {
 	ID        int64  `json:"id"`
 	NodeID    int64  `json:"node_id"`
@@ -248,6 +333,39 @@ type NodeSnapshot struct {
 	AffectedFilesJSON *string `json:"affected_files_json,omitempty"`
 	NextAction        *string `json:"next_action,omitempty"`
 	CreatedAt         string  `json:"created_at"`
+	PayloadJSON       *string `json:"payload_json,omitempty"`
+}
+
+type NodeApproval struct {
+	ID           int64   `json:"id"`
+	NodeID       int64   `json:"node_id"`
+	Decision     string  `json:"decision"`
+	Approver     string  `json:"approver"`
+	Role         string  `json:"role"`
+	Comment      *string `json:"comment,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	SupersededAt *string `json:"superseded_at,omitempty"`
+}
+
+type ApprovalPolicy struct {
+	Raw           string   `json:"raw"`
+	MinApprovals  int      `json:"min_approvals"`
+	RequiredRoles []string `json:"required_roles"`
+}
+
+type ApprovalPolicyStatus struct {
+	Policy        ApprovalPolicy `json:"policy"`
+	Satisfied     bool           `json:"satisfied"`
+	Approvals     int            `json:"approvals"`
+	ApprovedRoles []string       `json:"approved_roles"`
+	MissingRoles  []string       `json:"missing_roles"`
+}
+
+type NodeApprovalResult struct {
+	Approval       NodeApproval         `json:"approval"`
+	Node           GraphNode            `json:"node"`
+	Status         ApprovalPolicyStatus `json:"status"`
+	ChecklistItems []NodeChecklistItem  `json:"checklist_items,omitempty"`
 }
 
 type PlanningRule struct {
@@ -258,6 +376,32 @@ type PlanningRule struct {
 	UpdatedAt          string `json:"updated_at"`
 }
 
+type SupervisorPolicy struct {
+	Enabled               bool   `json:"enabled"`
+	IntervalSeconds       int    `json:"interval_seconds"`
+	StallTimeoutSeconds   int    `json:"stall_timeout_seconds"`
+	MaxRestarts           int    `json:"max_restarts"`
+	RestartBackoffSeconds int    `json:"restart_backoff_seconds"`
+	MaxBackoffSeconds     int    `json:"max_backoff_seconds"`
+	UpdatedAt             string `json:"updated_at"`
+}
+
+type SupervisorPolicyUpdateArgs struct {
+	Enabled               *bool
+	IntervalSeconds       *int
+	StallTimeoutSeconds   *int
+	MaxRestarts           *int
+	RestartBackoffSeconds *int
+	MaxBackoffSeconds     *int
+}
+
+type PlanningRuleUpdateArgs struct {
+	MaxTokenPerSlice *int
+	MaxFilesPerSlice *int
+	ReplanTriggers   []string
+	ApprovalPolicy   *string
+}
+
 type TaskCreateArgs struct {
 	Level           string
 	Title           string
@@ -267,9 +411,26 @@ type TaskCreateArgs struct {
 }
 
 type TaskFilter struct {
-	Level    string
-	Status   string
-	ParentID *int64
+	Level          string
+	Status         string
+	ParentID       *int64
+	IncludeDeleted bool
+}
+
+type TaskTreeNode struct {
+	Task            Task           `json:"task"`
+	RolledUpStatus  string         `json:"rolled_up_status"`
+	TotalCases      int            `json:"total_cases"`
+	DoneCases       int            `json:"done_cases"`
+	ProgressPercent int            `json:"progress_percent"`
+	Children        []TaskTreeNode `json:"children"`
+}
+
+type TaskUpdateArgs struct {
+	Title           *string
+	Priority        *int
+	AssigneeSession *string
+	NextAction      *string
 }
 
 type CaseBeginArgs struct {
@@ -292,10 +453,39 @@ type CaseCompleteArgs struct {
 }
 
 type LockAcquireArgs struct {
-	ScopeType    string
-	ScopePath    string
-	OwnerSession string
-	TTLSeconds   int
+	ScopeType      string
+	ScopePath      string
+	OwnerSession   string
+	OwnerSessionID *int64
+	OwnerThreadID  *int64
+	OwnerCaseID    *int64
+	TTLSeconds     int
+}
+
+type CaseLockClaimArgs struct {
+	CaseID         int64
+	Paths          []string
+	OwnerSession   string
+	OwnerSessionID *int64
+	OwnerThreadID  *int64
+	TTLSeconds     int
+}
+
+type LockFilter struct {
+	State          string
+	OwnerSession   string
+	OwnerSessionID *int64
+	OwnerThreadID  *int64
+	OwnerCaseID    *int64
+}
+
+type LockReleaseByOwnerArgs struct {
+	OwnerSessionID *int64
+	OwnerThreadID  *int64
+	OwnerCaseID    *int64
+	// LockIDs, when set, narrows the release to these locks of the owner.
+	LockIDs []int64
+	Reason  string
 }
 
 type WorktreeCreateArgs struct {
@@ -307,6 +497,8 @@ type WorktreeCreateArgs struct {
 	ParentWorktree *int64
 	OwnerSessionID *int64
 	MergeState     string
+	// BaseRef is the commit the worktree branch was created from.
+	BaseRef string
 }
 
 type MergeRequestArgs struct {
@@ -391,14 +583,53 @@ type ThreadUpdateArgs struct {
 	ScopeTaskIDsJSON *string
 	ScopeCaseIDsJSON *string
 	ScopeNodeIDsJSON *string
+	HeartbeatAt      *string
+	RestartCount     *int
+	LastRestartAt    *string
+	StatusReason     *string
+	RunnerBackend    *string
+	// ProcessID is cleared when set to 0.
+	ProcessID *int64
 }
 
 type ReviewJobCreateArgs struct {
-	MergeRequestID   int64
-	SessionID        int64
-	ReviewerThreadID *int64
-	State            string
-	NotesJSON        json.RawMessage
+	MergeRequestID     int64
+	SessionID          int64
+	ReviewerThreadID   *int64
+	MainMergeRequestID *int64
+	State              string
+	NotesJSON          json.RawMessage
+}
+
+type ReviewFinding struct {
+	ID          int64  `json:"id"`
+	ReviewJobID int64  `json:"review_job_id"`
+	FilePath    string `json:"file_path"`
+	Line        *int   `json:"line,omitempty"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type ReviewFindingArgs struct {
+	FilePath string
+	Line     *int
+	Severity string
+	Message  string
+}
+
+type ReviewSubmitArgs struct {
+	ReviewJobID int64
+	Verdict     string
+	Summary     string
+	Findings    []ReviewFindingArgs
+}
+
+type ReviewSubmission struct {
+	ReviewJob        ReviewJob           `json:"review_job"`
+	Findings         []ReviewFinding     `json:"findings"`
+	MergeRequest     MergeRequest        `json:"merge_request"`
+	MainMergeRequest *MainMergeQueueItem `json:"main_merge_request,omitempty"`
 }
 
 type ReviewJobUpdateArgs struct {
@@ -431,10 +662,34 @@ type GraphNodeCreateArgs struct {
 }
 
 type GraphNodeFilter struct {
-	NodeType string
-	Facet    string
-	Status   string
-	ParentID *int64
+	NodeType       string
+	Facet          string
+	Status         string
+	ParentID       *int64
+	IncludeDeleted bool
+}
+
+type GraphNodeUpdateArgs struct {
+	Title             *string
+	Status            *string
+	Priority          *int
+	Summary           *string
+	RiskLevel         *int
+	TokenEstimate     *int
+	AffectedFilesJSON *string
+	OwnerSessionID    *int64
+	Reason            string
+}
+
+type GraphNodeDeleteResult struct {
+	Node           GraphNode `json:"node"`
+	DeletedNodeIDs []int64   `json:"deleted_node_ids"`
+	EdgesRemoved   int       `json:"edges_removed"`
+}
+
+type GraphNodeTaskLinkFilter struct {
+	NodeID *int64
+	TaskID *int64
 }
 
 type GraphEdgeCreateArgs struct {
@@ -451,6 +706,55 @@ type NodeChecklistUpsertArgs struct {
 	Facet    string
 }
 
+// PlanImportArgs describes a whole outline import. With PlanNodeID the
+// slices go under that plan; otherwise a plan is created under InitiativeID,
+// or under a new initiative titled InitiativeTitle.
+type PlanImportArgs struct {
+	PlanNodeID      int64
+	InitiativeID    int64
+	InitiativeTitle string
+	PlanTitle       string
+	PlanSummary     string
+	PlanChecklist   []PlanImportChecklistItem
+	Slices          []PlanImportSlice
+	Priority        int
+	OwnerSessionID  *int64
+}
+
+type PlanImportSlice struct {
+	Title             string
+	Summary           string
+	Priority          int
+	RiskLevel         *int
+	TokenEstimate     *int
+	AffectedFilesJSON string
+	Checklist         []PlanImportChecklistItem
+	// AfterSlices indexes earlier or later slices of the same import;
+	// AfterNodeIDs names existing graph nodes.
+	AfterSlices  []int
+	AfterNodeIDs []int64
+}
+
+type PlanImportChecklistItem struct {
+	ItemText string
+	Status   string
+}
+
+type PlanImportResult struct {
+	Initiative      *GraphNode  `json:"initiative,omitempty"`
+	Plan            GraphNode   `json:"plan"`
+	Slices          []GraphNode `json:"slices"`
+	DependencyEdges []GraphEdge `json:"dependency_edges"`
+}
+
+type NodeApprovalArgs struct {
+	NodeID          int64
+	Approver        string
+	Role            string
+	Comment         string
+	RequiredChanges []string
+}
+
 type NodeSnapshotCreateArgs struct {
 	NodeID            int64
 	SnapshotType      string
@@ -474,3 +778,148 @@ type InboxMessageCreateArgs struct {
 	ReceiverThreadID int64
 	Message          string
 }
+
+type SearchResult struct {
+	EntityType string  `json:"entity_type"`
+	EntityID   int64   `json:"entity_id"`
+	ParentID   *int64  `json:"parent_id,omitempty"`
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+}
+
+type SearchArgs struct {
+	Query       string
+	EntityTypes []string
+	Limit       int
+	MatchAny    bool
+	Raw         bool
+}
+
+type ThreadUsage struct {
+	ThreadID          int64    `json:"thread_id"`
+	Attempt           int      `json:"attempt"`
+	SessionID         int64    `json:"session_id"`
+	ProviderType      *string  `json:"provider_type,omitempty"`
+	InputTokens       int64    `json:"input_tokens"`
+	CachedInputTokens int64    `json:"cached_input_tokens"`
+	OutputTokens      int64    `json:"output_tokens"`
+	TotalTokens       int64    `json:"total_tokens"`
+	CostUSD           *float64 `json:"cost_usd,omitempty"`
+	Source            string   `json:"source"`
+	RecordedAt        string   `json:"recorded_at"`
+}
+
+type ThreadUsageArgs struct {
+	ThreadID          int64
+	Attempt           int
+	SessionID         int64
+	ProviderType      string
+	InputTokens       int64
+	CachedInputTokens int64
+	OutputTokens      int64
+	TotalTokens       int64
+	CostUSD           *float64
+	Source            string
+}
+
+// ThreadUsageTotal sums every attempt of one thread.
+type ThreadUsageTotal struct {
+	ThreadID          int64    `json:"thread_id"`
+	SessionID         int64    `json:"session_id"`
+	Role              string   `json:"role"`
+	ScopeNodeIDsJSON  *string  `json:"-"`
+	Attempts          int      `json:"attempts"`
+	InputTokens       int64    `json:"input_tokens"`
+	CachedInputTokens int64    `json:"cached_input_tokens"`
+	OutputTokens      int64    `json:"output_tokens"`
+	TotalTokens       int64    `json:"total_tokens"`
+	CostUSD           *float64 `json:"cost_usd,omitempty"`
+}
+
+type SessionBudget struct {
+	SessionID     int64    `json:"session_id"`
+	TokenBudget   *int64   `json:"token_budget,omitempty"`
+	CostBudgetUSD *float64 `json:"cost_budget_usd,omitempty"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+// SessionBudgetArgs sets the limits that are non-nil; zero clears a limit.
+type SessionBudgetArgs struct {
+	SessionID     int64
+	TokenBudget   *int64
+	CostBudgetUSD *float64
+}
+
+// TranscriptTurn is one parsed turn of a thread log; Seq starts at 1.
+type TranscriptTurn struct {
+	ThreadID  int64  `json:"thread_id"`
+	Seq       int    `json:"seq"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+type TranscriptTurnArgs struct {
+	Role    string
+	Content string
+}
+
+// TranscriptCursor is how far a thread log has been parsed: Offset bytes into
+// piece Segment, where piece N is the log's Nth rotated segment once it is
+// rotated and the live log until then. The zero value is the start of the log.
+type TranscriptCursor struct {
+	Segment int
+	Offset  int64
+}
+
+// TranscriptAppendArgs carries the turns parsed between From and To. When
+// ContinueLast is set, the first turn carries on the last stored turn, which
+// was still being written when it was parsed.
+type TranscriptAppendArgs struct {
+	ThreadID     int64
+	From         TranscriptCursor
+	To           TranscriptCursor
+	Turns        []TranscriptTurnArgs
+	ContinueLast bool
+}
+
+// ThreadDirective tracks one directive through pending -> sent ->
+// acknowledged -> applied; failed means delivery was never confirmed.
+type ThreadDirective struct {
+	ID             int64   `json:"id"`
+	ThreadID       int64   `json:"thread_id"`
+	SessionID      int64   `json:"session_id"`
+	Mode           string  `json:"mode"`
+	Directive      string  `json:"directive"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	LastError      *string `json:"last_error,omitempty"`
+	CreatedAt      string  `json:"created_at"`
+	SentAt         *string `json:"sent_at,omitempty"`
+	DeliveredAt    *string `json:"delivered_at,omitempty"`
+	AcknowledgedAt *string `json:"acknowledged_at,omitempty"`
+	AppliedAt      *string `json:"applied_at,omitempty"`
+	UpdatedAt      string  `json:"updated_at"`
+}
+
+type ThreadDirectiveCreateArgs struct {
+	ThreadID  int64
+	SessionID int64
+	Mode      string
+	Directive string
+}
+
+// ThreadDirectiveAdvanceArgs moves a directive forward. CountAttempt records
+// one more delivery attempt.
+type ThreadDirectiveAdvanceArgs struct {
+	Status       string
+	LastError    *string
+	CountAttempt bool
+}
+
+type ThreadDirectiveFilter struct {
+	ThreadID  int64
+	SessionID int64
+	Status    string
+}
@@ -105,8 +105,10 @@ func (c *Client) PaneExists(ctx context.Context, paneID string) bool {
 	if strings.TrimSpace(paneID) == "" {
 		return false
 	}
-	_, err := c.run(ctx, "tmux", "display-message", "-p", "-t", paneID, "#{pane_id}")
-	return err == nil
+	// Some tmux versions exit 0 with empty output for an unknown target, so
+	// the echoed pane id must match rather than relying on the exit status.
+	output, err := c.run(ctx, "tmux", "display-message", "-p", "-t", paneID, "#{pane_id}")
+	return err == nil && output == strings.TrimSpace(paneID)
 }
 
 // SendKeys sends text via load-buffer + paste-buffer pattern for reliable